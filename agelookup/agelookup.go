@@ -0,0 +1,149 @@
+// Package agelookup は age で暗号化されたファイルやインラインの暗号文を復号する探索関数を提供します。
+// SOPS のツールチェーンを使わずに age を利用しているチーム向けです。
+//
+// Package agelookup provides lookup functions that decrypt age-encrypted files or inline ciphertexts.
+// It is intended for teams using age without the full SOPS toolchain.
+package agelookup
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ebi-yade/go-tempura"
+)
+
+// Decrypter は登録された identity を使って age の暗号文を復号します。
+//
+// Decrypter decrypts age ciphertexts with the registered identities.
+type Decrypter struct {
+	Identities []age.Identity
+}
+
+func New(identities ...age.Identity) *Decrypter {
+	return &Decrypter{Identities: identities}
+}
+
+// File は、prefixを取り除いたキーを暗号化されたファイルのパスとして扱う探索関数を返します。
+// ファイルが存在しない場合は見つからなかったものとして扱います。
+//
+// File returns a lookup function that treats the key, with the prefix removed, as the path of an encrypted file.
+// A missing file is treated as not found.
+func (d *Decrypter) File() tempura.LookupAnyWithError {
+	return tempura.FuncWithError(func(path string) (string, bool, error) {
+		f, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer f.Close()
+
+		val, err := d.decrypt(f)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+		return val, true, nil
+	})
+}
+
+// Inline は、prefixを取り除いたキーを暗号文そのものとして扱う探索関数を返します。
+// ASCII armor 形式と、バイナリ形式を base64 エンコードしたものの両方を受け付けます。
+//
+// Inline returns a lookup function that treats the key, with the prefix removed, as the ciphertext itself.
+// Both the ASCII armored format and the base64-encoded binary format are accepted.
+func (d *Decrypter) Inline() tempura.LookupAnyWithError {
+	return tempura.FuncWithError(func(ciphertext string) (string, bool, error) {
+		var src io.Reader
+		if trimmed := strings.TrimSpace(ciphertext); strings.HasPrefix(trimmed, armor.Header) {
+			src = strings.NewReader(trimmed)
+		} else {
+			raw, err := base64.StdEncoding.DecodeString(ciphertext)
+			if err != nil {
+				return "", false, fmt.Errorf("ciphertext is neither armored nor base64-encoded: %w", err)
+			}
+			src = bytes.NewReader(raw)
+		}
+
+		val, err := d.decrypt(src)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to decrypt inline ciphertext: %w", err)
+		}
+		return val, true, nil
+	})
+}
+
+func (d *Decrypter) decrypt(src io.Reader) (string, error) {
+	if len(d.Identities) == 0 {
+		return "", ErrNoIdentity
+	}
+
+	br := bufio.NewReader(src)
+	if peek, err := br.Peek(len(armor.Header)); err == nil && string(peek) == armor.Header {
+		src = armor.NewReader(br)
+	} else {
+		src = br
+	}
+
+	r, err := age.Decrypt(src, d.Identities...)
+	if err != nil {
+		return "", err
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// =================================================================================
+// Loading identities from disk or env
+// =================================================================================
+
+// IdentitiesFromFile は age-keygen が出力する形式の identity ファイルを読み込みます。
+//
+// IdentitiesFromFile loads an identity file in the format written by age-keygen.
+func IdentitiesFromFile(path string) ([]age.Identity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open identity file: %w", err)
+	}
+	defer f.Close()
+
+	ids, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identity file %s: %w", path, err)
+	}
+	return ids, nil
+}
+
+// IdentitiesFromEnv は環境変数の値を identity ファイルの中身として読み込みます。
+//
+// IdentitiesFromEnv loads the value of the environment variable as the content of an identity file.
+func IdentitiesFromEnv(name string) ([]age.Identity, error) {
+	val, ok := os.LookupEnv(name)
+	if !ok || val == "" {
+		return nil, fmt.Errorf("environment variable %s: %w", name, ErrNoIdentity)
+	}
+
+	ids, err := age.ParseIdentities(strings.NewReader(val))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identities in %s: %w", name, err)
+	}
+	return ids, nil
+}
+
+// =================================================================================
+// Defined errors that you can handle with errors.Is / errors.As
+// =================================================================================
+
+var ErrNoIdentity = fmt.Errorf("no age identity available")
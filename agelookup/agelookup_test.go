@@ -0,0 +1,172 @@
+package agelookup_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/agelookup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encrypt(t *testing.T, recipient age.Recipient, plaintext string, armored bool) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	var dst io.Writer = buf
+	var armorWriter io.WriteCloser
+	if armored {
+		armorWriter = armor.NewWriter(buf)
+		dst = armorWriter
+	}
+
+	w, err := age.Encrypt(dst, recipient)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, plaintext)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	if armorWriter != nil {
+		require.NoError(t, armorWriter.Close())
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecrypter(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	binaryPath := filepath.Join(dir, "secret.age")
+	require.NoError(t, os.WriteFile(binaryPath, encrypt(t, identity.Recipient(), "binary-secret", false), 0o600))
+	armoredPath := filepath.Join(dir, "secret.age.asc")
+	require.NoError(t, os.WriteFile(armoredPath, encrypt(t, identity.Recipient(), "armored-secret", true), 0o600))
+
+	armoredInline := string(encrypt(t, identity.Recipient(), "inline-armored", true))
+	base64Inline := base64.StdEncoding.EncodeToString(encrypt(t, identity.Recipient(), "inline-base64", false))
+
+	tests := []struct {
+		name     string
+		lookup   tempura.MultiLookup
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "binary file",
+			lookup:   tempura.MultiLookup{tempura.DotPrefix("age"): agelookup.New(identity).File()},
+			args:     []string{"age." + binaryPath},
+			expected: "binary-secret",
+		},
+		{
+			name:     "armored file",
+			lookup:   tempura.MultiLookup{tempura.DotPrefix("age"): agelookup.New(identity).File()},
+			args:     []string{"age." + armoredPath},
+			expected: "armored-secret",
+		},
+		{
+			name:     "armored inline ciphertext",
+			lookup:   tempura.MultiLookup{tempura.DotPrefix("age"): agelookup.New(identity).Inline()},
+			args:     []string{"age." + armoredInline},
+			expected: "inline-armored",
+		},
+		{
+			name:     "base64 inline ciphertext",
+			lookup:   tempura.MultiLookup{tempura.DotPrefix("age"): agelookup.New(identity).Inline()},
+			args:     []string{"age." + base64Inline},
+			expected: "inline-base64",
+		},
+		{
+			name: "missing file falls through to the next arg",
+			lookup: tempura.MultiLookup{
+				tempura.DotPrefix("age"):     agelookup.New(identity).File(),
+				tempura.DotPrefix("default"): tempura.Func(func(val string) (string, bool) { return val, true }),
+			},
+			args:     []string{"age." + filepath.Join(dir, "missing.age"), "default.fallback"},
+			expected: "fallback",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "wrong identity",
+			lookup: tempura.MultiLookup{tempura.DotPrefix("age"): agelookup.New(other).File()},
+			args:   []string{"age." + binaryPath},
+			checkErr: func(t *testing.T, err error) {
+				expected := &age.NoIdentityMatchError{}
+				assert.ErrorAs(t, err, &expected)
+			},
+		},
+		{
+			name:   "no identities",
+			lookup: tempura.MultiLookup{tempura.DotPrefix("age"): agelookup.New().Inline()},
+			args:   []string{"age." + base64Inline},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, agelookup.ErrNoIdentity)
+			},
+		},
+		{
+			name:   "malformed inline ciphertext",
+			lookup: tempura.MultiLookup{tempura.DotPrefix("age"): agelookup.New(identity).Inline()},
+			args:   []string{"age.not a ciphertext"},
+			checkErr: func(t *testing.T, err error) {
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := tt.lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestIdentitiesFromFile(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "keys.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# created: test\n"+identity.String()+"\n"), 0o600))
+
+	ids, err := agelookup.IdentitiesFromFile(path)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+
+	ciphertext := base64.StdEncoding.EncodeToString(encrypt(t, identity.Recipient(), "from-file", false))
+	val, ok, err := agelookup.New(ids...).Inline()(ciphertext)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "from-file", val)
+}
+
+func TestIdentitiesFromEnv(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	t.Setenv("TEMPURA_TEST_AGE_KEY", identity.String())
+	ids, err := agelookup.IdentitiesFromEnv("TEMPURA_TEST_AGE_KEY")
+	require.NoError(t, err)
+	assert.Len(t, ids, 1)
+
+	_, err = agelookup.IdentitiesFromEnv("TEMPURA_TEST_AGE_KEY_UNSET")
+	assert.ErrorIs(t, err, agelookup.ErrNoIdentity)
+}
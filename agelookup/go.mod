@@ -0,0 +1,21 @@
+module github.com/ebi-yade/go-tempura/agelookup
+
+go 1.21
+
+require (
+	filippo.io/age v1.2.1
+	github.com/ebi-yade/go-tempura v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/crypto v0.24.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.24.0 // indirect
+	golang.org/x/time v0.8.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/ebi-yade/go-tempura => ../
@@ -0,0 +1,192 @@
+package tempura
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// =================================================================================
+// Audit log of accesses to sensitive prefixes
+// =================================================================================
+
+// AuditEvent は、機密として指定された prefix に対する1回の探索の記録です。値そのものは含みません。
+//
+// AuditEvent is a record of a single lookup against a prefix marked sensitive. It never contains the value itself.
+type AuditEvent struct {
+	Time time.Time
+	// Subject は WithAuditSubject で ctx に設定された、探索を行った主体 (ユーザーやジョブの名前など) です。
+	// Subject is who performed the lookup, such as the name of a user or job, set in ctx via WithAuditSubject.
+	Subject  string
+	Prefix   Prefix
+	Arg      string
+	Key      string
+	Provider string
+	// Outcome は found, not_found, error, cache_hit, negative_cache_hit のいずれかです。
+	// Outcome is one of found, not_found, error, cache_hit and negative_cache_hit.
+	Outcome string
+	Err     error
+}
+
+// AuditSink は AuditEvent を受け取ります。探索と並行に呼び出されるため、実装は goroutine セーフである必要があります。
+//
+// AuditSink receives AuditEvents. It is called concurrently with lookups, so implementations must be goroutine-safe.
+type AuditSink interface {
+	Audit(ctx context.Context, e AuditEvent)
+}
+
+type AuditSinkFunc func(ctx context.Context, e AuditEvent)
+
+func (fn AuditSinkFunc) Audit(ctx context.Context, e AuditEvent) {
+	fn(ctx, e)
+}
+
+type audit struct {
+	sink      AuditSink
+	sensitive map[Prefix]bool
+}
+
+// WithAudit は、 sensitive に含まれる prefix に対する全ての探索 (キャッシュからの解決を含む) を sink に記録します。
+// sensitive を指定しない場合は、全ての prefix が記録の対象になります。
+// シークレットへのアクセスの監査の要件を満たすためのもので、複数回指定した場合はそれぞれの sink に記録されます。
+// WithRedactKeys を指定した場合は、 Arg と Key もキーの名前を伏せたものになります。
+//
+// WithAudit records every lookup, including ones resolved from caches, against the prefixes in sensitive to sink.
+// If no sensitive prefixes are given, every prefix is audited.
+// It is meant to satisfy secret-access auditing requirements; if specified multiple times, events are recorded to each sink.
+// With WithRedactKeys, Arg and Key hold redacted key names as well.
+//
+//	sink, err := tempura.NewJSONFileAuditSink("/var/log/tempura/audit.jsonl")
+//	lookup := tempura.MultiLookup{...}.BindContext(ctx, tempura.WithAudit(sink, tempura.DotPrefix("secret")))
+func WithAudit(sink AuditSink, sensitive ...Prefix) Option {
+	a := audit{sink: sink}
+	if len(sensitive) > 0 {
+		a.sensitive = make(map[Prefix]bool, len(sensitive))
+		for _, prefix := range sensitive {
+			a.sensitive[prefix] = true
+		}
+	}
+	return func(o *options) {
+		o.audits = append(o.audits, a)
+	}
+}
+
+type auditSubjectKey struct{}
+
+// WithAuditSubject は、 ctx で行われる探索の主体を subject として AuditEvent に記録させます。
+//
+// WithAuditSubject makes AuditEvents record subject as who performed lookups with ctx.
+func WithAuditSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, auditSubjectKey{}, subject)
+}
+
+// recordAudit は、 prefix が機密として指定されている sink に e を記録します。
+//
+// recordAudit records e to the sinks for which prefix is marked sensitive.
+func (m *MultiLookupContext) recordAudit(ctx context.Context, e AuditEvent) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	once := false
+	for _, a := range m.opts.audits {
+		if a.sensitive != nil && !a.sensitive[e.Prefix] {
+			continue
+		}
+		if !once {
+			once = true
+			e.Time = time.Now()
+			e.Subject, _ = ctx.Value(auditSubjectKey{}).(string)
+			e.Arg, e.Key = m.keyName(e.Arg), m.keyName(e.Key)
+		}
+		a.sink.Audit(ctx, e)
+	}
+}
+
+// =================================================================================
+// Built-in sinks
+// =================================================================================
+
+// NewSlogAuditSink は、 AuditEvent を logger に slog.LevelInfo で出力する AuditSink を返します。
+//
+// NewSlogAuditSink returns an AuditSink that writes AuditEvents to logger at slog.LevelInfo.
+func NewSlogAuditSink(logger *slog.Logger) AuditSink {
+	return AuditSinkFunc(func(ctx context.Context, e AuditEvent) {
+		attrs := []slog.Attr{
+			slog.String("subject", e.Subject),
+			slog.Any("prefix", e.Prefix),
+			slog.String("arg", e.Arg),
+			slog.String("key", e.Key),
+			slog.String("provider", e.Provider),
+			slog.String("outcome", e.Outcome),
+		}
+		if e.Err != nil {
+			attrs = append(attrs, slog.Any("error", e.Err))
+		}
+		logger.LogAttrs(ctx, slog.LevelInfo, "audit", attrs...)
+	})
+}
+
+// JSONFileAuditSink は、 AuditEvent を1行に1つの JSON としてファイルに追記する AuditSink です。
+//
+// JSONFileAuditSink is an AuditSink that appends AuditEvents to a file as one JSON object per line.
+type JSONFileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewJSONFileAuditSink は、 path のファイルを所有者のみが読み書きできる権限で追記用に開きます。
+//
+// NewJSONFileAuditSink opens the file at path for appending, readable and writable only by the owner.
+func NewJSONFileAuditSink(path string) (*JSONFileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &JSONFileAuditSink{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+type jsonAuditEvent struct {
+	Time     time.Time `json:"time"`
+	Subject  string    `json:"subject,omitempty"`
+	Prefix   string    `json:"prefix"`
+	Arg      string    `json:"arg"`
+	Key      string    `json:"key"`
+	Provider string    `json:"provider,omitempty"`
+	Outcome  string    `json:"outcome"`
+	Error    string    `json:"error,omitempty"`
+}
+
+func (s *JSONFileAuditSink) Audit(ctx context.Context, e AuditEvent) {
+	je := jsonAuditEvent{
+		Time:     e.Time,
+		Subject:  e.Subject,
+		Prefix:   fmt.Sprint(e.Prefix),
+		Arg:      e.Arg,
+		Key:      e.Key,
+		Provider: e.Provider,
+		Outcome:  e.Outcome,
+	}
+	if e.Err != nil {
+		je.Error = e.Err.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enc.Encode(je); err != nil {
+		slog.Warn("failed to write audit log", slog.Any("error", err))
+	}
+}
+
+// Close はファイルを閉じます。
+//
+// Close closes the file.
+func (s *JSONFileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
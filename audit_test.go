@@ -0,0 +1,129 @@
+package tempura_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAudit(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		if key == "FAIL" {
+			return "", false, errUnavailable
+		}
+		return "secret-of-" + key, true, nil
+	}
+	keyAsValue := func(key string) (string, bool) {
+		return key, true
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []map[string]any
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name: "found",
+			args: []string{"secret.DB_PASS"},
+			expected: []map[string]any{
+				{"subject": "deploy-job", "prefix": "secret", "arg": "secret.DB_PASS", "key": "DB_PASS", "provider": "LookupAnyWithContextError", "outcome": "found"},
+			},
+		},
+		{
+			name: "cache hit",
+			args: []string{"secret.CACHED"},
+			expected: []map[string]any{
+				{"subject": "deploy-job", "prefix": "secret", "arg": "secret.CACHED", "key": "CACHED", "outcome": "cache_hit"},
+			},
+		},
+		{
+			name:     "prefix not marked sensitive",
+			args:     []string{"env.HOME"},
+			expected: nil,
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "error",
+			args: []string{"secret.FAIL"},
+			expected: []map[string]any{
+				{"subject": "deploy-job", "prefix": "secret", "arg": "secret.FAIL", "key": "FAIL", "provider": "LookupAnyWithContextError", "outcome": "error", "error": "service unavailable"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+			sink, err := tempura.NewJSONFileAuditSink(path)
+			require.NoError(t, err)
+			cache := tempura.NewTTLCache(time.Minute)
+			cache.Set("secret.CACHED", "cached")
+			ctx := tempura.WithAuditSubject(context.Background(), "deploy-job")
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+				tempura.DotPrefix("env"):    tempura.Func(keyAsValue),
+			}.BindContext(ctx, tempura.WithAudit(sink, tempura.DotPrefix("secret")), tempura.WithCache(cache))
+
+			_, _ = lookup.FuncMapValue(tt.args...)
+			require.NoError(t, sink.Close())
+
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+			b, err := os.ReadFile(path)
+			require.NoError(t, err)
+			var events []map[string]any
+			scanner := bufio.NewScanner(bytes.NewReader(b))
+			for scanner.Scan() {
+				event := map[string]any{}
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+				assert.NotEmpty(t, event["time"])
+				assert.NotContains(t, scanner.Text(), "secret-of-", "values should never be audited")
+				delete(event, "time")
+				events = append(events, event)
+			}
+			assert.Equal(t, tt.expected, events)
+		})
+	}
+}
+
+func TestNewSlogAuditSink(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	sink := tempura.NewSlogAuditSink(slog.New(slog.NewJSONHandler(buf, nil)))
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.Func(func(key string) (string, bool) {
+			return "secret-of-" + key, true
+		}),
+	}.BindContext(tempura.WithAuditSubject(context.Background(), "alice"), tempura.WithAudit(sink))
+
+	_, err := lookup.FuncMapValue("secret.DB_PASS")
+	require.NoError(t, err)
+
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "audit", record["msg"])
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "alice", record["subject"])
+	assert.Equal(t, "secret.DB_PASS", record["arg"])
+	assert.Equal(t, "found", record["outcome"])
+	assert.NotContains(t, buf.String(), "secret-of-")
+}
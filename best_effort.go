@@ -0,0 +1,49 @@
+package tempura
+
+import (
+	"context"
+	"errors"
+)
+
+// BestEffort は、 fn を失敗しても描画を止めない探索関数として包むミドルウェアです。
+// fn のエラーはログ、メトリクス、フック、スパンに記録されますが、探索の結果としては見つからなかったものとして扱われ、次に一致する prefix やフォールバックの引数へ進みます。
+// 一時的な失敗を見つからなかったこととしてキャッシュすることはありません。
+//
+// BestEffort is a middleware wrapping fn as a lookup function whose failures never abort the render.
+// Errors of fn are recorded in logs, metrics, hooks and spans, but the lookup treats them as misses and continues to the next matching prefix or fallback arg.
+// Transient failures are never cached as misses.
+//
+//	lookup := tempura.MultiLookup{
+//		tempura.DotPrefix("remote"): tempura.BestEffort(remoteLookup),
+//	}.BindContext(ctx)
+//	// en: {{ lookup "remote.FEATURE" "default.off" }} renders "off" while the remote is down
+func BestEffort(fn LookupFunc) LookupAnyWithContextError {
+	next := withContextError("BestEffort", fn)
+	return func(ctx context.Context, val string) (any, bool, error) {
+		v, ok, err := next(ctx, val)
+		if err != nil {
+			return nil, false, bestEffortError{err: err}
+		}
+		return v, ok, nil
+	}
+}
+
+// bestEffortError は、 BestEffort の探索関数が返したエラーを表し、 runTask で見つからなかったことに変換されます。
+//
+// bestEffortError represents an error returned by a BestEffort lookup function, turned into a miss by runTask.
+type bestEffortError struct {
+	err error
+}
+
+func (e bestEffortError) Error() string {
+	return e.err.Error()
+}
+
+func (e bestEffortError) Unwrap() error {
+	return e.err
+}
+
+func isBestEffort(err error) bool {
+	var bee bestEffortError
+	return errors.As(err, &bee)
+}
@@ -0,0 +1,114 @@
+package tempura_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBestEffort(t *testing.T) {
+	t.Parallel()
+
+	fetchRemote := func(ctx context.Context, key string) (string, bool, error) {
+		if key == "FAIL" {
+			return "", false, errUnavailable
+		}
+		return "remote-" + key, key != "MISSING", nil
+	}
+	keyAsValue := func(key string) (string, bool) {
+		return key, true
+	}
+
+	tests := []struct {
+		name     string
+		fn       tempura.LookupFunc
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "found",
+			fn:       tempura.FuncWithContextError(fetchRemote),
+			args:     []string{"remote.FEATURE", "default.off"},
+			expected: "remote-FEATURE",
+		},
+		{
+			name:     "error falls back",
+			fn:       tempura.FuncWithContextError(fetchRemote),
+			args:     []string{"remote.FAIL", "default.off"},
+			expected: "off",
+		},
+		{
+			name:     "synchronous lookup function",
+			fn:       tempura.FuncWithError(func(key string) (string, bool, error) { return "", false, errUnavailable }),
+			args:     []string{"remote.FEATURE", "default.off"},
+			expected: "off",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "error without fallback is reported as not found",
+			fn:   tempura.FuncWithContextError(fetchRemote),
+			args: []string{"remote.FAIL"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+				assert.NotErrorIs(t, err, errUnavailable)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var recorded error
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("remote"):  tempura.BestEffort(tt.fn),
+				tempura.DotPrefix("default"): tempura.Func(keyAsValue),
+			}.BindContext(context.Background(), tempura.WithHooks(tempura.Hooks{
+				AfterLookup: func(ctx context.Context, e tempura.LookupEvent) {
+					if e.Err != nil {
+						recorded = e.Err
+					}
+				},
+			}))
+
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				assert.ErrorIs(t, recorded, errUnavailable, "the error should still be recorded")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestBestEffort_NegativeTTL(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	flaky := func(ctx context.Context, key string) (string, bool, error) {
+		if calls.Add(1) == 1 {
+			return "", false, errUnavailable
+		}
+		return "remote-" + key, true, nil
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("remote"): tempura.BestEffort(tempura.FuncWithContextError(flaky)),
+	}.BindContext(context.Background(), tempura.WithCache(tempura.NewTTLCache(time.Minute, tempura.NegativeTTL(time.Minute))))
+
+	_, err := lookup.FuncMapValue("remote.FEATURE")
+	require.ErrorIs(t, err, tempura.ErrNotFound)
+
+	val, err := lookup.FuncMapValue("remote.FEATURE")
+	require.NoError(t, err, "a transient failure should not be cached as a miss")
+	assert.Equal(t, "remote-FEATURE", val)
+}
@@ -0,0 +1,62 @@
+// Package buildlookup は、 runtime/debug のビルド情報からバージョンなどのメタデータを返す探索関数を提供します。
+//
+// Package buildlookup provides a lookup function that returns version metadata from the build info of runtime/debug.
+package buildlookup
+
+import (
+	"runtime/debug"
+
+	"github.com/ebi-yade/go-tempura"
+)
+
+// New は、prefixを取り除いたキーに応じて info の値を返す探索関数を返します。
+// info が nil の場合は debug.ReadBuildInfo の結果が使われます。
+//
+//   - "version": メインモジュールのバージョン / the version of the main module
+//   - "path": メインモジュールのパス / the path of the main module
+//   - "goversion": ビルドに使われた Go のバージョン / the Go version used for the build
+//   - "revision": VCS のリビジョン / the VCS revision
+//   - "time": VCS のコミット時刻 / the VCS commit time
+//   - "dirty": 作業ツリーに変更があったかどうかの bool / whether the working tree was modified, as bool
+//
+// New returns a lookup function that returns the values of info depending on the key with the prefix removed.
+// If info is nil, the result of debug.ReadBuildInfo is used.
+// Values missing from the build info (e.g. VCS settings under `go run`) are treated as not found.
+func New(info *debug.BuildInfo) tempura.LookupAny {
+	if info == nil {
+		info, _ = debug.ReadBuildInfo()
+	}
+
+	return func(key string) (any, bool) {
+		if info == nil {
+			return nil, false
+		}
+
+		switch key {
+		case "version":
+			return info.Main.Version, info.Main.Version != ""
+		case "path":
+			return info.Main.Path, info.Main.Path != ""
+		case "goversion":
+			return info.GoVersion, info.GoVersion != ""
+		case "revision":
+			return setting(info, "vcs.revision")
+		case "time":
+			return setting(info, "vcs.time")
+		case "dirty":
+			val, ok := setting(info, "vcs.modified")
+			return val == "true", ok
+		}
+
+		return nil, false
+	}
+}
+
+func setting(info *debug.BuildInfo, key string) (string, bool) {
+	for _, s := range info.Settings {
+		if s.Key == key {
+			return s.Value, true
+		}
+	}
+	return "", false
+}
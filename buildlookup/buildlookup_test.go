@@ -0,0 +1,100 @@
+package buildlookup_test
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/buildlookup"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	info := &debug.BuildInfo{
+		GoVersion: "go1.21.0",
+		Main:      debug.Module{Path: "example.com/app", Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+			{Key: "vcs.time", Value: "2024-04-01T00:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("build"):   buildlookup.New(info),
+		tempura.DotPrefix("minimal"): buildlookup.New(&debug.BuildInfo{GoVersion: "go1.21.0"}),
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "version",
+			args:     []string{"build.version"},
+			expected: "v1.2.3",
+		},
+		{
+			name:     "path",
+			args:     []string{"build.path"},
+			expected: "example.com/app",
+		},
+		{
+			name:     "goversion",
+			args:     []string{"build.goversion"},
+			expected: "go1.21.0",
+		},
+		{
+			name:     "revision",
+			args:     []string{"build.revision"},
+			expected: "0123456789abcdef",
+		},
+		{
+			name:     "time",
+			args:     []string{"build.time"},
+			expected: "2024-04-01T00:00:00Z",
+		},
+		{
+			name:     "dirty",
+			args:     []string{"build.dirty"},
+			expected: true,
+		},
+		{
+			name:     "missing vcs settings fall through to the next arg",
+			args:     []string{"minimal.revision", "build.revision"},
+			expected: "0123456789abcdef",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "unknown key",
+			args: []string{"build.branch"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestNew_ReadBuildInfo(t *testing.T) {
+	t.Parallel()
+
+	val, ok := buildlookup.New(nil)("goversion")
+	assert.True(t, ok)
+	assert.NotEmpty(t, val)
+}
@@ -0,0 +1,354 @@
+package tempura
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// =================================================================================
+// Cache for MultiLookupContext
+// =================================================================================
+
+// Cache は WithCache で MultiLookupContext に登録するキャッシュです。キーは prefix を含む引数そのものです。
+// 複数の goroutine から同時に呼び出されるため、実装は goroutine セーフである必要があります。
+//
+// Cache is a cache registered to MultiLookupContext via WithCache. The keys are the args themselves, including the prefix.
+// Implementations must be safe for concurrent use by multiple goroutines.
+//
+// Invalidate, Clear は、秘密情報をローテーションした後などに、プロセスを再起動せずに値を再取得させるために使います。
+// Invalidate and Clear are used to force a refresh without restarting the process, e.g. after rotating a secret.
+type Cache interface {
+	Get(key string) (any, bool)
+	Set(key string, val any)
+	Invalidate(key string)
+	Clear()
+}
+
+// NegativeCache は、値が見つからなかったことも記録できる Cache です。
+// 記録されている間、その引数に対する探索関数は呼び出されません。
+//
+// NegativeCache is a Cache that can also record that a value was not found.
+// While a miss is recorded, lookup functions are not called for the arg.
+type NegativeCache interface {
+	Cache
+	GetMiss(key string) bool
+	SetMiss(key string)
+}
+
+// StaleCache は、期限切れの値を再取得の間だけ返し続けられる Cache です (stale-while-revalidate)。
+// GetStale は期限切れでも保持している値を返し、その値について最初の呼び出しでのみ revalidate を true にします。
+// MultiLookupContext は revalidate が true のとき、値をそのまま返しつつバックグラウンドで再取得して Set します。
+//
+// StaleCache is a Cache that can keep serving expired values while they are refreshed (stale-while-revalidate).
+// GetStale returns the value it still holds even if expired, with revalidate set to true only on the first call for that value.
+// When revalidate is true, MultiLookupContext returns the value as is and refreshes it in the background with Set.
+type StaleCache interface {
+	Cache
+	GetStale(key string) (val any, revalidate bool, ok bool)
+}
+
+// TTLCache は、値を一定時間だけ保持する Cache です。
+// テンプレートは同じ秘密情報を何度も参照することが多いため、参照ごとにバックエンドへ問い合わせることを避けられます。
+//
+// TTLCache is a Cache that holds values for a fixed duration.
+// Templates frequently reference the same secret many times, so it avoids hitting the backend for every reference.
+type TTLCache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	staleTTL    time.Duration
+	prefixTTLs  []prefixTTL
+
+	mu        sync.Mutex
+	entries   map[string]ttlEntry
+	lastSweep time.Time
+}
+
+type prefixTTL struct {
+	prefix Prefix
+	ttl    time.Duration
+}
+
+type ttlEntry struct {
+	val          any
+	miss         bool
+	staleAt      time.Time
+	expiresAt    time.Time
+	revalidating bool
+}
+
+// TTLCacheOption は NewTTLCache で TTLCache の振る舞いを変更します。
+//
+// TTLCacheOption changes the behavior of TTLCache via NewTTLCache.
+type TTLCacheOption func(*TTLCache)
+
+// NegativeTTL は、見つからなかったことを ttl の間だけ記録します (ネガティブキャッシュ)。
+// 省略可能なキーを多く含むテンプレートが、見つからないままのキーのためにリモートのバックエンドへ問い合わせ続けることを防ぎます。
+// 通常は値の TTL よりも短く設定します。
+//
+// NegativeTTL records that a value was not found for ttl (negative caching).
+// It keeps templates full of optional keys from hammering remote backends for keys that will keep missing.
+// It is usually shorter than the TTL of values.
+func NegativeTTL(ttl time.Duration) TTLCacheOption {
+	return func(c *TTLCache) {
+		c.negativeTTL = ttl
+	}
+}
+
+// PrefixTTL は、 prefix に一致するキーの値の TTL を ttl で上書きします。複数指定した場合は最初に一致したものが使われます。
+//
+// PrefixTTL overrides the TTL of values whose keys match prefix with ttl. If specified multiple times, the first match wins.
+func PrefixTTL(prefix Prefix, ttl time.Duration) TTLCacheOption {
+	return func(c *TTLCache) {
+		c.prefixTTLs = append(c.prefixTTLs, prefixTTL{prefix: prefix, ttl: ttl})
+	}
+}
+
+// StaleWhileRevalidate は、 TTL が切れた値をさらに window の間だけ保持し、 StaleCache として返し続けます。
+// バックエンドが遅い場合でも、再取得をバックグラウンドで行うことで描画の待ち時間を一定に保てます。
+// 再取得に失敗した場合、値は window が過ぎるまで返され続け、その後は同期的に取得されます。
+//
+// StaleWhileRevalidate holds values for another window after their TTL expires and keeps serving them as a StaleCache.
+// Refreshing in the background keeps render latency flat even when backends are slow.
+// If a refresh fails, the value keeps being served until window passes, and is fetched synchronously after that.
+func StaleWhileRevalidate(window time.Duration) TTLCacheOption {
+	return func(c *TTLCache) {
+		c.staleTTL = window
+	}
+}
+
+func NewTTLCache(ttl time.Duration, opts ...TTLCacheOption) *TTLCache {
+	c := &TTLCache{
+		ttl:     ttl,
+		entries: make(map[string]ttlEntry),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *TTLCache) Get(key string) (any, bool) {
+	entry, ok := c.get(key)
+	if !ok || entry.miss || !time.Now().Before(entry.staleAt) {
+		return nil, false
+	}
+	return entry.val, true
+}
+
+func (c *TTLCache) GetStale(key string) (val any, revalidate bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || entry.miss {
+		return nil, false, false
+	}
+	now := time.Now()
+	if !now.Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false, false
+	}
+	if now.Before(entry.staleAt) || entry.revalidating {
+		return entry.val, false, true
+	}
+	entry.revalidating = true
+	c.entries[key] = entry
+	return entry.val, true, true
+}
+
+func (c *TTLCache) Set(key string, val any) {
+	ttl := c.ttl
+	for _, p := range c.prefixTTLs {
+		if p.prefix.Match(key) {
+			ttl = p.ttl
+			break
+		}
+	}
+	c.set(key, ttlEntry{val: val}, ttl, c.staleTTL)
+}
+
+func (c *TTLCache) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+func (c *TTLCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]ttlEntry)
+}
+
+func (c *TTLCache) GetMiss(key string) bool {
+	entry, ok := c.get(key)
+	return ok && entry.miss
+}
+
+// SetMiss は、 NegativeTTL が指定されている場合にのみ見つからなかったことを記録します。
+//
+// SetMiss records the miss only if NegativeTTL is specified.
+func (c *TTLCache) SetMiss(key string) {
+	if c.negativeTTL <= 0 {
+		return
+	}
+	c.set(key, ttlEntry{miss: true}, c.negativeTTL, 0)
+}
+
+func (c *TTLCache) get(key string) (ttlEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return ttlEntry{}, false
+	}
+	if !time.Now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return ttlEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *TTLCache) set(key string, entry ttlEntry, ttl, staleTTL time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entry.staleAt = now.Add(ttl)
+	entry.expiresAt = entry.staleAt.Add(staleTTL)
+	c.entries[key] = entry
+
+	// 参照されなくなったキーが溜まり続けないよう、 TTL ごとに期限切れのエントリを掃除する
+	// en: Sweep expired entries once per TTL so that keys no longer referenced don't pile up
+	if now.Sub(c.lastSweep) >= c.ttl {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+}
+
+// LRUCache は、エントリ数と概算のメモリ使用量に上限を持つ Cache です。上限を超えると最も長く使われていないエントリから捨てられます。
+// 多数の異なるキーを解決し続ける長時間稼働のサーバーで、メモ化によってメモリが際限なく増えることを防ぎます。
+//
+// LRUCache is a Cache bounded by the number of entries and the approximate memory usage. When a limit is exceeded, the least recently used entries are evicted.
+// It prevents memoization from growing without limit in long-running servers resolving thousands of distinct keys.
+type LRUCache struct {
+	maxEntries int
+	maxBytes   int
+
+	mu      sync.Mutex
+	ll      *list.List
+	entries map[string]*list.Element
+	bytes   int
+}
+
+type lruEntry struct {
+	key  string
+	val  any
+	size int
+}
+
+// NewLRUCache は LRUCache を生成します。 maxEntries, maxBytes が 0 以下の場合、その上限は設けません。
+// メモリ使用量はキーの長さと、 string, []byte の場合は値の長さから概算されます (それ以外の型は固定のサイズとして扱います)。
+//
+// NewLRUCache creates an LRUCache. If maxEntries or maxBytes is 0 or less, the corresponding limit is not applied.
+// Memory usage is approximated from the length of the key and, for string and []byte, of the value (other types count as a fixed size).
+func NewLRUCache(maxEntries, maxBytes int) *LRUCache {
+	return &LRUCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ll:         list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*lruEntry).val, true
+}
+
+func (c *LRUCache) Set(key string, val any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := len(key) + approximateSize(val)
+	if c.maxBytes > 0 && size > c.maxBytes {
+		// en: An entry larger than the whole cache would evict everything for nothing
+		c.remove(key)
+		return
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		c.bytes += size - entry.size
+		entry.val, entry.size = val, size
+		c.ll.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.ll.PushFront(&lruEntry{key: key, val: val, size: size})
+		c.bytes += size
+	}
+
+	for (c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.ll.Back().Value.(*lruEntry).key)
+	}
+}
+
+func (c *LRUCache) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(key)
+}
+
+func (c *LRUCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.entries = make(map[string]*list.Element)
+	c.bytes = 0
+}
+
+// Len は現在保持しているエントリ数を返します。
+//
+// Len returns the number of entries currently held.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *LRUCache) remove(key string) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.ll.Remove(elem)
+	delete(c.entries, key)
+	c.bytes -= elem.Value.(*lruEntry).size
+}
+
+func approximateSize(val any) int {
+	switch v := val.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	default:
+		return 16
+	}
+}
@@ -0,0 +1,256 @@
+package tempura_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLCache(t *testing.T) {
+	t.Parallel()
+
+	cache := tempura.NewTTLCache(50 * time.Millisecond)
+	cache.Set("env.FOO", "foo")
+
+	val, ok := cache.Get("env.FOO")
+	assert.True(t, ok)
+	assert.Equal(t, "foo", val)
+
+	_, ok = cache.Get("env.BAR")
+	assert.False(t, ok)
+
+	time.Sleep(60 * time.Millisecond)
+	_, ok = cache.Get("env.FOO")
+	assert.False(t, ok, "entry should expire after TTL")
+}
+
+func TestMultiLookupContext_WithCache(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		calls.Add(1)
+		if key == "MISSING" {
+			return "", false, nil
+		}
+		return "secret-of-" + key, true, nil
+	}
+	keyAsValue := func(val string) (string, bool) {
+		return val, true
+	}
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"):  tempura.FuncWithContextError(fetchSecret),
+		tempura.DotPrefix("default"): tempura.Func(keyAsValue),
+	}.BindContext(context.Background(), tempura.WithCache(tempura.NewTTLCache(time.Minute)))
+
+	for i := 0; i < 3; i++ {
+		val, err := lookup.FuncMapValue("secret.DB_PASS")
+		assert.NoError(t, err)
+		assert.Equal(t, "secret-of-DB_PASS", val)
+	}
+	assert.Equal(t, int32(1), calls.Load(), "backend should be called only once")
+
+	for i := 0; i < 3; i++ {
+		val, err := lookup.FuncMapValue("secret.MISSING", "default.fallback")
+		assert.NoError(t, err)
+		assert.Equal(t, "fallback", val)
+	}
+	assert.Equal(t, int32(4), calls.Load(), "misses should not be cached")
+}
+
+func TestLRUCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("max entries", func(t *testing.T) {
+		cache := tempura.NewLRUCache(2, 0)
+		cache.Set("env.A", "a")
+		cache.Set("env.B", "b")
+		_, _ = cache.Get("env.A") // en: make B the least recently used
+		cache.Set("env.C", "c")
+
+		_, ok := cache.Get("env.B")
+		assert.False(t, ok, "least recently used entry should be evicted")
+		val, ok := cache.Get("env.A")
+		assert.True(t, ok)
+		assert.Equal(t, "a", val)
+		assert.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		cache := tempura.NewLRUCache(0, 32)
+		cache.Set("env.A", "0123456789") // 15 bytes
+		cache.Set("env.B", "0123456789") // 15 bytes
+		cache.Set("env.C", "0123456789") // 15 bytes, evicts A
+
+		_, ok := cache.Get("env.A")
+		assert.False(t, ok)
+		assert.Equal(t, 2, cache.Len())
+
+		cache.Set("env.HUGE", string(make([]byte, 64)))
+		_, ok = cache.Get("env.HUGE")
+		assert.False(t, ok, "entry larger than the limit should not be cached")
+		assert.Equal(t, 2, cache.Len(), "oversized entry should not evict others")
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		cache := tempura.NewLRUCache(1, 0)
+		cache.Set("env.A", "a")
+		cache.Set("env.A", "A")
+
+		val, ok := cache.Get("env.A")
+		assert.True(t, ok)
+		assert.Equal(t, "A", val)
+		assert.Equal(t, 1, cache.Len())
+	})
+}
+
+func TestTTLCache_NegativeTTL(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		calls.Add(1)
+		return "", false, nil
+	}
+	keyAsValue := func(val string) (string, bool) {
+		return val, true
+	}
+
+	cache := tempura.NewTTLCache(time.Minute, tempura.NegativeTTL(50*time.Millisecond))
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"):  tempura.FuncWithContextError(fetchSecret),
+		tempura.DotPrefix("default"): tempura.Func(keyAsValue),
+	}.BindContext(context.Background(), tempura.WithCache(cache))
+
+	for i := 0; i < 3; i++ {
+		val, err := lookup.FuncMapValue("secret.OPTIONAL", "default.fallback")
+		assert.NoError(t, err)
+		assert.Equal(t, "fallback", val)
+	}
+	assert.Equal(t, int32(1), calls.Load(), "misses should be cached")
+	assert.True(t, cache.GetMiss("secret.OPTIONAL"))
+
+	_, err := lookup.FuncMapValue("secret.OPTIONAL")
+	assert.ErrorIs(t, err, tempura.ErrNotFound, "cached miss should still be reported as not found")
+
+	time.Sleep(60 * time.Millisecond)
+	_, _ = lookup.FuncMapValue("secret.OPTIONAL", "default.fallback")
+	assert.Equal(t, int32(2), calls.Load(), "miss should expire after the negative TTL")
+}
+
+func TestTTLCache_WithoutNegativeTTL(t *testing.T) {
+	t.Parallel()
+
+	cache := tempura.NewTTLCache(time.Minute)
+	cache.SetMiss("secret.OPTIONAL")
+	assert.False(t, cache.GetMiss("secret.OPTIONAL"), "misses should not be recorded without NegativeTTL")
+}
+
+func TestTTLCache_PrefixTTL(t *testing.T) {
+	t.Parallel()
+
+	cache := tempura.NewTTLCache(time.Minute,
+		tempura.PrefixTTL(tempura.DotPrefix("secret"), 50*time.Millisecond),
+	)
+	cache.Set("secret.DB_PASS", "p@ssword!")
+	cache.Set("env.DB_USER", "root")
+
+	time.Sleep(60 * time.Millisecond)
+	_, ok := cache.Get("secret.DB_PASS")
+	assert.False(t, ok, "prefix TTL should override the default TTL")
+	_, ok = cache.Get("env.DB_USER")
+	assert.True(t, ok, "other prefixes should keep the default TTL")
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	t.Parallel()
+
+	caches := map[string]tempura.Cache{
+		"TTLCache": tempura.NewTTLCache(time.Minute),
+		"LRUCache": tempura.NewLRUCache(10, 0),
+	}
+
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			cache.Set("secret.A", "a")
+			cache.Set("secret.B", "b")
+			cache.Set("secret.C", "c")
+
+			cache.Invalidate("secret.A")
+			_, ok := cache.Get("secret.A")
+			assert.False(t, ok, "invalidated entry should be removed")
+			_, ok = cache.Get("secret.B")
+			assert.True(t, ok, "other entries should be kept")
+
+			cache.Clear()
+			_, ok = cache.Get("secret.B")
+			assert.False(t, ok, "all entries should be removed")
+
+			cache.Set("secret.D", "d")
+			val, ok := cache.Get("secret.D")
+			assert.True(t, ok, "cache should be usable after Clear")
+			assert.Equal(t, "d", val)
+		})
+	}
+}
+
+func TestTTLCache_StaleWhileRevalidate(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		n := calls.Add(1)
+		return fmt.Sprintf("%s-v%d", key, n), true, nil
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}.BindContext(context.Background(), tempura.WithCache(
+		tempura.NewTTLCache(30*time.Millisecond, tempura.StaleWhileRevalidate(time.Minute)),
+	))
+
+	val, err := lookup.FuncMapValue("secret.DB_PASS")
+	require.NoError(t, err)
+	assert.Equal(t, "DB_PASS-v1", val)
+
+	time.Sleep(40 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		val, err = lookup.FuncMapValue("secret.DB_PASS")
+		require.NoError(t, err)
+		assert.Equal(t, "DB_PASS-v1", val, "stale value should be served while revalidating")
+	}
+	lookup.WaitClose()
+	assert.Equal(t, int32(2), calls.Load(), "revalidation should run only once")
+
+	val, err = lookup.FuncMapValue("secret.DB_PASS")
+	require.NoError(t, err)
+	assert.Equal(t, "DB_PASS-v2", val)
+}
+
+func TestTTLCache_GetStale(t *testing.T) {
+	t.Parallel()
+
+	cache := tempura.NewTTLCache(30*time.Millisecond, tempura.StaleWhileRevalidate(30*time.Millisecond))
+	cache.Set("env.FOO", "foo")
+
+	val, revalidate, ok := cache.GetStale("env.FOO")
+	assert.Equal(t, []any{"foo", false, true}, []any{val, revalidate, ok})
+
+	time.Sleep(40 * time.Millisecond)
+	_, ok = cache.Get("env.FOO")
+	assert.False(t, ok, "Get should not return stale values")
+	val, revalidate, ok = cache.GetStale("env.FOO")
+	assert.Equal(t, []any{"foo", true, true}, []any{val, revalidate, ok})
+	val, revalidate, ok = cache.GetStale("env.FOO")
+	assert.Equal(t, []any{"foo", false, true}, []any{val, revalidate, ok}, "only the first caller should revalidate")
+
+	time.Sleep(30 * time.Millisecond)
+	_, _, ok = cache.GetStale("env.FOO")
+	assert.False(t, ok, "entry should expire after the stale window")
+}
@@ -0,0 +1,130 @@
+package tempura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = fmt.Errorf("circuit breaker is open")
+
+// CircuitBreakerOption は CircuitBreaker の振る舞いを変更します。
+//
+// CircuitBreakerOption changes the behavior of CircuitBreaker.
+type CircuitBreakerOption func(*circuitBreaker)
+
+// FallbackWhenOpen は、回路が開いている間、エラーの代わりに「見つからなかった」として扱い、後続の引数にフォールバックさせます。
+//
+// FallbackWhenOpen treats lookups as not found instead of failing while the circuit is open, so that the following args act as fallbacks.
+func FallbackWhenOpen() CircuitBreakerOption {
+	return func(cb *circuitBreaker) {
+		cb.fallback = true
+	}
+}
+
+// CircuitBreaker は、 fn が threshold 回連続でエラーを返すと回路を開き、 cooldown の間は fn を呼び出さずに ErrCircuitOpen を返すミドルウェアです。
+// cooldown が経過すると1回だけ試行し、成功すれば回路を閉じ、失敗すれば再び開きます。
+// リモートのバックエンドが停止している間、全ての描画がタイムアウトまで待たされることを防ぎます。
+// 呼び出し元の context.Context が終了したことによる失敗と、キーがないことを表す ErrNotFound を包んだエラーは数えません。 fn の panic は失敗として数えます。
+//
+// CircuitBreaker is a middleware that opens the circuit after fn returns errors threshold times in a row, and then returns ErrCircuitOpen without calling fn for cooldown.
+// After cooldown it lets a single trial through, closing the circuit if it succeeds and opening it again if it fails.
+// It keeps every render from waiting for full timeouts while a remote backend is down.
+// Failures caused by the caller's context.Context being done and errors wrapping ErrNotFound, which mean absent keys, are not counted. Panics of fn are counted as failures.
+//
+//	lookup := tempura.MultiLookup{
+//		tempura.DotPrefix("vault"): tempura.CircuitBreaker(vaultLookup, 5, 30*time.Second),
+//	}.BindContext(ctx)
+func CircuitBreaker(fn LookupFunc, threshold int, cooldown time.Duration, opts ...CircuitBreakerOption) LookupAnyWithContextError {
+	cb := &circuitBreaker{
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+	}
+	for _, opt := range opts {
+		opt(cb)
+	}
+	next := withContextError("CircuitBreaker", fn)
+
+	return func(ctx context.Context, val string) (any, bool, error) {
+		if !cb.allow() {
+			if cb.fallback {
+				return nil, false, nil
+			}
+			return nil, false, ErrCircuitOpen
+		}
+
+		finished := false
+		defer func() {
+			if !finished {
+				cb.fail() // en: next panicked; count it so that the trial ends, and let the panic propagate
+			}
+		}()
+		v, ok, err := next(ctx, val)
+		finished = true
+		switch {
+		case err == nil, errors.Is(err, ErrNotFound): // en: ErrNotFound means a miss, not a failure of the backend
+			cb.succeed()
+		case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+			cb.abort()
+		default:
+			cb.fail()
+		}
+		return v, ok, err
+	}
+}
+
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	fallback  bool
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trying   bool
+}
+
+// allow は fn を呼び出してよいかを返します。回路が開いてから cooldown が経過していれば、1回だけ試行を許可します。
+//
+// allow reports whether fn may be called. Once cooldown has passed since the circuit opened, it allows a single trial.
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.failures < cb.threshold {
+		return true
+	}
+	if cb.trying || time.Since(cb.openedAt) < cb.cooldown {
+		return false
+	}
+	cb.trying = true
+	return true
+}
+
+func (cb *circuitBreaker) succeed() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures = 0
+	cb.trying = false
+}
+
+func (cb *circuitBreaker) fail() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.trying = false
+	if cb.failures >= cb.threshold {
+		cb.openedAt = time.Now()
+	}
+}
+
+func (cb *circuitBreaker) abort() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.trying = false
+}
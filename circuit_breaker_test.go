@@ -0,0 +1,142 @@
+package tempura_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errUnavailable = fmt.Errorf("service unavailable")
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	var down atomic.Bool
+	down.Store(true)
+	vault := tempura.FuncWithError(func(key string) (string, bool, error) {
+		calls.Add(1)
+		if down.Load() {
+			return "", false, errUnavailable
+		}
+		return "vault:" + key, true, nil
+	})
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("vault"): tempura.CircuitBreaker(vault, 2, 30*time.Millisecond),
+	}.BindContext(context.Background())
+
+	for i := 0; i < 2; i++ {
+		_, err := lookup.FuncMapValue("vault.KEY")
+		assert.ErrorIs(t, err, errUnavailable)
+	}
+	_, err := lookup.FuncMapValue("vault.KEY")
+	assert.ErrorIs(t, err, tempura.ErrCircuitOpen)
+	assert.Equal(t, int32(2), calls.Load(), "an open circuit should fail fast")
+
+	time.Sleep(40 * time.Millisecond)
+	_, err = lookup.FuncMapValue("vault.KEY")
+	assert.ErrorIs(t, err, errUnavailable, "a failed trial should open the circuit again")
+	_, err = lookup.FuncMapValue("vault.KEY")
+	assert.ErrorIs(t, err, tempura.ErrCircuitOpen)
+
+	down.Store(false)
+	time.Sleep(40 * time.Millisecond)
+	val, err := lookup.FuncMapValue("vault.KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "vault:KEY", val)
+	val, err = lookup.FuncMapValue("vault.KEY")
+	require.NoError(t, err, "a successful trial should close the circuit")
+	assert.Equal(t, "vault:KEY", val)
+}
+
+func TestCircuitBreaker_Fallback(t *testing.T) {
+	t.Parallel()
+
+	vault := tempura.FuncWithError(func(key string) (string, bool, error) {
+		return "", false, errUnavailable
+	})
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("vault"):   tempura.CircuitBreaker(vault, 1, time.Hour, tempura.FallbackWhenOpen()),
+		tempura.DotPrefix("default"): tempura.Func(func(key string) (string, bool) { return key, true }),
+	}.BindContext(context.Background())
+
+	_, err := lookup.FuncMapValue("vault.KEY", "default.fallback")
+	assert.ErrorIs(t, err, errUnavailable)
+
+	val, err := lookup.FuncMapValue("vault.KEY", "default.fallback")
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", val)
+}
+
+func TestCircuitBreaker_Canceled(t *testing.T) {
+	t.Parallel()
+
+	vault := tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+		<-ctx.Done()
+		return "", false, ctx.Err()
+	})
+	breaker := tempura.CircuitBreaker(vault, 1, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	lookup := tempura.MultiLookup{tempura.DotPrefix("vault"): breaker}.BindContext(ctx)
+	for i := 0; i < 2; i++ {
+		_, err := lookup.FuncMapValue("vault.KEY")
+		assert.ErrorIs(t, err, context.Canceled, "cancellation by the caller should not open the circuit")
+	}
+}
+
+func TestCircuitBreaker_NotFound(t *testing.T) {
+	t.Parallel()
+
+	vault := tempura.FuncWithError(func(key string) (string, bool, error) {
+		return "", false, fmt.Errorf("secret %s: %w", key, tempura.ErrNotFound)
+	})
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("vault"): tempura.CircuitBreaker(vault, 1, time.Hour),
+	}.BindContext(context.Background())
+
+	for i := 0; i < 2; i++ {
+		_, err := lookup.FuncMapValue("vault.KEY")
+		assert.ErrorIs(t, err, tempura.ErrNotFound, "absent keys should not open the circuit")
+		assert.NotErrorIs(t, err, tempura.ErrCircuitOpen)
+	}
+}
+
+func TestCircuitBreaker_Panic(t *testing.T) {
+	t.Parallel()
+
+	var broken atomic.Bool
+	broken.Store(true)
+	vault := tempura.FuncWithError(func(key string) (string, bool, error) {
+		if broken.Load() {
+			panic("nil map")
+		}
+		return "vault:" + key, true, nil
+	})
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("vault"): tempura.CircuitBreaker(vault, 1, 30*time.Millisecond),
+	}.BindContext(context.Background())
+
+	_, err := lookup.FuncMapValue("vault.KEY")
+	var pe tempura.PanicError
+	assert.ErrorAs(t, err, &pe)
+	_, err = lookup.FuncMapValue("vault.KEY")
+	assert.ErrorIs(t, err, tempura.ErrCircuitOpen, "a panic should count as a failure")
+
+	time.Sleep(40 * time.Millisecond)
+	_, err = lookup.FuncMapValue("vault.KEY")
+	assert.ErrorAs(t, err, &pe, "a panicking trial should end the trial")
+
+	broken.Store(false)
+	time.Sleep(40 * time.Millisecond)
+	val, err := lookup.FuncMapValue("vault.KEY")
+	require.NoError(t, err, "the circuit should close after a panicking trial")
+	assert.Equal(t, "vault:KEY", val)
+}
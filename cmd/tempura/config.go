@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config は --config で指定する YAML または JSON のファイルの内容です。ローカルの描画と CI で同じ解決の設定を共有できます。
+//
+// config is the content of the YAML or JSON file specified by --config. It lets local renders and CI share the same resolution setup.
+//
+//	providers:
+//	  secret:
+//	    kind: file
+//	    options:
+//	      dir: /run/secrets
+//	  vault:
+//	    kind: exec
+//	    options:
+//	      commands: {vault: /usr/local/bin/vault-get}
+//	      timeout: 5s
+//	      pass_env: [VAULT_ADDR, VAULT_TOKEN]
+type config struct {
+	Providers map[string]providerConfig `yaml:"providers"`
+}
+
+// loadConfig は name のファイルを config として読み込みます。打ち間違いに気付けるよう、未知のフィールドはエラーとします。
+//
+// loadConfig reads the file name as a config. Unknown fields are errors so that typos are noticed.
+func loadConfig(name string) (config, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+	var cfg config
+	dec := yaml.NewDecoder(bytes.NewReader(b)) // en: YAML is a superset of JSON
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return config{}, fmt.Errorf("failed to parse config %s: %w", name, err)
+	}
+	return cfg, nil
+}
+
+// mergeProviders は、 --config の providers に --provider の指定を加えます。同じ prefix が両方にある場合はエラーとします。
+//
+// mergeProviders adds the specs of --provider to the providers of --config. A prefix in both is an error.
+func mergeProviders(fromConfig, fromFlags map[string]providerConfig) (map[string]providerConfig, error) {
+	merged := make(map[string]providerConfig, len(fromConfig)+len(fromFlags))
+	for prefix, cfg := range fromConfig {
+		merged[prefix] = cfg
+	}
+	for prefix, cfg := range fromFlags {
+		if _, ok := merged[prefix]; ok {
+			return nil, fmt.Errorf("prefix %s is registered by both --config and --provider", prefix)
+		}
+		merged[prefix] = cfg
+	}
+	return merged, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_RenderConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "DB_PASS"), []byte("p4ss\n"), 0o600))
+	t.Setenv("TEMPURA_TEST_HOST", "db")
+
+	tests := []struct {
+		name     string
+		config   string
+		args     []string
+		code     int
+		expected string
+		stderr   string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "YAML",
+			config:   "providers:\n  secret:\n    kind: file\n    options:\n      dir: " + dir + "\n",
+			args:     []string{"-p", "env=env"},
+			expected: "p4ss@db",
+		},
+		{
+			name:     "JSON",
+			config:   `{"providers": {"secret": {"kind": "file", "options": {"dir": "` + dir + `"}}, "env": {"kind": "env"}}}`,
+			expected: "p4ss@db",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "unknown option",
+			config: "providers:\n  secret:\n    kind: file\n    options:\n      directory: " + dir + "\n",
+			args:   []string{"-p", "env=env"},
+			code:   1,
+			stderr: "field directory not found",
+		},
+		{
+			name:   "unknown field",
+			config: "provider:\n  env:\n    kind: env\n",
+			code:   1,
+			stderr: "field provider not found",
+		},
+		{
+			name:   "missing kind",
+			config: "providers:\n  secret:\n    options:\n      dir: " + dir + "\n",
+			args:   []string{"-p", "env=env"},
+			code:   1,
+			stderr: "invalid provider for secret: kind is required",
+		},
+		{
+			name:   "prefix in both",
+			config: "providers:\n  env:\n    kind: env\n",
+			args:   []string{"-p", "env=env"},
+			code:   1,
+			stderr: "registered by both --config and --provider",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			config := filepath.Join(t.TempDir(), "tempura.yaml")
+			require.NoError(t, os.WriteFile(config, []byte(tt.config), 0o644))
+
+			args := append([]string{"render", "-c", config}, tt.args...)
+			args = append(args, "-")
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), args, strings.NewReader(`{{ lookup "secret.DB_PASS" }}@{{ lookup "env.TEMPURA_TEST_HOST" }}`), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Equal(t, tt.expected, stdout.String())
+			assert.Contains(t, stderr.String(), tt.stderr)
+		})
+	}
+}
+
+func TestRun_RenderLibraryKinds(t *testing.T) {
+	dir := t.TempDir()
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	identities := filepath.Join(dir, "key.txt")
+	require.NoError(t, os.WriteFile(identities, []byte(id.String()+"\n"), 0o600))
+	encrypted := filepath.Join(dir, "DB_PASS.age")
+	f, err := os.Create(encrypted)
+	require.NoError(t, err)
+	w, err := age.Encrypt(f, id.Recipient())
+	require.NoError(t, err)
+	_, err = w.Write([]byte("p4ss"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/computeMetadata/v1/instance/zone" || r.Header.Get("Metadata-Flavor") != "Google" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("asia-northeast1-a"))
+	}))
+	t.Cleanup(metadata.Close)
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		config   string
+		args     []string
+		template string
+		code     int
+		expected string
+		stderr   string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "age",
+			args:     []string{"-p", "sec=age:" + identities},
+			template: `{{ lookup "sec.` + encrypted + `" }}`,
+			expected: "p4ss",
+		},
+		{
+			name:     "metadata",
+			config:   "providers:\n  meta:\n    kind: metadata\n    options:\n      cloud: gce\n      endpoint: " + metadata.URL + "\n",
+			template: `{{ lookup "meta.instance/zone" }}`,
+			expected: "asia-northeast1-a",
+		},
+		{
+			name:     "fixed time",
+			config:   "providers:\n  time:\n    kind: time\n    options:\n      fixed: 2024-01-02T03:04:05Z\n",
+			template: `{{ lookup "time.now" }} {{ lookup "time.2006" }}`,
+			expected: "2024-01-02T03:04:05Z 2024",
+		},
+		{
+			name:     "seeded rand",
+			config:   "providers:\n  rand:\n    kind: rand\n    options:\n      seed: 42\n",
+			template: `{{ len (lookup "rand.hex:8") }}`,
+			expected: "8",
+		},
+		{
+			name:     "build info of a binary",
+			args:     []string{"-p", "build=build:" + exe},
+			template: `{{ lookup "build.goversion" }}`,
+			expected: runtime.Version(),
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "age without identities",
+			args:   []string{"-p", "sec=age"},
+			code:   1,
+			stderr: "age requires identities",
+		},
+		{
+			name:   "unknown cloud",
+			args:   []string{"-p", "meta=metadata:ec3"},
+			code:   1,
+			stderr: "metadata requires a cloud of ec2, gce or azure",
+		},
+		{
+			name:   "not a Go binary",
+			args:   []string{"-p", "build=build:" + identities},
+			code:   1,
+			stderr: "invalid provider for build",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"render"}, tt.args...)
+			if tt.config != "" {
+				config := filepath.Join(t.TempDir(), "tempura.yaml")
+				require.NoError(t, os.WriteFile(config, []byte(tt.config), 0o644))
+				args = append(args, "-c", config)
+			}
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), args, strings.NewReader(tt.template), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Equal(t, tt.expected, stdout.String())
+			assert.Contains(t, stderr.String(), tt.stderr)
+		})
+	}
+}
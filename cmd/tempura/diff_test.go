@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_RenderDiff(t *testing.T) {
+	t.Setenv("TEMPURA_TEST_HOST", "db")
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "config.tmpl")
+	require.NoError(t, os.WriteFile(tmpl, []byte("user: app\nhost: {{ lookup \"env.TEMPURA_TEST_HOST\" }}\n"), 0o644))
+
+	tests := []struct {
+		name     string
+		existing string
+		flags    []string
+		code     int
+		diff     string
+		written  string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "diff",
+			existing: "user: app\nhost: localhost\n",
+			flags:    []string{"--diff"},
+			diff:     "-host: localhost\n+host: db\n",
+			written:  "user: app\nhost: db\n",
+		},
+		{
+			name:     "check without drift",
+			existing: "user: app\nhost: db\n",
+			flags:    []string{"--diff", "--check"},
+			written:  "user: app\nhost: db\n",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:     "check with drift",
+			existing: "user: app\nhost: localhost\n",
+			flags:    []string{"--check"},
+			code:     1,
+			diff:     "-host: localhost\n+host: db\n",
+			written:  "user: app\nhost: localhost\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "config")
+			require.NoError(t, os.WriteFile(output, []byte(tt.existing), 0o644))
+
+			args := append([]string{"render", "-p", "env=env", "-o", output}, tt.flags...)
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), append(args, tmpl), strings.NewReader(""), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Contains(t, stdout.String(), tt.diff)
+			if tt.diff == "" {
+				assert.Empty(t, stdout.String())
+			}
+			b, err := os.ReadFile(output)
+			require.NoError(t, err)
+			assert.Equal(t, tt.written, string(b))
+		})
+	}
+
+	t.Run("without output", func(t *testing.T) {
+		stderr := &bytes.Buffer{}
+		code := run(context.Background(), []string{"render", "-p", "env=env", "--diff", tmpl}, strings.NewReader(""), &bytes.Buffer{}, stderr)
+		assert.Equal(t, 2, code)
+		assert.Contains(t, stderr.String(), "require --output")
+	})
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// execStopTimeout は、子プロセスにシグナルを送ってから強制的に終了させるまでの猶予です。
+//
+// execStopTimeout is the grace period between sending a signal to the child process and killing it.
+const execStopTimeout = 10 * time.Second
+
+// runExec は exec サブコマンドを実行します。 envconsul と同様に、解決した値を環境変数や描画したファイルとして渡してコマンドを実行し、その終了コードを返します。
+//
+// runExec executes the exec subcommand. As envconsul does, it runs a command with resolved values passed as environment variables or rendered files, and returns its exit code.
+func runExec(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := pflag.NewFlagSet("exec", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: tempura exec [flags] -- <command> [args...]\n\nflags:\n%s\nproviders:\n%s", fs.FlagUsages(), providerUsage)
+	}
+	providers := addProviderFlags(fs)
+	defer providers.close()
+	rep := addReporterFlags(fs, stdout, stderr)
+	env := fs.StringArrayP("env", "e", nil, "set the environment variable `NAME=KEY` to the value of KEY (repeatable)")
+	templates := fs.StringArrayP("template", "t", nil, "render `NAME=FILE` into a temporary directory and set the environment variable NAME to its path (repeatable)")
+	dataFile := fs.StringP("data", "d", "", "YAML or JSON `file` used as the data of templates")
+	restart := fs.Bool("restart", false, "restart the command when the config, the templates or the files read by providers change the resolved values")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if err := rep.validate(); err != nil {
+		fmt.Fprintf(stderr, "tempura: %v\n", err)
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	envVars, err := parseAssignments(*env)
+	if err != nil {
+		fmt.Fprintf(stderr, "tempura: invalid --env: %v\n", err)
+		return 2
+	}
+	files, err := parseAssignments(*templates)
+	if err != nil {
+		fmt.Fprintf(stderr, "tempura: invalid --template: %v\n", err)
+		return 2
+	}
+
+	cfg := execConfig{
+		providers: providers,
+		env:       envVars,
+		templates: files,
+		dataFile:  *dataFile,
+		command:   fs.Args(),
+		restart:   *restart,
+	}
+	code, err := runChild(ctx, cfg, stdin, stdout, stderr, rep)
+	if err != nil {
+		rep.error(err)
+		return 1
+	}
+	return code
+}
+
+type execConfig struct {
+	providers *providerFlags
+	env       []assignment
+	templates []assignment
+	dataFile  string
+	command   []string
+	restart   bool
+}
+
+// assignment は NAME=VALUE 形式の指定です。
+//
+// assignment is a spec in the form of NAME=VALUE.
+type assignment struct {
+	name  string
+	value string
+}
+
+func parseAssignments(specs []string) ([]assignment, error) {
+	assignments := make([]assignment, 0, len(specs))
+	for _, spec := range specs {
+		name, value, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("%q must be in the form of NAME=VALUE", spec)
+		}
+		assignments = append(assignments, assignment{name: name, value: value})
+	}
+	return assignments, nil
+}
+
+// resolved は、子プロセスに渡す解決済みの値です。再起動が必要かを判断するため、比較できる形で保持します。
+//
+// resolved holds the values passed to the child process, in a comparable form to decide whether a restart is needed.
+type resolved struct {
+	env   []string // en: NAME=VALUE
+	files []renderedFile
+}
+
+type renderedFile struct {
+	name    string // en: the environment variable set to the path
+	base    string
+	content []byte
+}
+
+// resolve は、 --env のキーを解決し、 --template のテンプレートを描画します。
+//
+// resolve resolves the keys of --env and renders the templates of --template.
+func resolve(ctx context.Context, cfg execConfig, stdin io.Reader) (resolved, error) {
+	m, _, err := cfg.providers.multiLookup(stdin)
+	if err != nil {
+		return resolved{}, err
+	}
+	mc := m.BindContext(ctx)
+	if err := mc.Validate(); err != nil {
+		return resolved{}, err
+	}
+
+	var r resolved
+	for _, a := range cfg.env {
+		val, err := mc.Resolve(ctx, a.value)
+		if err != nil {
+			return resolved{}, fmt.Errorf("failed to resolve %s for %s: %w", a.value, a.name, err)
+		}
+		r.env = append(r.env, a.name+"="+fmt.Sprint(val))
+	}
+	if len(cfg.templates) == 0 {
+		return r, nil
+	}
+	data, err := loadData(cfg.dataFile)
+	if err != nil {
+		return resolved{}, err
+	}
+	for _, a := range cfg.templates {
+		text, err := os.ReadFile(a.value)
+		if err != nil {
+			return resolved{}, fmt.Errorf("failed to read template: %w", err)
+		}
+		b, err := executeTemplate(mc, cfg.providers.funcName, a.value, text, data)
+		if err != nil {
+			return resolved{}, err
+		}
+		base := strings.TrimSuffix(filepath.Base(a.value), ".tmpl")
+		r.files = append(r.files, renderedFile{name: a.name, base: base, content: b})
+	}
+	return r, nil
+}
+
+// materialize は、描画したファイルを一時ディレクトリに書き込み、子プロセスの環境変数を返します。一時ディレクトリは呼び出し元が削除します。
+//
+// materialize writes the rendered files into a temporary directory and returns the environment of the child process. The caller removes the temporary directory.
+func (r resolved) materialize() (environ []string, dir string, err error) {
+	environ = append(os.Environ(), r.env...)
+	if len(r.files) == 0 {
+		return environ, "", nil
+	}
+	dir, err = os.MkdirTemp("", "tempura-exec-")
+	if err != nil {
+		return nil, "", err
+	}
+	for i, f := range r.files {
+		// en: a subdirectory per file keeps the base names even if they collide
+		path := filepath.Join(dir, fmt.Sprint(i), f.base)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			os.RemoveAll(dir)
+			return nil, "", err
+		}
+		if err := os.WriteFile(path, f.content, 0o600); err != nil { // en: rendered files often contain secrets
+			os.RemoveAll(dir)
+			return nil, "", err
+		}
+		environ = append(environ, f.name+"="+path)
+	}
+	return environ, dir, nil
+}
+
+// child は実行中の子プロセスです。
+//
+// child is a running child process.
+type child struct {
+	cmd  *exec.Cmd
+	dir  string
+	done chan error
+}
+
+func startChild(r resolved, command []string, stdin io.Reader, stdout, stderr io.Writer) (*child, error) {
+	environ, dir, err := r.materialize()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = environ
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stderr
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	c := &child{cmd: cmd, dir: dir, done: make(chan error, 1)}
+	go func() { c.done <- cmd.Wait() }()
+	return c, nil
+}
+
+// stop は、子プロセスに sig を送り、 execStopTimeout 以内に終了しない場合は強制的に終了させます。
+//
+// stop sends sig to the child process, and kills it if it does not exit within execStopTimeout.
+func (c *child) stop(sig os.Signal) error {
+	if err := c.cmd.Process.Signal(sig); err != nil {
+		_ = c.cmd.Process.Kill() // en: signals other than Kill are unavailable on Windows
+	}
+	select {
+	case err := <-c.done:
+		return c.cleanup(err)
+	case <-time.After(execStopTimeout):
+		_ = c.cmd.Process.Kill()
+		return c.cleanup(<-c.done)
+	}
+}
+
+func (c *child) cleanup(err error) error {
+	if c.dir != "" {
+		os.RemoveAll(c.dir)
+	}
+	return err
+}
+
+// exitCode は、子プロセスの Wait のエラーを終了コードに変換します。シグナルで終了した場合は 1 です。
+//
+// exitCode converts the error of Wait of the child process into an exit code. It is 1 if the process was terminated by a signal.
+func exitCode(err error) (int, error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if code := exitErr.ExitCode(); code >= 0 {
+			return code, nil
+		}
+		return 1, nil
+	}
+	return 0, err
+}
+
+// runChild は子プロセスを実行し、終了するとその終了コードを返します。
+// cfg.restart の場合は、設定、テンプレート、探索関数が読むファイルの変更で解決した値が変わるたびに子プロセスを再起動します。
+// 再び解決できなかった場合は、エラーを出力して実行中の子プロセスを続けます。
+//
+// runChild runs the child process and returns its exit code when it exits.
+// With cfg.restart, it restarts the child process whenever changes of the config, the templates or the files read by lookup functions change the resolved values.
+// If resolving again fails, it writes the error and keeps the running child process.
+func runChild(ctx context.Context, cfg execConfig, stdin io.Reader, stdout, stderr io.Writer, rep *reporter) (int, error) {
+	if cfg.restart {
+		if _, usesStdin, err := cfg.providers.multiLookup(stdin); err == nil && usesStdin {
+			return 0, fmt.Errorf("--restart cannot be used with the stdin provider, which reads stdin only once")
+		}
+	}
+	current, err := resolve(ctx, cfg, stdin)
+	if err != nil {
+		return 0, err
+	}
+	// en: receive the signal itself as well as ctx, to forward the same one to the child
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	c, err := startChild(current, cfg.command, stdin, stdout, stderr)
+	if err != nil {
+		return 0, err
+	}
+	if !cfg.restart {
+		select {
+		case err := <-c.done:
+			return exitCode(c.cleanup(err))
+		case sig := <-sigs:
+			return exitCode(c.stop(sig))
+		case <-ctx.Done():
+			return exitCode(c.stop(stopSignal(sigs)))
+		}
+	}
+
+	targets, err := newWatchTargets(rep)
+	if err != nil {
+		_ = c.stop(syscall.SIGTERM)
+		return 0, err
+	}
+	defer targets.close()
+	addTargets := func() {
+		for _, path := range append([]string{cfg.dataFile}, cfg.providers.watchPaths()...) {
+			targets.add(path)
+		}
+		for _, a := range cfg.templates {
+			targets.add(a.value)
+		}
+	}
+	addTargets()
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case err := <-c.done:
+			return exitCode(c.cleanup(err))
+		case sig := <-sigs:
+			return exitCode(c.stop(sig))
+		case <-ctx.Done():
+			return exitCode(c.stop(stopSignal(sigs)))
+		case event, ok := <-targets.watcher.Events:
+			if ok && targets.matches(event) {
+				debounce = time.After(watchDebounce)
+			}
+		case err, ok := <-targets.watcher.Errors:
+			if ok {
+				rep.error(err)
+			}
+		case <-debounce:
+			debounce = nil
+			addTargets()
+			next, err := resolve(ctx, cfg, stdin)
+			if err != nil {
+				rep.error(err)
+				continue
+			}
+			if reflect.DeepEqual(current, next) {
+				continue
+			}
+			_ = c.stop(syscall.SIGTERM)
+			if c, err = startChild(next, cfg.command, stdin, stdout, stderr); err != nil {
+				return 0, err
+			}
+			current = next
+			rep.info("restarted %s", cfg.command[0])
+		}
+	}
+}
+
+// stopSignal は、 ctx と同時に受け取ったシグナルがあればそれを、なければ SIGTERM を返します。
+//
+// stopSignal returns the signal received along with ctx if any, and otherwise SIGTERM.
+func stopSignal(sigs <-chan os.Signal) os.Signal {
+	select {
+	case sig := <-sigs:
+		return sig
+	default:
+		return syscall.SIGTERM
+	}
+}
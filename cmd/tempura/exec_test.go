@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755))
+	return path
+}
+
+func TestRun_Exec(t *testing.T) {
+	dir := t.TempDir()
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.MkdirAll(secrets, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "DB_PASS"), []byte("p4ss\n"), 0o600))
+	tmpl := filepath.Join(dir, "config.yaml.tmpl")
+	require.NoError(t, os.WriteFile(tmpl, []byte(`pass: {{ lookup "secret.DB_PASS" }}`), 0o644))
+	printEnv := writeScript(t, dir, "print-env.sh", `echo "$DB_PASS"`)
+	printFile := writeScript(t, dir, "print-file.sh", `basename "$CONFIG"; cat "$CONFIG"`)
+	fail := writeScript(t, dir, "fail.sh", `exit 3`)
+
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		expected string
+		stderr   string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "environment variables",
+			args:     []string{"exec", "-p", "secret=file:" + secrets, "-e", "DB_PASS=secret.DB_PASS", "--", printEnv},
+			expected: "p4ss\n",
+		},
+		{
+			name:     "rendered files",
+			args:     []string{"exec", "-p", "secret=file:" + secrets, "-t", "CONFIG=" + tmpl, "--", printFile},
+			expected: "config.yaml\npass: p4ss",
+		},
+		{
+			name: "exit code of the command",
+			args: []string{"exec", "-p", "env=env", "--", fail},
+			code: 3,
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "not found",
+			args:   []string{"exec", "-p", "secret=file:" + secrets, "-e", "API_KEY=secret.API_KEY", "--", printEnv},
+			code:   1,
+			stderr: "failed to resolve secret.API_KEY for API_KEY",
+		},
+		{
+			name:   "invalid env",
+			args:   []string{"exec", "-p", "env=env", "-e", "DB_PASS", "--", printEnv},
+			code:   2,
+			stderr: "invalid --env",
+		},
+		{
+			name:   "missing command",
+			args:   []string{"exec", "-p", "env=env"},
+			code:   2,
+			stderr: "usage: tempura exec",
+		},
+		{
+			name:   "unknown command",
+			args:   []string{"exec", "-p", "env=env", "--", filepath.Join(dir, "missing")},
+			code:   1,
+			stderr: "no such file or directory",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), tt.args, strings.NewReader(""), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Equal(t, tt.expected, stdout.String())
+			assert.Contains(t, stderr.String(), tt.stderr)
+		})
+	}
+}
+
+func TestRun_ExecRestart(t *testing.T) {
+	dir := t.TempDir()
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.MkdirAll(secrets, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "DB_PASS"), []byte("p4ss"), 0o600))
+	server := writeScript(t, dir, "server.sh", `echo "$DB_PASS"; exec sleep 30`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stdout, stderr := &syncBuffer{}, &syncBuffer{}
+	done := make(chan int)
+	go func() {
+		done <- run(ctx, []string{"exec", "--restart", "-p", "secret=file:" + secrets, "-e", "DB_PASS=secret.DB_PASS", "--", server}, strings.NewReader(""), stdout, stderr)
+	}()
+	require.Eventually(t, func() bool { return stdout.String() == "p4ss\n" }, 5*time.Second, 10*time.Millisecond, stderr.String())
+
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "DB_PASS"), []byte("n3w"), 0o600))
+	require.Eventually(t, func() bool { return stdout.String() == "p4ss\nn3w\n" }, 5*time.Second, 10*time.Millisecond, stderr.String())
+	assert.Contains(t, stderr.String(), "tempura: restarted")
+
+	cancel()
+	assert.Equal(t, 1, <-done) // en: the command is terminated by SIGTERM
+}
+
+func TestRun_ExecForwardSignal(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "config.yaml.tmpl")
+	require.NoError(t, os.WriteFile(tmpl, []byte(`user: app`), 0o644))
+	server := writeScript(t, dir, "server.sh", `trap 'echo "got INT"; exit 5' INT; echo "$CONFIG"; while :; do sleep 0.1; done`)
+
+	stdout, stderr := &syncBuffer{}, &syncBuffer{}
+	done := make(chan int)
+	go func() {
+		done <- run(context.Background(), []string{"exec", "-p", "env=env", "-t", "CONFIG=" + tmpl, "--", server}, strings.NewReader(""), stdout, stderr)
+	}()
+	require.Eventually(t, func() bool { return strings.HasSuffix(stdout.String(), "\n") }, 5*time.Second, 10*time.Millisecond, stderr.String())
+	config := strings.TrimSpace(stdout.String())
+	require.FileExists(t, config)
+
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGINT))
+	assert.Equal(t, 5, <-done, stderr.String())
+	assert.Equal(t, config+"\ngot INT\n", stdout.String())
+	assert.NoFileExists(t, config) // en: the rendered files are removed
+}
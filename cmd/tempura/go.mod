@@ -0,0 +1,36 @@
+module github.com/ebi-yade/go-tempura/cmd/tempura
+
+go 1.21
+
+require (
+	filippo.io/age v1.2.1
+	github.com/ebi-yade/go-tempura v0.0.0-00010101000000-000000000000
+	github.com/ebi-yade/go-tempura/agelookup v0.0.0-00010101000000-000000000000
+	github.com/ebi-yade/go-tempura/keyringlookup v0.0.0-00010101000000-000000000000
+	github.com/ebi-yade/go-tempura/wasmlookup v0.0.0-00010101000000-000000000000
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/spf13/pflag v1.0.10
+	github.com/stretchr/testify v1.9.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/alessio/shellescape v1.4.1 // indirect
+	github.com/danieljoos/wincred v1.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
+	github.com/tetratelabs/wazero v1.7.3 // indirect
+	github.com/zalando/go-keyring v0.2.5 // indirect
+	golang.org/x/crypto v0.24.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.24.0 // indirect
+	golang.org/x/time v0.8.0 // indirect
+)
+
+replace (
+	github.com/ebi-yade/go-tempura => ../../
+	github.com/ebi-yade/go-tempura/agelookup => ../../agelookup
+	github.com/ebi-yade/go-tempura/keyringlookup => ../../keyringlookup
+	github.com/ebi-yade/go-tempura/wasmlookup => ../../wasmlookup
+)
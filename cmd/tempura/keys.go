@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/spf13/pflag"
+)
+
+// runKeys は keys サブコマンドを実行します。テンプレートが必要とする prefix とキーの組を一覧にし、 --check を指定した場合はそれぞれが現在解決できるかを添えます。
+// --check で解決できないキーがあった場合は 1 を返します。
+//
+// runKeys executes the keys subcommand. It lists the pairs of a prefix and a key that templates require, and with --check, annotates whether each currently resolves.
+// It returns 1 if any key does not resolve with --check.
+func runKeys(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := pflag.NewFlagSet("keys", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: tempura keys [flags] <template|dir>...\n\nflags:\n%s\nproviders:\n%s", fs.FlagUsages(), providerUsage)
+	}
+	providers := addProviderFlags(fs)
+	defer providers.close()
+	rep := addReporterFlags(fs, stdout, stderr)
+	pattern := fs.String("pattern", tempura.DefaultRenderDirPattern, "`pattern` of the names of templates in directories")
+	check := fs.Bool("check", false, "annotate whether each key currently resolves")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if err := rep.validate(); err != nil {
+		fmt.Fprintf(stderr, "tempura: %v\n", err)
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	m, _, err := providers.multiLookup(stdin)
+	if err != nil {
+		rep.error(err)
+		return 1
+	}
+	files, err := templateFiles(fs.Args(), *pattern)
+	if err != nil {
+		rep.error(err)
+		return 1
+	}
+
+	var refs []tempura.KeyRef
+	seen := make(map[string]bool)
+	for _, file := range files {
+		t, err := parseTemplateFile(file, providers.funcName)
+		if err != nil {
+			rep.error(err)
+			return 1
+		}
+		for _, ref := range tempura.Keys(t, providers.funcName, m) {
+			signature := fmt.Sprintf("%v\x00%s", ref.Prefix, ref.Arg)
+			if !seen[signature] {
+				seen[signature] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Arg != refs[j].Arg {
+			return refs[i].Arg < refs[j].Arg
+		}
+		return fmt.Sprint(refs[i].Prefix) < fmt.Sprint(refs[j].Prefix)
+	})
+
+	code := 0
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	for _, ref := range refs {
+		if !*check {
+			fmt.Fprintf(w, "%v\t%s\n", ref.Prefix, ref.Key)
+			continue
+		}
+		status := resolveStatus(ctx, m, ref)
+		if status != "found" {
+			code = 1
+		}
+		fmt.Fprintf(w, "%v\t%s\t%s\n", ref.Prefix, ref.Key, status)
+	}
+	if err := w.Flush(); err != nil {
+		rep.error(err)
+		return 1
+	}
+	return code
+}
+
+// resolveStatus は、 ref の prefix に登録された探索関数だけで ref を解決し、その結果を found, not_found, error: ... のいずれかで返します。値そのものは返しません。
+//
+// resolveStatus resolves ref only with the lookup function registered for its prefix, and returns the outcome as one of found, not_found and error: .... It never returns the value itself.
+func resolveStatus(ctx context.Context, m tempura.MultiLookup, ref tempura.KeyRef) string {
+	single := tempura.MultiLookup{ref.Prefix: m[ref.Prefix]}
+	_, err := single.BindContext(ctx).Resolve(ctx, ref.Arg)
+	switch {
+	case err == nil:
+		return "found"
+	case errors.Is(err, tempura.ErrNotFound):
+		return "not_found"
+	default:
+		return "error: " + err.Error()
+	}
+}
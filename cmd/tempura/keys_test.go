@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_Keys(t *testing.T) {
+	dir := t.TempDir()
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.MkdirAll(secrets, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "DB_PASS"), []byte("p4ss"), 0o600))
+	templates := filepath.Join(dir, "templates")
+	require.NoError(t, os.MkdirAll(templates, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(templates, "app.yaml.tmpl"), []byte(`{{ lookup "secret.DB_PASS" }} {{ lookup "secret.API_KEY" }}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(templates, "db.yaml.tmpl"), []byte(`{{ lookup "secret.DB_PASS" }} {{ lookup "unknown.KEY" }}`), 0o644))
+
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		expected string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "keys",
+			args:     []string{"keys", "-p", "secret=file:" + secrets, templates},
+			expected: "secret  API_KEY\nsecret  DB_PASS\n",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:     "check",
+			args:     []string{"keys", "-p", "secret=file:" + secrets, "--check", templates},
+			code:     1,
+			expected: "secret  API_KEY  not_found\nsecret  DB_PASS  found\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), tt.args, strings.NewReader(""), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Equal(t, tt.expected, stdout.String())
+		})
+	}
+}
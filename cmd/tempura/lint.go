@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/spf13/pflag"
+)
+
+// runLint は lint サブコマンドを実行します。テンプレートの解析に失敗した場合や問題が見つかった場合は 1 を返すため、 pre-commit フックや CI の検査に使えます。
+//
+// runLint executes the lint subcommand. It returns 1 if templates fail to parse or problems are found, so it suits pre-commit hooks and CI gates.
+func runLint(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := pflag.NewFlagSet("lint", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: tempura lint [flags] <template|dir>...\n\nflags:\n%s\nproviders:\n%s", fs.FlagUsages(), providerUsage)
+	}
+	providers := addProviderFlags(fs)
+	defer providers.close()
+	rep := addReporterFlags(fs, stdout, stderr)
+	pattern := fs.String("pattern", tempura.DefaultRenderDirPattern, "`pattern` of the names of templates in directories")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if err := rep.validate(); err != nil {
+		fmt.Fprintf(stderr, "tempura: %v\n", err)
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	m, _, err := providers.multiLookup(stdin)
+	if err != nil {
+		rep.error(err)
+		return 1
+	}
+	files, err := templateFiles(fs.Args(), *pattern)
+	if err != nil {
+		rep.error(err)
+		return 1
+	}
+
+	code := 0
+	for _, file := range files {
+		t, err := parseTemplateFile(file, providers.funcName)
+		if err != nil {
+			rep.error(err)
+			code = 1
+			continue
+		}
+		for _, issue := range tempura.Lint(t, providers.funcName, m) {
+			rep.issue(issue)
+			code = 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_Lint(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "conf.d"), 0o755))
+	good := filepath.Join(dir, "good.yaml.tmpl")
+	require.NoError(t, os.WriteFile(good, []byte(`host: {{ lookup "env.HOST" }}`), 0o644))
+	bad := filepath.Join(dir, "conf.d", "bad.yaml.tmpl")
+	require.NoError(t, os.WriteFile(bad, []byte("user: app\nhome: {{ lookup \"evn.HOME\" }}\n"), 0o644))
+	broken := filepath.Join(dir, "broken.txt")
+	require.NoError(t, os.WriteFile(broken, []byte(`{{ lookup "env.HOME" `), 0o644))
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		// ==================== VALID CASES ====================
+		{name: "no issues", args: []string{"lint", "-p", "env=env", good}},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "directory",
+			args:   []string{"lint", "-p", "env=env", dir},
+			code:   1,
+			stdout: bad + ":2:16: evn.HOME matches no registered prefix",
+		},
+		{
+			name:   "parse error",
+			args:   []string{"lint", "-p", "env=env", "--pattern", "*.txt", dir},
+			code:   1,
+			stderr: "broken.txt",
+		},
+		{
+			name:   "no templates",
+			args:   []string{"lint", "-p", "env=env"},
+			code:   2,
+			stderr: "usage: tempura lint",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), tt.args, strings.NewReader(""), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Contains(t, stdout.String(), tt.stdout)
+			assert.Contains(t, stderr.String(), tt.stderr)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Command tempura は、 tempura の探索関数を使ってテンプレートを描画するコマンドです。
+// Go のコードを書くことなく、運用や CI のジョブからライブラリと同じ値の解決を使えるようにするためのものです。
+//
+// Command tempura renders templates with lookup functions of tempura.
+// It lets non-Go consumers such as ops and CI jobs use the resolution of the library without writing Go.
+//
+//	tempura render -p env=env -p secret=file:/run/secrets -d values.yaml -o config.yaml config.yaml.tmpl
+//	tempura render -c tempura.yaml config.yaml.tmpl
+//	tempura render -c tempura.yaml -d services.yaml -O /etc/app bundle.tmpl
+//	tempura exec -c tempura.yaml -e DB_PASS=secret.DB_PASS -- ./server
+//	tempura serve -c tempura.yaml --token-file /run/secrets/tempura-token
+//	tempura providers -c tempura.yaml
+//	generate-template | tempura render -c tempura.yaml --error-format json > config.yaml
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const usage = `usage: tempura <command> [flags]
+
+commands:
+  render    render a template
+  lint      check lookup calls in templates against the providers
+  keys      list the keys templates require
+  exec      run a command with resolved values in its environment
+  serve     expose resolution and rendering over HTTP
+  providers list the kinds of providers, discovered plugins and their status
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM) // en: SIGTERM is what container runtimes and systemd send
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run はコマンドを実行し、終了コードを返します。
+//
+// run executes the command and returns the exit code.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "render":
+		return runRender(ctx, args[1:], stdin, stdout, stderr)
+	case "lint":
+		return runLint(ctx, args[1:], stdin, stdout, stderr)
+	case "keys":
+		return runKeys(ctx, args[1:], stdin, stdout, stderr)
+	case "exec":
+		return runExec(ctx, args[1:], stdin, stdout, stderr)
+	case "serve":
+		return runServe(ctx, args[1:], stdin, stdout, stderr)
+	case "providers":
+		return runProviders(ctx, args[1:], stdin, stdout, stderr)
+	case "-h", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "tempura: unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_Render(t *testing.T) {
+	dir := t.TempDir()
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.MkdirAll(secrets, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "DB_PASS"), []byte("p4ss\n"), 0o600))
+	tmpl := filepath.Join(dir, "config.yaml.tmpl")
+	require.NoError(t, os.WriteFile(tmpl, []byte("user: {{ .User }}\npass: {{ lookup \"secret.DB_PASS\" }}\nhost: {{ lookup \"env.TEMPURA_TEST_HOST\" }}\n"), 0o644))
+	data := filepath.Join(dir, "values.json")
+	require.NoError(t, os.WriteFile(data, []byte(`{"User": "app"}`), 0o644))
+	t.Setenv("TEMPURA_TEST_HOST", "db")
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		code     int
+		expected string
+		stderr   string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "template file",
+			args:     []string{"render", "-p", "secret=file:" + secrets, "-p", "env=env", "-d", data, tmpl},
+			expected: "user: app\npass: p4ss\nhost: db\n",
+		},
+		{
+			name:     "template from stdin",
+			args:     []string{"render", "--provider", "env=env", "--func", "env", "-"},
+			stdin:    `{{ env "env.TEMPURA_TEST_HOST" }}`,
+			expected: "db",
+		},
+		{
+			name:     "template from stdin by default",
+			args:     []string{"render", "-p", "env=env"},
+			stdin:    `{{ lookup "env.TEMPURA_TEST_HOST" }}`,
+			expected: "db",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "stdin read twice",
+			args:   []string{"render", "-p", "in=stdin", "-"},
+			stdin:  `{{ lookup "in.db" }}`,
+			code:   1,
+			stderr: "cannot both read stdin",
+		},
+		{
+			name:   "lookup error",
+			args:   []string{"render", "-p", "secret=file:" + dir, "-p", "env=env", tmpl},
+			code:   1,
+			stderr: "config.yaml.tmpl:2:",
+		},
+		{
+			name:   "invalid provider",
+			args:   []string{"render", "-p", "secret=vault", tmpl},
+			code:   1,
+			stderr: `unknown kind "vault"`,
+		},
+		{
+			name:   "too many templates",
+			args:   []string{"render", "-p", "env=env", "a.tmpl", "b.tmpl"},
+			code:   2,
+			stderr: "usage: tempura render",
+		},
+		{
+			name:   "unknown command",
+			args:   []string{"draw"},
+			code:   2,
+			stderr: `unknown command "draw"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), tt.args, strings.NewReader(tt.stdin), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Equal(t, tt.expected, stdout.String())
+			assert.Contains(t, stderr.String(), tt.stderr)
+		})
+	}
+}
+
+func TestRun_RenderOutput(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "out.txt")
+	stderr := &bytes.Buffer{}
+	code := run(context.Background(), []string{"render", "-p", "in=stdin", "-o", output, "/dev/null"}, strings.NewReader("{}"), &bytes.Buffer{}, stderr)
+	require.Equal(t, 0, code, stderr.String())
+	b, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Empty(t, b)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ebi-yade/go-tempura"
+	"gopkg.in/yaml.v3"
+)
+
+// loadOverrides は、 --values のファイルと --set の指定から、キーと値の対応を作ります。
+// Helm と同様に、後に指定した --values ほど優先され、 --set は全ての --values より優先されます。 --set の値は常に文字列です。
+//
+// loadOverrides builds the map of keys to values from the --values files and the --set specs.
+// As in Helm, later --values take precedence, and --set takes precedence over all --values. Values of --set are always strings.
+func loadOverrides(valuesFiles, sets []string) (map[string]any, error) {
+	overrides := make(map[string]any)
+	for _, file := range valuesFiles {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values: %w", err)
+		}
+		var doc map[string]any
+		if err := yaml.Unmarshal(b, &doc); err != nil { // en: YAML is a superset of JSON
+			return nil, fmt.Errorf("failed to parse values %s: %w", file, err)
+		}
+		flattenValues("", doc, overrides)
+	}
+	for _, spec := range sets {
+		key, val, ok := strings.Cut(spec, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set %q: must be in the form of KEY=VALUE", spec)
+		}
+		overrides[key] = val
+	}
+	for key := range overrides {
+		if !strings.Contains(key, ".") {
+			return nil, fmt.Errorf("invalid override %q: keys must be in the form of PREFIX.KEY", key)
+		}
+	}
+	return overrides, nil
+}
+
+// flattenValues は、入れ子になった doc の葉の値を、ドットで連結したキーで out に書き込みます。
+//
+// flattenValues writes the leaf values of the nested doc into out, with keys joined by dots.
+//
+// e.g. {"secret": {"DB_PASS": "p4ss"}} => {"secret.DB_PASS": "p4ss"}
+func flattenValues(prefix string, doc map[string]any, out map[string]any) {
+	for key, val := range doc {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if child, ok := val.(map[string]any); ok {
+			flattenValues(key, child, out)
+			continue
+		}
+		out[key] = val
+	}
+}
+
+// withOverrides は、 overrides のキーを全ての探索関数より優先して返すよう m の探索関数を包みます。
+// どの prefix にも一致しないキーのためには、最初のドットまでを prefix として overrides だけを返す探索関数を登録します。
+//
+// withOverrides wraps the lookup functions of m so that the keys of overrides are returned in precedence over all of them.
+// For keys matching no prefix, it registers lookup functions returning only overrides, with the part up to the first dot as the prefix.
+func withOverrides(m tempura.MultiLookup, overrides map[string]any) tempura.MultiLookup {
+	if len(overrides) == 0 {
+		return m
+	}
+	wrapped := make(tempura.MultiLookup, len(m))
+	for prefix, fn := range m {
+		wrapped[prefix] = overrideFunc(prefix, fn, overrides)
+	}
+	for key := range overrides {
+		covered := false
+		for prefix := range wrapped {
+			covered = covered || prefix.Match(key)
+		}
+		if first, _, _ := strings.Cut(key, "."); !covered {
+			wrapped[tempura.DotPrefix(first)] = overrideFunc(tempura.DotPrefix(first), tempura.Func(func(string) (any, bool) { return nil, false }), overrides)
+		}
+	}
+	return wrapped
+}
+
+// overrideFunc は、 prefix を付け直したキーが overrides にあればその値を返し、なければ fn を呼び出す、 fn と同じ種類の探索関数を返します。
+//
+// overrideFunc returns a lookup function of the same kind as fn, which returns the value in overrides for the key with prefix put back, and otherwise calls fn.
+func overrideFunc(prefix tempura.Prefix, fn tempura.LookupFunc, overrides map[string]any) tempura.LookupFunc {
+	lookup := func(key string) (any, bool) {
+		val, ok := overrides[fmt.Sprintf("%v.%s", prefix, key)] // en: the CLI registers only DotPrefix
+		return val, ok
+	}
+	switch fn := fn.(type) {
+	case tempura.LookupAny:
+		return tempura.LookupAny(func(key string) (any, bool) {
+			if val, ok := lookup(key); ok {
+				return val, true
+			}
+			return fn(key)
+		})
+	case tempura.LookupAnyWithError:
+		return tempura.LookupAnyWithError(func(key string) (any, bool, error) {
+			if val, ok := lookup(key); ok {
+				return val, true, nil
+			}
+			return fn(key)
+		})
+	case tempura.LookupAnyWithContext:
+		return tempura.LookupAnyWithContext(func(ctx context.Context, key string) (any, bool) {
+			if val, ok := lookup(key); ok {
+				return val, true
+			}
+			return fn(ctx, key)
+		})
+	case tempura.LookupAnyWithContextError:
+		return tempura.LookupAnyWithContextError(func(ctx context.Context, key string) (any, bool, error) {
+			if val, ok := lookup(key); ok {
+				return val, true, nil
+			}
+			return fn(ctx, key)
+		})
+	default:
+		return fn // en: left for Validate to report
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_RenderOverrides(t *testing.T) {
+	dir := t.TempDir()
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.MkdirAll(secrets, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "DB_PASS"), []byte("p4ss\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "API_KEY"), []byte("k3y\n"), 0o600))
+	base := filepath.Join(dir, "base.yaml")
+	require.NoError(t, os.WriteFile(base, []byte("secret:\n  DB_PASS: base\n  API_KEY: base\napp:\n  replicas: 3\n"), 0o644))
+	dev := filepath.Join(dir, "dev.json")
+	require.NoError(t, os.WriteFile(dev, []byte(`{"secret": {"DB_PASS": "dev"}}`), 0o644))
+	t.Setenv("TEMPURA_TEST_HOST", "db")
+
+	tmpl := `{{ lookup "secret.DB_PASS" }} {{ lookup "secret.API_KEY" }} {{ lookup "env.TEMPURA_TEST_HOST" }}`
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		code     int
+		expected string
+		stderr   string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "no overrides",
+			args:     []string{"-p", "secret=file:" + secrets, "-p", "env=env"},
+			stdin:    tmpl,
+			expected: "p4ss k3y db",
+		},
+		{
+			name:     "set",
+			args:     []string{"-p", "secret=file:" + secrets, "-p", "env=env", "--set", "secret.DB_PASS=s3t", "--set", "env.TEMPURA_TEST_HOST=localhost"},
+			stdin:    tmpl,
+			expected: "s3t k3y localhost",
+		},
+		{
+			name:     "later values and set take precedence",
+			args:     []string{"-p", "secret=file:" + secrets, "-p", "env=env", "--values", base, "--values", dev, "--set", "secret.API_KEY=s3t"},
+			stdin:    tmpl,
+			expected: "dev s3t db",
+		},
+		{
+			name:     "prefix without providers",
+			args:     []string{"--values", base},
+			stdin:    `{{ lookup "app.replicas" }}`,
+			expected: "3",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "key without prefix",
+			args:   []string{"-p", "env=env", "--set", "DEBUG=true"},
+			code:   1,
+			stderr: "keys must be in the form of PREFIX.KEY",
+		},
+		{
+			name:   "invalid set",
+			args:   []string{"-p", "env=env", "--set", "secret.DB_PASS"},
+			code:   1,
+			stderr: "must be in the form of KEY=VALUE",
+		},
+		{
+			name:   "missing values",
+			args:   []string{"-p", "env=env", "--values", filepath.Join(dir, "missing.yaml")},
+			code:   1,
+			stderr: "failed to read values",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), append([]string{"render"}, tt.args...), strings.NewReader(tt.stdin), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Equal(t, tt.expected, stdout.String())
+			assert.Contains(t, stderr.String(), tt.stderr)
+		})
+	}
+}
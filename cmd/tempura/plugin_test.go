@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/pluginlookup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain は、 TEMPURA_TEST_PLUGIN が設定されている場合、テストの実行ファイルをプラグインとして動かします。
+//
+// TestMain runs the test executable as a plugin if TEMPURA_TEST_PLUGIN is set.
+func TestMain(m *testing.M) {
+	if os.Getenv("TEMPURA_TEST_PLUGIN") == "" {
+		os.Exit(m.Run())
+	}
+	info := pluginlookup.Info{Protocol: pluginlookup.ProtocolVersion, Description: "test plugin"}
+	lookup := tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+		return "plugin-of-" + key, key != "MISSING", nil
+	})
+	if err := pluginlookup.Serve(context.Background(), os.Stdin, os.Stdout, info, lookup); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestRun_RenderPlugin(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	t.Setenv("TEMPURA_TEST_PLUGIN", "serve")
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		code     int
+		expected string
+		stderr   string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "plugin",
+			args:     []string{"render", "-p", "foo=plugin:" + exe},
+			stdin:    `{{ lookup "foo.DB_PASS" }} {{ lookup "foo.API_KEY" }}`,
+			expected: "plugin-of-DB_PASS plugin-of-API_KEY",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "not found",
+			args:   []string{"render", "-p", "foo=plugin:" + exe},
+			stdin:  `{{ lookup "foo.MISSING" }}`,
+			code:   1,
+			stderr: "not found",
+		},
+		{
+			name:   "missing command",
+			args:   []string{"render", "-p", "foo=plugin"},
+			code:   1,
+			stderr: "plugin requires a command",
+		},
+		{
+			name:   "not a plugin",
+			args:   []string{"render", "-p", "foo=plugin:true"},
+			stdin:  `{{ lookup "foo.DB_PASS" }}`,
+			code:   1,
+			stderr: "protocol violation",
+		},
+		{
+			name:   "missing wasm path",
+			args:   []string{"render", "-p", "foo=wasm"},
+			code:   1,
+			stderr: "wasm requires a path",
+		},
+		{
+			name:   "not wasm",
+			args:   []string{"render", "-p", "foo=wasm:" + exe},
+			code:   1,
+			stderr: "failed to compile",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), tt.args, strings.NewReader(tt.stdin), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Equal(t, tt.expected, stdout.String())
+			assert.Contains(t, stderr.String(), tt.stderr)
+		})
+	}
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/agelookup"
+	"github.com/ebi-yade/go-tempura/buildlookup"
+	"github.com/ebi-yade/go-tempura/execlookup"
+	"github.com/ebi-yade/go-tempura/hostlookup"
+	"github.com/ebi-yade/go-tempura/k8slookup"
+	"github.com/ebi-yade/go-tempura/keyringlookup"
+	"github.com/ebi-yade/go-tempura/metadatalookup"
+	"github.com/ebi-yade/go-tempura/pluginlookup"
+	"github.com/ebi-yade/go-tempura/randlookup"
+	"github.com/ebi-yade/go-tempura/stdinlookup"
+	"github.com/ebi-yade/go-tempura/timelookup"
+	"github.com/ebi-yade/go-tempura/wasmlookup"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// providerKind は、登録できる探索関数の種類の説明です。 --help と providers サブコマンドで表示します。
+//
+// providerKind describes a kind of lookup functions that can be registered. It is shown by --help and the providers subcommand.
+type providerKind struct {
+	name        string
+	usage       string // en: in the form of --provider
+	options     []string
+	description string
+}
+
+var providerKinds = []providerKind{
+	{"env", "env", nil, "environment variables"},
+	{"file", "file:DIR", []string{"dir"}, "contents of files under DIR, without trailing newlines"},
+	{"stdin", "stdin", nil, "a JSON or YAML document read from stdin, by dot-separated paths"},
+	{"host", "host", nil, "metadata of the host, such as hostname and ip"},
+	{"k8s", "k8s[:DIR]", []string{"dir", "env"}, "pod information of the Kubernetes Downward API"},
+	{"keyring", "keyring[:SVC]", []string{"service"}, "credentials in the OS keyring"},
+	{"exec", "exec", []string{"commands", "timeout", "max_output_bytes", "env", "pass_env", "dir"}, "output of allowed commands (--config only)"},
+	{"plugin", "plugin:CMD", []string{"command", "args", "env", "timeout"}, "an external provider executable speaking the plugin protocol over stdio"},
+	{"wasm", "wasm:PATH", []string{"path", "args", "env", "timeout", "memory_limit_pages"}, "a provider compiled to WASM for WASI, run in a sandbox"},
+	{"age", "age:IDENTITY", []string{"identities", "identities_env", "inline"}, "age-encrypted files at the keys, or inline ciphertexts with inline: true"},
+	{"metadata", "metadata:CLOUD", []string{"cloud", "endpoint", "timeout"}, "instance metadata of ec2, gce or azure"},
+	{"time", "time", []string{"fixed"}, "the current time, such as now, unix or a layout of time.Format"},
+	{"rand", "rand", []string{"seed"}, "random values, such as uuid, hex:N and alnum:N"},
+	{"build", "build[:BIN]", []string{"binary"}, "build info of the Go binary BIN, or of tempura itself"},
+}
+
+// providerUsage は --help で表示する探索関数の種類の一覧です。
+//
+// providerUsage is the list of kinds of lookup functions shown by --help.
+var providerUsage = func() string {
+	b := &strings.Builder{}
+	for _, k := range providerKinds {
+		fmt.Fprintf(b, "  %-15s %s\n", k.usage, k.description)
+	}
+	b.WriteString("\nremote backends such as ssm, vault and kms are reachable through plugin or wasm providers.\n")
+	return b.String()
+}()
+
+// providerFlags は、サブコマンドに共通する探索関数の登録のためのフラグです。
+//
+// providerFlags are the flags for registering lookup functions, common to subcommands.
+type providerFlags struct {
+	specs    []string
+	config   string
+	funcName string
+	values   []string
+	sets     []string
+	pool     *providerPool
+}
+
+func addProviderFlags(fs *pflag.FlagSet) *providerFlags {
+	f := &providerFlags{pool: &providerPool{}}
+	fs.StringArrayVarP(&f.specs, "provider", "p", nil, "register a provider as `PREFIX=KIND[:ARG]` (repeatable)")
+	fs.StringVarP(&f.config, "config", "c", "", "YAML or JSON `file` declaring providers")
+	fs.StringVar(&f.funcName, "func", tempura.DefaultFuncName, "`name` of the lookup function in the template")
+	fs.StringArrayVar(&f.values, "values", nil, "YAML or JSON `file` of values overriding every provider, keyed by nested prefixes and keys (repeatable)")
+	fs.StringArrayVar(&f.sets, "set", nil, "override a key with a string as `KEY=VALUE`, in precedence over --values (repeatable)")
+	return f
+}
+
+// multiLookup は、 --config と --provider の指定から MultiLookup を組み立て、 --values と --set の値を最優先にします。 stdin を読む探索関数が含まれるかも返します。
+//
+// multiLookup builds a MultiLookup from --config and --provider, with the values of --values and --set taking the highest precedence. It also reports whether a lookup function reading stdin is included.
+func (f *providerFlags) multiLookup(stdin io.Reader) (m tempura.MultiLookup, usesStdin bool, err error) {
+	providers, err := f.configs()
+	if err != nil {
+		return nil, false, err
+	}
+	overrides, err := loadOverrides(f.values, f.sets)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(providers) == 0 && len(overrides) == 0 {
+		return nil, false, fmt.Errorf("no provider registered: specify --provider, --config, --values or --set")
+	}
+	m, usesStdin, err = newMultiLookup(providers, stdin, f.pool)
+	if err != nil {
+		return nil, false, err
+	}
+	return withOverrides(m, overrides), usesStdin, nil
+}
+
+// configs は、 --config と --provider の指定を合わせた prefix ごとの providerConfig を返します。
+//
+// configs returns the providerConfigs per prefix, combining --config and --provider.
+func (f *providerFlags) configs() (map[string]providerConfig, error) {
+	providers, err := parseProviderFlags(f.specs)
+	if err != nil {
+		return nil, err
+	}
+	if f.config == "" {
+		return providers, nil
+	}
+	c, err := loadConfig(f.config)
+	if err != nil {
+		return nil, err
+	}
+	return mergeProviders(c.Providers, providers)
+}
+
+// close は、起動したプラグインやコンパイルしたモジュールを閉じます。
+//
+// close closes the started plugins and the compiled modules.
+func (f *providerFlags) close() {
+	f.pool.close()
+}
+
+// providerConfig は1つの prefix に登録する探索関数の定義です。 Options の内容は Kind ごとに異なります。
+//
+// providerConfig defines the lookup function registered for a prefix. The content of Options depends on Kind.
+type providerConfig struct {
+	Kind    string    `yaml:"kind"`
+	Options yaml.Node `yaml:"options"`
+}
+
+// providerArgOption は、 PREFIX=KIND:ARG 形式の ARG を受け取る Kind と、それを渡す Options のフィールドの名前の対応です。
+//
+// providerArgOption maps the Kinds accepting ARG of PREFIX=KIND:ARG to the names of the fields of Options ARG is passed to.
+var providerArgOption = map[string]string{
+	"file":     "dir",
+	"k8s":      "dir",
+	"keyring":  "service",
+	"plugin":   "command",
+	"wasm":     "path",
+	"age":      "identities",
+	"metadata": "cloud",
+	"build":    "binary",
+}
+
+// parseProviderFlags は PREFIX=KIND[:ARG] 形式の指定を、 prefix ごとの providerConfig に変換します。
+//
+// parseProviderFlags converts specs in the form of PREFIX=KIND[:ARG] into providerConfigs per prefix.
+func parseProviderFlags(specs []string) (map[string]providerConfig, error) {
+	configs := make(map[string]providerConfig, len(specs))
+	for _, spec := range specs {
+		prefix, provider, ok := strings.Cut(spec, "=")
+		if !ok || prefix == "" {
+			return nil, fmt.Errorf("invalid provider %q: must be in the form of PREFIX=KIND[:ARG]", spec)
+		}
+		if _, ok := configs[prefix]; ok {
+			return nil, fmt.Errorf("invalid provider %q: prefix %s is already registered", spec, prefix)
+		}
+		kind, arg, hasArg := strings.Cut(provider, ":")
+		cfg := providerConfig{Kind: kind}
+		if hasArg {
+			name, ok := providerArgOption[kind]
+			if !ok {
+				return nil, fmt.Errorf("invalid provider %q: %s takes no argument", spec, kind)
+			}
+			if err := cfg.Options.Encode(map[string]string{name: arg}); err != nil {
+				return nil, err
+			}
+		}
+		configs[prefix] = cfg
+	}
+	return configs, nil
+}
+
+// newMultiLookup は、 prefix ごとの providerConfig から MultiLookup を組み立てます。 stdin を読む探索関数が含まれるかも返します。
+//
+// newMultiLookup builds a MultiLookup from providerConfigs per prefix. It also reports whether a lookup function reading stdin is included.
+func newMultiLookup(configs map[string]providerConfig, stdin io.Reader, pool *providerPool) (m tempura.MultiLookup, usesStdin bool, err error) {
+	prefixes := make([]string, 0, len(configs))
+	for prefix := range configs {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes) // en: report errors in a stable order
+
+	m = make(tempura.MultiLookup, len(configs))
+	for _, prefix := range prefixes {
+		cfg := configs[prefix]
+		fn, err := newProvider(prefix, cfg, stdin, pool)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid provider for %s: %w", prefix, err)
+		}
+		m[tempura.DotPrefix(prefix)] = fn
+		usesStdin = usesStdin || cfg.Kind == "stdin"
+	}
+	return m, usesStdin, nil
+}
+
+func newProvider(prefix string, cfg providerConfig, stdin io.Reader, pool *providerPool) (tempura.LookupFunc, error) {
+	switch cfg.Kind {
+	case "env":
+		return tempura.Func(os.LookupEnv), decodeOptions(cfg.Options, &struct{}{})
+	case "file":
+		var o struct {
+			Dir string `yaml:"dir"`
+		}
+		if err := decodeOptions(cfg.Options, &o); err != nil {
+			return nil, err
+		}
+		if o.Dir == "" {
+			return nil, fmt.Errorf("file requires a directory, such as file:/run/secrets")
+		}
+		return fileLookup(os.DirFS(o.Dir)), nil
+	case "stdin":
+		return stdinlookup.New(stdin), decodeOptions(cfg.Options, &struct{}{})
+	case "host":
+		return hostlookup.New(), decodeOptions(cfg.Options, &struct{}{})
+	case "k8s":
+		var o struct {
+			Dir string            `yaml:"dir"`
+			Env map[string]string `yaml:"env"`
+		}
+		if err := decodeOptions(cfg.Options, &o); err != nil {
+			return nil, err
+		}
+		return k8slookup.New(k8slookup.Config{Dir: o.Dir, Env: o.Env}), nil
+	case "keyring":
+		var o struct {
+			Service string `yaml:"service"`
+		}
+		if err := decodeOptions(cfg.Options, &o); err != nil {
+			return nil, err
+		}
+		return keyringlookup.New(o.Service), nil
+	case "exec":
+		var o struct {
+			Commands       map[string]string `yaml:"commands"`
+			Timeout        time.Duration     `yaml:"timeout"`
+			MaxOutputBytes int               `yaml:"max_output_bytes"`
+			Env            []string          `yaml:"env"`
+			PassEnv        []string          `yaml:"pass_env"`
+			Dir            string            `yaml:"dir"`
+		}
+		if err := decodeOptions(cfg.Options, &o); err != nil {
+			return nil, err
+		}
+		if len(o.Commands) == 0 {
+			return nil, fmt.Errorf("exec requires commands")
+		}
+		return execlookup.New(execlookup.Config{
+			Commands:       o.Commands,
+			Timeout:        o.Timeout,
+			MaxOutputBytes: o.MaxOutputBytes,
+			Env:            o.Env,
+			PassEnv:        o.PassEnv,
+			Dir:            o.Dir,
+		}), nil
+	case "plugin":
+		var o struct {
+			Command string        `yaml:"command"`
+			Args    []string      `yaml:"args"`
+			Env     []string      `yaml:"env"`
+			Timeout time.Duration `yaml:"timeout"`
+		}
+		if err := decodeOptions(cfg.Options, &o); err != nil {
+			return nil, err
+		}
+		if o.Command == "" {
+			return nil, fmt.Errorf("plugin requires a command, such as plugin:%sfoo", pluginlookup.CommandPrefix)
+		}
+		pc := pluginlookup.Config{
+			Command: o.Command,
+			Args:    o.Args,
+			Env:     o.Env,
+			Timeout: o.Timeout,
+			Stderr:  os.Stderr, // en: an *os.File is inherited as is, without a goroutine copying into a shared writer
+		}
+		return pool.get(prefix, pc, func() (pooledProvider, error) {
+			p := pluginlookup.New(pc)
+			return pooledProvider{lookup: p.Lookup(), close: func() { p.Close() }, info: p.Info}, nil
+		})
+	case "wasm":
+		var o struct {
+			Path             string        `yaml:"path"`
+			Args             []string      `yaml:"args"`
+			Env              []string      `yaml:"env"`
+			Timeout          time.Duration `yaml:"timeout"`
+			MemoryLimitPages uint32        `yaml:"memory_limit_pages"`
+		}
+		if err := decodeOptions(cfg.Options, &o); err != nil {
+			return nil, err
+		}
+		if o.Path == "" {
+			return nil, fmt.Errorf("wasm requires a path, such as wasm:provider.wasm")
+		}
+		wasm, err := os.ReadFile(o.Path)
+		if err != nil {
+			return nil, err
+		}
+		wc := wasmlookup.Config{
+			Name:             filepath.Base(o.Path),
+			Args:             o.Args,
+			Env:              o.Env,
+			Timeout:          o.Timeout,
+			MemoryLimitPages: o.MemoryLimitPages,
+			Stderr:           os.Stderr,
+		}
+		// en: key the pool by the content as well, so that a rebuilt module is compiled again
+		return pool.get(prefix, struct {
+			wasmlookup.Config
+			Wasm []byte
+		}{wc, wasm}, func() (pooledProvider, error) {
+			m, err := wasmlookup.New(context.Background(), wasm, wc)
+			if err != nil {
+				return pooledProvider{}, err
+			}
+			return pooledProvider{lookup: m.Lookup(), close: func() { m.Close(context.Background()) }, info: m.Info}, nil
+		})
+	case "age":
+		var o struct {
+			Identities    string `yaml:"identities"`
+			IdentitiesEnv string `yaml:"identities_env"`
+			Inline        bool   `yaml:"inline"`
+		}
+		if err := decodeOptions(cfg.Options, &o); err != nil {
+			return nil, err
+		}
+		var ids []age.Identity
+		var err error
+		switch {
+		case o.Identities != "" && o.IdentitiesEnv != "":
+			return nil, fmt.Errorf("age takes either identities or identities_env")
+		case o.Identities != "":
+			ids, err = agelookup.IdentitiesFromFile(o.Identities)
+		case o.IdentitiesEnv != "":
+			ids, err = agelookup.IdentitiesFromEnv(o.IdentitiesEnv)
+		default:
+			return nil, fmt.Errorf("age requires identities, such as age:key.txt")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if o.Inline {
+			return agelookup.New(ids...).Inline(), nil
+		}
+		return agelookup.New(ids...).File(), nil
+	case "metadata":
+		var o struct {
+			Cloud    string        `yaml:"cloud"`
+			Endpoint string        `yaml:"endpoint"`
+			Timeout  time.Duration `yaml:"timeout"`
+		}
+		if err := decodeOptions(cfg.Options, &o); err != nil {
+			return nil, err
+		}
+		mc := metadatalookup.Config{Endpoint: o.Endpoint}
+		if o.Timeout > 0 {
+			mc.Client = &http.Client{Timeout: o.Timeout}
+		}
+		switch o.Cloud {
+		case "ec2":
+			return metadatalookup.EC2(mc), nil
+		case "gce":
+			return metadatalookup.GCE(mc), nil
+		case "azure":
+			return metadatalookup.Azure(mc), nil
+		default:
+			return nil, fmt.Errorf("metadata requires a cloud of ec2, gce or azure, such as metadata:ec2")
+		}
+	case "time":
+		var o struct {
+			Fixed time.Time `yaml:"fixed"`
+		}
+		if err := decodeOptions(cfg.Options, &o); err != nil {
+			return nil, err
+		}
+		if o.Fixed.IsZero() {
+			return timelookup.New(nil), nil
+		}
+		return timelookup.New(timelookup.Fixed(o.Fixed)), nil // en: for reproducible renders
+	case "rand":
+		var o struct {
+			Seed *int64 `yaml:"seed"`
+		}
+		if err := decodeOptions(cfg.Options, &o); err != nil {
+			return nil, err
+		}
+		if o.Seed == nil {
+			return randlookup.New(nil), nil
+		}
+		return randlookup.New(randlookup.Seeded(*o.Seed)), nil // en: deterministic, never for secrets
+	case "build":
+		var o struct {
+			Binary string `yaml:"binary"`
+		}
+		if err := decodeOptions(cfg.Options, &o); err != nil {
+			return nil, err
+		}
+		if o.Binary == "" {
+			return buildlookup.New(nil), nil
+		}
+		info, err := buildinfo.ReadFile(o.Binary)
+		if err != nil {
+			return nil, err
+		}
+		return buildlookup.New(info), nil
+	case "":
+		return nil, fmt.Errorf("kind is required")
+	default:
+		return nil, fmt.Errorf("unknown kind %q", cfg.Kind)
+	}
+}
+
+// providerPool は、プラグインのプロセスやコンパイルした WASM のモジュールのように作るのに費用がかかる探索関数を、設定が変わらない間は描画や再解決をまたいで使い回します。
+//
+// providerPool reuses lookup functions costly to create, such as plugin processes and compiled WASM modules, across renders and re-resolutions while their configs stay the same.
+type providerPool struct {
+	mu        sync.Mutex
+	providers map[string]pooledProvider // en: by prefix
+}
+
+type pooledProvider struct {
+	cfg    any
+	lookup tempura.LookupFunc
+	close  func()
+	info   func(ctx context.Context) (pluginlookup.Info, error)
+}
+
+// get は prefix に登録された探索関数を返します。まだないか設定が変わった場合は、古い探索関数を閉じて open で新しく作ります。
+//
+// get returns the lookup function registered for prefix. If there is none yet or the config has changed, it closes the old one and creates a new one with open.
+func (p *providerPool) get(prefix string, cfg any, open func() (pooledProvider, error)) (tempura.LookupFunc, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pooled, ok := p.providers[prefix]; ok {
+		if reflect.DeepEqual(pooled.cfg, cfg) {
+			return pooled.lookup, nil
+		}
+		pooled.close()
+		delete(p.providers, prefix)
+	}
+	pooled, err := open()
+	if err != nil {
+		return nil, err
+	}
+	if p.providers == nil {
+		p.providers = make(map[string]pooledProvider)
+	}
+	pooled.cfg = cfg
+	p.providers[prefix] = pooled
+	return pooled.lookup, nil
+}
+
+// info は、 prefix に登録された探索関数がハンドシェイクで返す情報を返します。ハンドシェイクのない探索関数の場合は false を返します。
+//
+// info returns what the lookup function registered for prefix returns in the handshake. It returns false for lookup functions without handshakes.
+func (p *providerPool) info(ctx context.Context, prefix string) (pluginlookup.Info, bool, error) {
+	p.mu.Lock()
+	pooled, ok := p.providers[prefix]
+	p.mu.Unlock()
+	if !ok {
+		return pluginlookup.Info{}, false, nil
+	}
+	info, err := pooled.info(ctx)
+	return info, true, err
+}
+
+func (p *providerPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for prefix, pooled := range p.providers {
+		pooled.close()
+		delete(p.providers, prefix)
+	}
+}
+
+// decodeOptions は、 options を out に復号します。打ち間違いに気付けるよう、未知のフィールドはエラーとします。
+//
+// decodeOptions decodes options into out. Unknown fields are errors so that typos are noticed.
+func decodeOptions(options yaml.Node, out any) error {
+	if options.Kind == 0 {
+		return nil
+	}
+	b, err := yaml.Marshal(&options)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	return nil
+}
+
+// fileLookup は、prefixを取り除いたキーを fsys のファイルのパスとして、末尾の改行を取り除いた内容を返す探索関数を返します。
+//
+// fileLookup returns a lookup function that returns the content of the file in fsys at the key with the prefix removed, without trailing newlines.
+func fileLookup(fsys fs.FS) tempura.LookupAnyWithError {
+	return tempura.FuncWithError(func(key string) (string, bool, error) {
+		if !fs.ValidPath(key) {
+			return "", false, fmt.Errorf("invalid file path: %s", key)
+		}
+		b, err := fs.ReadFile(fsys, key)
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return strings.TrimRight(string(b), "\r\n"), true, nil
+	})
+}
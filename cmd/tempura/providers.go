@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ebi-yade/go-tempura/pluginlookup"
+	"github.com/spf13/pflag"
+)
+
+// providersHealthTimeout は、プラグインやモジュールのハンドシェイクを待つ時間です。
+//
+// providersHealthTimeout is how long the handshakes of plugins and modules are waited for.
+const providersHealthTimeout = 5 * time.Second
+
+// runProviders は providers サブコマンドを実行します。組み込みの探索関数の種類とそのオプション、 PATH から見つかったプラグイン、登録した prefix を、それぞれの状態とともに一覧にします。
+// PATH から見つかっただけのプラグインは確かめられた実行ファイルとは限らないため、 --health を指定した場合にだけ起動して状態を確認します。
+// 状態が ok でないプラグインや prefix があった場合は 1 を返します。
+//
+// runProviders executes the providers subcommand. It lists the built-in kinds of lookup functions with their options, the plugins discovered in PATH and the registered prefixes, with the status of each.
+// Since plugins merely discovered in PATH are not necessarily vetted executables, they are started to check their status only with --health.
+// It returns 1 if any plugin or prefix is not ok.
+func runProviders(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := pflag.NewFlagSet("providers", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: tempura providers [flags]\n\nflags:\n%s", fs.FlagUsages())
+	}
+	providers := addProviderFlags(fs)
+	defer providers.close()
+	rep := addReporterFlags(fs, stdout, stderr)
+	health := fs.Bool("health", false, "start the plugins discovered in PATH to check their status")
+	noHealth := fs.Bool("no-health", false, "list without starting the plugins and modules of registered prefixes to check their status")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if err := rep.validate(); err != nil {
+		fmt.Fprintf(stderr, "tempura: %v\n", err)
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	configs, err := providers.configs()
+	if err != nil {
+		rep.error(err)
+		return 1
+	}
+
+	code := 0
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tARG\tOPTIONS\tDESCRIPTION")
+	for _, k := range providerKinds {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.name, orDash(providerArgOption[k.name]), orDash(strings.Join(k.options, ",")), k.description)
+	}
+
+	discovered := pluginlookup.Discover()
+	names := make([]string, 0, len(discovered))
+	for name := range discovered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "\nPLUGIN\tCOMMAND\tSTATUS\tDESCRIPTION")
+	for _, name := range names {
+		status, description := "-", "-"
+		if *health {
+			p := pluginlookup.New(pluginlookup.Config{Command: discovered[name], Timeout: providersHealthTimeout, Stderr: os.Stderr})
+			info, err := p.Info(ctx)
+			p.Close()
+			status, description = healthStatus(info, err)
+		}
+		if strings.HasPrefix(status, "error") {
+			code = 1
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, discovered[name], status, description)
+	}
+
+	if len(configs) > 0 {
+		prefixes := make([]string, 0, len(configs))
+		for prefix := range configs {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+		fmt.Fprintln(w, "\nPREFIX\tKIND\tSTATUS\tDESCRIPTION")
+		for _, prefix := range prefixes {
+			status, description := prefixStatus(ctx, prefix, configs[prefix], stdin, providers.pool, *noHealth)
+			if strings.HasPrefix(status, "error") {
+				code = 1
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", prefix, configs[prefix].Kind, status, description)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		rep.error(err)
+		return 1
+	}
+	return code
+}
+
+// prefixStatus は、 prefix に登録する探索関数を作り、その状態と説明を返します。プラグインとモジュールは noHealth でない場合にハンドシェイクで確認します。
+//
+// prefixStatus creates the lookup function registered for prefix, and returns its status and description. Plugins and modules are checked with their handshakes unless noHealth.
+func prefixStatus(ctx context.Context, prefix string, cfg providerConfig, stdin io.Reader, pool *providerPool, noHealth bool) (status, description string) {
+	if _, err := newProvider(prefix, cfg, stdin, pool); err != nil {
+		return "error: " + err.Error(), "-"
+	}
+	if noHealth {
+		return "-", "-"
+	}
+	ctx, cancel := context.WithTimeout(ctx, providersHealthTimeout)
+	defer cancel()
+	info, ok, err := pool.info(ctx, prefix)
+	if !ok {
+		return "ok", "-" // en: nothing to check beyond the options without handshakes
+	}
+	return healthStatus(info, err)
+}
+
+// healthStatus は、ハンドシェイクの結果を状態と説明に変換します。
+//
+// healthStatus converts the outcome of a handshake into a status and a description.
+func healthStatus(info pluginlookup.Info, err error) (status, description string) {
+	if err != nil {
+		return "error: " + err.Error(), "-"
+	}
+	return "ok", orDash(info.Description)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
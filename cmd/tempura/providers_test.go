@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_Providers(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	t.Setenv("TEMPURA_TEST_PLUGIN", "serve")
+	dir := t.TempDir()
+	writeScript(t, dir, "tempura-provider-test", `exec "`+exe+`" "$@"`)
+	writeScript(t, dir, "tempura-provider-broken", `exit 1`)
+	t.Setenv("PATH", dir)
+
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		expected []string
+		stderr   string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name: "without health",
+			args: []string{"--no-health", "-p", "env=env", "-p", "foo=plugin:" + exe},
+			expected: []string{
+				`(?m)^file\s+dir\s+dir\s+contents of files under DIR`,
+				`(?m)^wasm\s+path\s+path,args,env,timeout,memory_limit_pages\s`,
+				`(?m)^broken\s+\S+/tempura-provider-broken\s+-\s+-$`,
+				`(?m)^test\s+\S+/tempura-provider-test\s+-\s+-$`,
+				`(?m)^env\s+env\s+-\s+-$`,
+				`(?m)^foo\s+plugin\s+-\s+-$`,
+			},
+		},
+		{
+			name: "healthy prefixes",
+			args: []string{"-p", "env=env", "-p", "foo=plugin:" + exe},
+			expected: []string{
+				`(?m)^broken\s+\S+/tempura-provider-broken\s+-\s+-$`, // en: discovered plugins are not started without --health
+				`(?m)^test\s+\S+/tempura-provider-test\s+-\s+-$`,
+				`(?m)^env\s+env\s+ok\s+-$`,
+				`(?m)^foo\s+plugin\s+ok\s+test plugin$`,
+			},
+		},
+		{
+			name: "healthy discovered plugins",
+			args: []string{"--health"},
+			code: 1, // en: for the broken plugin
+			expected: []string{
+				`(?m)^test\s+\S+/tempura-provider-test\s+ok\s+test plugin$`,
+			},
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:     "broken plugin",
+			args:     []string{"--health"},
+			code:     1,
+			expected: []string{`(?m)^broken\s+\S+/tempura-provider-broken\s+error: .*protocol violation`},
+		},
+		{
+			name:     "invalid prefix",
+			args:     []string{"--no-health", "-p", "foo=plugin"},
+			code:     1,
+			expected: []string{`(?m)^foo\s+plugin\s+error: plugin requires a command`},
+		},
+		{
+			name:   "invalid provider",
+			args:   []string{"-p", "foo"},
+			code:   1,
+			stderr: "must be in the form of PREFIX=KIND[:ARG]",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), append([]string{"providers"}, tt.args...), strings.NewReader(""), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			for _, expected := range tt.expected {
+				assert.Regexp(t, expected, stdout.String())
+			}
+			assert.Contains(t, stderr.String(), tt.stderr)
+		})
+	}
+}
+
+func TestProviderKinds(t *testing.T) {
+	for _, k := range providerKinds {
+		for _, option := range k.options {
+			cfg := providerConfig{Kind: k.name}
+			require.NoError(t, cfg.Options.Encode(map[string]any{option: nil}))
+			_, err := newProvider("foo", cfg, strings.NewReader(""), &providerPool{})
+			if err != nil {
+				assert.NotContains(t, err.Error(), "not found in type", "%s does not take %s", k.name, option)
+			}
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// runRender は render サブコマンドを実行します。
+//
+// runRender executes the render subcommand.
+func runRender(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := pflag.NewFlagSet("render", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: tempura render [flags] [template|-]\n\nflags:\n%s\nproviders:\n%s", fs.FlagUsages(), providerUsage)
+	}
+	providers := addProviderFlags(fs)
+	defer providers.close()
+	rep := addReporterFlags(fs, stdout, stderr)
+	dataFile := fs.StringP("data", "d", "", "YAML or JSON `file` used as the data of the template")
+	output := fs.StringP("output", "o", "", "write to `file` instead of stdout")
+	outputDir := fs.StringP("output-dir", "O", "", "split the output at lines of \"--- tempura:output PATH\" and write each part to PATH under `dir`")
+	watchMode := fs.BoolP("watch", "w", false, "render again whenever the template, the data, the config or the files read by providers change")
+	diff := fs.Bool("diff", false, "print a unified diff against the existing --output or --output-dir files before writing them (shows rendered values)")
+	check := fs.Bool("check", false, "with --diff, exit with 1 without writing if the output differs from the existing --output or --output-dir files")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if err := rep.validate(); err != nil {
+		fmt.Fprintf(stderr, "tempura: %v\n", err)
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	template := "-" // en: read the template from stdin by default, for pipelines
+	if fs.NArg() == 1 {
+		template = fs.Arg(0)
+	}
+	if *output != "" && *outputDir != "" {
+		fmt.Fprintln(stderr, "tempura: --output and --output-dir cannot be used together")
+		return 2
+	}
+	if (*diff || *check) && *output == "" && *outputDir == "" {
+		fmt.Fprintln(stderr, "tempura: --diff and --check require --output or --output-dir")
+		return 2
+	}
+
+	cfg := renderConfig{
+		template:  template,
+		providers: providers,
+		dataFile:  *dataFile,
+		output:    *output,
+		outputDir: *outputDir,
+		diff:      *diff || *check,
+		check:     *check,
+	}
+	if *watchMode {
+		if err := watch(ctx, cfg, stdin, stdout, rep); err != nil {
+			rep.error(err)
+			return 1
+		}
+		return 0
+	}
+	if err := render(ctx, cfg, stdin, stdout); err != nil {
+		rep.error(err)
+		return 1
+	}
+	return 0
+}
+
+type renderConfig struct {
+	template  string
+	providers *providerFlags
+	dataFile  string
+	output    string
+	outputDir string
+	diff      bool
+	check     bool
+}
+
+func render(ctx context.Context, cfg renderConfig, stdin io.Reader, stdout io.Writer) error {
+	m, usesStdin, err := cfg.providers.multiLookup(stdin)
+	if err != nil {
+		return err
+	}
+
+	var text []byte
+	if cfg.template == "-" {
+		if usesStdin {
+			return fmt.Errorf("the template and the stdin provider cannot both read stdin")
+		}
+		text, err = io.ReadAll(stdin)
+	} else {
+		text, err = os.ReadFile(cfg.template)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read template: %w", err)
+	}
+
+	data, err := loadData(cfg.dataFile)
+	if err != nil {
+		return err
+	}
+	mc := m.BindContext(ctx)
+	if err := mc.Validate(); err != nil {
+		return err
+	}
+	b, err := executeTemplate(mc, cfg.providers.funcName, cfg.template, text, data)
+	if err != nil {
+		return err
+	}
+
+	var outputs []renderedOutput
+	switch {
+	case cfg.outputDir != "":
+		if outputs, err = splitOutputs(cfg.outputDir, b); err != nil {
+			return err
+		}
+	case cfg.output != "":
+		outputs = []renderedOutput{{path: cfg.output, content: b}}
+	default:
+		_, err = stdout.Write(b)
+		return err
+	}
+
+	if cfg.diff {
+		var differing []renderedOutput
+		for _, o := range outputs {
+			differs, err := writeDiff(stdout, o.path, o.content)
+			if err != nil {
+				return err
+			}
+			if differs {
+				differing = append(differing, o)
+			}
+		}
+		if cfg.check {
+			if len(differing) > 0 {
+				return fmt.Errorf("%s: %w", outputPaths(differing), errOutputDiffers)
+			}
+			return nil
+		}
+	}
+	return writeOutputs(outputs)
+}
+
+// writeOutputs は outputs を書き込みます。失敗しても書きかけのファイルが残らないよう、全てを一時ファイルに書いてから名前を変更します。
+// 描画したファイルは秘密を含むことが多いため、新しいファイルは 0600 、新しいディレクトリは 0700 で作り、既にあるファイルはそのパーミッションを保ちます。
+//
+// writeOutputs writes outputs. It writes all of them to temporary files before renaming them, so that a failure never leaves files half-written.
+// Since rendered files often contain secrets, new files are created with 0600 and new directories with 0700, while existing files keep their permissions.
+func writeOutputs(outputs []renderedOutput) (err error) {
+	temps := make([]string, 0, len(outputs))
+	defer func() {
+		if err != nil {
+			for _, temp := range temps {
+				os.Remove(temp)
+			}
+		}
+	}()
+	for _, o := range outputs {
+		temp, err := writeTemp(o)
+		if err != nil {
+			return err
+		}
+		temps = append(temps, temp)
+	}
+	for i, o := range outputs {
+		if err := os.Rename(temps[i], o.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTemp(o renderedOutput) (string, error) {
+	mode := fs.FileMode(0o600)
+	if info, err := os.Stat(o.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(o.path), 0o700); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(filepath.Dir(o.path), "."+filepath.Base(o.path)+".*") // en: in the same directory, for rename to be atomic
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(o.content)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// loadData は、テンプレートのデータとして YAML または JSON の file を読み込みます。 file が空の場合は nil を返します。
+//
+// loadData loads the YAML or JSON file as the data of templates. It returns nil if file is empty.
+func loadData(file string) (any, error) {
+	if file == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+	var data any
+	if err := yaml.Unmarshal(b, &data); err != nil { // en: YAML is a superset of JSON
+		return nil, fmt.Errorf("failed to parse data: %w", err)
+	}
+	return data, nil
+}
+
+// executeTemplate は、 mc を funcName として登録して、 name のテンプレート text を描画します。
+// 失敗しても書きかけの出力が残らないよう、メモリ上に描画します。
+//
+// executeTemplate renders the template text of name with mc registered as funcName.
+// It renders into memory so that a failure never leaves a truncated output.
+func executeTemplate(mc *tempura.MultiLookupContext, funcName, name string, text []byte, data any) ([]byte, error) {
+	t, err := tempura.RegisterTo(newTemplate(name), funcName, mc).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	b := &bytes.Buffer{}
+	if err := t.Execute(b, data); err != nil {
+		return nil, tempura.AnnotateTemplateError(err)
+	}
+	return b.Bytes(), nil
+}
+
+var errOutputDiffers = errors.New("rendered output differs from the existing file")
+
+// writeDiff は、既にある name のファイルと rendered の unified diff を w に書き込み、差分があるかを返します。 name のファイルがない場合は空のファイルと比較します。
+//
+// writeDiff writes the unified diff between the existing file name and rendered to w, and reports whether they differ. If the file name does not exist, it is compared as an empty file.
+func writeDiff(w io.Writer, name string, rendered []byte) (bool, error) {
+	existing, err := os.ReadFile(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if bytes.Equal(existing, rendered) {
+		return false, nil
+	}
+	err = difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(existing)),
+		B:        difflib.SplitLines(string(rendered)),
+		FromFile: name,
+		ToFile:   name + " (rendered)",
+		Context:  3,
+	})
+	return true, err
+}
+
+// newTemplate は、エラーの位置に表示されるよう、テンプレートのファイル名を名前とした template.Template を返します。
+//
+// newTemplate returns a template.Template named after the template file, so that it appears in error positions.
+func newTemplate(name string) *template.Template {
+	if name == "-" {
+		name = "stdin"
+	}
+	return template.New(filepath.Base(name))
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/spf13/pflag"
+)
+
+// diagnostic は、 --error-format json で stderr に1行ずつ書き込まれるエラーや診断です。
+//
+// diagnostic is an error or a diagnostic written to stderr line by line with --error-format json.
+type diagnostic struct {
+	Level    string `json:"level"`
+	Message  string `json:"message"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Template string `json:"template,omitempty"`
+	Call     string `json:"call,omitempty"`
+	Arg      string `json:"arg,omitempty"`
+}
+
+// reporter は、エラーや診断を --error-format に従って書き込みます。
+//
+// reporter writes errors and diagnostics according to --error-format.
+type reporter struct {
+	format string
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func addReporterFlags(fs *pflag.FlagSet, stdout, stderr io.Writer) *reporter {
+	r := &reporter{stdout: stdout, stderr: stderr}
+	fs.StringVar(&r.format, "error-format", "text", "`format` of errors and diagnostics: text or json (written to stderr)")
+	return r
+}
+
+// validate は --error-format の値を検査します。
+//
+// validate checks the value of --error-format.
+func (r *reporter) validate() error {
+	if r.format != "text" && r.format != "json" {
+		return fmt.Errorf("invalid --error-format %q: must be text or json", r.format)
+	}
+	return nil
+}
+
+// parseErrorLocation は、 text/template が解析のエラーに付ける位置の形式です。
+//
+// parseErrorLocation is the format of positions text/template gives to parse errors.
+var parseErrorLocation = regexp.MustCompile(`^template: (.*?):(\d+):(?:(\d+):)? (.*)$`)
+
+// error は err を書き込みます。 json の場合は、 TemplateError やテンプレートの解析のエラーの位置を構造化して書き込みます。
+//
+// error writes err. With json, it writes the positions of TemplateErrors and parse errors of templates as structured fields.
+func (r *reporter) error(err error) {
+	if r.format != "json" {
+		fmt.Fprintf(r.stderr, "tempura: %v\n", err)
+		return
+	}
+	r.write(newDiagnostic(err))
+}
+
+// newDiagnostic は、 TemplateError やテンプレートの解析のエラーの位置を構造化した diagnostic を返します。
+//
+// newDiagnostic returns a diagnostic with the positions of TemplateErrors and parse errors of templates as structured fields.
+func newDiagnostic(err error) diagnostic {
+	d := diagnostic{Level: "error", Message: err.Error()}
+	var te tempura.TemplateError
+	if errors.As(err, &te) {
+		d.File, d.Line, d.Column, d.Template, d.Call = te.File, te.Line, te.Column, te.Template, te.Call
+	} else if match := parseErrorLocation.FindStringSubmatch(innermost(err).Error()); match != nil {
+		d.File = match[1]
+		d.Line, _ = strconv.Atoi(match[2])
+		d.Column, _ = strconv.Atoi(match[3])
+	}
+	return d
+}
+
+// info は、描画の完了などの情報を stderr に書き込みます。
+//
+// info writes information, such as completion of renders, to stderr.
+func (r *reporter) info(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if r.format != "json" {
+		fmt.Fprintf(r.stderr, "tempura: %s\n", msg)
+		return
+	}
+	r.write(diagnostic{Level: "info", Message: msg})
+}
+
+// issue は、 Lint が見つけた問題を書き込みます。 text の場合は stdout に、 json の場合は stderr に書き込みます。
+//
+// issue writes a problem found by Lint. With text it goes to stdout, and with json to stderr.
+func (r *reporter) issue(issue tempura.Issue) {
+	if r.format != "json" {
+		fmt.Fprintln(r.stdout, issue)
+		return
+	}
+	r.write(diagnostic{
+		Level:    "warning",
+		Message:  issue.Message,
+		File:     issue.File,
+		Line:     issue.Line,
+		Column:   issue.Column,
+		Template: issue.Template,
+		Arg:      issue.Arg,
+	})
+}
+
+func (r *reporter) write(d diagnostic) {
+	b, _ := json.Marshal(d)
+	fmt.Fprintf(r.stderr, "%s\n", b)
+}
+
+func innermost(err error) error {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
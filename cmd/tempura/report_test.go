@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_ErrorFormat(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "bad.yaml.tmpl")
+	require.NoError(t, os.WriteFile(tmpl, []byte("host: {{ lookup \"env.HOST\" }}\nport: {{ lookup \"evn.PORT\" }}\n"), 0o644))
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		code     int
+		stdout   string
+		expected []diagnostic
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:   "text",
+			args:   []string{"render", "-p", "env=env", "--error-format", "text"},
+			stdin:  `{{ lookup "env.TEMPURA_TEST_MISSING" }}`,
+			code:   1,
+			stdout: "",
+		},
+		{
+			name:  "render error",
+			args:  []string{"render", "-p", "env=env", "--error-format", "json"},
+			stdin: "a: 1\nb: {{ lookup \"env.TEMPURA_TEST_MISSING\" }}\n",
+			code:  1,
+			expected: []diagnostic{{
+				Level:    "error",
+				File:     "stdin",
+				Line:     2,
+				Column:   6,
+				Template: "stdin",
+				Call:     `lookup "env.TEMPURA_TEST_MISSING"`,
+			}},
+		},
+		{
+			name:     "parse error",
+			args:     []string{"render", "-p", "env=env", "--error-format", "json"},
+			stdin:    "a: 1\nb: {{ lookup \n",
+			code:     1,
+			expected: []diagnostic{{Level: "error", Message: "unclosed action", File: "stdin", Line: 3}},
+		},
+		{
+			name: "lint issues",
+			args: []string{"lint", "-p", "env=env", "--error-format", "json", tmpl},
+			code: 1,
+			expected: []diagnostic{{
+				Level:    "warning",
+				Message:  "matches no registered prefix",
+				File:     tmpl,
+				Line:     2,
+				Column:   16,
+				Template: tmpl,
+				Arg:      "evn.PORT",
+			}},
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "unknown format",
+			args: []string{"render", "-p", "env=env", "--error-format", "xml"},
+			code: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), tt.args, strings.NewReader(tt.stdin), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Equal(t, tt.stdout, stdout.String())
+			if tt.expected == nil {
+				return
+			}
+
+			var actual []diagnostic
+			dec := json.NewDecoder(stderr)
+			for dec.More() {
+				var d diagnostic
+				require.NoError(t, dec.Decode(&d))
+				actual = append(actual, d)
+			}
+			require.Len(t, actual, len(tt.expected))
+			for i, expected := range tt.expected {
+				assert.Contains(t, actual[i].Message, expected.Message)
+				expected.Message = actual[i].Message
+				assert.Equal(t, expected, actual[i])
+			}
+		})
+	}
+}
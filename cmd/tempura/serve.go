@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// serveMaxBodyBytes はリクエストのボディの最大サイズです。
+	// serveMaxBodyBytes limits the size of request bodies.
+	serveMaxBodyBytes = 1 << 20
+
+	// serveShutdownTimeout は、終了時に処理中のリクエストを待つ時間です。
+	// serveShutdownTimeout is how long in-flight requests are waited for on shutdown.
+	serveShutdownTimeout = 10 * time.Second
+
+	// serveTokenEnv はトークンを渡す環境変数の名前です。
+	// serveTokenEnv is the name of the environment variable passing the token.
+	serveTokenEnv = "TEMPURA_SERVE_TOKEN"
+)
+
+// runServe は serve サブコマンドを実行します。設定した探索関数による解決とテンプレートの描画を HTTP で公開し、サイドカーや Go 以外のサービスが1つの設定を共有できるようにします。
+//
+// runServe executes the serve subcommand. It exposes resolution and rendering of templates with the configured lookup functions over HTTP, so that sidecars and non-Go services can share one configuration.
+func runServe(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := pflag.NewFlagSet("serve", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: tempura serve [flags]\n\nendpoints:\n%s\nflags:\n%s\nproviders:\n%s", serveUsage, fs.FlagUsages(), providerUsage)
+	}
+	providers := addProviderFlags(fs)
+	defer providers.close()
+	rep := addReporterFlags(fs, stdout, stderr)
+	addr := fs.String("addr", "127.0.0.1:8600", "`address` to listen on")
+	tokenFile := fs.String("token-file", "", "`file` containing the bearer token clients must send (default: $"+serveTokenEnv+")")
+	noAuth := fs.Bool("no-auth", false, "serve without authentication, only for trusted networks")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if err := rep.validate(); err != nil {
+		fmt.Fprintf(stderr, "tempura: %v\n", err)
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	token, err := serveToken(*tokenFile)
+	if err != nil {
+		rep.error(err)
+		return 1
+	}
+	if token == "" && !*noAuth {
+		fmt.Fprintf(stderr, "tempura: serve requires --token-file or $%s, or --no-auth explicitly\n", serveTokenEnv)
+		return 2
+	}
+
+	m, _, err := providers.multiLookup(stdin)
+	if err != nil {
+		rep.error(err)
+		return 1
+	}
+	if err := m.BindContext(ctx).Validate(); err != nil {
+		rep.error(err)
+		return 1
+	}
+	if err := serve(ctx, *addr, newServeHandler(m, providers.funcName, token), rep); err != nil {
+		rep.error(err)
+		return 1
+	}
+	return 0
+}
+
+const serveUsage = `  POST /v1/resolve  resolve {"keys": [...]} like the lookup function, falling back through keys
+  POST /v1/render   render {"template": "...", "data": {...}} and respond the output
+  GET  /healthz     respond 200 without authentication
+`
+
+func serveToken(tokenFile string) (string, error) {
+	if tokenFile == "" {
+		return os.Getenv(serveTokenEnv), nil
+	}
+	b, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", tokenFile)
+	}
+	return token, nil
+}
+
+// serve は ctx が終了するまで addr で handler を公開し、終了時には処理中のリクエストを待ちます。
+//
+// serve exposes handler on addr until ctx is done, waiting for in-flight requests on shutdown.
+func serve(ctx context.Context, addr string, handler http.Handler, rep *reporter) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	rep.info("serving on %s", ln.Addr())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// newServeHandler は serve サブコマンドのエンドポイントを持つ http.Handler を返します。 token が空でない場合、 /healthz 以外では Bearer トークンを要求します。
+//
+// newServeHandler returns an http.Handler with the endpoints of the serve subcommand. If token is not empty, it requires the bearer token except on /healthz.
+func newServeHandler(m tempura.MultiLookup, funcName, token string) http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("/v1/resolve", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Keys []string `json:"keys"`
+		}
+		if !decodeServeRequest(w, r, &req) {
+			return
+		}
+		if len(req.Keys) == 0 {
+			writeServeJSON(w, http.StatusBadRequest, diagnostic{Level: "error", Message: "keys are required"})
+			return
+		}
+		mc, _ := tempura.FromRequest(r)
+		val, err := mc.FuncMapValue(req.Keys...)
+		if err != nil {
+			writeServeJSON(w, resolveStatusCode(err), diagnostic{Level: "error", Message: err.Error()})
+			return
+		}
+		writeServeJSON(w, http.StatusOK, map[string]any{"value": val})
+	})
+	api.HandleFunc("/v1/render", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Template string `json:"template"`
+			Data     any    `json:"data"`
+		}
+		if !decodeServeRequest(w, r, &req) {
+			return
+		}
+		mc, _ := tempura.FromRequest(r)
+		b, err := executeTemplate(mc, funcName, "template", []byte(req.Template), req.Data)
+		if err != nil {
+			status := http.StatusUnprocessableEntity // en: the template is broken
+			var te tempura.TemplateError
+			if errors.As(err, &te) {
+				status = resolveStatusCode(err)
+			}
+			writeServeJSON(w, status, newDiagnostic(err))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(b)
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok\n")
+	})
+	mux.Handle("/", requireToken(token, tempura.Middleware(m)(api)))
+	return mux
+}
+
+// resolveStatusCode は、解決のエラーを HTTP のステータスコードに変換します。キーが見つからない場合は 404 、探索関数のエラーは 502 です。
+//
+// resolveStatusCode converts an error of resolution into an HTTP status code: 404 if keys are not found, and 502 for errors of lookup functions.
+func resolveStatusCode(err error) int {
+	switch {
+	case errors.Is(err, tempura.ErrNotFound), errors.Is(err, tempura.ErrMatchFailed):
+		return http.StatusNotFound
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tempura"`)
+			writeServeJSON(w, http.StatusUnauthorized, diagnostic{Level: "error", Message: "invalid or missing bearer token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func decodeServeRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeServeJSON(w, http.StatusMethodNotAllowed, diagnostic{Level: "error", Message: "method not allowed"})
+		return false
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, serveMaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeServeJSON(w, http.StatusBadRequest, diagnostic{Level: "error", Message: fmt.Sprintf("invalid request: %v", err)})
+		return false
+	}
+	return true
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
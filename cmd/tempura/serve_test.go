@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeHandler(t *testing.T) {
+	t.Parallel()
+
+	m := tempura.MultiLookup{
+		tempura.DotPrefix("env"): tempura.Func(func(key string) (string, bool) { return "env-of-" + key, key != "MISSING" }),
+		tempura.DotPrefix("secret"): tempura.FuncWithError(func(key string) (string, bool, error) {
+			return "", false, errors.New("vault sealed")
+		}),
+	}
+	srv := httptest.NewServer(newServeHandler(m, "lookup", "s3cret"))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		body     string
+		status   int
+		expected string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "resolve",
+			method:   http.MethodPost,
+			path:     "/v1/resolve",
+			token:    "s3cret",
+			body:     `{"keys": ["env.HOST"]}`,
+			status:   http.StatusOK,
+			expected: `{"value":"env-of-HOST"}`,
+		},
+		{
+			name:     "resolve with fallback",
+			method:   http.MethodPost,
+			path:     "/v1/resolve",
+			token:    "s3cret",
+			body:     `{"keys": ["env.MISSING", "env.PORT"]}`,
+			status:   http.StatusOK,
+			expected: `{"value":"env-of-PORT"}`,
+		},
+		{
+			name:     "render",
+			method:   http.MethodPost,
+			path:     "/v1/render",
+			token:    "s3cret",
+			body:     `{"template": "{{ .User }}@{{ lookup \"env.HOST\" }}", "data": {"User": "app"}}`,
+			status:   http.StatusOK,
+			expected: "app@env-of-HOST",
+		},
+		{
+			name:     "health without token",
+			method:   http.MethodGet,
+			path:     "/healthz",
+			status:   http.StatusOK,
+			expected: "ok",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:     "missing token",
+			method:   http.MethodPost,
+			path:     "/v1/resolve",
+			body:     `{"keys": ["env.HOST"]}`,
+			status:   http.StatusUnauthorized,
+			expected: `{"level":"error","message":"invalid or missing bearer token"}`,
+		},
+		{
+			name:   "wrong token",
+			method: http.MethodPost,
+			path:   "/v1/resolve",
+			token:  "guess",
+			body:   `{"keys": ["env.HOST"]}`,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "not found",
+			method: http.MethodPost,
+			path:   "/v1/resolve",
+			token:  "s3cret",
+			body:   `{"keys": ["env.MISSING"]}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "lookup error",
+			method: http.MethodPost,
+			path:   "/v1/resolve",
+			token:  "s3cret",
+			body:   `{"keys": ["secret.DB_PASS"]}`,
+			status: http.StatusBadGateway,
+		},
+		{
+			name:   "no keys",
+			method: http.MethodPost,
+			path:   "/v1/resolve",
+			token:  "s3cret",
+			body:   `{"keys": []}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown field",
+			method: http.MethodPost,
+			path:   "/v1/resolve",
+			token:  "s3cret",
+			body:   `{"key": "env.HOST"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "method not allowed",
+			method: http.MethodGet,
+			path:   "/v1/resolve",
+			token:  "s3cret",
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			name:     "render error",
+			method:   http.MethodPost,
+			path:     "/v1/render",
+			token:    "s3cret",
+			body:     `{"template": "a: {{ lookup \"env.MISSING\" }}"}`,
+			status:   http.StatusNotFound,
+			expected: `"line":1,"column":6,"template":"template","call":"lookup \"env.MISSING\""}`,
+		},
+		{
+			name:     "broken template",
+			method:   http.MethodPost,
+			path:     "/v1/render",
+			token:    "s3cret",
+			body:     `{"template": "{{ lookup "}`,
+			status:   http.StatusUnprocessableEntity,
+			expected: `"file":"template","line":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			res, err := srv.Client().Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			b, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.status, res.StatusCode, string(b))
+			assert.Contains(t, string(b), tt.expected)
+		})
+	}
+}
+
+func TestRun_Serve(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600))
+	t.Setenv("TEMPURA_TEST_HOST", "db")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stderr := &syncBuffer{}
+	done := make(chan int)
+	go func() {
+		done <- run(ctx, []string{"serve", "-p", "env=env", "--addr", "127.0.0.1:0", "--token-file", tokenFile}, strings.NewReader(""), &bytes.Buffer{}, stderr)
+	}()
+
+	serving := regexp.MustCompile(`serving on (\S+)`)
+	require.Eventually(t, func() bool { return serving.MatchString(stderr.String()) }, 5*time.Second, 10*time.Millisecond, stderr.String())
+	addr := serving.FindStringSubmatch(stderr.String())[1]
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/v1/resolve", strings.NewReader(`{"keys": ["env.TEMPURA_TEST_HOST"]}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"value": "db"}`, string(b))
+
+	cancel()
+	assert.Equal(t, 0, <-done)
+}
+
+func TestRun_ServeWithoutToken(t *testing.T) {
+	t.Setenv(serveTokenEnv, "")
+	stderr := &bytes.Buffer{}
+	code := run(context.Background(), []string{"serve", "-p", "env=env"}, strings.NewReader(""), &bytes.Buffer{}, stderr)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr.String(), "--no-auth explicitly")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// outputDirective は、 --output-dir を指定した場合に、続く出力を書き込むファイルを示す行の形式です。
+// Helm のチャートのように、 range などで1つのテンプレートから設定ファイルの一式を生成できます。
+//
+// outputDirective is the form of lines naming the file the following output is written to, with --output-dir.
+// As with Helm charts, it lets a single template generate a whole bundle of config files, with range and so on.
+//
+//	--- tempura:output app/config.yaml
+//	{{ range .services }}--- tempura:output {{ .name }}.env
+//	{{ end }}
+var outputDirective = regexp.MustCompile(`(?m)^---[ \t]+tempura:output[ \t]+(.*?)[ \t]*(?:\r?\n|$)`)
+
+// renderedOutput は、描画した出力と、その書き込み先のパスです。
+//
+// renderedOutput is a rendered output and the path to write it to.
+type renderedOutput struct {
+	path    string
+	content []byte
+}
+
+// splitOutputs は、描画した b を outputDirective の行で分割し、 dir 以下のパスとの組にします。
+// 最初の行より前には空白しか置けず、パスは dir の外を指さない相対パスで、重複してはいけません。
+//
+// splitOutputs splits the rendered b at the lines of outputDirective, pairing each part with a path under dir.
+// Only whitespace may precede the first line, and paths must be relative, must not point outside dir and must not be duplicated.
+func splitOutputs(dir string, b []byte) ([]renderedOutput, error) {
+	locs := outputDirective.FindAllSubmatchIndex(b, -1)
+	if len(locs) == 0 {
+		return nil, fmt.Errorf("no %q line in the rendered output for --output-dir", "--- tempura:output PATH")
+	}
+	if len(bytes.TrimSpace(b[:locs[0][0]])) != 0 {
+		return nil, fmt.Errorf("rendered output precedes the first %q line", "--- tempura:output PATH")
+	}
+
+	outputs := make([]renderedOutput, 0, len(locs))
+	seen := make(map[string]bool, len(locs))
+	for i, loc := range locs {
+		name := string(b[loc[2]:loc[3]])
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("invalid output path %q: must be a relative path within --output-dir", name)
+		}
+		name = filepath.Clean(name)
+		if seen[name] {
+			return nil, fmt.Errorf("output path %q appears more than once", name)
+		}
+		seen[name] = true
+
+		end := len(b)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		outputs = append(outputs, renderedOutput{path: filepath.Join(dir, name), content: b[loc[1]:end]})
+	}
+	return outputs, nil
+}
+
+// outputPaths は outputs のパスを , で連結します。
+//
+// outputPaths joins the paths of outputs with commas.
+func outputPaths(outputs []renderedOutput) string {
+	paths := make([]string, len(outputs))
+	for i, o := range outputs {
+		paths[i] = o.path
+	}
+	return strings.Join(paths, ", ")
+}
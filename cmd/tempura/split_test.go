@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_RenderOutputDir(t *testing.T) {
+	t.Setenv("TEMPURA_TEST_HOST", "db")
+	bundle := `{{ range .services }}--- tempura:output {{ .name }}/config.yaml
+name: {{ .name }}
+host: {{ lookup "env.TEMPURA_TEST_HOST" }}
+{{ end }}--- tempura:output README
+generated
+`
+	data := filepath.Join(t.TempDir(), "data.yaml")
+	require.NoError(t, os.WriteFile(data, []byte("services:\n  - name: api\n  - name: worker\n"), 0o644))
+
+	tests := []struct {
+		name     string
+		template string
+		existing map[string]string
+		flags    []string
+		code     int
+		stdout   string
+		stderr   string
+		written  map[string]string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "split",
+			template: bundle,
+			written: map[string]string{
+				"api/config.yaml":    "name: api\nhost: db\n",
+				"worker/config.yaml": "name: worker\nhost: db\n",
+				"README":             "generated\n",
+			},
+		},
+		{
+			name:     "diff",
+			template: bundle,
+			existing: map[string]string{"api/config.yaml": "name: api\nhost: localhost\n", "README": "generated\n"},
+			flags:    []string{"--diff"},
+			stdout:   "-host: localhost\n+host: db\n",
+			written: map[string]string{
+				"api/config.yaml":    "name: api\nhost: db\n",
+				"worker/config.yaml": "name: worker\nhost: db\n",
+				"README":             "generated\n",
+			},
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:     "check with drift",
+			template: bundle,
+			existing: map[string]string{"api/config.yaml": "name: api\nhost: db\n", "README": "generated\n"},
+			flags:    []string{"--check"},
+			code:     1,
+			stdout:   "+name: worker\n",
+			stderr:   "worker/config.yaml: rendered output differs",
+			written:  map[string]string{"api/config.yaml": "name: api\nhost: db\n", "README": "generated\n"},
+		},
+		{
+			name:     "no directive",
+			template: "host: db\n",
+			code:     1,
+			stderr:   "no \"--- tempura:output PATH\" line",
+		},
+		{
+			name:     "output before directive",
+			template: "host: db\n--- tempura:output config.yaml\n",
+			code:     1,
+			stderr:   "rendered output precedes",
+		},
+		{
+			name:     "outside the directory",
+			template: "--- tempura:output ../config.yaml\n",
+			code:     1,
+			stderr:   "invalid output path \"../config.yaml\"",
+		},
+		{
+			name:     "duplicated path",
+			template: "--- tempura:output config.yaml\n--- tempura:output ./config.yaml\n",
+			code:     1,
+			stderr:   "output path \"config.yaml\" appears more than once",
+		},
+		{
+			name:     "with output",
+			template: bundle,
+			flags:    []string{"-o", "config.yaml"},
+			code:     2,
+			stderr:   "cannot be used together",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tmpl := filepath.Join(dir, "bundle.tmpl")
+			require.NoError(t, os.WriteFile(tmpl, []byte(tt.template), 0o644))
+			out := filepath.Join(dir, "out")
+			for name, content := range tt.existing {
+				require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(out, name)), 0o755))
+				require.NoError(t, os.WriteFile(filepath.Join(out, name), []byte(content), 0o644))
+			}
+
+			args := append([]string{"render", "-p", "env=env", "-d", data, "-O", out}, tt.flags...)
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(context.Background(), append(args, tmpl), strings.NewReader(""), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Contains(t, stdout.String(), tt.stdout)
+			assert.Contains(t, stderr.String(), tt.stderr)
+
+			written := make(map[string]string)
+			_ = filepath.WalkDir(out, func(path string, d os.DirEntry, err error) error {
+				if err == nil && d.Type().IsRegular() {
+					b, _ := os.ReadFile(path)
+					rel, _ := filepath.Rel(out, path)
+					written[filepath.ToSlash(rel)] = string(b)
+				}
+				return nil
+			})
+			if tt.written == nil {
+				tt.written = map[string]string{}
+			}
+			assert.Equal(t, tt.written, written)
+		})
+	}
+}
+
+func TestRun_RenderOutputMode(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "bundle.tmpl")
+	require.NoError(t, os.WriteFile(tmpl, []byte("--- tempura:output secrets/db.env\nDB_PASS=p4ss\n"), 0o644))
+	existing := filepath.Join(dir, "existing")
+	require.NoError(t, os.WriteFile(existing, []byte("old"), 0o640))
+	require.NoError(t, os.Chmod(existing, 0o640)) // en: regardless of umask
+
+	tests := []struct {
+		name string
+		args []string
+		path string
+		mode os.FileMode
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name: "new output",
+			args: []string{"-o", filepath.Join(dir, "new")},
+			path: filepath.Join(dir, "new"),
+			mode: 0o600,
+		},
+		{
+			name: "existing output",
+			args: []string{"-o", existing},
+			path: existing,
+			mode: 0o640,
+		},
+		{
+			name: "new output dir",
+			args: []string{"-O", filepath.Join(dir, "out")},
+			path: filepath.Join(dir, "out", "secrets", "db.env"),
+			mode: 0o600,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stderr := &bytes.Buffer{}
+			code := run(context.Background(), append(append([]string{"render", "-p", "env=env"}, tt.args...), tmpl), strings.NewReader(""), &bytes.Buffer{}, stderr)
+			require.Equal(t, 0, code, stderr.String())
+			info, err := os.Stat(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.mode, info.Mode().Perm())
+			entries, err := os.ReadDir(filepath.Dir(tt.path))
+			require.NoError(t, err)
+			for _, entry := range entries {
+				assert.False(t, strings.HasPrefix(entry.Name(), "."), "temporary file %s is left", entry.Name())
+			}
+		})
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "out", "secrets"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+}
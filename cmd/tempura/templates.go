@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// templateFiles は、 paths のファイルと、 paths のディレクトリ以下で名前が pattern に一致するファイルを順に返します。
+//
+// templateFiles returns the files in paths, and the files under the directories in paths whose names match pattern, in order.
+func templateFiles(paths []string, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if matched, _ := filepath.Match(pattern, d.Name()); matched && d.Type().IsRegular() {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// parseTemplateFile は、 funcName を仮の関数として登録して file を解析します。診断に表示されるよう、テンプレートの名前は file のパスです。
+//
+// parseTemplateFile parses file with a placeholder registered as funcName. The template is named after the path of file so that it appears in diagnostics.
+func parseTemplateFile(file, funcName string) (*template.Template, error) {
+	text, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	placeholder := template.FuncMap{funcName: func(args ...string) (any, error) { return nil, nil }}
+	return template.New(file).Funcs(placeholder).Parse(string(text))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ebi-yade/go-tempura/k8slookup"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce は、エディタの保存などで続けて起きる変更を1回の描画にまとめる間隔です。
+//
+// watchDebounce is the interval coalescing successive changes, such as saves by editors, into a single render.
+const watchDebounce = 100 * time.Millisecond
+
+// watch は、描画した後、テンプレート、データ、設定、探索関数が読むファイルが変更されるたびに描画し直します。
+// 描画のエラーは rep で stderr に出力して監視を続け、 ctx が終了すると nil を返します。
+//
+// watch renders, then renders again whenever the template, the data, the config or the files read by lookup functions change.
+// Errors of renders are written to stderr and watching continues. It returns nil when ctx is done.
+func watch(ctx context.Context, cfg renderConfig, stdin io.Reader, stdout io.Writer, rep *reporter) error {
+	if cfg.template == "-" {
+		return fmt.Errorf("--watch cannot be used with a template from stdin")
+	}
+	if _, usesStdin, err := cfg.providers.multiLookup(stdin); err == nil && usesStdin {
+		return fmt.Errorf("--watch cannot be used with the stdin provider, which reads stdin only once")
+	}
+	targets, err := newWatchTargets(rep)
+	if err != nil {
+		return err
+	}
+	defer targets.close()
+
+	renderOnce := func() {
+		for _, path := range append([]string{cfg.template, cfg.dataFile}, cfg.providers.watchPaths()...) {
+			targets.add(path)
+		}
+		if err := render(ctx, cfg, stdin, stdout); err != nil {
+			rep.error(err)
+			return
+		}
+		rep.info("rendered %s", cfg.template)
+	}
+
+	renderOnce()
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-targets.watcher.Events:
+			if !ok {
+				return nil
+			}
+			if targets.matches(event) {
+				debounce = time.After(watchDebounce)
+			}
+		case err, ok := <-targets.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			rep.error(err)
+		case <-debounce:
+			debounce = nil
+			renderOnce()
+		}
+	}
+}
+
+// watchTargets は、監視するファイルやディレクトリのパスの集合です。
+//
+// watchTargets is the set of paths of the files and directories to watch.
+type watchTargets struct {
+	watcher *fsnotify.Watcher
+	paths   map[string]bool
+	rep     *reporter
+}
+
+func newWatchTargets(rep *reporter) (*watchTargets, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &watchTargets{watcher: watcher, paths: make(map[string]bool), rep: rep}, nil
+}
+
+// add は path を監視の対象に加えます。空のパスや既に加えたパスは無視します。
+//
+// add adds path to the targets. Empty paths and paths already added are ignored.
+func (w *watchTargets) add(path string) {
+	if path == "" {
+		return
+	}
+	path, err := filepath.Abs(path)
+	if err != nil || w.paths[path] {
+		return
+	}
+	w.paths[path] = true
+	// en: watch parent directories of files, since editors often replace files instead of writing them
+	dir := path
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	if err := w.watcher.Add(dir); err != nil {
+		w.rep.error(fmt.Errorf("failed to watch %s: %w", dir, err))
+	}
+}
+
+// matches は、 event が監視の対象のファイルやディレクトリ以下の変更かを返します。
+//
+// matches reports whether event is a change of a target file or under a target directory.
+func (w *watchTargets) matches(event fsnotify.Event) bool {
+	return w.paths[event.Name] || w.paths[filepath.Dir(event.Name)]
+}
+
+func (w *watchTargets) close() error {
+	return w.watcher.Close()
+}
+
+// watchPaths は、 --config と --values のファイルと、探索関数が読むファイルやディレクトリ、 WASM のモジュールのパスを返します。
+//
+// watchPaths returns the paths of the --config and --values files, and the paths of the files and directories read by lookup functions and of WASM modules.
+func (f *providerFlags) watchPaths() []string {
+	paths := append([]string{f.config}, f.values...)
+	providers, err := parseProviderFlags(f.specs)
+	if err != nil {
+		return paths
+	}
+	if f.config != "" {
+		if c, err := loadConfig(f.config); err == nil {
+			for prefix, cfg := range c.Providers {
+				providers[prefix] = cfg
+			}
+		}
+	}
+	for _, cfg := range providers {
+		var o struct {
+			Dir        string `yaml:"dir"`
+			Path       string `yaml:"path"`
+			Identities string `yaml:"identities"`
+		}
+		switch cfg.Kind {
+		case "file":
+			_ = cfg.Options.Decode(&o)
+			paths = append(paths, o.Dir)
+		case "k8s":
+			_ = cfg.Options.Decode(&o)
+			if o.Dir == "" {
+				o.Dir = k8slookup.DefaultDir
+			}
+			paths = append(paths, o.Dir)
+		case "wasm":
+			_ = cfg.Options.Decode(&o)
+			paths = append(paths, o.Path)
+		case "age":
+			_ = cfg.Options.Decode(&o)
+			paths = append(paths, o.Identities)
+		}
+	}
+	return paths
+}
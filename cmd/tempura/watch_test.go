@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer は、監視中の描画と並行に読めるよう排他制御した bytes.Buffer です。
+//
+// syncBuffer is a bytes.Buffer with mutual exclusion, so that it can be read concurrently with renders while watching.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRun_RenderWatch(t *testing.T) {
+	dir := t.TempDir()
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.MkdirAll(secrets, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "DB_PASS"), []byte("p4ss"), 0o600))
+	tmpl := filepath.Join(dir, "config.tmpl")
+	require.NoError(t, os.WriteFile(tmpl, []byte(`pass: {{ lookup "secret.DB_PASS" }}`), 0o644))
+	output := filepath.Join(dir, "config")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stderr := &syncBuffer{}
+	done := make(chan int)
+	go func() {
+		done <- run(ctx, []string{"render", "--watch", "-p", "secret=file:" + secrets, "-o", output, tmpl}, strings.NewReader(""), &bytes.Buffer{}, stderr)
+	}()
+
+	content := func() string {
+		b, _ := os.ReadFile(output)
+		return string(b)
+	}
+	require.Eventually(t, func() bool { return content() == "pass: p4ss" }, 5*time.Second, 10*time.Millisecond, stderr.String())
+
+	require.NoError(t, os.WriteFile(tmpl, []byte(`password: {{ lookup "secret.DB_PASS" }}`), 0o644))
+	require.Eventually(t, func() bool { return content() == "password: p4ss" }, 5*time.Second, 10*time.Millisecond, stderr.String())
+
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "DB_PASS"), []byte("n3w"), 0o600))
+	require.Eventually(t, func() bool { return content() == "password: n3w" }, 5*time.Second, 10*time.Millisecond, stderr.String())
+
+	cancel()
+	assert.Equal(t, 0, <-done)
+}
+
+func TestRun_RenderWatch_Stdin(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	code := run(context.Background(), []string{"render", "--watch", "-p", "env=env", "-"}, strings.NewReader(""), &bytes.Buffer{}, stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "cannot be used with a template from stdin")
+}
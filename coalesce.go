@@ -0,0 +1,130 @@
+package tempura
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchLookupFunc は、複数のキーをまとめて探索する関数です。見つからなかったキーは戻り値の map に含めません。
+//
+// BatchLookupFunc looks up multiple keys at once. Keys not found are omitted from the returned map.
+type BatchLookupFunc func(ctx context.Context, keys []string) (map[string]any, error)
+
+// Coalesce は、 window の間に届いた個々の探索を1回の fn の呼び出しにまとめるミドルウェアです。
+// 多くのキーを同じバックエンドから参照するテンプレートの描画で、往復の回数を大きく減らせます。
+// maxBatch が正の場合は、キーの数が maxBatch に達した時点で window を待たずに fn を呼び出します。
+//
+// Coalesce is a middleware that coalesces individual lookups arriving within window into one call of fn.
+// It dramatically reduces round trips during renders that reference many keys from the same backend.
+// If maxBatch is positive, fn is called without waiting for window once the number of keys reaches maxBatch.
+//
+// fn には最初に届いた探索の context.Context から値だけを引き継いだものが渡され、キャンセルは伝わりません。タイムアウトは fn の中で設定してください。
+// NOTE: fn receives a context.Context that inherits only the values of the first lookup in the batch, not its cancellation. Set a timeout inside fn.
+//
+//	ssm := tempura.Coalesce(func(ctx context.Context, keys []string) (map[string]any, error) {
+//		out, err := client.GetParameters(ctx, &ssm.GetParametersInput{Names: keys, WithDecryption: aws.Bool(true)})
+//		...
+//	}, 5*time.Millisecond, 10)
+func Coalesce(fn BatchLookupFunc, window time.Duration, maxBatch int) LookupAnyWithContextError {
+	c := &coalescer{fn: fn, window: window, maxBatch: maxBatch}
+	return func(ctx context.Context, key string) (any, bool, error) {
+		select {
+		case res := <-c.enqueue(ctx, key):
+			return res.val, res.ok, res.err
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+type coalescer struct {
+	fn       BatchLookupFunc
+	window   time.Duration
+	maxBatch int
+
+	mu  sync.Mutex
+	cur *pendingBatch
+}
+
+// pendingBatch は、 fn の呼び出しを待っている1つのまとまりです。
+//
+// pendingBatch is a batch waiting for fn to be called.
+type pendingBatch struct {
+	ctx     context.Context
+	keys    []string
+	waiters map[string][]chan batchResult
+	flushed bool
+}
+
+type batchResult struct {
+	val any
+	ok  bool
+	err error
+}
+
+func (c *coalescer) enqueue(ctx context.Context, key string) <-chan batchResult {
+	ch := make(chan batchResult, 1) // en: buffered so that flush never blocks on callers that gave up
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.cur
+	if b == nil {
+		b = &pendingBatch{
+			ctx:     context.WithoutCancel(ctx),
+			waiters: make(map[string][]chan batchResult),
+		}
+		c.cur = b
+		time.AfterFunc(c.window, func() { c.flush(b) })
+	}
+	if _, ok := b.waiters[key]; !ok {
+		b.keys = append(b.keys, key)
+	}
+	b.waiters[key] = append(b.waiters[key], ch)
+
+	if c.maxBatch > 0 && len(b.keys) >= c.maxBatch {
+		c.cur = nil // en: later lookups start a new batch
+		go c.flush(b)
+	}
+	return ch
+}
+
+// flush は b について fn を1回だけ呼び出し、待っている全ての探索に結果を配ります。
+//
+// flush calls fn only once for b and delivers the results to all the waiting lookups.
+func (c *coalescer) flush(b *pendingBatch) {
+	c.mu.Lock()
+	if b.flushed {
+		c.mu.Unlock()
+		return
+	}
+	b.flushed = true
+	if c.cur == b {
+		c.cur = nil
+	}
+	c.mu.Unlock()
+
+	vals, err := c.call(b)
+	for key, chs := range b.waiters {
+		res := batchResult{err: err}
+		if err == nil {
+			res.val, res.ok = vals[key]
+		}
+		for _, ch := range chs {
+			ch <- res
+		}
+	}
+}
+
+// call は b について fn を呼び出します。 fn はタイマーの goroutine で呼ばれることがあり、その panic はプロセス全体を止めてしまうため、 PanicError に変換します。
+//
+// call calls fn for b. Since fn may be called on the goroutine of the timer, where a panic would bring down the whole process, it converts a panic into a PanicError.
+func (c *coalescer) call(b *pendingBatch) (vals map[string]any, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			vals, err = nil, PanicError{Value: v}
+		}
+	}()
+	return c.fn(b.ctx, b.keys)
+}
@@ -0,0 +1,150 @@
+package tempura_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBatchBackend records every batched call.
+type fakeBatchBackend struct {
+	mu    sync.Mutex
+	calls [][]string
+	err   error
+}
+
+func (b *fakeBatchBackend) GetMany(ctx context.Context, keys []string) (map[string]any, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	b.calls = append(b.calls, sorted)
+	if b.err != nil {
+		return nil, b.err
+	}
+	out := make(map[string]any, len(keys))
+	for _, key := range keys {
+		if key != "MISSING" {
+			out[key] = "batch:" + key
+		}
+	}
+	return out, nil
+}
+
+func TestCoalesce(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		maxBatch      int
+		keys          []string
+		expectedCalls int
+	}{
+		// ==================== VALID CASES ====================
+		{name: "lookups within the window are coalesced", keys: []string{"A", "B", "C", "A"}, expectedCalls: 1},
+		{name: "a full batch is flushed without waiting", maxBatch: 2, keys: []string{"A", "B", "C", "D"}, expectedCalls: 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			backend := &fakeBatchBackend{}
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("ssm"): tempura.Coalesce(backend.GetMany, 50*time.Millisecond, tt.maxBatch),
+			}.BindContext(context.Background())
+
+			wg := &sync.WaitGroup{}
+			for _, key := range tt.keys {
+				wg.Add(1)
+				go func(key string) {
+					defer wg.Done()
+					val, err := lookup.FuncMapValue("ssm." + key)
+					assert.NoError(t, err)
+					assert.Equal(t, "batch:"+key, val)
+				}(key)
+			}
+			wg.Wait()
+
+			backend.mu.Lock()
+			defer backend.mu.Unlock()
+			assert.Len(t, backend.calls, tt.expectedCalls)
+		})
+	}
+}
+
+func TestCoalesce_Fallback(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		err      error
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "missing key falls through to the next arg", args: []string{"ssm.MISSING", "default.fallback"}, expected: "fallback"},
+		// ==================== INVALID CASES ====================
+		{
+			name: "batch error is returned",
+			err:  errUnavailable,
+			args: []string{"ssm.A", "default.fallback"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			backend := &fakeBatchBackend{err: tt.err}
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("ssm"):     tempura.Coalesce(backend.GetMany, time.Millisecond, 0),
+				tempura.DotPrefix("default"): tempura.Func(func(key string) (string, bool) { return key, true }),
+			}.BindContext(context.Background())
+
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestCoalesce_Panic(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("ssm"): tempura.Coalesce(func(ctx context.Context, keys []string) (map[string]any, error) {
+			panic("boom")
+		}, 10*time.Millisecond, 0),
+	}.BindContext(context.Background())
+
+	wg := &sync.WaitGroup{}
+	for _, key := range []string{"A", "B"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			_, err := lookup.FuncMapValue("ssm." + key)
+			var pe tempura.PanicError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, "boom", pe.Value)
+		}(key)
+	}
+	wg.Wait()
+}
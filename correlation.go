@@ -0,0 +1,73 @@
+package tempura
+
+import (
+	"context"
+	"log/slog"
+)
+
+// =================================================================================
+// Propagation of correlation IDs
+// =================================================================================
+
+// CorrelationIDs は、探索をそれを発生させたリクエストに結び付けるための ID です。
+//
+// CorrelationIDs are the IDs tying a lookup back to the request that originated it.
+type CorrelationIDs struct {
+	TraceID   string
+	RequestID string
+}
+
+type traceIDKey struct{}
+type requestIDKey struct{}
+
+// WithTraceID は、 ctx で行われる探索のログとフックのイベントに traceID を含めます。
+//
+// WithTraceID includes traceID in the logs and hook events of lookups performed with ctx.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// WithRequestID は、 ctx で行われる探索のログとフックのイベントに requestID を含めます。
+//
+// WithRequestID includes requestID in the logs and hook events of lookups performed with ctx.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// WithCorrelationIDs は、 ctx から CorrelationIDs を取り出す方法を fn に変更します。
+// 指定しない場合は WithTraceID と WithRequestID で設定された値を使います。
+// 既存のミドルウェアが独自のキーで ctx に設定した ID を使うためのもので、 OpenTelemetry のトレース ID は oteltempura.CorrelationIDs で取り出せます。
+//
+// WithCorrelationIDs changes how CorrelationIDs are extracted from ctx to fn.
+// Without it, the values set via WithTraceID and WithRequestID are used.
+// It lets existing middleware that stores IDs in ctx under its own keys be reused; the trace ID of OpenTelemetry is extracted by oteltempura.CorrelationIDs.
+func WithCorrelationIDs(fn func(ctx context.Context) CorrelationIDs) Option {
+	return func(o *options) {
+		o.correlation = fn
+	}
+}
+
+func (m *MultiLookupContext) correlationIDs(ctx context.Context) CorrelationIDs {
+	if ctx == nil {
+		return CorrelationIDs{}
+	}
+	if m.opts.correlation != nil {
+		return m.opts.correlation(ctx)
+	}
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return CorrelationIDs{TraceID: traceID, RequestID: requestID}
+}
+
+// appendAttrs は、空でない ID をログの属性として attrs に追加します。
+//
+// appendAttrs appends non-empty IDs to attrs as attributes of logs.
+func (ids CorrelationIDs) appendAttrs(attrs []slog.Attr) []slog.Attr {
+	if ids.TraceID != "" {
+		attrs = append(attrs, slog.String("trace_id", ids.TraceID))
+	}
+	if ids.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", ids.RequestID))
+	}
+	return attrs
+}
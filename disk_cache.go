@@ -0,0 +1,162 @@
+package tempura
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DiskCache は、値を AES-GCM で暗号化してディレクトリ内のファイルに保持する Cache です。
+// CLI のようにプロセスが毎回終了する場合でも、変更のない秘密情報を再取得せずに済みます。
+// 復号できたとしても、秘密情報をディスクに残すことはセキュリティ上のトレードオフであるため、明示的に NewDiskCache を呼び出した場合のみ利用されます。
+//
+// DiskCache is a Cache that holds values in files within a directory, encrypted with AES-GCM.
+// It lets CLI invocations across process restarts avoid refetching unchanged secrets.
+// Leaving secrets on disk, even encrypted, is a security tradeoff, so it is used only when NewDiskCache is called explicitly.
+//
+// 値は encoding/gob で保存されるため、 gob でエンコードできない型の値はディスクには保存されません。保存に失敗した場合は、 WithCache で登録した MultiLookupContext が WithLogger のロガーに出力します。
+// NOTE: Values are stored with encoding/gob, so values of types that gob cannot encode are not persisted. Failures to persist are logged by the MultiLookupContext it is registered to via WithCache, with the logger of WithLogger.
+type DiskCache struct {
+	dir     string
+	ttl     time.Duration
+	aead    cipher.AEAD
+	nameKey []byte
+}
+
+// diskCacheNameLabel は、 AES の鍵からファイル名のための HMAC の鍵を導出する際のラベルです。
+//
+// diskCacheNameLabel is the label for deriving the HMAC key for file names from the AES key.
+const diskCacheNameLabel = "tempura DiskCache file names"
+
+type diskEntry struct {
+	Val       any
+	ExpiresAt time.Time
+}
+
+// NewDiskCache は、 dir に値を ttl の間だけ保持する DiskCache を生成します。
+// key は AES の鍵で、16, 24, 32 バイトのいずれかである必要があります。鍵が異なるファイルは見つからなかったものとして扱われます。
+//
+// NewDiskCache creates a DiskCache holding values in dir for ttl.
+// key is the AES key and must be 16, 24, or 32 bytes long. Files written with a different key are treated as not found.
+func NewDiskCache(dir string, key []byte, ttl time.Duration) (*DiskCache, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key for DiskCache: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AES-GCM: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(diskCacheNameLabel))
+	return &DiskCache{dir: dir, ttl: ttl, aead: aead, nameKey: mac.Sum(nil)}, nil
+}
+
+func (c *DiskCache) Get(key string) (any, bool) {
+	sealed, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, false
+	}
+	// キーを追加データとすることで、ファイルを別のキーの値として読み込ませることを防ぐ
+	// en: Using the key as additional data prevents a file from being read as the value of another key
+	plain, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry diskEntry
+	if err := gob.NewDecoder(bytes.NewReader(plain)).Decode(&entry); err != nil {
+		return nil, false
+	}
+	if !time.Now().Before(entry.ExpiresAt) {
+		c.Invalidate(key)
+		return nil, false
+	}
+	return entry.Val, true
+}
+
+func (c *DiskCache) Set(key string, val any) {
+	_ = c.setErr(key, val)
+}
+
+func (c *DiskCache) setErr(key string, val any) error {
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(diskEntry{Val: val, ExpiresAt: time.Now().Add(c.ttl)}); err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, buf.Bytes(), []byte(key))
+
+	// 読み込み中のプロセスが書きかけのファイルを見ないよう、一時ファイルに書いてから置き換える
+	// en: Write to a temporary file and rename it so that readers never see a partially written file
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return fmt.Errorf("failed to replace cache file: %w", err)
+	}
+	return nil
+}
+
+func (c *DiskCache) Invalidate(key string) {
+	_ = os.Remove(c.path(key))
+}
+
+func (c *DiskCache) Clear() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if isDiskCacheFile(entry.Name()) {
+			_ = os.Remove(filepath.Join(c.dir, entry.Name()))
+		}
+	}
+}
+
+// path は、キーの HMAC をファイル名にします。 HMAC の鍵は AES の鍵から導出されるため、ディレクトリを読めても鍵を知らなければ、どのキーがキャッシュされているかを確かめられません。
+//
+// path names the file after the HMAC of the key. Since the HMAC key is derived from the AES key, those who can read the directory but do not know the key cannot tell which keys are cached.
+func (c *DiskCache) path(key string) string {
+	mac := hmac.New(sha256.New, c.nameKey)
+	mac.Write([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func isDiskCacheFile(name string) bool {
+	if len(name) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
@@ -0,0 +1,147 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{0x42}, 32)
+
+	var calls atomic.Int32
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		calls.Add(1)
+		return "secret-of-" + key, true, nil
+	}
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}
+
+	// en: each iteration simulates a separate CLI invocation
+	for i := 0; i < 3; i++ {
+		cache, err := tempura.NewDiskCache(dir, key, time.Minute)
+		require.NoError(t, err)
+		val, err := secrets.BindContext(context.Background(), tempura.WithCache(cache)).FuncMapValue("secret.DB_PASS")
+		require.NoError(t, err)
+		assert.Equal(t, "secret-of-DB_PASS", val)
+	}
+	assert.Equal(t, int32(1), calls.Load(), "backend should be called only once across restarts")
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "secret-of-DB_PASS", "value should be encrypted on disk")
+	assert.NotContains(t, files[0].Name(), "DB_PASS", "key should not appear in the file name")
+	sum := sha256.Sum256([]byte("secret.DB_PASS"))
+	assert.NotEqual(t, hex.EncodeToString(sum[:]), files[0].Name(), "file name should not be guessable without the key")
+
+	other, err := tempura.NewDiskCache(dir, bytes.Repeat([]byte{0x24}, 32), time.Minute)
+	require.NoError(t, err)
+	other.Set("secret.DB_PASS", "other")
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2, "file names should depend on the key")
+}
+
+func TestDiskCache_Logger(t *testing.T) {
+	t.Parallel()
+
+	cache, err := tempura.NewDiskCache(t.TempDir(), bytes.Repeat([]byte{0x42}, 32), time.Minute)
+	require.NoError(t, err)
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.Func(func(key string) (any, bool) { return make(chan int), true }), // en: gob cannot encode channels
+	}
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+
+	_, err = secrets.BindContext(context.Background(), tempura.WithCache(cache), tempura.WithLogger(logger)).FuncMapValue("secret.DB_PASS")
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "failed to write to the cache")
+	assert.Contains(t, buf.String(), "arg=secret.DB_PASS")
+}
+
+func TestDiskCache_Miss(t *testing.T) {
+	t.Parallel()
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		setup func(t *testing.T, dir string, cache *tempura.DiskCache)
+	}{
+		// ==================== INVALID CASES ====================
+		{
+			name: "written with a different key",
+			ttl:  time.Minute,
+			setup: func(t *testing.T, dir string, cache *tempura.DiskCache) {
+				other, err := tempura.NewDiskCache(dir, bytes.Repeat([]byte{0x24}, 32), time.Minute)
+				require.NoError(t, err)
+				other.Set("env.FOO", "foo")
+			},
+		},
+		{
+			name: "expired",
+			ttl:  20 * time.Millisecond,
+			setup: func(t *testing.T, dir string, cache *tempura.DiskCache) {
+				cache.Set("env.FOO", "foo")
+				time.Sleep(30 * time.Millisecond)
+			},
+		},
+		{
+			name: "invalidated",
+			ttl:  time.Minute,
+			setup: func(t *testing.T, dir string, cache *tempura.DiskCache) {
+				cache.Set("env.FOO", "foo")
+				cache.Invalidate("env.FOO")
+			},
+		},
+		{
+			name: "cleared",
+			ttl:  time.Minute,
+			setup: func(t *testing.T, dir string, cache *tempura.DiskCache) {
+				cache.Set("env.FOO", "foo")
+				cache.Clear()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			cache, err := tempura.NewDiskCache(dir, key, tt.ttl)
+			require.NoError(t, err)
+
+			tt.setup(t, dir, cache)
+			_, ok := cache.Get("env.FOO")
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestNewDiskCache_InvalidKey(t *testing.T) {
+	t.Parallel()
+
+	_, err := tempura.NewDiskCache(t.TempDir(), []byte("short"), time.Minute)
+	assert.ErrorContains(t, err, "invalid key")
+}
@@ -0,0 +1,93 @@
+package tempura
+
+import (
+	"sync"
+)
+
+// =================================================================================
+// Dry run reporting lookups without calling lookup functions
+// =================================================================================
+
+// DryRunLookup は、ドライランの中で解決されるはずだった1つの引数と、それに一致した prefix の組です。
+//
+// DryRunLookup is a pair of an arg that would have been resolved during a dry run and a prefix matching it.
+type DryRunLookup struct {
+	Arg    string
+	Prefix Prefix
+	Key    string
+}
+
+// DryRunReport は、ドライランの中で解決されるはずだった引数を集めます。
+//
+// DryRunReport collects the args that would have been resolved during a dry run.
+type DryRunReport struct {
+	mu      sync.Mutex
+	lookups []DryRunLookup
+	seen    map[DryRunLookup]bool
+}
+
+// NewDryRunReport は空の DryRunReport を返します。
+//
+// NewDryRunReport returns an empty DryRunReport.
+func NewDryRunReport() *DryRunReport {
+	return &DryRunReport{seen: make(map[DryRunLookup]bool)}
+}
+
+// Lookups は、記録された (引数, prefix) の組を重複なしに最初に記録された順に返します。
+//
+// Lookups returns the recorded pairs of an arg and a prefix without duplicates, in the order they were first recorded.
+func (r *DryRunReport) Lookups() []DryRunLookup {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]DryRunLookup(nil), r.lookups...)
+}
+
+func (r *DryRunReport) record(l DryRunLookup) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.seen[l] {
+		return
+	}
+	r.seen[l] = true
+	r.lookups = append(r.lookups, l)
+}
+
+// WithDryRun は、探索関数もキャッシュも使わずに、 FuncMapValue と Resolve に一致する全ての引数と prefix の組を report に記録させます。
+// FuncMapValue は最初に一致した引数をそのまま仮の値として返すため、認証情報のない CI でも「このテンプレートには何が必要か」を調べられます。
+// どの prefix にも一致しない場合は、通常どおり ErrMatchFailed を返します。
+//
+// WithDryRun makes FuncMapValue and Resolve record every pair of a matching arg and prefix to report, using neither lookup functions nor caches.
+// FuncMapValue returns the first matching arg itself as a placeholder value, enabling "what does this template need?" analysis in CI without credentials.
+// If no prefix matches, it returns ErrMatchFailed as usual.
+//
+//	report := tempura.NewDryRunReport()
+//	lookup := secrets.BindContext(ctx, tempura.WithDryRun(report))
+//	tpl.Funcs(template.FuncMap{"secret": lookup.FuncMapValue}).Execute(io.Discard, data)
+//	for _, l := range report.Lookups() { ... }
+func WithDryRun(report *DryRunReport) Option {
+	return func(o *options) {
+		o.dryRun = report
+	}
+}
+
+// dryRun は、 args に一致する全ての prefix を記録し、最初に一致した引数を返します。
+//
+// dryRun records every prefix matching args and returns the first matching arg.
+func (m *MultiLookupContext) dryRun(args []string) (any, error) {
+	var placeholder any
+	for _, arg := range args {
+		regs := m.match(arg)
+		for _, reg := range regs {
+			m.opts.dryRun.record(DryRunLookup{Arg: arg, Prefix: reg.prefix, Key: reg.prefix.Strip(arg)})
+		}
+		if placeholder == nil && len(regs) > 0 {
+			placeholder = arg
+		}
+	}
+	if placeholder == nil {
+		return nil, m.matchFailed(args)
+	}
+	return placeholder, nil
+}
@@ -0,0 +1,97 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDryRun(t *testing.T) {
+	t.Parallel()
+
+	mustNotCall := func(ctx context.Context, key string) (string, bool, error) {
+		t.Errorf("lookup function should not be called: %s", key)
+		return "", false, nil
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		lookups  []tempura.DryRunLookup
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "single key",
+			text:     `password={{ secret "secret.DB_PASS" }}`,
+			expected: "password=secret.DB_PASS",
+			lookups: []tempura.DryRunLookup{
+				{Arg: "secret.DB_PASS", Prefix: tempura.DotPrefix("secret"), Key: "DB_PASS"},
+			},
+		},
+		{
+			name:     "fallbacks are recorded as well",
+			text:     `{{ secret "env.DB_PASS" "secret.DB_PASS" }} {{ secret "env.DB_PASS" }}`,
+			expected: "env.DB_PASS env.DB_PASS",
+			lookups: []tempura.DryRunLookup{
+				{Arg: "env.DB_PASS", Prefix: tempura.DotPrefix("env"), Key: "DB_PASS"},
+				{Arg: "secret.DB_PASS", Prefix: tempura.DotPrefix("secret"), Key: "DB_PASS"},
+			},
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "no prefix matched",
+			text: `{{ secret "vault.DB_PASS" }}`,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrMatchFailed)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			report := tempura.NewDryRunReport()
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(mustNotCall),
+				tempura.DotPrefix("env"):    tempura.FuncWithContextError(mustNotCall),
+			}.BindContext(context.Background(), tempura.WithDryRun(report))
+
+			tpl := template.Must(template.New("").Funcs(template.FuncMap{"secret": lookup.FuncMapValue}).Parse(tt.text))
+			buf := &bytes.Buffer{}
+			err := tpl.Execute(buf, nil)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+			assert.Equal(t, tt.lookups, report.Lookups())
+		})
+	}
+}
+
+func TestWithDryRun_Resolve(t *testing.T) {
+	t.Parallel()
+
+	report := tempura.NewDryRunReport()
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+			t.Errorf("lookup function should not be called: %s", key)
+			return "", false, nil
+		}),
+	}.BindContext(context.Background(), tempura.WithDryRun(report))
+
+	val, err := lookup.Resolve(context.Background(), "secret.API_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "secret.API_KEY", val)
+	assert.Equal(t, []tempura.DryRunLookup{{Arg: "secret.API_KEY", Prefix: tempura.DotPrefix("secret"), Key: "API_KEY"}}, report.Lookups())
+}
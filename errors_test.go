@@ -0,0 +1,118 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// throttlingError imitates an error type of an SDK, such as types.ThrottlingException of the AWS SDK.
+type throttlingError struct {
+	RetryAfter time.Duration
+}
+
+func (e *throttlingError) Error() string {
+	return fmt.Sprintf("throttled: retry after %s", e.RetryAfter)
+}
+
+// errPermissionDenied imitates a sentinel error of an SDK, such as the permission denied error of Vault.
+var errPermissionDenied = fmt.Errorf("permission denied")
+
+func TestErrors_PreserveProviderErrors(t *testing.T) {
+	t.Parallel()
+
+	sdkErr := fmt.Errorf("vault: %w: %w", errPermissionDenied, &throttlingError{RetryAfter: time.Second})
+	failing := tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+		return "", false, sdkErr
+	})
+	failingSync := tempura.FuncWithError(func(key string) (string, bool, error) {
+		return "", false, sdkErr
+	})
+
+	resolve := func(fn tempura.LookupFunc) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			_, err := tempura.MultiLookup{tempura.DotPrefix("vault"): fn}.BindContext(ctx).FuncMapValue("vault.DB_PASS")
+			return err
+		}
+	}
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		// ==================== INVALID CASES ====================
+		{name: "FuncMapValue", run: resolve(failing)},
+		{name: "FuncMapValue with a synchronous function", run: resolve(failingSync)},
+		{
+			name: "FuncMapValue of MultiLookup",
+			run: func(ctx context.Context) error {
+				_, err := tempura.MultiLookup{tempura.DotPrefix("vault"): failingSync}.FuncMapValue("vault.DB_PASS")
+				return err
+			},
+		},
+		{
+			name: "Resolve",
+			run: func(ctx context.Context) error {
+				_, err := tempura.MultiLookup{tempura.DotPrefix("vault"): failing}.BindContext(ctx).Resolve(ctx, "vault.DB_PASS")
+				return err
+			},
+		},
+		{
+			name: "Preload",
+			run: func(ctx context.Context) error {
+				return tempura.MultiLookup{tempura.DotPrefix("vault"): failing}.BindContext(ctx).Preload(ctx, "vault.DB_PASS")
+			},
+		},
+		{
+			name: "text/template",
+			run: func(ctx context.Context) error {
+				lookup := tempura.MultiLookup{tempura.DotPrefix("vault"): failing}.BindContext(ctx)
+				tpl := template.Must(template.New("").Funcs(template.FuncMap{"vault": lookup.FuncMapValue}).Parse(`{{ vault "vault.DB_PASS" }}`))
+				return tpl.Execute(&bytes.Buffer{}, nil)
+			},
+		},
+		{
+			name: "RenderAll",
+			run: func(ctx context.Context) error {
+				lookup := tempura.MultiLookup{tempura.DotPrefix("vault"): failing}.BindContext(ctx)
+				tpl := template.Must(template.New("db").Funcs(template.FuncMap{"vault": func(...string) (any, error) { return nil, nil }}).Parse(`{{ vault "vault.DB_PASS" }}`))
+				return lookup.RenderAll(ctx, "vault", []tempura.RenderJob{{Template: tpl, Out: &bytes.Buffer{}}}, 1)
+			},
+		},
+		{name: "RateLimit", run: resolve(tempura.RateLimit(failing, 100, 1))},
+		{name: "CircuitBreaker", run: resolve(tempura.CircuitBreaker(failing, 3, time.Minute))},
+		{name: "Hedge", run: resolve(tempura.Hedge(failing, 0.9, time.Second))},
+		{name: "Failover", run: resolve(tempura.Failover(failing, failing, 3))},
+		{
+			name: "Coalesce",
+			run: resolve(tempura.Coalesce(func(ctx context.Context, keys []string) (map[string]any, error) {
+				return nil, sdkErr
+			}, time.Millisecond, 10)),
+		},
+		{name: "nested middlewares", run: resolve(tempura.RateLimit(tempura.CircuitBreaker(tempura.Hedge(failing, 0.9, time.Second), 3, time.Minute), 100, 1))},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.run(context.Background())
+			require.Error(t, err)
+			assert.ErrorIs(t, err, errPermissionDenied)
+			var te *throttlingError
+			if assert.True(t, errors.As(err, &te)) {
+				assert.Equal(t, time.Second, te.RetryAfter)
+			}
+			assert.NotErrorIs(t, err, tempura.ErrNotFound, "provider errors should not be mistaken for misses")
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Package execlookup は、許可されたコマンドを実行してその標準出力を値とする探索関数を提供します。
+// 既存の秘密情報取得スクリプトを、安全に探索関数として再利用するためのものです。
+//
+// Package execlookup provides a lookup function that runs an allowed command and uses its standard output as the value.
+// It lets existing secret-fetching scripts be reused safely as lookup sources.
+package execlookup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+)
+
+const (
+	DefaultTimeout        = 10 * time.Second
+	DefaultMaxOutputBytes = 1 << 20
+)
+
+// Config は探索関数が実行できるコマンドとその実行条件を定義します。
+// 子プロセスの環境変数は Env と PassEnv で明示したものだけになります。
+//
+// Config defines the commands the lookup function can run and how they are run.
+// The environment of the child process consists only of what is declared by Env and PassEnv.
+type Config struct {
+	// Commands はキーで使用できるコマンド名と、実行ファイルのパスの対応です。
+	// Commands maps the command names usable in keys to the paths of executables.
+	Commands map[string]string
+
+	// Timeout は1回の実行の制限時間です。0 の場合は DefaultTimeout が使われます。
+	// Timeout limits a single execution. DefaultTimeout is used if it is 0.
+	Timeout time.Duration
+
+	// MaxOutputBytes は標準出力の最大サイズです。0 の場合は DefaultMaxOutputBytes が使われます。
+	// MaxOutputBytes limits the size of the standard output. DefaultMaxOutputBytes is used if it is 0.
+	MaxOutputBytes int
+
+	// Env は子プロセスに渡す "KEY=value" 形式の環境変数です。
+	// Env is the environment variables in the form of "KEY=value" passed to the child process.
+	Env []string
+
+	// PassEnv は親プロセスから引き継ぐ環境変数の名前です。
+	// PassEnv is the names of environment variables inherited from the parent process.
+	PassEnv []string
+
+	// Dir は子プロセスの作業ディレクトリです。
+	// Dir is the working directory of the child process.
+	Dir string
+}
+
+// New は、prefixを取り除いたキーを "コマンド名 引数..." として解釈し、許可されたコマンドを実行する探索関数を返します。
+// 終了コード 0 で終了した場合は、末尾の改行を取り除いた標準出力を値として返します。
+//
+// New returns a lookup function that interprets the key, with the prefix removed, as "command args..." and runs the command if allowed.
+// When the command exits with code 0, its standard output without trailing newlines is returned as the value.
+func New(cfg Config) tempura.LookupAnyWithContextError {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	maxOutput := cfg.MaxOutputBytes
+	if maxOutput <= 0 {
+		maxOutput = DefaultMaxOutputBytes
+	}
+
+	return tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+		fields := strings.Fields(key)
+		if len(fields) == 0 {
+			return "", false, fmt.Errorf("no command specified: %w", ErrCommandNotAllowed)
+		}
+		path, ok := cfg.Commands[fields[0]]
+		if !ok {
+			return "", false, fmt.Errorf("%s: %w", fields[0], ErrCommandNotAllowed)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		stdout := &limitedBuffer{limit: maxOutput}
+		stderr := &limitedBuffer{limit: maxOutput}
+		cmd := exec.CommandContext(ctx, path, fields[1:]...)
+		cmd.Dir = cfg.Dir
+		cmd.Env = environ(cfg.Env, cfg.PassEnv)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		cmd.WaitDelay = time.Second // en: don't hang on grandchildren holding the pipes
+
+		err := cmd.Run()
+		if stdout.exceeded {
+			return "", false, fmt.Errorf("%s: %w (limit: %d bytes)", fields[0], ErrOutputTooLarge, maxOutput)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", false, fmt.Errorf("%s: %w", fields[0], ctxErr)
+		}
+		if err != nil {
+			exitErr := &exec.ExitError{}
+			if errors.As(err, &exitErr) {
+				return "", false, fmt.Errorf("%s exited with code %d: %s: %w", fields[0], exitErr.ExitCode(), strings.TrimSpace(stderr.String()), err)
+			}
+			return "", false, fmt.Errorf("failed to run %s: %w", fields[0], err)
+		}
+
+		return strings.TrimRight(stdout.String(), "\r\n"), true, nil
+	})
+}
+
+func environ(env, passEnv []string) []string {
+	result := make([]string, 0, len(env)+len(passEnv))
+	result = append(result, env...)
+	for _, name := range passEnv {
+		if val, ok := os.LookupEnv(name); ok {
+			result = append(result, fmt.Sprintf("%s=%s", name, val))
+		}
+	}
+	return result
+}
+
+// limitedBuffer は上限を超えた書き込みを捨てて、超過したことを記録します。
+//
+// limitedBuffer discards writes beyond the limit and records that it was exceeded.
+// NOTE: bytes.Buffer is not embedded so that io.Copy cannot bypass Write via ReadFrom.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if rest := b.limit - b.buf.Len(); len(p) > rest {
+		b.exceeded = true
+		if rest > 0 {
+			b.buf.Write(p[:rest])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
+
+// =================================================================================
+// Defined errors that you can handle with errors.Is / errors.As
+// =================================================================================
+
+var ErrCommandNotAllowed = fmt.Errorf("command not allowed")
+var ErrOutputTooLarge = fmt.Errorf("output too large")
@@ -0,0 +1,105 @@
+package execlookup_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/execlookup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755))
+	return path
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("TEMPURA_TEST_PASSED", "passed")
+	t.Setenv("TEMPURA_TEST_SCRUBBED", "leaked")
+
+	dir := t.TempDir()
+	cfg := execlookup.Config{
+		Commands: map[string]string{
+			"echo":  writeScript(t, dir, "echo.sh", `echo "$@"`),
+			"env":   writeScript(t, dir, "env.sh", `echo "$FIXED:$TEMPURA_TEST_PASSED:$TEMPURA_TEST_SCRUBBED"`),
+			"sleep": writeScript(t, dir, "sleep.sh", `exec sleep 5`),
+			"large": writeScript(t, dir, "large.sh", `i=0; while [ $i -lt 100 ]; do echo 0123456789; i=$((i+1)); done`),
+			"fail":  writeScript(t, dir, "fail.sh", `echo "permission denied" >&2; exit 3`),
+		},
+		Timeout:        200 * time.Millisecond,
+		MaxOutputBytes: 64,
+		Env:            []string{"FIXED=fixed"},
+		PassEnv:        []string{"TEMPURA_TEST_PASSED"},
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("cmd"): execlookup.New(cfg),
+	}.BindContext(context.Background())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "arguments are passed",
+			args:     []string{"cmd.echo hello world"},
+			expected: "hello world",
+		},
+		{
+			name:     "environment is scrubbed",
+			args:     []string{"cmd.env"},
+			expected: "fixed:passed:",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "command not in the allowlist",
+			args: []string{"cmd.rm -rf /"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, execlookup.ErrCommandNotAllowed)
+			},
+		},
+		{
+			name: "timeout",
+			args: []string{"cmd.sleep"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+			},
+		},
+		{
+			name: "output too large",
+			args: []string{"cmd.large"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, execlookup.ErrOutputTooLarge)
+			},
+		},
+		{
+			name: "non-zero exit code",
+			args: []string{"cmd.fail"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "exited with code 3: permission denied")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package tempura
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// =================================================================================
+// Interpolation without templates
+// =================================================================================
+
+// Expand は、 s の中の ${key} という形のプレースホルダーを m で解決した値に置き換えます。 text/template による解析を経ないため、単純な埋め込みに使えます。
+// envsubst と同様に ${key:-default} と書くと、キーが見つからない場合やどの prefix にも一致しない場合に default を使います。
+// ${ の後に } がない場合や、 default のないキーが見つからない場合はエラーを返します。 ${ で始まらない $ はそのまま残ります。
+//
+// Expand replaces placeholders of the form ${key} in s with the values resolved by m. It skips parsing by text/template, for simple interpolation.
+// As envsubst does, ${key:-default} uses default when the key is not found or matches no prefix.
+// It returns an error if ${ has no closing }, or if a key without default is not found. A $ not followed by { is left as is.
+//
+//	dsn, err := tempura.Expand("postgres://app:${secret.DB_PASS}@${env.DB_HOST:-localhost}:5432/app", lookup)
+func Expand(s string, m MultiLookup) (string, error) {
+	b := &strings.Builder{}
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(s[start+2:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated placeholder: %q", s[start:])
+		}
+		b.WriteString(s[:start])
+
+		val, err := expandPlaceholder(s[start+2:start+2+end], m.FuncMapValue)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(val)
+		s = s[start+2+end+1:]
+	}
+}
+
+// expandPlaceholder は、 ${ と } の間の key または key:-default を解決します。
+//
+// expandPlaceholder resolves key or key:-default between ${ and }.
+func expandPlaceholder(placeholder string, lookup func(args ...string) (any, error)) (string, error) {
+	key, def, hasDefault := strings.Cut(placeholder, ":-")
+	val, err := lookup(key)
+	if err != nil {
+		if hasDefault && (errors.Is(err, ErrNotFound) || errors.Is(err, ErrMatchFailed)) {
+			return def, nil
+		}
+		return "", fmt.Errorf("failed to expand ${%s}: %w", key, err)
+	}
+	return fmt.Sprint(val), nil
+}
+
+// Mapping は、 os.Expand に渡せる形で m を公開します。 os.Expand を中心に作られた既存のコードの探索先を tempura に切り替えられます。
+// Expand と同様に ${key:-default} の default を使えますが、見つからないキーやエラーになったキーは os.Getenv と同様に空文字列になります。エラーを知りたい場合は MappingWithErrors を使ってください。
+//
+// Mapping exposes m in the form os.Expand accepts, so existing code built around os.Expand can switch its backend to tempura.
+// The default of ${key:-default} works as in Expand, but keys not found or failing expand to empty strings as with os.Getenv. Use MappingWithErrors to know the errors.
+//
+//	s := os.Expand("postgres://app:${secret.DB_PASS}@${env.DB_HOST:-localhost}/app", lookup.Mapping())
+func (m MultiLookup) Mapping() func(string) string {
+	return func(placeholder string) string {
+		val, _ := expandPlaceholder(placeholder, m.FuncMapValue)
+		return val
+	}
+}
+
+// MappingWithErrors は Mapping と同様ですが、展開に失敗したキーのエラーを集め、 errors.Join でまとめて返す関数を合わせて返します。
+//
+// MappingWithErrors is like Mapping, but also returns a function that returns the errors of the keys failed to expand, joined with errors.Join.
+//
+//	mapping, errs := lookup.MappingWithErrors()
+//	s := os.Expand(text, mapping)
+//	if err := errs(); err != nil {
+//		return err
+//	}
+func (m MultiLookup) MappingWithErrors() (mapping func(string) string, errs func() error) {
+	return newMapping(m.FuncMapValue)
+}
+
+// Mapping は、 os.Expand に渡せる形で m を公開します。 os.Expand を中心に作られた既存のコードの探索先を tempura に切り替えられます。
+// Expand と同様に ${key:-default} の default を使えますが、見つからないキーやエラーになったキーは os.Getenv と同様に空文字列になります。エラーを知りたい場合は MappingWithErrors を使ってください。
+//
+// Mapping exposes m in the form os.Expand accepts, so existing code built around os.Expand can switch its backend to tempura.
+// The default of ${key:-default} works as in Expand, but keys not found or failing expand to empty strings as with os.Getenv. Use MappingWithErrors to know the errors.
+//
+//	s := os.Expand(text, secrets.BindContext(ctx).Mapping())
+func (m *MultiLookupContext) Mapping() func(string) string {
+	return func(placeholder string) string {
+		val, _ := expandPlaceholder(placeholder, m.FuncMapValue)
+		return val
+	}
+}
+
+// MappingWithErrors は Mapping と同様ですが、展開に失敗したキーのエラーを集め、 errors.Join でまとめて返す関数を合わせて返します。
+//
+// MappingWithErrors is like Mapping, but also returns a function that returns the errors of the keys failed to expand, joined with errors.Join.
+func (m *MultiLookupContext) MappingWithErrors() (mapping func(string) string, errs func() error) {
+	return newMapping(m.FuncMapValue)
+}
+
+func newMapping(lookup func(args ...string) (any, error)) (mapping func(string) string, errs func() error) {
+	var mu sync.Mutex
+	var collected []error
+	mapping = func(placeholder string) string {
+		val, err := expandPlaceholder(placeholder, lookup)
+		if err != nil {
+			mu.Lock()
+			collected = append(collected, err)
+			mu.Unlock()
+		}
+		return val
+	}
+	errs = func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return errors.Join(collected...)
+	}
+	return mapping, errs
+}
@@ -0,0 +1,137 @@
+package tempura_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("env"): tempura.Func(func(key string) (string, bool) { return "env-of-" + key, key != "MISSING" }),
+		tempura.DotPrefix("secret"): tempura.FuncWithError(func(key string) (string, bool, error) {
+			return "", false, errUnavailable
+		}),
+		tempura.DotPrefix("port"): tempura.Func(func(key string) (int, bool) { return 5432, true }),
+	}
+
+	tests := []struct {
+		name     string
+		s        string
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "placeholders", s: "${env.HOST}:${port.DB}/app", expected: "env-of-HOST:5432/app"},
+		{name: "no placeholders", s: "plain $HOME text", expected: "plain $HOME text"},
+		{name: "default unused", s: "${env.HOST:-localhost}", expected: "env-of-HOST"},
+		{name: "default for not found", s: "${env.MISSING:-localhost}", expected: "localhost"},
+		{name: "default for no prefix matched", s: "${vault.HOST:-}", expected: ""},
+		// ==================== INVALID CASES ====================
+		{
+			name: "not found",
+			s:    "${env.MISSING}",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+		{
+			name: "no prefix matched",
+			s:    "${vault.HOST}",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrMatchFailed)
+			},
+		},
+		{
+			name: "lookup error ignores default",
+			s:    "${secret.DB_PASS:-fallback}",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+			},
+		},
+		{
+			name: "unterminated",
+			s:    "host: ${env.HOST",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "unterminated placeholder")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := tempura.Expand(tt.s, lookup)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestMultiLookup_Mapping(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("env"): tempura.Func(func(key string) (string, bool) { return "env-of-" + key, key != "MISSING" }),
+	}
+
+	tests := []struct {
+		name    string
+		mapping func() func(string) string
+	}{
+		{
+			name:    "Mapping",
+			mapping: lookup.Mapping,
+		},
+		{
+			name: "MappingWithErrors",
+			mapping: func() func(string) string {
+				mapping, _ := lookup.MappingWithErrors()
+				return mapping
+			},
+		},
+		{
+			name:    "context Mapping",
+			mapping: lookup.BindContext(context.Background()).Mapping,
+		},
+		{
+			name: "context MappingWithErrors",
+			mapping: func() func(string) string {
+				mapping, _ := lookup.BindContext(context.Background()).MappingWithErrors()
+				return mapping
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := os.Expand("${env.HOST}:${env.MISSING}:${env.MISSING:-5432}:${vault.PORT:-8200}", tt.mapping())
+			assert.Equal(t, "env-of-HOST::5432:8200", actual)
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		mapping, errs := lookup.BindContext(context.Background()).MappingWithErrors()
+		actual := os.Expand("${env.HOST}:${env.MISSING}:${vault.PORT}", mapping)
+		assert.Equal(t, "env-of-HOST::", actual)
+		err := errs()
+		assert.ErrorIs(t, err, tempura.ErrNotFound)
+		assert.ErrorIs(t, err, tempura.ErrMatchFailed)
+	})
+}
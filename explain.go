@@ -0,0 +1,132 @@
+package tempura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Explanation は、1つのキーがどのように解決されるかの説明です。 String は人が読むための形式を、 JSON のタグは機械が読むための形式を提供します。
+//
+// Explanation is an account of how a single key is resolved. String gives a human-readable form, and the JSON tags give a machine-readable one.
+type Explanation struct {
+	Arg string `json:"arg"`
+	// Cached は、 WithCache で登録されたキャッシュに値があることを表します。 Explain はキャッシュを使わずに全ての探索関数を呼び出します。
+	// Cached reports that the cache registered via WithCache holds a value. Explain calls every lookup function without using caches.
+	Cached     bool        `json:"cached"`
+	Candidates []Candidate `json:"candidates"`
+	// Selected は FuncMapValue が採用する Candidates の添字です。どれも値を返さなかった場合は -1 です。
+	// Selected is the index of Candidates adopted by FuncMapValue. It is -1 if none returned a value.
+	Selected int `json:"selected"`
+}
+
+// Candidate は、登録された1つの prefix についての説明です。値そのものは型と長さに置き換えられます。
+//
+// Candidate is an account of a single registered prefix. The value itself is replaced by its type and length.
+type Candidate struct {
+	Prefix   string `json:"prefix"`
+	Matched  bool   `json:"matched"`
+	Provider string `json:"provider,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Found    bool   `json:"found"`
+	Value    string `json:"value,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (e *Explanation) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s (cached: %t)\n", e.Arg, e.Cached)
+	for i, c := range e.Candidates {
+		if !c.Matched {
+			fmt.Fprintf(b, "  [ ] %s: not matched\n", c.Prefix)
+			continue
+		}
+		result := "not found"
+		switch {
+		case c.Error != "":
+			result = "error: " + c.Error
+		case c.Found:
+			result = "found " + c.Value
+		}
+		selected := ""
+		if i == e.Selected {
+			selected = " <- selected"
+		}
+		fmt.Fprintf(b, "  [x] %s (%s) key=%q: %s%s\n", c.Prefix, c.Provider, c.Key, result, selected)
+	}
+	return b.String()
+}
+
+// Explain は、 key に対してどの prefix が検討され、どれが一致し、それぞれの探索関数が何を返したかを説明します。
+// 「なぜこの値が間違っているのか」を調べるためのもので、一致した全ての探索関数をキャッシュを使わずに呼び出します。返された値は伏せられます。
+// WithRedactKeys を指定した場合は、 Arg と Key もキーの名前を伏せたものになります。
+//
+// Explain gives an account of which prefixes were considered for key, which matched, and what each lookup function returned.
+// It is meant for debugging "why is this value wrong" incidents, and calls every matching lookup function without using caches. Returned values are redacted.
+// With WithRedactKeys, Arg and Key hold redacted key names as well.
+func (m *MultiLookupContext) Explain(ctx context.Context, key string) (*Explanation, error) {
+	if ctx == nil {
+		return nil, ErrContextUntypedNil
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	e := &Explanation{Arg: m.keyName(key), Selected: -1}
+	if m.opts.cache != nil {
+		_, e.Cached = m.opts.cache.Get(key)
+	}
+
+	matches := m.match(key)
+	matched := make(map[Prefix]bool, len(matches))
+	for _, reg := range matches {
+		matched[reg.prefix] = true
+		c := Candidate{Prefix: fmt.Sprint(reg.prefix), Matched: true, Provider: providerType(reg.fn), Key: m.keyName(reg.prefix.Strip(key))}
+
+		res, err := m.resolveOne(ctx, reg, key)
+		if err == nil {
+			err = res.err
+		}
+		switch {
+		case err != nil:
+			var ke KeyError
+			if errors.As(err, &ke) {
+				err = ke.Err // en: the candidate already tells the prefix, key and provider
+			}
+			c.Error = err.Error()
+		case res.ok:
+			c.Found, c.Value = true, redact(res.val)
+			if e.Selected < 0 {
+				e.Selected = len(e.Candidates)
+			}
+		}
+		e.Candidates = append(e.Candidates, c)
+	}
+
+	var unmatched []Candidate
+	for prefix := range m.MultiLookup {
+		if !matched[prefix] {
+			unmatched = append(unmatched, Candidate{Prefix: fmt.Sprint(prefix)})
+		}
+	}
+	sort.Slice(unmatched, func(i, j int) bool { return unmatched[i].Prefix < unmatched[j].Prefix })
+	e.Candidates = append(e.Candidates, unmatched...)
+
+	return e, nil
+}
+
+// redact は値を型と長さに置き換えます。
+//
+// redact replaces a value with its type and length.
+func redact(val any) string {
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("%T(len=%d)", val, rv.Len())
+	default:
+		return fmt.Sprintf("%T", val)
+	}
+}
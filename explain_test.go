@@ -0,0 +1,66 @@
+package tempura_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiLookupContext_Explain(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("app"): tempura.Func(func(key string) (string, bool) {
+			return "", false
+		}),
+		tempura.DotPrefix("app.db"): tempura.FuncWithError(func(key string) (string, bool, error) {
+			if key == "FAIL" {
+				return "", false, errUnavailable
+			}
+			return "hunter2", true, nil
+		}),
+		tempura.DotPrefix("env"): tempura.Func(func(key string) (string, bool) {
+			return "/home/tempura", true
+		}),
+	}.BindContext(context.Background())
+
+	t.Run("found by a nested prefix", func(t *testing.T) {
+		t.Parallel()
+
+		e, err := lookup.Explain(context.Background(), "app.db.PASSWORD")
+		require.NoError(t, err)
+		assert.Equal(t, &tempura.Explanation{
+			Arg: "app.db.PASSWORD",
+			Candidates: []tempura.Candidate{
+				{Prefix: "app", Matched: true, Provider: "LookupAny", Key: "db.PASSWORD"},
+				{Prefix: "app.db", Matched: true, Provider: "LookupAnyWithError", Key: "PASSWORD", Found: true, Value: "string(len=7)"},
+				{Prefix: "env"},
+			},
+			Selected: 1,
+		}, e)
+
+		assert.Equal(t, `app.db.PASSWORD (cached: false)
+  [x] app (LookupAny) key="db.PASSWORD": not found
+  [x] app.db (LookupAnyWithError) key="PASSWORD": found string(len=7) <- selected
+  [ ] env: not matched
+`, e.String())
+
+		out, err := json.Marshal(e)
+		require.NoError(t, err)
+		assert.NotContains(t, string(out), "hunter2", "values should be redacted")
+		assert.NotContains(t, e.String(), "hunter2", "values should be redacted")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		e, err := lookup.Explain(context.Background(), "app.db.FAIL")
+		require.NoError(t, err)
+		assert.Equal(t, -1, e.Selected)
+		assert.Equal(t, "service unavailable", e.Candidates[1].Error)
+	})
+}
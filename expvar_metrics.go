@@ -0,0 +1,56 @@
+package tempura
+
+import (
+	"expvar"
+	"time"
+)
+
+// ExpvarMetrics は、基本的なカウンタを expvar で公開する Metrics です。 Prometheus を使わない場合でも、依存を増やさずに探索の状況を確認できます。
+// namespace という名前の expvar.Map に、 lookups (探索の総数), errors (エラーの数), cache_hits (キャッシュから解決された数) を公開します。
+//
+// ExpvarMetrics is a Metrics publishing basic counters via expvar. It gives visibility into lookups without any dependency, for users who don't want Prometheus.
+// It publishes lookups (total lookups), errors (number of errors) and cache_hits (number of resolutions from caches) in an expvar.Map named namespace.
+//
+//	lookup := secrets.BindContext(ctx, tempura.WithMetrics(tempura.NewExpvarMetrics("tempura")))
+//	// en: curl localhost:8080/debug/vars | jq .tempura
+type ExpvarMetrics struct {
+	lookups   *expvar.Int
+	errors    *expvar.Int
+	cacheHits *expvar.Int
+}
+
+// NewExpvarMetrics は、 namespace に公開する ExpvarMetrics を返します。
+// 同じ namespace で複数回呼び出した場合は、同じカウンタを共有します。
+//
+// NewExpvarMetrics returns ExpvarMetrics published under namespace.
+// Calling it multiple times with the same namespace shares the same counters.
+func NewExpvarMetrics(namespace string) *ExpvarMetrics {
+	vars, ok := expvar.Get(namespace).(*expvar.Map)
+	if !ok {
+		vars = expvar.NewMap(namespace) // en: panics if namespace is already used by a variable other than expvar.Map
+	}
+	return &ExpvarMetrics{
+		lookups:   expvarInt(vars, "lookups"),
+		errors:    expvarInt(vars, "errors"),
+		cacheHits: expvarInt(vars, "cache_hits"),
+	}
+}
+
+func expvarInt(vars *expvar.Map, name string) *expvar.Int {
+	if v, ok := vars.Get(name).(*expvar.Int); ok {
+		return v
+	}
+	v := &expvar.Int{}
+	vars.Set(name, v)
+	return v
+}
+
+func (m *ExpvarMetrics) ObserveLookup(prefix Prefix, outcome string, elapsed time.Duration) {
+	m.lookups.Add(1)
+	switch outcome {
+	case outcomeError:
+		m.errors.Add(1)
+	case outcomeCacheHit, outcomeNegativeCacheHit:
+		m.cacheHits.Add(1)
+	}
+}
@@ -0,0 +1,42 @@
+package tempura_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpvarMetrics(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		if key == "FAIL" {
+			return "", false, errUnavailable
+		}
+		return "secret-of-" + key, true, nil
+	}
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}
+	cache := tempura.NewTTLCache(time.Minute)
+
+	// en: metrics created twice with the same namespace share the counters
+	for i := 0; i < 2; i++ {
+		lookup := secrets.BindContext(context.Background(),
+			tempura.WithMetrics(tempura.NewExpvarMetrics("tempura_test_expvar")),
+			tempura.WithCache(cache),
+		)
+		_, _ = lookup.FuncMapValue("secret.DB_PASS")
+		_, _ = lookup.FuncMapValue("secret.FAIL")
+	}
+
+	var published map[string]int
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("tempura_test_expvar").String()), &published))
+	assert.Equal(t, map[string]int{"lookups": 4, "errors": 2, "cache_hits": 1}, published)
+}
@@ -0,0 +1,191 @@
+package tempura
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultHealthCheckInterval は、 Failover が正常でないプライマリの回復を確認する既定の間隔です。
+//
+// DefaultHealthCheckInterval is the default interval at which Failover checks whether an unhealthy primary has recovered.
+const DefaultHealthCheckInterval = 30 * time.Second
+
+// FailoverEvent は、 Failover のプライマリが正常でなくなった、または回復したことを表します。
+//
+// FailoverEvent represents that the primary of Failover became unhealthy or recovered.
+type FailoverEvent struct {
+	// Healthy は、プライマリが回復した場合に true です。
+	// Healthy is true if the primary recovered.
+	Healthy bool
+	// Err は、プライマリが正常でなくなった原因のエラーです。
+	// Err is the error that made the primary unhealthy.
+	Err error
+}
+
+// FailoverOption は Failover の振る舞いを変更します。
+//
+// FailoverOption changes the behavior of Failover.
+type FailoverOption func(*failover)
+
+// WithHealthCheck は、プライマリが正常でない間、 interval ごとに check をバックグラウンドで実行し、 nil を返せばプライマリに戻します。
+// check が nil の場合は interval ごとに、指定しない場合は DefaultHealthCheckInterval ごとに、1回の探索をプライマリで試行します。 check の panic は失敗として扱います。
+//
+// WithHealthCheck runs check in the background every interval while the primary is unhealthy, and routes back to the primary once it returns nil.
+// If check is nil, a single lookup is tried against the primary every interval instead, and every DefaultHealthCheckInterval without this option. A panic of check is treated as a failure.
+func WithHealthCheck(check func(ctx context.Context) error, interval time.Duration) FailoverOption {
+	return func(f *failover) {
+		f.check = check
+		f.interval = interval
+	}
+}
+
+// OnFailover は、プライマリが正常でなくなったときと回復したときに fn を呼び出します。
+//
+// OnFailover calls fn when the primary becomes unhealthy and when it recovers.
+func OnFailover(fn func(FailoverEvent)) FailoverOption {
+	return func(f *failover) {
+		f.onEvent = fn
+	}
+}
+
+// Failover は、 primary が threshold 回連続でエラーを返すと、回復するまで探索を fallback (例えば最後に正常だった値のファイルのスナップショット) に振り向けるミドルウェアです。
+// primary が見つからなかった場合は、 ErrNotFound を包んだエラーで報告した場合も含め、エラーではないためフォールバックしません。
+//
+// Failover is a middleware that routes lookups to fallback (e.g. a file snapshot of the last known good values) until primary recovers, once primary returns errors threshold times in a row.
+// If primary does not find a value, including when it reports so with an error wrapping ErrNotFound, it is not an error, so fallback is not used.
+//
+//	lookup := tempura.MultiLookup{
+//		tempura.DotPrefix("vault"): tempura.Failover(vaultLookup, snapshotLookup, 3,
+//			tempura.WithHealthCheck(vaultHealth, 10*time.Second),
+//			tempura.OnFailover(func(e tempura.FailoverEvent) { slog.Warn("vault failover", slog.Bool("healthy", e.Healthy)) }),
+//		),
+//	}.BindContext(ctx)
+func Failover(primary, fallback LookupFunc, threshold int, opts ...FailoverOption) LookupAnyWithContextError {
+	f := &failover{
+		primary:   withContextError("Failover", primary),
+		fallback:  withContextError("Failover", fallback),
+		threshold: max(threshold, 1),
+		interval:  DefaultHealthCheckInterval,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return func(ctx context.Context, val string) (any, bool, error) {
+		if f.usePrimary(ctx) {
+			v, ok, err := f.primary(ctx, val)
+			switch {
+			case err == nil:
+				f.recover()
+				return v, ok, nil
+			case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+				return v, ok, err
+			case errors.Is(err, ErrNotFound): // en: the primary answered that the key is absent
+				return v, ok, err
+			case !f.fail(err):
+				return v, ok, err
+			}
+		}
+		return f.fallback(ctx, val)
+	}
+}
+
+type failover struct {
+	primary   LookupAnyWithContextError
+	fallback  LookupAnyWithContextError
+	threshold int
+	check     func(ctx context.Context) error
+	interval  time.Duration
+	onEvent   func(FailoverEvent)
+
+	mu        sync.Mutex
+	failures  int
+	unhealthy bool
+	lastCheck time.Time
+	checking  bool
+}
+
+// usePrimary は、この探索をプライマリで行うかを返します。正常でない間は interval ごとに回復を確認します。
+//
+// usePrimary reports whether this lookup goes to the primary. While unhealthy, it checks for recovery every interval.
+func (f *failover) usePrimary(ctx context.Context) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.unhealthy {
+		return true
+	}
+	if time.Since(f.lastCheck) < f.interval {
+		return false
+	}
+	f.lastCheck = time.Now()
+	if f.check == nil {
+		return true // en: this lookup probes the primary
+	}
+	if !f.checking {
+		f.checking = true
+		go f.runCheck(context.WithoutCancel(ctx))
+	}
+	return false
+}
+
+// runCheck は check を実行し、成功すればプライマリに戻します。 check はバックグラウンドの goroutine で実行されるため、その panic から回復します。
+//
+// runCheck runs check and routes back to the primary if it succeeds. Since check runs on a background goroutine, it recovers from a panic of it.
+func (f *failover) runCheck(ctx context.Context) {
+	defer func() {
+		f.mu.Lock()
+		f.checking = false
+		f.mu.Unlock()
+	}()
+	if f.callCheck(ctx) == nil {
+		f.recover()
+	}
+}
+
+func (f *failover) callCheck(ctx context.Context) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = PanicError{Value: v}
+		}
+	}()
+	return f.check(ctx)
+}
+
+func (f *failover) recover() {
+	f.mu.Lock()
+	recovered := f.unhealthy
+	f.failures, f.unhealthy = 0, false
+	f.mu.Unlock()
+
+	if recovered {
+		f.emit(FailoverEvent{Healthy: true})
+	}
+}
+
+// fail はプライマリの失敗を記録し、プライマリが正常でない状態であれば true を返します。
+//
+// fail records a failure of the primary and returns true if the primary is unhealthy.
+func (f *failover) fail(err error) bool {
+	f.mu.Lock()
+	f.failures++
+	failedOver := !f.unhealthy && f.failures >= f.threshold
+	if failedOver {
+		f.unhealthy, f.lastCheck = true, time.Now()
+	}
+	unhealthy := f.unhealthy
+	f.mu.Unlock()
+
+	if failedOver {
+		f.emit(FailoverEvent{Err: err})
+	}
+	return unhealthy
+}
+
+func (f *failover) emit(e FailoverEvent) {
+	if f.onEvent != nil {
+		f.onEvent(e)
+	}
+}
@@ -0,0 +1,154 @@
+package tempura_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailover(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		healthCheck bool
+	}{
+		// ==================== VALID CASES ====================
+		{name: "recovery through the health check", healthCheck: true},
+		{name: "recovery through a probing lookup"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var down atomic.Bool
+			down.Store(true)
+			vault := tempura.FuncWithError(func(key string) (string, bool, error) {
+				if down.Load() {
+					return "", false, errUnavailable
+				}
+				return "vault:" + key, true, nil
+			})
+			snapshot := tempura.Func(func(key string) (string, bool) {
+				return "snapshot:" + key, true
+			})
+
+			mu := &sync.Mutex{}
+			var events []tempura.FailoverEvent
+			opts := []tempura.FailoverOption{
+				tempura.OnFailover(func(e tempura.FailoverEvent) {
+					mu.Lock()
+					defer mu.Unlock()
+					events = append(events, e)
+				}),
+			}
+			if tt.healthCheck {
+				opts = append(opts, tempura.WithHealthCheck(func(ctx context.Context) error {
+					if down.Load() {
+						return errUnavailable
+					}
+					return nil
+				}, 20*time.Millisecond))
+			} else {
+				opts = append(opts, tempura.WithHealthCheck(nil, 20*time.Millisecond))
+			}
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("vault"): tempura.Failover(vault, snapshot, 2, opts...),
+			}.BindContext(context.Background())
+
+			_, err := lookup.FuncMapValue("vault.KEY")
+			assert.ErrorIs(t, err, errUnavailable, "errors below the threshold should be returned")
+			for i := 0; i < 2; i++ {
+				val, err := lookup.FuncMapValue("vault.KEY")
+				require.NoError(t, err)
+				assert.Equal(t, "snapshot:KEY", val)
+			}
+
+			down.Store(false)
+			assert.Eventually(t, func() bool {
+				val, err := lookup.FuncMapValue("vault.KEY")
+				return err == nil && val == "vault:KEY"
+			}, time.Second, 10*time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.Len(t, events, 2)
+			assert.False(t, events[0].Healthy)
+			assert.ErrorIs(t, events[0].Err, errUnavailable)
+			assert.True(t, events[1].Healthy)
+		})
+	}
+}
+
+func TestFailover_NotFound(t *testing.T) {
+	t.Parallel()
+
+	vault := tempura.FuncWithError(func(key string) (string, bool, error) {
+		return "", false, fmt.Errorf("secret %s: %w", key, tempura.ErrNotFound)
+	})
+	snapshot := tempura.Func(func(key string) (string, bool) {
+		return "snapshot:" + key, true
+	})
+	var failovers atomic.Int32
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("vault"): tempura.Failover(vault, snapshot, 1,
+			tempura.OnFailover(func(tempura.FailoverEvent) { failovers.Add(1) }),
+		),
+	}.BindContext(context.Background())
+
+	for i := 0; i < 2; i++ {
+		_, err := lookup.FuncMapValue("vault.KEY")
+		assert.ErrorIs(t, err, tempura.ErrNotFound, "absent keys should not fall back to the snapshot")
+	}
+	assert.Zero(t, failovers.Load())
+}
+
+func TestFailover_HealthCheckPanic(t *testing.T) {
+	t.Parallel()
+
+	var down atomic.Bool
+	down.Store(true)
+	vault := tempura.FuncWithError(func(key string) (string, bool, error) {
+		if down.Load() {
+			return "", false, errUnavailable
+		}
+		return "vault:" + key, true, nil
+	})
+	snapshot := tempura.Func(func(key string) (string, bool) {
+		return "snapshot:" + key, true
+	})
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("vault"): tempura.Failover(vault, snapshot, 1,
+			tempura.WithHealthCheck(func(ctx context.Context) error {
+				if down.Load() {
+					panic("boom")
+				}
+				return nil
+			}, 20*time.Millisecond),
+		),
+	}.BindContext(context.Background())
+
+	val, err := lookup.FuncMapValue("vault.KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot:KEY", val)
+
+	time.Sleep(50 * time.Millisecond)
+	val, err = lookup.FuncMapValue("vault.KEY") // en: runs the panicking check
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot:KEY", val)
+
+	down.Store(false)
+	assert.Eventually(t, func() bool {
+		val, err := lookup.FuncMapValue("vault.KEY")
+		return err == nil && val == "vault:KEY"
+	}, time.Second, 10*time.Millisecond, "a panicking check should not block later checks")
+}
@@ -0,0 +1,45 @@
+// Package fasttempura は、 tempura の探索を valyala/fasttemplate の TagFunc として提供します。
+// fasttemplate で単純な埋め込みを行う高スループットなサービスでも、テンプレートと同じ探索関数の登録を使えます。
+//
+// Package fasttempura provides lookups of tempura as TagFunc of valyala/fasttemplate.
+// High-throughput services using fasttemplate for simple interpolation can reuse the same registrations of lookup functions as templates.
+//
+//	t := fasttemplate.New("postgres://app:{{secret.DB_PASS}}@{{env.DB_HOST}}/app", "{{", "}}")
+//	dsn, err := t.ExecuteFuncStringWithErr(fasttempura.TagFunc(secrets.BindContext(ctx)))
+package fasttempura
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/valyala/fasttemplate"
+)
+
+// Lookup は、 tempura.MultiLookup と *tempura.MultiLookupContext を表します。
+//
+// Lookup represents tempura.MultiLookup and *tempura.MultiLookupContext.
+type Lookup interface {
+	FuncMapValue(args ...string) (any, error)
+}
+
+// TagFunc は、タグを m で解決した値を書き込む fasttemplate.TagFunc を返します。
+// タグは空白で区切られた引数の列として FuncMapValue に渡されるため、 {{env.FOO env.BAR}} のように書くと、テンプレートと同様に順に探索されます。
+// 解決できなかったタグのエラーは、 ExecuteFuncStringWithErr などの戻り値として返されます。
+//
+// TagFunc returns a fasttemplate.TagFunc writing the values of tags resolved by m.
+// A tag is passed to FuncMapValue as a list of args separated by spaces, so {{env.FOO env.BAR}} is looked up in order, as in templates.
+// Errors of tags that could not be resolved are returned by ExecuteFuncStringWithErr and the like.
+func TagFunc(m Lookup) fasttemplate.TagFunc {
+	return func(w io.Writer, tag string) (int, error) {
+		args := strings.Fields(tag)
+		if len(args) == 0 {
+			return 0, fmt.Errorf("empty tag")
+		}
+		val, err := m.FuncMapValue(args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to resolve tag %q: %w", tag, err)
+		}
+		return fmt.Fprint(w, val)
+	}
+}
@@ -0,0 +1,62 @@
+package fasttempura_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/fasttempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasttemplate"
+)
+
+func TestTagFunc(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("env"):  tempura.Func(func(key string) (string, bool) { return "env-of-" + key, key != "MISSING" }),
+		tempura.DotPrefix("port"): tempura.Func(func(key string) (int, bool) { return 5432, true }),
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "tags", text: "{{env.HOST}}:{{ port.DB }}", expected: "env-of-HOST:5432"},
+		{name: "fallback", text: "{{env.MISSING env.HOST}}", expected: "env-of-HOST"},
+		// ==================== INVALID CASES ====================
+		{
+			name: "not found",
+			text: "{{env.MISSING}}",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+		{
+			name: "empty tag",
+			text: "{{ }}",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "empty tag")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tpl := fasttemplate.New(tt.text, "{{", "}}")
+			s, err := tpl.ExecuteFuncStringWithErr(fasttempura.TagFunc(lookup.BindContext(context.Background())))
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, s)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+module github.com/ebi-yade/go-tempura/fasttempura
+
+go 1.21
+
+require (
+	github.com/ebi-yade/go-tempura v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.9.0
+	github.com/valyala/fasttemplate v1.2.2
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/time v0.8.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/ebi-yade/go-tempura => ../
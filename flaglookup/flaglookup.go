@@ -0,0 +1,61 @@
+// Package flaglookup は、コマンドライン引数で渡されたフラグの値を返す探索関数を提供します。
+// 1つのテンプレートで、環境変数による指定とフラグによる指定の両方に対応できるようにするためのものです。
+//
+// Package flaglookup provides lookup functions that return the values of flags passed on the command line.
+// It lets one template serve both env-driven and flag-driven invocations.
+package flaglookup
+
+import (
+	"flag"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/spf13/pflag"
+)
+
+// New は、prefixを取り除いたキーをフラグ名として fs の値を返す探索関数を返します。
+// コマンドラインで明示的に指定されたフラグだけが見つかったものとして扱われるため、後続の引数でデフォルト値を指定できます。
+// fs が nil の場合は flag.CommandLine が使われます。
+//
+// New returns a lookup function that returns the value of fs, using the key with the prefix removed as the flag name.
+// Only flags explicitly set on the command line are treated as found, so that later args can provide defaults.
+// If fs is nil, flag.CommandLine is used.
+func New(fs *flag.FlagSet) tempura.LookupAny {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+
+	return func(name string) (any, bool) {
+		var found *flag.Flag
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == name {
+				found = f
+			}
+		})
+		if found == nil {
+			return nil, false
+		}
+		if getter, ok := found.Value.(flag.Getter); ok {
+			return getter.Get(), true
+		}
+		return found.Value.String(), true
+	}
+}
+
+// Pflag は github.com/spf13/pflag の FlagSet を対象にした New です。
+// fs が nil の場合は pflag.CommandLine が使われます。
+//
+// Pflag is New for FlagSet of github.com/spf13/pflag.
+// If fs is nil, pflag.CommandLine is used.
+func Pflag(fs *pflag.FlagSet) tempura.LookupAny {
+	if fs == nil {
+		fs = pflag.CommandLine
+	}
+
+	return func(name string) (any, bool) {
+		f := fs.Lookup(name)
+		if f == nil || !f.Changed {
+			return nil, false
+		}
+		return f.Value.String(), true
+	}
+}
@@ -0,0 +1,90 @@
+package flaglookup_test
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/flaglookup"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("db-host", "localhost", "")
+	fs.Int("db-port", 3306, "")
+	fs.String("db-user", "root", "")
+	require.NoError(t, fs.Parse([]string{"--db-host=db.example.com", "--db-port", "13306"}))
+
+	pfs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	pfs.String("db-host", "localhost", "")
+	pfs.String("db-user", "root", "")
+	require.NoError(t, pfs.Parse([]string{"--db-host", "pdb.example.com"}))
+
+	keyAsValue := func(val string) (string, bool) {
+		return val, true
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("flag"):    flaglookup.New(fs),
+		tempura.DotPrefix("pflag"):   flaglookup.Pflag(pfs),
+		tempura.DotPrefix("default"): tempura.Func(keyAsValue),
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "string flag",
+			args:     []string{"flag.db-host", "default.127.0.0.1"},
+			expected: "db.example.com",
+		},
+		{
+			name:     "typed flag keeps its type",
+			args:     []string{"flag.db-port"},
+			expected: 13306,
+		},
+		{
+			name:     "unset flag falls through to the next arg",
+			args:     []string{"flag.db-user", "default.admin"},
+			expected: "admin",
+		},
+		{
+			name:     "pflag",
+			args:     []string{"pflag.db-host"},
+			expected: "pdb.example.com",
+		},
+		{
+			name:     "unset pflag falls through to the next arg",
+			args:     []string{"pflag.db-user", "default.admin"},
+			expected: "admin",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "undefined flag",
+			args: []string{"flag.db-name", "pflag.db-name"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
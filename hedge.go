@@ -0,0 +1,112 @@
+package tempura
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// hedgeSamples は、遅延の計算に使う直近のレイテンシの数です。
+//
+// hedgeSamples is the number of recent latencies used to compute the delay.
+const hedgeSamples = 100
+
+// Hedge は、 fn が一定時間内に返らない場合に2回目の試行を開始し、先に成功した方の結果を採用するミドルウェアです (ヘッジリクエスト)。
+// 待つ時間は直近に成功した呼び出しのレイテンシの percentile (0 から 1) 分位数で、十分な記録がない間は initialDelay です。
+// 重要な描画経路で、遅いバックエンドによるテールレイテンシを抑えます。冪等な探索関数にのみ使ってください。
+//
+// Hedge is a middleware that fires a second attempt if fn hasn't returned within a delay, and takes whichever succeeds first (hedged requests).
+// The delay is the percentile (from 0 to 1) of the latencies of recent successful calls, or initialDelay until enough are recorded.
+// It reduces the tail latency caused by slow backends on critical render paths. Use it only for idempotent lookup functions.
+//
+//	lookup := tempura.MultiLookup{
+//		tempura.DotPrefix("vault"): tempura.Hedge(vaultLookup, 0.95, 100*time.Millisecond),
+//	}.BindContext(ctx)
+func Hedge(fn LookupFunc, percentile float64, initialDelay time.Duration) LookupAnyWithContextError {
+	h := &hedger{percentile: percentile, initialDelay: initialDelay}
+	next := withContextError("Hedge", fn)
+
+	return func(ctx context.Context, val string) (any, bool, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel() // en: stops the attempt that lost
+
+		results := make(chan lookupResult, 2) // en: buffered so that the losing attempt never blocks
+		attempt := func() {
+			// en: attempts run on their own goroutines, where safeCallLookup cannot recover panics
+			defer func() {
+				if v := recover(); v != nil {
+					results <- lookupResult{err: PanicError{Value: v}}
+				}
+			}()
+			start := time.Now()
+			v, ok, err := next(ctx, val)
+			if err == nil {
+				h.observe(time.Since(start))
+			}
+			results <- lookupResult{val: v, ok: ok, err: err}
+		}
+
+		go attempt()
+		timer := time.NewTimer(h.delay())
+		defer timer.Stop()
+		select {
+		case res := <-results:
+			return res.val, res.ok, res.err
+		case <-timer.C:
+			go attempt()
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+
+		// 先に返った方が失敗した場合は、もう一方の結果を待つ
+		// en: If the attempt that returned first failed, wait for the other one
+		res := <-results
+		if res.err != nil {
+			select {
+			case other := <-results:
+				return other.val, other.ok, other.err
+			case <-ctx.Done():
+			}
+		}
+		return res.val, res.ok, res.err
+	}
+}
+
+type hedger struct {
+	percentile   float64
+	initialDelay time.Duration
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func (h *hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.samples) < hedgeSamples {
+		h.samples = append(h.samples, d)
+		return
+	}
+	h.samples[h.next] = d
+	h.next = (h.next + 1) % hedgeSamples
+}
+
+// delay は、記録されたレイテンシの percentile 分位数を返します。記録が少ないうちは外れ値に左右されないよう initialDelay を返します。
+//
+// delay returns the percentile of the recorded latencies. While there are few records, it returns initialDelay so as not to be swayed by outliers.
+func (h *hedger) delay() time.Duration {
+	h.mu.Lock()
+	if len(h.samples) < hedgeSamples/10 {
+		h.mu.Unlock()
+		return h.initialDelay
+	}
+	sorted := append([]time.Duration(nil), h.samples...)
+	h.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(h.percentile * float64(len(sorted)-1))
+	return sorted[min(max(index, 0), len(sorted)-1)]
+}
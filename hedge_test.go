@@ -0,0 +1,104 @@
+package tempura_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedge(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		firstDelay    time.Duration
+		firstErr      error
+		expectedCalls int32
+		maxDuration   time.Duration
+	}{
+		// ==================== VALID CASES ====================
+		{name: "fast attempt is not hedged", expectedCalls: 1, maxDuration: 50 * time.Millisecond},
+		{name: "slow attempt is hedged", firstDelay: time.Second, expectedCalls: 2, maxDuration: 500 * time.Millisecond},
+		{name: "failed attempt waits for the hedge", firstDelay: 20 * time.Millisecond, firstErr: errUnavailable, expectedCalls: 2, maxDuration: 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+			vault := tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+				if calls.Add(1) == 1 {
+					select {
+					case <-time.After(tt.firstDelay):
+					case <-ctx.Done():
+						return "", false, ctx.Err()
+					}
+					if tt.firstErr != nil {
+						return "", false, tt.firstErr
+					}
+					return "first:" + key, true, nil
+				}
+				time.Sleep(30 * time.Millisecond)
+				return "hedged:" + key, true, nil
+			})
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("vault"): tempura.Hedge(vault, 0.95, 10*time.Millisecond),
+			}.BindContext(context.Background())
+
+			start := time.Now()
+			val, err := lookup.FuncMapValue("vault.KEY")
+			require.NoError(t, err)
+			assert.Less(t, time.Since(start), tt.maxDuration)
+			assert.Equal(t, tt.expectedCalls, calls.Load())
+			if tt.expectedCalls == 1 {
+				assert.Equal(t, "first:KEY", val)
+			} else {
+				assert.Equal(t, "hedged:KEY", val)
+			}
+		})
+	}
+}
+
+func TestHedge_Panic(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	vault := tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+		if calls.Add(1) == 1 {
+			time.Sleep(100 * time.Millisecond)
+			return "first:" + key, true, nil
+		}
+		panic("boom")
+	})
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("vault"): tempura.Hedge(vault, 0.95, 10*time.Millisecond),
+	}.BindContext(context.Background())
+
+	val, err := lookup.FuncMapValue("vault.KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "first:KEY", val)
+	assert.Equal(t, int32(2), calls.Load())
+
+	t.Run("both attempts panic", func(t *testing.T) {
+		t.Parallel()
+
+		lookup := tempura.MultiLookup{
+			tempura.DotPrefix("vault"): tempura.Hedge(tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+				time.Sleep(20 * time.Millisecond)
+				panic("boom")
+			}), 0.95, 10*time.Millisecond),
+		}.BindContext(context.Background())
+
+		_, err := lookup.FuncMapValue("vault.KEY")
+		var pe tempura.PanicError
+		require.ErrorAs(t, err, &pe)
+		assert.Equal(t, "boom", pe.Value)
+	})
+}
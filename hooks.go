@@ -0,0 +1,141 @@
+package tempura
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// =================================================================================
+// Hooks around provider calls
+// =================================================================================
+
+// LookupEvent は1回の探索関数の呼び出しの結果です。値そのものは含みません。
+//
+// LookupEvent is the result of a single call of a lookup function. It never contains the value itself.
+type LookupEvent struct {
+	Prefix Prefix
+	// Arg は prefix を含む引数、 Key は prefix を取り除いたキーです。
+	// Arg is the arg including the prefix, and Key is the key with the prefix removed.
+	Arg      string
+	Key      string
+	Provider string
+	Duration time.Duration
+	Found    bool
+	Err      error
+	// CorrelationIDs は、探索の ctx に含まれていたトレース ID とリクエスト ID です。
+	// CorrelationIDs are the trace ID and request ID carried by the context of the lookup.
+	CorrelationIDs CorrelationIDs
+}
+
+// Hooks は、全ての探索関数の呼び出しの前後に呼び出される関数です。
+// 関数ごとに包むことなく、独自のログ、メトリクス、ポリシーを1か所で実装できます。
+//
+// Hooks are functions invoked around every call of lookup functions.
+// They give integrators a single place to implement custom logging, metrics, or policy without wrapping each function.
+type Hooks struct {
+	// BeforeLookup がエラーを返した場合、探索関数は呼び出されず、そのエラーが探索の結果になります。
+	// If BeforeLookup returns an error, the lookup function is not called and the error becomes the result of the lookup.
+	BeforeLookup func(ctx context.Context, prefix Prefix, key string) error
+	AfterLookup  func(ctx context.Context, e LookupEvent)
+}
+
+// WithHooks は hooks を登録します。複数回指定した場合は、登録した順に呼び出されます。
+//
+// WithHooks registers hooks. If specified multiple times, they are invoked in the order of registration.
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, hooks)
+	}
+}
+
+func (m *MultiLookupContext) beforeLookup(ctx context.Context, prefix Prefix, key string) error {
+	for _, h := range m.opts.hooks {
+		if h.BeforeLookup == nil {
+			continue
+		}
+		if err := h.BeforeLookup(ctx, prefix, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MultiLookupContext) afterLookup(ctx context.Context, e LookupEvent) {
+	for _, h := range m.opts.hooks {
+		if h.AfterLookup != nil {
+			h.AfterLookup(ctx, e)
+		}
+	}
+}
+
+// =================================================================================
+// Error reporting
+// =================================================================================
+
+// ErrorEvent は、 FuncMapValue または Resolve が返したエラーと、その解決の文脈です。
+//
+// ErrorEvent is an error returned by FuncMapValue or Resolve, along with the context of the resolution.
+type ErrorEvent struct {
+	// Args は FuncMapValue または Resolve に渡された引数の列です。
+	// Args is the list of args passed to FuncMapValue or Resolve.
+	Args []string
+	// Prefix と Key は、エラーを返した探索関数の prefix と、 prefix を取り除いたキーです。
+	// ErrNotFound や ErrMatchFailed のように探索関数に由来しないエラーの場合は、 nil と空文字列です。
+	// Prefix and Key are the prefix of the lookup function that returned the error and the key with the prefix removed.
+	// For errors not originating from lookup functions, such as ErrNotFound and ErrMatchFailed, they are nil and empty.
+	Prefix Prefix
+	Key    string
+	// Err はテンプレートに返されるものと同じ、ラップされたエラーです。
+	// Err is the wrapped error, the same one returned to the template.
+	Err            error
+	CorrelationIDs CorrelationIDs
+}
+
+// WithOnError は、 FuncMapValue または Resolve がエラーを返すたびに fn を呼び出します。
+// エラーの追跡サービス (Sentry など) に、探索の失敗を役に立つ情報と共に送るために使います。
+// 複数回指定した場合は、登録した順に呼び出されます。
+//
+// WithOnError calls fn every time FuncMapValue or Resolve returns an error.
+// Use it to forward lookup failures to error trackers such as Sentry with useful metadata.
+// If specified multiple times, they are called in the order of registration.
+//
+//	tempura.WithOnError(func(ctx context.Context, e tempura.ErrorEvent) {
+//		sentry.CaptureException(e.Err)
+//	})
+func WithOnError(fn func(ctx context.Context, e ErrorEvent)) Option {
+	return func(o *options) {
+		o.onError = append(o.onError, fn)
+	}
+}
+
+// WithErrorTransform は、 FuncMapValue または Resolve がエラーを返す前に fn を呼び出し、その結果を代わりに返します。
+// fn はエラーを調べ、包み、置き換え、または nil を返して値に変えることができるため、組織全体のエラーメッセージの規約や、特定のエラーの既定値への変換を1か所で実装できます。
+// WithOnMissing の後、 WithOnError の前に適用されます。
+//
+// WithErrorTransform calls fn before FuncMapValue or Resolve returns an error, and returns its result instead.
+// fn can inspect, wrap or replace the error, or return nil to turn it into a value, so organization-wide conventions of error messages, or conversions of specific errors to defaults, are enforced in one place.
+// It is applied after WithOnMissing and before WithOnError.
+//
+//	tempura.WithErrorTransform(func(ctx context.Context, args []string, err error) (any, error) {
+//		if errors.Is(err, context.DeadlineExceeded) && strings.HasPrefix(args[0], "flag.") {
+//			return false, nil // en: feature flags default to off
+//		}
+//		return nil, fmt.Errorf("[config] %s: %w", strings.Join(args, " "), err)
+//	})
+func WithErrorTransform(fn func(ctx context.Context, args []string, err error) (any, error)) Option {
+	return func(o *options) {
+		o.errorTransform = fn
+	}
+}
+
+func (m *MultiLookupContext) reportError(ctx context.Context, args []string, err error) {
+	e := ErrorEvent{Args: args, Err: err, CorrelationIDs: m.correlationIDs(ctx)}
+	var ke KeyError
+	if errors.As(err, &ke) {
+		e.Prefix, e.Key = ke.Prefix, ke.Key
+	}
+	for _, fn := range m.opts.onError {
+		fn(ctx, e)
+	}
+}
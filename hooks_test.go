@@ -0,0 +1,287 @@
+package tempura_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDenied = fmt.Errorf("access denied by policy")
+
+func TestWithHooks(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		if key == "FAIL" {
+			return "", false, errUnavailable
+		}
+		return "secret-of-" + key, true, nil
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		event    tempura.LookupEvent
+		called   bool
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "found",
+			args:     []string{"secret.DB_PASS"},
+			expected: "secret-of-DB_PASS",
+			event:    tempura.LookupEvent{Prefix: tempura.DotPrefix("secret"), Arg: "secret.DB_PASS", Key: "DB_PASS", Provider: "LookupAnyWithContextError", Found: true},
+			called:   true,
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "error",
+			args:   []string{"secret.FAIL"},
+			event:  tempura.LookupEvent{Prefix: tempura.DotPrefix("secret"), Arg: "secret.FAIL", Key: "FAIL", Provider: "LookupAnyWithContextError", Err: errUnavailable},
+			called: true,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+			},
+		},
+		{
+			name:  "denied by BeforeLookup",
+			args:  []string{"secret.ROOT"},
+			event: tempura.LookupEvent{Prefix: tempura.DotPrefix("secret"), Arg: "secret.ROOT", Key: "ROOT", Provider: "LookupAnyWithContextError", Err: errDenied},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errDenied)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mu := &sync.Mutex{}
+			var events []tempura.LookupEvent
+			called := false
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+					called = true
+					return fetchSecret(ctx, key)
+				}),
+			}.BindContext(context.Background(), tempura.WithHooks(tempura.Hooks{
+				BeforeLookup: func(ctx context.Context, prefix tempura.Prefix, key string) error {
+					if key == "ROOT" {
+						return errDenied
+					}
+					return nil
+				},
+				AfterLookup: func(ctx context.Context, e tempura.LookupEvent) {
+					mu.Lock()
+					defer mu.Unlock()
+					events = append(events, e)
+				},
+			}))
+
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, val)
+			}
+
+			assert.Equal(t, tt.called, called)
+			require.Len(t, events, 1)
+			events[0].Duration = 0
+			assert.Equal(t, tt.event, events[0])
+		})
+	}
+}
+
+func TestWithOnError(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		switch key {
+		case "FAIL":
+			return "", false, errUnavailable
+		case "MISSING":
+			return "", false, nil
+		}
+		return "secret-of-" + key, true, nil
+	}
+
+	tests := []struct {
+		name     string
+		resolve  bool
+		args     []string
+		expected []tempura.ErrorEvent
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "no error",
+			args:     []string{"secret.DB_PASS"},
+			expected: nil,
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "provider error",
+			args: []string{"secret.MISSING", "secret.FAIL"},
+			expected: []tempura.ErrorEvent{
+				{Args: []string{"secret.MISSING", "secret.FAIL"}, Prefix: tempura.DotPrefix("secret"), Key: "FAIL"},
+			},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+			},
+		},
+		{
+			name:    "provider error via Resolve",
+			resolve: true,
+			args:    []string{"secret.FAIL"},
+			expected: []tempura.ErrorEvent{
+				{Args: []string{"secret.FAIL"}, Prefix: tempura.DotPrefix("secret"), Key: "FAIL"},
+			},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+			},
+		},
+		{
+			name: "not found",
+			args: []string{"secret.MISSING"},
+			expected: []tempura.ErrorEvent{
+				{Args: []string{"secret.MISSING"}},
+			},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var events []tempura.ErrorEvent
+			onError := func(ctx context.Context, e tempura.ErrorEvent) {
+				events = append(events, e)
+			}
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+			}.BindContext(context.Background(), tempura.WithOnError(onError))
+
+			var err error
+			if tt.resolve {
+				_, err = lookup.Resolve(context.Background(), tt.args[0])
+			} else {
+				_, err = lookup.FuncMapValue(tt.args...)
+			}
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Len(t, events, len(tt.expected))
+			for i, e := range events {
+				assert.Equal(t, err, e.Err, "the error returned to the caller should be reported")
+				e.Err = nil
+				assert.Equal(t, tt.expected[i], e)
+			}
+		})
+	}
+}
+
+func TestWithErrorTransform(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		switch key {
+		case "FAIL":
+			return "", false, errUnavailable
+		case "DENIED":
+			return "", false, errDenied
+		}
+		return "", false, nil
+	}
+	transform := func(ctx context.Context, args []string, err error) (any, error) {
+		switch {
+		case errors.Is(err, errUnavailable):
+			return "default", nil
+		case errors.Is(err, tempura.ErrNotFound):
+			return nil, err
+		}
+		return nil, fmt.Errorf("[config] %s: %w", strings.Join(args, " "), err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		reported bool
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "converted to a default",
+			args:     []string{"secret.FAIL"},
+			expected: "default",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:     "wrapped",
+			args:     []string{"secret.DENIED"},
+			reported: true,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errDenied)
+				assert.ErrorContains(t, err, "[config] secret.DENIED: ")
+			},
+		},
+		{
+			name:     "kept as is",
+			args:     []string{"secret.MISSING"},
+			reported: true,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var reported error
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+			}.BindContext(context.Background(), tempura.WithErrorTransform(transform), tempura.WithOnError(func(ctx context.Context, e tempura.ErrorEvent) {
+				reported = e.Err
+			}))
+
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.reported {
+				assert.Equal(t, err, reported, "the transformed error should be reported")
+			} else {
+				assert.NoError(t, reported)
+			}
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+
+			val, err = lookup.Resolve(context.Background(), tt.args[0])
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val, "Resolve should apply the transform as well")
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package hostlookup は、ホスト名や IP アドレスなど、描画しているホストの情報を返す探索関数を提供します。
+//
+// Package hostlookup provides a lookup function that returns metadata of the host rendering templates, such as hostname and IP addresses.
+package hostlookup
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/ebi-yade/go-tempura"
+)
+
+// New は、prefixを取り除いたキーに応じてホストの情報を返す探索関数を返します。
+//
+//   - "hostname": os.Hostname の値 / the value of os.Hostname
+//   - "fqdn": DNS で解決した完全修飾ドメイン名 (解決できなければ hostname) / the fully qualified domain name resolved via DNS (hostname if it cannot be resolved)
+//   - "ip", "ipv4", "ipv6": ループバックでない最初のアドレス / the first non-loopback address
+//   - "ips": ループバックでない全てのアドレスの []string / all non-loopback addresses as []string
+//   - "os", "arch": runtime.GOOS, runtime.GOARCH
+//
+// fqdn の DNS の問い合わせは探索の ctx に従うため、遅いリゾルバで描画が止まることはありません。 context.Context を受け取るため、 BindContext した MultiLookupContext で使います。
+//
+// New returns a lookup function that returns host metadata depending on the key with the prefix removed.
+// The DNS query for "fqdn" follows the ctx of the lookup, so a slow resolver never stalls the render. Since it accepts context.Context, use it with a MultiLookupContext from BindContext.
+func New() tempura.LookupAnyWithContextError {
+	return func(ctx context.Context, key string) (any, bool, error) {
+		switch key {
+		case "hostname":
+			name, err := os.Hostname()
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to get hostname: %w", err)
+			}
+			return name, true, nil
+
+		case "fqdn":
+			name, err := os.Hostname()
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to get hostname: %w", err)
+			}
+			fqdn, err := fqdn(ctx, name)
+			if err != nil {
+				return nil, false, err
+			}
+			return fqdn, true, nil
+
+		case "ip", "ipv4", "ipv6":
+			ips, err := unicastIPs()
+			if err != nil {
+				return nil, false, err
+			}
+			for _, ip := range ips {
+				isV4 := ip.To4() != nil
+				if key == "ip" || (key == "ipv4" && isV4) || (key == "ipv6" && !isV4) {
+					return ip.String(), true, nil
+				}
+			}
+			return nil, false, nil
+
+		case "ips":
+			ips, err := unicastIPs()
+			if err != nil {
+				return nil, false, err
+			}
+			result := make([]string, 0, len(ips))
+			for _, ip := range ips {
+				result = append(result, ip.String())
+			}
+			return result, len(result) > 0, nil
+
+		case "os":
+			return runtime.GOOS, true, nil
+
+		case "arch":
+			return runtime.GOARCH, true, nil
+		}
+
+		return nil, false, nil
+	}
+}
+
+// fqdn は hostname の完全修飾ドメイン名を DNS で解決します。解決できなければ hostname を返しますが、 ctx が終了した場合はそのエラーを返します。
+//
+// fqdn resolves the fully qualified domain name of hostname via DNS. It returns hostname if it cannot be resolved, but the error of ctx if ctx is done.
+func fqdn(ctx context.Context, hostname string) (string, error) {
+	cname, err := net.DefaultResolver.LookupCNAME(ctx, hostname)
+	if err != nil || cname == "" {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return hostname, nil
+	}
+	return strings.TrimSuffix(cname, "."), nil
+}
+
+// unicastIPs は IPv4 を優先して、ループバックでないユニキャストアドレスを返します。
+//
+// unicastIPs returns non-loopback unicast addresses, IPv4 first.
+func unicastIPs() ([]net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface addresses: %w", err)
+	}
+
+	var v4, v6 []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			v4 = append(v4, ipNet.IP)
+		} else {
+			v6 = append(v6, ipNet.IP)
+		}
+	}
+	return append(v4, v6...), nil
+}
@@ -0,0 +1,92 @@
+package hostlookup_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/hostlookup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("host"): hostlookup.New(),
+	}.BindContext(context.Background())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "hostname",
+			args:     []string{"host.hostname"},
+			expected: hostname,
+		},
+		{
+			name:     "os",
+			args:     []string{"host.os"},
+			expected: runtime.GOOS,
+		},
+		{
+			name:     "arch",
+			args:     []string{"host.arch"},
+			expected: runtime.GOARCH,
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "unknown key",
+			args: []string{"host.kernel"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestNew_Network(t *testing.T) {
+	t.Parallel()
+
+	fn := hostlookup.New()
+	ctx := context.Background()
+
+	fqdn, ok, err := fn(ctx, "fqdn")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.NotEmpty(t, fqdn)
+
+	ips, ok, err := fn(ctx, "ips")
+	require.NoError(t, err)
+	if !ok {
+		t.Skip("no non-loopback address on this host")
+	}
+	ip, ok, err := fn(ctx, "ip")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.NotNil(t, net.ParseIP(ip.(string)))
+	assert.Contains(t, ips, ip)
+}
@@ -0,0 +1,193 @@
+package tempura
+
+import (
+	"fmt"
+	"html/template"
+	"reflect"
+)
+
+// =================================================================================
+// Types of values safe for html/template
+// =================================================================================
+
+// ReturnTypeError は、探索関数が html/template で安全に扱えない型の値を返したことを表します。
+//
+// ReturnTypeError represents that a lookup function returned a value of a type html/template cannot safely handle.
+type ReturnTypeError struct {
+	Type reflect.Type
+}
+
+func (e ReturnTypeError) Error() string {
+	if _, ok := htmlContentTypes[e.Type]; ok {
+		return fmt.Sprintf("returned %v, which bypasses the contextual escaping of html/template", e.Type)
+	}
+	return fmt.Sprintf("returned %v, which html/template cannot render as a single value", e.Type)
+}
+
+// WithHTMLSafeReturnTypes は、探索関数が返した値の型を html/template で安全に扱える型 (文字列、真偽値、数値、 fmt.Stringer とそれらのポインタ) に制限します。
+// スライスやマップなどの値と、 template.HTML などの文脈に応じたエスケープを迂回する型は、 ReturnTypeError を包んだ KeyError になります。
+// WithTrustedContent で指定した prefix の値は検査されません。
+// 探索関数の型引数は実行時には失われているため、検査は値が返されるたびに行われます。
+// Preload または Prefetch と組み合わせると、デプロイの前に問題のある探索関数を見つけられます。
+//
+// WithHTMLSafeReturnTypes restricts the types of values returned by lookup functions to ones html/template can safely handle: strings, booleans, numbers, fmt.Stringer and pointers to them.
+// Values such as slices and maps, and types bypassing the contextual escaping such as template.HTML, become a KeyError wrapping a ReturnTypeError.
+// Values of the prefixes specified in WithTrustedContent are not checked.
+// The type arguments of lookup functions are lost at run time, so the check is performed each time a value is returned.
+// Combined with Preload or Prefetch, it finds the offending lookup functions before deployment.
+//
+//	lookup := secrets.BindContext(ctx, tempura.WithHTMLSafeReturnTypes())
+//	if err := lookup.Prefetch(ctx, tpl, "secret"); err != nil {
+//		log.Fatalf("failed to validate secrets: %+v", err)
+//	}
+func WithHTMLSafeReturnTypes() Option {
+	return func(o *options) {
+		o.htmlSafe = true
+	}
+}
+
+// htmlContentTypes は、 html/template がエスケープ済みとして扱う型です。
+//
+// htmlContentTypes are the types html/template treats as already escaped.
+var htmlContentTypes = map[reflect.Type]struct{}{
+	reflect.TypeOf(template.CSS("")):      {},
+	reflect.TypeOf(template.HTML("")):     {},
+	reflect.TypeOf(template.HTMLAttr("")): {},
+	reflect.TypeOf(template.JS("")):       {},
+	reflect.TypeOf(template.JSStr("")):    {},
+	reflect.TypeOf(template.URL("")):      {},
+	reflect.TypeOf(template.Srcset("")):   {},
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// checkHTMLType は、 val が html/template で安全に扱えない型の場合に ReturnTypeError を返します。
+//
+// checkHTMLType returns a ReturnTypeError if val is of a type html/template cannot safely handle.
+func checkHTMLType(val any) error {
+	if val == nil {
+		return nil
+	}
+	typ := reflect.TypeOf(val)
+	t := typ
+	for {
+		if _, ok := htmlContentTypes[t]; ok {
+			return ReturnTypeError{Type: typ}
+		}
+		if t.Implements(stringerType) {
+			return nil
+		}
+		if t.Kind() != reflect.Pointer {
+			break
+		}
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return nil
+	}
+	return ReturnTypeError{Type: typ}
+}
+
+// ContentType は、 html/template がエスケープ済みとして扱う文字列の種類です。
+//
+// ContentType is a kind of strings html/template treats as already escaped.
+type ContentType int
+
+const (
+	ContentHTML ContentType = iota + 1
+	ContentHTMLAttr
+	ContentJS
+	ContentJSStr
+	ContentCSS
+	ContentURL
+	ContentSrcset
+)
+
+func (t ContentType) String() string {
+	switch t {
+	case ContentHTML:
+		return "HTML"
+	case ContentHTMLAttr:
+		return "HTMLAttr"
+	case ContentJS:
+		return "JS"
+	case ContentJSStr:
+		return "JSStr"
+	case ContentCSS:
+		return "CSS"
+	case ContentURL:
+		return "URL"
+	case ContentSrcset:
+		return "Srcset"
+	default:
+		return fmt.Sprintf("ContentType(%d)", int(t))
+	}
+}
+
+func (t ContentType) wrap(s string) any {
+	switch t {
+	case ContentHTML:
+		return template.HTML(s)
+	case ContentHTMLAttr:
+		return template.HTMLAttr(s)
+	case ContentJS:
+		return template.JS(s)
+	case ContentJSStr:
+		return template.JSStr(s)
+	case ContentCSS:
+		return template.CSS(s)
+	case ContentURL:
+		return template.URL(s)
+	case ContentSrcset:
+		return template.Srcset(s)
+	default:
+		return s
+	}
+}
+
+// WithTrustedContent は、 prefixes の探索関数が返した文字列を typ の型 (ContentHTML であれば template.HTML) に変換し、 html/template でエスケープされないようにします。
+// CMS から取得した描画済みの HTML の断片など、エスケープしてはならない値のためのものです。
+// 意図しない XSS を避けるため、エスケープを迂回するのは明示的に指定した prefix だけで、その探索関数が文字列以外を返した場合はエラーになります。
+//
+// WithTrustedContent converts strings returned by the lookup functions of prefixes into the type of typ, such as template.HTML for ContentHTML, so that html/template does not escape them.
+// It is for values that must not be escaped, such as pre-rendered HTML fragments from a CMS.
+// To avoid accidental XSS, only the prefixes explicitly specified bypass the escaping, and it is an error for their lookup functions to return anything but strings.
+//
+//	lookup := tempura.MultiLookup{
+//		tempura.DotPrefix("cms"):  tempura.FuncWithContextError(fetchFragment),
+//		tempura.DotPrefix("text"): tempura.FuncWithContextError(fetchText), // en: still escaped
+//	}.BindContext(ctx, tempura.WithTrustedContent(tempura.ContentHTML, tempura.DotPrefix("cms")))
+func WithTrustedContent(typ ContentType, prefixes ...Prefix) Option {
+	return func(o *options) {
+		if o.trusted == nil {
+			o.trusted = make(map[Prefix]ContentType, len(prefixes))
+		}
+		for _, prefix := range prefixes {
+			o.trusted[prefix] = typ
+		}
+	}
+}
+
+// checkContent は、 WithTrustedContent と WithHTMLSafeReturnTypes を、 prefix の探索関数が見つけた値に適用します。
+//
+// checkContent applies WithTrustedContent and WithHTMLSafeReturnTypes to the value found by the lookup function of prefix.
+func (m *MultiLookupContext) checkContent(prefix Prefix, res lookupResult) lookupResult {
+	if typ, ok := m.opts.trusted[prefix]; ok {
+		s, ok := res.val.(string)
+		if !ok {
+			return lookupResult{err: fmt.Errorf("trusted %v content must be a string, but %T was returned", typ, res.val), matched: true}
+		}
+		res.val = typ.wrap(s)
+		return res
+	}
+	if m.opts.htmlSafe {
+		if err := checkHTMLType(res.val); err != nil {
+			return lookupResult{err: err, matched: true}
+		}
+	}
+	return res
+}
@@ -0,0 +1,176 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	htmltemplate "html/template"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHTMLSafeReturnTypes(t *testing.T) {
+	t.Parallel()
+
+	port := 5432
+	values := map[string]any{
+		"STRING":   "p@ssword!",
+		"INT":      8080,
+		"FLOAT":    0.5,
+		"BOOL":     true,
+		"POINTER":  &port,
+		"STRINGER": time.Second,
+		"URL":      &url.URL{Scheme: "https", Host: "example.com"},
+		"HTML":     htmltemplate.HTML("<b>bold</b>"),
+		"JS":       htmltemplate.JS("alert(1)"),
+		"SLICE":    []string{"a", "b"},
+		"MAP":      map[string]string{"a": "b"},
+		"STRUCT":   struct{ Host string }{Host: "localhost"},
+	}
+	getValue := func(key string) (any, bool) {
+		val, ok := values[key]
+		return val, ok
+	}
+
+	tests := []struct {
+		name     string
+		opts     []tempura.Option
+		key      string
+		typeName string
+	}{
+		// ==================== VALID CASES ====================
+		{name: "string", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "STRING"},
+		{name: "int", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "INT"},
+		{name: "float", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "FLOAT"},
+		{name: "bool", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "BOOL"},
+		{name: "pointer", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "POINTER"},
+		{name: "fmt.Stringer", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "STRINGER"},
+		{name: "pointer to fmt.Stringer", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "URL"},
+		{name: "without the option", key: "HTML"},
+		// ==================== INVALID CASES ====================
+		{name: "template.HTML", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "HTML", typeName: "template.HTML"},
+		{name: "template.JS", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "JS", typeName: "template.JS"},
+		{name: "slice", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "SLICE", typeName: "[]string"},
+		{name: "map", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "MAP", typeName: "map[string]string"},
+		{name: "struct", opts: []tempura.Option{tempura.WithHTMLSafeReturnTypes()}, key: "STRUCT", typeName: "struct { Host string }"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("conf"): tempura.Func(getValue),
+			}.BindContext(context.Background(), tt.opts...)
+
+			val, err := lookup.FuncMapValue("conf." + tt.key)
+			if tt.typeName == "" {
+				require.NoError(t, err)
+				assert.Equal(t, values[tt.key], val)
+				return
+			}
+			require.Error(t, err)
+			var rte tempura.ReturnTypeError
+			require.ErrorAs(t, err, &rte)
+			assert.Equal(t, tt.typeName, rte.Type.String())
+
+			var ke tempura.KeyError
+			require.ErrorAs(t, err, &ke)
+			assert.Equal(t, tt.key, ke.Key)
+		})
+	}
+}
+
+func TestWithTrustedContent(t *testing.T) {
+	t.Parallel()
+
+	fragments := map[string]any{
+		"BANNER": `<b>sale</b>`,
+		"LINK":   `javascript:void(0)`,
+		"COUNT":  3,
+	}
+	fetchFragment := func(ctx context.Context, key string) (any, bool, error) {
+		val, ok := fragments[key]
+		return val, ok, nil
+	}
+	lookup := func(opts ...tempura.Option) *tempura.MultiLookupContext {
+		return tempura.MultiLookup{
+			tempura.DotPrefix("cms"):  tempura.FuncWithContextError(fetchFragment),
+			tempura.DotPrefix("text"): tempura.FuncWithContextError(fetchFragment),
+		}.BindContext(context.Background(), opts...)
+	}
+
+	tests := []struct {
+		name     string
+		opts     []tempura.Option
+		text     string
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "HTML",
+			opts:     []tempura.Option{tempura.WithTrustedContent(tempura.ContentHTML, tempura.DotPrefix("cms"))},
+			text:     `<div>{{ lookup "cms.BANNER" }}</div>`,
+			expected: `<div><b>sale</b></div>`,
+		},
+		{
+			name:     "other prefixes are still escaped",
+			opts:     []tempura.Option{tempura.WithTrustedContent(tempura.ContentHTML, tempura.DotPrefix("cms"))},
+			text:     `<div>{{ lookup "text.BANNER" }}</div>`,
+			expected: `<div>&lt;b&gt;sale&lt;/b&gt;</div>`,
+		},
+		{
+			name:     "URL",
+			opts:     []tempura.Option{tempura.WithTrustedContent(tempura.ContentURL, tempura.DotPrefix("cms"))},
+			text:     `<a href="{{ lookup "cms.LINK" }}">link</a>`,
+			expected: `<a href="javascript:void%280%29">link</a>`,
+		},
+		{
+			name:     "untrusted URL",
+			text:     `<a href="{{ lookup "cms.LINK" }}">link</a>`,
+			expected: `<a href="#ZgotmplZ">link</a>`,
+		},
+		{
+			name: "with WithHTMLSafeReturnTypes",
+			opts: []tempura.Option{
+				tempura.WithHTMLSafeReturnTypes(),
+				tempura.WithTrustedContent(tempura.ContentHTML, tempura.DotPrefix("cms")),
+			},
+			text:     `<div>{{ lookup "cms.BANNER" }}</div>`,
+			expected: `<div><b>sale</b></div>`,
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "not a string",
+			opts: []tempura.Option{tempura.WithTrustedContent(tempura.ContentHTML, tempura.DotPrefix("cms"))},
+			text: `{{ lookup "cms.COUNT" }}`,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "trusted HTML content must be a string, but int was returned")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tpl := htmltemplate.Must(tempura.RegisterToHTML(htmltemplate.New("page"), "lookup", lookup(tt.opts...)).Parse(tt.text))
+			buf := &bytes.Buffer{}
+			err := tpl.Execute(buf, nil)
+			if tt.checkErr != nil {
+				require.Error(t, err)
+				tt.checkErr(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
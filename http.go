@@ -0,0 +1,44 @@
+package tempura
+
+import (
+	"context"
+	"net/http"
+)
+
+// =================================================================================
+// Binding of HTTP requests
+// =================================================================================
+
+type requestLookupKey struct{}
+
+// Middleware は、受け取ったリクエストの context.Context に結び付けた MultiLookupContext をリクエストに持たせる net/http のミドルウェアを返します。
+// ハンドラーは FromRequest で取り出すことで、リクエストのキャンセルに従い、リクエストに紐付く値を探索関数に渡して描画できます。
+// 索引や統計などはリクエストの間で共有されるため、リクエストごとに BindContext を呼び出すより軽量です。
+//
+// Middleware returns net/http middleware that stores a MultiLookupContext, bound to the context.Context of the incoming request, on the request.
+// Handlers retrieve it with FromRequest, so that per-request renders respect the cancellation of the request and carry request-scoped values to lookup functions.
+// The index, statistics and so on are shared among requests, so it is lighter than calling BindContext for each request.
+//
+//	mux.Handle("/config", tempura.Middleware(secrets)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//		lookup, _ := tempura.FromRequest(r)
+//		tpl := template.Must(base.Clone()) // en: register the function on a clone per request
+//		_ = tempura.RegisterTo(tpl, "secret", lookup).Execute(w, nil)
+//	})))
+func Middleware(m MultiLookup, opts ...Option) func(http.Handler) http.Handler {
+	mc := m.BindContext(context.Background(), opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			ctx = context.WithValue(ctx, requestLookupKey{}, mc.withContext(ctx))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// FromRequest は、 Middleware が r に持たせた MultiLookupContext を返します。 Middleware を経由していない場合は false を返します。
+//
+// FromRequest returns the MultiLookupContext stored on r by Middleware. It returns false if r has not gone through Middleware.
+func FromRequest(r *http.Request) (*MultiLookupContext, bool) {
+	mc, ok := r.Context().Value(requestLookupKey{}).(*MultiLookupContext)
+	return mc, ok
+}
@@ -0,0 +1,95 @@
+package tempura_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantKey struct{}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		if err := ctx.Err(); err != nil {
+			return "", false, err
+		}
+		return fmt.Sprintf("%s-of-%v", key, ctx.Value(tenantKey{})), true, nil
+	}
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookup, ok := tempura.FromRequest(r)
+		if !ok {
+			http.Error(w, "no lookup", http.StatusInternalServerError)
+			return
+		}
+		tpl := template.Must(tempura.RegisterTo(template.New("page"), "secret", lookup).Parse(`{{ secret "secret.DB_PASS" }}`))
+		if err := tpl.Execute(w, nil); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		}
+	})
+	withTenant := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, r.URL.Query().Get("tenant"))))
+		})
+	}
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		canceled bool
+		code     int
+		body     string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:    "request-scoped values",
+			handler: withTenant(tempura.Middleware(secrets)(handler)),
+			code:    http.StatusOK,
+			body:    "DB_PASS-of-acme",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:     "canceled request",
+			handler:  tempura.Middleware(secrets)(handler),
+			canceled: true,
+			code:     http.StatusServiceUnavailable,
+			body:     "context canceled",
+		},
+		{
+			name:    "without the middleware",
+			handler: handler,
+			code:    http.StatusInternalServerError,
+			body:    "no lookup",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.canceled {
+				cancel()
+			}
+			req := httptest.NewRequest(http.MethodGet, "/?tenant=acme", nil).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.code, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.body)
+		})
+	}
+}
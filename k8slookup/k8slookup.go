@@ -0,0 +1,156 @@
+// Package k8slookup は、 Kubernetes の Downward API (ボリュームおよび環境変数) からポッドの情報を返す探索関数を提供します。
+// ポッド内のサイドカーや init コンテナで設定ファイルを描画する際に、ポッド名やラベルなどを参照できるようにするためのものです。
+//
+// Package k8slookup provides a lookup function that returns pod information exposed by the Kubernetes Downward API (volumes and env).
+// It lets sidecar/init containers rendering config inside a pod reference the pod name, labels, and so on.
+package k8slookup
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ebi-yade/go-tempura"
+)
+
+const (
+	DefaultDir = "/etc/podinfo"
+
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// DefaultEnv は、キーと Downward API で慣習的に使われる環境変数名の対応です。
+//
+// DefaultEnv maps keys to the environment variable names conventionally used with the Downward API.
+var DefaultEnv = map[string]string{
+	"name":            "POD_NAME",
+	"namespace":       "POD_NAMESPACE",
+	"uid":             "POD_UID",
+	"ip":              "POD_IP",
+	"node":            "NODE_NAME",
+	"serviceaccount":  "POD_SERVICE_ACCOUNT",
+	"limits.cpu":      "CPU_LIMIT",
+	"limits.memory":   "MEMORY_LIMIT",
+	"requests.cpu":    "CPU_REQUEST",
+	"requests.memory": "MEMORY_REQUEST",
+}
+
+// Config は Downward API の値をどこから読むかを定義します。
+//
+// Config defines where to read the values of the Downward API from.
+type Config struct {
+	// Dir は Downward API ボリュームのマウント先です。空文字列の場合は DefaultDir が使われます。
+	// Dir is the mount path of the Downward API volume. DefaultDir is used if empty.
+	Dir string
+
+	// Env はキーと環境変数名の対応です。 nil の場合は DefaultEnv が使われます。
+	// Env maps keys to environment variable names. DefaultEnv is used if nil.
+	Env map[string]string
+}
+
+// New は、prefixを取り除いたキーに応じてポッドの情報を返す探索関数を返します。
+//
+//   - "labels.<key>", "annotations.<key>": ボリューム内の labels, annotations ファイルの値 / the value in the labels or annotations file of the volume
+//   - "labels", "annotations": 全ての値の map[string]string / all values as map[string]string
+//   - それ以外: Env に対応する環境変数、次にボリューム内の同名のファイル / otherwise: the environment variable mapped by Env, then the file of the same name in the volume
+//
+// New returns a lookup function that returns pod information depending on the key with the prefix removed.
+// The namespace falls back to the one of the mounted service account token.
+func New(cfg Config) tempura.LookupAnyWithError {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = DefaultDir
+	}
+	env := cfg.Env
+	if env == nil {
+		env = DefaultEnv
+	}
+
+	return func(key string) (any, bool, error) {
+		for _, kind := range []string{"labels", "annotations"} {
+			if key == kind {
+				m, ok, err := readMap(filepath.Join(dir, kind))
+				return m, ok, err
+			}
+			if name, found := strings.CutPrefix(key, kind+"."); found {
+				m, _, err := readMap(filepath.Join(dir, kind))
+				if err != nil {
+					return nil, false, err
+				}
+				val, ok := m[name]
+				return val, ok, nil
+			}
+		}
+
+		if name, ok := env[key]; ok {
+			if val, ok := os.LookupEnv(name); ok {
+				return val, true, nil
+			}
+		}
+
+		if !filepath.IsLocal(key) {
+			return nil, false, fmt.Errorf("invalid key: %s", key)
+		}
+		if val, ok, err := readFile(filepath.Join(dir, key)); err != nil || ok {
+			return val, ok, err
+		}
+		if key == "namespace" {
+			return readFile(serviceAccountNamespaceFile)
+		}
+
+		return nil, false, nil
+	}
+}
+
+func readFile(path string) (any, bool, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(b)), true, nil
+}
+
+// readMap は labels, annotations ファイルの `key="value"` 形式の行を読み込みます。
+//
+// readMap reads lines in the form of `key="value"` of the labels or annotations file.
+func readMap(path string) (map[string]string, bool, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	m := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		k, v, found := strings.Cut(line, "=")
+		if !found {
+			return nil, false, fmt.Errorf("malformed line %d in %s", n, path) // en: the line may hold a value
+		}
+		unquoted, err := strconv.Unquote(v)
+		if err != nil {
+			return nil, false, fmt.Errorf("malformed value of %s in %s: %w", k, path, err)
+		}
+		m[k] = unquoted
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to scan %s: %w", path, err)
+	}
+
+	return m, true, nil
+}
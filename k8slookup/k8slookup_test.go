@@ -0,0 +1,88 @@
+package k8slookup_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/k8slookup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Setenv("TEMPURA_TEST_POD_NAME", "web-7d9f8")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "labels"), []byte("app=\"web\"\ntier=\"frontend\"\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "annotations"), []byte("note=\"line1\\nline2\"\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "namespace"), []byte("production\n"), 0o644))
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("pod"): k8slookup.New(k8slookup.Config{
+			Dir: dir,
+			Env: map[string]string{"name": "TEMPURA_TEST_POD_NAME", "namespace": "TEMPURA_TEST_POD_NAMESPACE"},
+		}),
+		tempura.DotPrefix("default"): tempura.Func(func(val string) (string, bool) { return val, true }),
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "env",
+			args:     []string{"pod.name"},
+			expected: "web-7d9f8",
+		},
+		{
+			name:     "file when env is not set",
+			args:     []string{"pod.namespace"},
+			expected: "production",
+		},
+		{
+			name:     "label",
+			args:     []string{"pod.labels.app"},
+			expected: "web",
+		},
+		{
+			name:     "all labels",
+			args:     []string{"pod.labels"},
+			expected: map[string]string{"app": "web", "tier": "frontend"},
+		},
+		{
+			name:     "quoted annotation",
+			args:     []string{"pod.annotations.note"},
+			expected: "line1\nline2",
+		},
+		{
+			name:     "missing label falls through to the next arg",
+			args:     []string{"pod.labels.version", "default.latest"},
+			expected: "latest",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "path traversal",
+			args: []string{"pod.../../etc/passwd"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "invalid key")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+module github.com/ebi-yade/go-tempura/keyringlookup
+
+go 1.21
+
+require (
+	github.com/ebi-yade/go-tempura v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.9.0
+	github.com/zalando/go-keyring v0.2.5
+)
+
+require (
+	github.com/alessio/shellescape v1.4.1 // indirect
+	github.com/danieljoos/wincred v1.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.24.0 // indirect
+	golang.org/x/time v0.8.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/ebi-yade/go-tempura => ../
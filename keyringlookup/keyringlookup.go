@@ -0,0 +1,43 @@
+// Package keyringlookup は、OS の認証情報ストア (macOS Keychain, Windows Credential Manager, Secret Service) から値を探索する関数を提供します。
+// 開発者の端末での描画において、平文のファイルを置かずに秘密情報を解決できるようにするためのものです。
+//
+// Package keyringlookup provides a lookup function backed by the operating system credential store
+// (macOS Keychain, Windows Credential Manager, Secret Service).
+// It lets renders on developer workstations resolve secrets without plaintext files.
+package keyringlookup
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/zalando/go-keyring"
+)
+
+// New は、 service に登録された認証情報を、prefixを取り除いたキーをユーザー名として探索する関数を返します。
+// service が空文字列の場合は、キーを "<service>/<user>" として解釈します。
+//
+// New returns a lookup function that resolves the credential registered for service, using the key with the prefix removed as the user name.
+// If service is empty, the key is interpreted as "<service>/<user>".
+func New(service string) tempura.LookupAnyWithError {
+	return tempura.FuncWithError(func(key string) (string, bool, error) {
+		service, user := service, key
+		if service == "" {
+			var found bool
+			service, user, found = strings.Cut(key, "/")
+			if !found {
+				return "", false, fmt.Errorf("key must be in the form of <service>/<user>: %s", key)
+			}
+		}
+
+		secret, err := keyring.Get(service, user)
+		if errors.Is(err, keyring.ErrNotFound) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("failed to get %s/%s from keyring: %w", service, user, err)
+		}
+		return secret, true, nil
+	})
+}
@@ -0,0 +1,69 @@
+package keyringlookup_test
+
+import (
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/keyringlookup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func TestNew(t *testing.T) {
+	keyring.MockInit()
+	require.NoError(t, keyring.Set("tempura-test", "db-pass", "p@ssword!"))
+	require.NoError(t, keyring.Set("other-service", "token", "XXXXXXXX"))
+
+	tests := []struct {
+		name     string
+		lookup   tempura.MultiLookup
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "fixed service",
+			lookup:   tempura.MultiLookup{tempura.DotPrefix("keyring"): keyringlookup.New("tempura-test")},
+			args:     []string{"keyring.db-pass"},
+			expected: "p@ssword!",
+		},
+		{
+			name:     "service in key",
+			lookup:   tempura.MultiLookup{tempura.DotPrefix("keyring"): keyringlookup.New("")},
+			args:     []string{"keyring.other-service/token"},
+			expected: "XXXXXXXX",
+		},
+		{
+			name: "missing secret falls through to the next arg",
+			lookup: tempura.MultiLookup{
+				tempura.DotPrefix("keyring"): keyringlookup.New("tempura-test"),
+				tempura.DotPrefix("default"): tempura.Func(func(val string) (string, bool) { return val, true }),
+			},
+			args:     []string{"keyring.missing", "default.fallback"},
+			expected: "fallback",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "service missing in key",
+			lookup: tempura.MultiLookup{tempura.DotPrefix("keyring"): keyringlookup.New("")},
+			args:   []string{"keyring.token"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "<service>/<user>")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := tt.lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
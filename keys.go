@@ -0,0 +1,64 @@
+package tempura
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"text/template"
+	"text/template/parse"
+)
+
+// =================================================================================
+// Extraction of keys from templates
+// =================================================================================
+
+// KeyRef は、テンプレートが参照する prefix とキーの組です。
+//
+// KeyRef is a pair of a prefix and a key referenced by a template.
+type KeyRef struct {
+	Prefix Prefix
+	// Key は prefix を取り除いたキー、 Arg はテンプレートに書かれた prefix を含む引数です。
+	// Key is the key with the prefix removed, and Arg is the arg including the prefix as written in the template.
+	Key string
+	Arg string
+}
+
+// Keys は、 tmpl に関連付けられた全てのテンプレートで name という関数に渡された文字列リテラルの引数から、 m に登録された prefix とキーの組を全て返します。
+// テンプレートに必要な設定の一覧を表示したり、先読みやドキュメントの生成に使えます。
+// 複数の prefix に一致する引数はそれぞれの組として、どの prefix にも一致しない引数は含まれずに (Lint で報告されます)、重複を除いて引数の順に並びます。
+//
+// Keys returns all pairs of a prefix registered in m and a key from the string literal args passed to the function name in all templates associated with tmpl.
+// Use it to display the configuration required by a template, or to drive prefetching or documentation generation.
+// Args matching multiple prefixes yield a pair for each, args matching no prefix are omitted (Lint reports them), and the pairs are deduplicated and sorted by arg.
+//
+//	for _, ref := range tempura.Keys(tpl, "secret", secrets) {
+//		fmt.Printf("%v\t%s\n", ref.Prefix, ref.Key)
+//	}
+func Keys(tmpl *template.Template, name string, m MultiLookup) []KeyRef {
+	mc := m.BindContext(context.Background())
+	var refs []KeyRef
+	seen := make(map[string]bool) // en: keyed by strings, since custom Prefixes may not be comparable
+	walkLookupCalls(tmpl, name, func(_ *template.Template, cmd *parse.CommandNode, _ bool) {
+		for _, node := range cmd.Args[1:] {
+			s, ok := node.(*parse.StringNode)
+			if !ok {
+				continue
+			}
+			for _, reg := range mc.match(s.Text) {
+				signature := fmt.Sprintf("%T %v\x00%s", reg.prefix, reg.prefix, s.Text)
+				if !seen[signature] {
+					seen[signature] = true
+					refs = append(refs, KeyRef{Prefix: reg.prefix, Key: reg.prefix.Strip(s.Text), Arg: s.Text})
+				}
+			}
+		}
+	})
+
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Arg != refs[j].Arg {
+			return refs[i].Arg < refs[j].Arg
+		}
+		return fmt.Sprintf("%T %v", refs[i].Prefix, refs[i].Prefix) < fmt.Sprintf("%T %v", refs[j].Prefix, refs[j].Prefix)
+	})
+	return refs
+}
@@ -0,0 +1,55 @@
+package tempura_test
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeys(t *testing.T) {
+	t.Parallel()
+
+	noop := tempura.Func(func(key string) (string, bool) { return "", false })
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("vault"):    noop,
+		tempura.DotPrefix("vault.db"): noop,
+		tempura.DotPrefix("default"):  noop,
+	}
+	placeholder := template.FuncMap{"secret": func(args ...string) (any, error) { return nil, nil }}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected []tempura.KeyRef
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name: "keys",
+			text: `{{ define "db" }}{{ secret "vault.db.PASS" "default.p@ss" }}{{ end }}api: {{ secret "vault.API_KEY" .Fallback }} {{ secret "vault.API_KEY" }} {{ secret "unknown.KEY" }}{{ template "db" }}`,
+			expected: []tempura.KeyRef{
+				{Prefix: tempura.DotPrefix("default"), Key: "p@ss", Arg: "default.p@ss"},
+				{Prefix: tempura.DotPrefix("vault"), Key: "API_KEY", Arg: "vault.API_KEY"},
+				{Prefix: tempura.DotPrefix("vault"), Key: "db.PASS", Arg: "vault.db.PASS"},
+				{Prefix: tempura.DotPrefix("vault.db"), Key: "PASS", Arg: "vault.db.PASS"},
+			},
+		},
+		{
+			name: "no calls",
+			text: `{{ .Value }}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tpl, err := template.New("config").Funcs(placeholder).Parse(tt.text)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, tempura.Keys(tpl, "secret", secrets))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Package kmslookup は、base64 エンコードされた暗号文をキーとして受け取り、テンプレートの描画時に KMS で復号する探索関数を提供します。
+// 暗号化された値をテンプレートの入力に直接埋め込めるようにするためのものです。
+//
+// Package kmslookup provides a lookup function whose key is a base64-encoded ciphertext that is decrypted via KMS at render time.
+// It enables encrypted values to live directly in template inputs.
+//
+// SDK への依存を避けるため、 KMS の呼び出しは Decrypter として注入します。
+// To avoid depending on any SDK, the KMS call is injected as a Decrypter:
+//
+//	// AWS KMS (github.com/aws/aws-sdk-go-v2/service/kms)
+//	awsKMS := kmslookup.DecrypterFunc(func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+//		out, err := client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: ciphertext})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.Plaintext, nil
+//	})
+//
+//	// GCP Cloud KMS (cloud.google.com/go/kms/apiv1)
+//	gcpKMS := kmslookup.DecrypterFunc(func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+//		resp, err := client.Decrypt(ctx, &kmspb.DecryptRequest{Name: keyName, Ciphertext: ciphertext})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return resp.Plaintext, nil
+//	})
+//
+//	lookup := tempura.MultiLookup{
+//		tempura.DotPrefix("kms"):    kmslookup.New(awsKMS),
+//		tempura.DotPrefix("gcpkms"): kmslookup.New(gcpKMS),
+//	}.BindContext(ctx)
+package kmslookup
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/ebi-yade/go-tempura"
+)
+
+// Decrypter は KMS の Decrypt API を抽象化したものです。
+//
+// Decrypter abstracts the Decrypt API of KMS.
+type Decrypter interface {
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+type DecrypterFunc func(ctx context.Context, ciphertext []byte) ([]byte, error)
+
+func (fn DecrypterFunc) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return fn(ctx, ciphertext)
+}
+
+// New は、prefixを取り除いたキーを base64 エンコードされた暗号文として復号する探索関数を返します。
+// 復号に失敗した場合は、フォールバックせずにエラーを返します。
+//
+// New returns a lookup function that decrypts the key, with the prefix removed, as a base64-encoded ciphertext.
+// If decryption fails, it returns an error instead of falling back.
+func New(d Decrypter) tempura.LookupAnyWithContextError {
+	return tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+		ciphertext, err := decodeBase64(key)
+		if err != nil {
+			return "", false, fmt.Errorf("ciphertext is not base64-encoded: %w", err)
+		}
+
+		plaintext, err := d.Decrypt(ctx, ciphertext)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to decrypt ciphertext: %w", err)
+		}
+		return string(plaintext), true, nil
+	})
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
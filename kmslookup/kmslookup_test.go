@@ -0,0 +1,81 @@
+package kmslookup_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/kmslookup"
+	"github.com/stretchr/testify/assert"
+)
+
+// reversing is a fake KMS whose "decryption" reverses the ciphertext.
+var reversing = kmslookup.DecrypterFunc(func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	if strings.HasPrefix(string(ciphertext), "denied") {
+		return nil, errAccessDenied
+	}
+	out := make([]byte, len(ciphertext))
+	for i, b := range ciphertext {
+		out[len(ciphertext)-1-i] = b
+	}
+	return out, nil
+})
+
+var errAccessDenied = fmt.Errorf("AccessDeniedException")
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("kms"): kmslookup.New(reversing),
+	}.BindContext(context.Background())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "standard encoding",
+			args:     []string{"kms." + base64.StdEncoding.EncodeToString([]byte("terces"))},
+			expected: "secret",
+		},
+		{
+			name:     "url-safe encoding without padding",
+			args:     []string{"kms." + base64.RawURLEncoding.EncodeToString([]byte("\xfb\xff?drowssap"))},
+			expected: "password?\xff\xfb",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "not base64",
+			args: []string{"kms.!!!"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "not base64-encoded")
+			},
+		},
+		{
+			name: "decrypter error is preserved",
+			args: []string{"kms." + base64.StdEncoding.EncodeToString([]byte("denied"))},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errAccessDenied)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package tempura
+
+import (
+	"strings"
+)
+
+// =================================================================================
+// Lenient mode for preview renders
+// =================================================================================
+
+// WithLenient は、 FuncMapValue と Resolve がエラーを返す代わりに Unresolved を値として返すようにします。
+// プレビューの描画で、解決できなかった箇所が空文字列ではなく <unresolved: env.FOO> のように分かる形で描画されます。
+// 本番の描画ではこのオプションを指定しないことで、これまで通りエラーとして失敗させてください。
+// WithOnMissing と WithErrorTransform の後に適用され、 Unresolved に変換されたエラーは WithOnError に報告されません。
+//
+// WithLenient makes FuncMapValue and Resolve return an Unresolved as the value instead of returning an error.
+// Preview renders then clearly show what could not be resolved, such as <unresolved: env.FOO>, rather than an empty string.
+// Leave this option out for production renders so that they still fail with errors.
+// It is applied after WithOnMissing and WithErrorTransform, and errors turned into Unresolved are not reported to WithOnError.
+func WithLenient() Option {
+	return func(o *options) {
+		o.lenient = true
+	}
+}
+
+// Unresolved は、 WithLenient のもとで解決できなかった引数の列を表す値です。 fmt.Stringer を実装しており、テンプレートでは <unresolved: env.FOO> と描画されます。
+//
+// Unresolved is the value representing a list of args that could not be resolved under WithLenient. It implements fmt.Stringer and renders as <unresolved: env.FOO> in templates.
+type Unresolved struct {
+	Args []string
+	// Err は、 WithLenient がなければ返されていたエラーです。
+	// Err is the error that would have been returned without WithLenient.
+	Err error
+
+	redact *keyRedactor
+}
+
+func (u Unresolved) String() string {
+	args := u.Args
+	if u.redact != nil {
+		args = make([]string, len(u.Args))
+		for i, arg := range u.Args {
+			args[i] = u.redact.key(arg)
+		}
+	}
+	return "<unresolved: " + strings.Join(args, " ") + ">"
+}
@@ -0,0 +1,106 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"text/template"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLenient(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		switch key {
+		case "FAIL":
+			return "", false, errUnavailable
+		case "DB_PASS":
+			return "p@ssword!", true, nil
+		}
+		return "", false, nil
+	}
+
+	tests := []struct {
+		name     string
+		opts     []tempura.Option
+		text     string
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "resolved",
+			opts:     []tempura.Option{tempura.WithLenient()},
+			text:     `{{ secret "secret.DB_PASS" }}`,
+			expected: "p@ssword!",
+		},
+		{
+			name:     "not found",
+			opts:     []tempura.Option{tempura.WithLenient()},
+			text:     `password={{ secret "secret.MISSING" "env.DB_PASS" }}`,
+			expected: "password=<unresolved: secret.MISSING env.DB_PASS>",
+		},
+		{
+			name:     "provider error",
+			opts:     []tempura.Option{tempura.WithLenient()},
+			text:     `{{ secret "secret.FAIL" }}`,
+			expected: "<unresolved: secret.FAIL>",
+		},
+		{
+			name:     "key names redacted",
+			opts:     []tempura.Option{tempura.WithLenient(), tempura.WithRedactKeys()},
+			text:     `{{ secret "secret.MISSING" }}`,
+			expected: "<unresolved: sha256:",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "strict render still fails",
+			text: `{{ secret "secret.MISSING" }}`,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+			}.BindContext(context.Background(), tt.opts...)
+
+			tpl := template.Must(template.New("").Funcs(template.FuncMap{"secret": lookup.FuncMapValue}).Parse(tt.text))
+			buf := &bytes.Buffer{}
+			err := tpl.Execute(buf, nil)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, buf.String(), tt.expected)
+		})
+	}
+}
+
+func TestUnresolved(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("env"): tempura.Func(func(key string) (string, bool) { return "", false }),
+	}.BindContext(context.Background(), tempura.WithLenient())
+
+	val, err := lookup.Resolve(context.Background(), "env.FOO")
+	require.NoError(t, err)
+	unresolved, ok := val.(tempura.Unresolved)
+	require.True(t, ok)
+	assert.Equal(t, []string{"env.FOO"}, unresolved.Args)
+	assert.True(t, errors.Is(unresolved.Err, tempura.ErrNotFound), "the original error should be kept")
+	assert.Equal(t, "<unresolved: env.FOO>", unresolved.String())
+}
@@ -0,0 +1,123 @@
+package tempura
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// =================================================================================
+// Lint of templates against registrations
+// =================================================================================
+
+// Issue は、 Lint がテンプレートに見つけた1つの問題です。
+//
+// Issue is a single problem Lint found in a template.
+type Issue struct {
+	// Template は呼び出しを含むテンプレートの名前、 File はそれを解析したときの名前です。
+	// Template is the name of the template containing the call, and File is the name under which it was parsed.
+	Template string
+	File     string
+	Line     int
+	Column   int
+	// Arg は問題のある引数です。呼び出し全体の問題の場合は空文字列です。
+	// Arg is the offending arg. It is empty for problems of the whole call.
+	Arg     string
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", i.File, i.Line, i.Column, i.Message)
+}
+
+// Lint は、 tmpl に関連付けられた全てのテンプレートから name という関数の呼び出しを探し、 m に登録されたどの prefix にも一致しない引数や、形の正しくない引数を報告します。
+// `evn.HOME` のような誤字を、デプロイの前に CI で見つけるためのものです。変数やパイプラインなど、実行するまで値が決まらない引数は検査されません。
+// 問題は位置の順に並びます。
+//
+// Lint finds calls of the function name in all templates associated with tmpl, and reports args matching no prefix registered in m, or of invalid shapes.
+// It lets CI catch typos like `evn.HOME` before deploy. Args whose values are unknown until execution, such as variables and pipelines, are not checked.
+// Issues are sorted by position.
+//
+//	for _, issue := range tempura.Lint(tpl, "param", params) {
+//		fmt.Println(issue) // en: config.yaml.tmpl:3:12: evn.HOME matches no registered prefix (did you mean env.HOME?)
+//	}
+func Lint(tmpl *template.Template, name string, m MultiLookup) []Issue {
+	mc := m.BindContext(context.Background())
+	var issues []Issue
+	walkLookupCalls(tmpl, name, func(t *template.Template, cmd *parse.CommandNode, _ bool) {
+		report := func(node parse.Node, arg, format string, args ...any) {
+			issue := Issue{Template: t.Name(), Arg: arg, Message: fmt.Sprintf(format, args...)}
+			location, _ := t.Tree.ErrorContext(node)
+			issue.File, issue.Line, issue.Column = splitLocation(location)
+			issues = append(issues, issue)
+		}
+
+		if len(cmd.Args) == 1 {
+			report(cmd, "", "%s takes at least one arg", name)
+		}
+		for _, node := range cmd.Args[1:] {
+			switch node := node.(type) {
+			case *parse.StringNode:
+				mc.lintArg(node.Text, func(format string, args ...any) { report(node, node.Text, format, args...) })
+			case *parse.NumberNode, *parse.BoolNode, *parse.NilNode:
+				report(node, "", "%s is not a string", node)
+			}
+		}
+	})
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return issues
+}
+
+func (m *MultiLookupContext) lintArg(arg string, report func(format string, args ...any)) {
+	if strings.TrimSpace(arg) == "" {
+		report("empty arg")
+		return
+	}
+	if strings.TrimSpace(arg) != arg {
+		report("%q has leading or trailing spaces", arg)
+	}
+	regs := m.match(arg)
+	if len(regs) == 0 {
+		if suggestion := m.suggest(arg, false); suggestion != "" {
+			report("%s matches no registered prefix (did you mean %s?)", arg, suggestion)
+		} else {
+			report("%s matches no registered prefix", arg)
+		}
+		return
+	}
+	for _, reg := range regs {
+		if reg.prefix.Strip(arg) == "" {
+			report("%s has an empty key for %v", arg, reg.prefix)
+		}
+	}
+}
+
+// splitLocation は、 parse.Tree.ErrorContext が返す "name:line:col" の形の位置を分割します。
+//
+// splitLocation splits a location of the form "name:line:col", as returned by parse.Tree.ErrorContext.
+func splitLocation(location string) (file string, line, column int) {
+	file = location
+	if i := strings.LastIndexByte(file, ':'); i >= 0 {
+		column, _ = strconv.Atoi(file[i+1:])
+		file = file[:i]
+	}
+	if i := strings.LastIndexByte(file, ':'); i >= 0 {
+		line, _ = strconv.Atoi(file[i+1:])
+		file = file[:i]
+	}
+	return file, line, column
+}
@@ -0,0 +1,82 @@
+package tempura_test
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	params := tempura.MultiLookup{
+		tempura.DotPrefix("env"):       tempura.Func(func(key string) (string, bool) { return "", false }),
+		tempura.DotPrefix("default"):   tempura.Func(func(key string) (string, bool) { return key, true }),
+		tempura.SlashPrefix("ssm/app"): tempura.Func(func(key string) (string, bool) { return "", false }),
+	}
+	placeholder := template.FuncMap{"param": func(args ...string) (any, error) { return nil, nil }}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name: "valid",
+			text: `{{ param "env.HOME" "ssm/app/HOME" "default./root" }} {{ param .Key }} {{ other "evn.HOME" }}`,
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "typos",
+			text: "home: {{ param \"evn.HOME\" \"default.~\" }}\nuser: {{ param \"env.USER\" \"defualt.root\" }}\n",
+			expected: []string{
+				"config:1:15: evn.HOME matches no registered prefix (did you mean env.HOME?)",
+				"config:2:26: defualt.root matches no registered prefix (did you mean default.root?)",
+			},
+		},
+		{
+			name: "shapes",
+			text: `{{ param }}{{ param "" }}{{ param "env." }}{{ param " env.HOME" }}{{ param 42 }}{{ param "unknown" }}`,
+			expected: []string{
+				"config:1:3: param takes at least one arg",
+				"config:1:20: empty arg",
+				"config:1:34: env. has an empty key for env",
+				`config:1:52: " env.HOME" has leading or trailing spaces`,
+				"config:1:52:  env.HOME matches no registered prefix (did you mean env.HOME?)",
+				"config:1:75: 42 is not a string",
+				"config:1:89: unknown matches no registered prefix",
+			},
+		},
+		{
+			name: "define blocks and nested calls",
+			text: `{{ define "db" }}{{ if param "evn.DB" }}{{ printf "%s" (param "evn.PASS") }}{{ end }}{{ end }}`,
+			expected: []string{
+				"config:1:29: evn.DB matches no registered prefix (did you mean env.DB?)",
+				"config:1:62: evn.PASS matches no registered prefix (did you mean env.PASS?)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tpl, err := template.New("config").Funcs(placeholder).Funcs(template.FuncMap{"other": func(string) string { return "" }}).Parse(tt.text)
+			require.NoError(t, err)
+			issues := tempura.Lint(tpl, "param", params)
+			actual := make([]string, 0, len(issues))
+			for _, issue := range issues {
+				actual = append(actual, issue.String())
+			}
+			assert.Equal(t, len(tt.expected), len(actual), actual)
+			if len(tt.expected) > 0 {
+				assert.Equal(t, tt.expected, actual)
+			}
+		})
+	}
+}
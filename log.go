@@ -0,0 +1,130 @@
+package tempura
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// =================================================================================
+// Structured logging of resolutions
+// =================================================================================
+
+const (
+	outcomeFound            = "found"
+	outcomeNotFound         = "not_found"
+	outcomeError            = "error"
+	outcomeCacheHit         = "cache_hit"
+	outcomeNegativeCacheHit = "negative_cache_hit"
+)
+
+// logEnabled は、ログの属性を組み立てる前に、それが出力されるかを確認するために使います。
+// 探索はテンプレートのループの中で繰り返し実行されるため、出力されない記録のためにアロケーションを行わないようにします。
+//
+// logEnabled is used to check whether a record is emitted before building its attributes.
+// Lookups run repeatedly inside template loops, so avoid allocating for records that will never be emitted.
+func logEnabled(logger *slog.Logger, ctx context.Context, level slog.Level) bool {
+	if ctx == nil {
+		ctx = context.Background() // en: MultiLookupContext may be generated without BindContext
+	}
+	return logger.Enabled(ctx, level)
+}
+
+// logLookup は1回の探索関数の呼び出しを記録します。値そのものは記録しません。
+//
+// logLookup records a single call of a lookup function. The value itself is never recorded.
+func logLookup(ctx context.Context, logger *slog.Logger, level slog.Level, prefix Prefix, arg, key string, fn LookupFunc, elapsed time.Duration, res lookupResult, ids CorrelationIDs) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	outcome := outcomeOf(res)
+	attrs := []slog.Attr{
+		slog.Any("prefix", prefix),
+		slog.String("arg", arg),
+		slog.String("key", key),
+		slog.String("provider", providerType(fn)),
+		slog.Duration("duration", elapsed),
+		slog.String("outcome", outcome),
+	}
+	if res.err != nil {
+		attrs = append(attrs, slog.Any("error", res.err))
+	}
+	attrs = ids.appendAttrs(attrs)
+	logger.LogAttrs(ctx, level, "lookup", attrs...)
+}
+
+// slowLookup は、 WithSlowLookupThreshold を超えた探索関数の呼び出しを警告します。
+//
+// slowLookup warns of a call of a lookup function that exceeded WithSlowLookupThreshold.
+func (m *MultiLookupContext) slowLookup(ctx context.Context, prefix Prefix, key string, fn LookupFunc, elapsed time.Duration) {
+	if !logEnabled(m.logger(), ctx, slog.LevelWarn) {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	attrs := []slog.Attr{
+		slog.Any("prefix", prefix),
+		slog.String("key", m.keyName(key)),
+		slog.String("provider", providerType(fn)),
+		slog.Duration("duration", elapsed),
+		slog.Duration("threshold", m.opts.slowLookup),
+	}
+	m.logger().LogAttrs(ctx, slog.LevelWarn, "slow lookup", m.correlationIDs(ctx).appendAttrs(attrs)...)
+}
+
+func outcomeOf(res lookupResult) string {
+	switch {
+	case res.err != nil:
+		return outcomeError
+	case res.ok:
+		return outcomeFound
+	default:
+		return outcomeNotFound
+	}
+}
+
+// cacheHit は、キャッシュから解決された arg をログ、メトリクス、監査ログ、 Stats に記録します。
+//
+// cacheHit records arg resolved from a cache in logs, metrics, audit logs and Stats.
+func (m *MultiLookupContext) cacheHit(ctx context.Context, arg, outcome string) {
+	if m.logEnabled(ctx) {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		attrs := []slog.Attr{slog.String("arg", m.keyName(arg)), slog.String("outcome", outcome)}
+		m.logger().LogAttrs(ctx, m.logLevel(), "lookup", m.correlationIDs(ctx).appendAttrs(attrs)...)
+	}
+	if m.stats != nil || m.opts.metrics != nil || len(m.opts.audits) > 0 {
+		var buf [4]registration
+		var prefix Prefix
+		if regs := m.matchInto(arg, buf[:0]); len(regs) > 0 {
+			prefix = regs[0].prefix
+		}
+		m.stats.recordCacheHit(prefix)
+		if m.opts.metrics != nil {
+			m.opts.metrics.ObserveLookup(prefix, outcome, 0)
+		}
+		if len(m.opts.audits) > 0 && prefix != nil {
+			m.recordAudit(ctx, AuditEvent{Prefix: prefix, Arg: arg, Key: prefix.Strip(arg), Outcome: outcome})
+		}
+	}
+}
+
+// providerType は、探索関数の型の名前をアロケーションなしで返します。
+//
+// providerType returns the name of the type of a lookup function without allocating.
+func providerType(fn LookupFunc) string {
+	switch fn.(type) {
+	case LookupAny:
+		return "LookupAny"
+	case LookupAnyWithError:
+		return "LookupAnyWithError"
+	case LookupAnyWithContext:
+		return "LookupAnyWithContext"
+	case LookupAnyWithContextError:
+		return "LookupAnyWithContextError"
+	default:
+		return "unknown"
+	}
+}
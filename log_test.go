@@ -0,0 +1,224 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogLevel(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		switch key {
+		case "FAIL":
+			return "", false, errUnavailable
+		case "MISSING":
+			return "", false, nil
+		}
+		return "secret-of-" + key, true, nil
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []map[string]any
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name: "found",
+			args: []string{"secret.DB_PASS"},
+			expected: []map[string]any{
+				{"msg": "lookup", "level": "INFO", "prefix": "secret", "arg": "secret.DB_PASS", "key": "DB_PASS", "provider": "LookupAnyWithContextError", "outcome": "found"},
+			},
+		},
+		{
+			name: "cache hit",
+			args: []string{"secret.CACHED"},
+			expected: []map[string]any{
+				{"msg": "lookup", "level": "INFO", "arg": "secret.CACHED", "outcome": "cache_hit"},
+			},
+		},
+		{
+			name: "not found, then found by the fallback",
+			args: []string{"secret.MISSING", "secret.API_KEY"},
+			expected: []map[string]any{ // en: sorted by arg, since the lookups run concurrently
+				{"msg": "lookup", "arg": "secret.API_KEY", "outcome": "found"},
+				{"msg": "lookup", "arg": "secret.MISSING", "outcome": "not_found"},
+			},
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "error",
+			args: []string{"secret.FAIL"},
+			expected: []map[string]any{
+				{"msg": "lookup", "arg": "secret.FAIL", "outcome": "error", "error": "service unavailable"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+			cache := tempura.NewTTLCache(time.Minute)
+			cache.Set("secret.CACHED", "cached")
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+			}.BindContext(context.Background(), tempura.WithLogger(logger), tempura.WithLogLevel(slog.LevelInfo), tempura.WithCache(cache))
+
+			_, _ = lookup.FuncMapValue(tt.args...)
+
+			assert.NotContains(t, buf.String(), "secret-of-", "values should never be logged")
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, len(tt.expected))
+			records := make([]map[string]any, len(lines))
+			for i, line := range lines {
+				require.NoError(t, json.Unmarshal([]byte(line), &records[i]))
+			}
+			sort.Slice(records, func(i, j int) bool { return records[i]["arg"].(string) < records[j]["arg"].(string) })
+			for i, record := range records {
+				for k, v := range tt.expected[i] {
+					assert.Equal(t, v, record[k], k)
+				}
+			}
+		})
+	}
+}
+
+func TestWithSlowLookupThreshold(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		if key == "SLOW" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return "secret-of-" + key, true, nil
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected map[string]any
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "slow",
+			args:     []string{"secret.SLOW"},
+			expected: map[string]any{"msg": "slow lookup", "level": "WARN", "prefix": "secret", "key": "SLOW", "provider": "LookupAnyWithContextError", "threshold": float64(10 * time.Millisecond)},
+		},
+		{
+			name:     "fast",
+			args:     []string{"secret.FAST"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+			}.BindContext(context.Background(), tempura.WithLogger(logger), tempura.WithSlowLookupThreshold(10*time.Millisecond))
+
+			_, err := lookup.FuncMapValue(tt.args...)
+			require.NoError(t, err)
+
+			if tt.expected == nil {
+				assert.Empty(t, buf.String())
+				return
+			}
+			record := map[string]any{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			for k, v := range tt.expected {
+				assert.Equal(t, v, record[k], k)
+			}
+			assert.GreaterOrEqual(t, record["duration"], float64(30*time.Millisecond))
+		})
+	}
+}
+
+func TestWithCorrelationIDs(t *testing.T) {
+	t.Parallel()
+
+	type customKey struct{}
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		opts     []tempura.Option
+		expected tempura.CorrelationIDs
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "set via WithTraceID and WithRequestID",
+			ctx:      tempura.WithRequestID(tempura.WithTraceID(context.Background(), "4bf92f3577b34da6"), "req-42"),
+			expected: tempura.CorrelationIDs{TraceID: "4bf92f3577b34da6", RequestID: "req-42"},
+		},
+		{
+			name: "extracted by a custom function",
+			ctx:  context.WithValue(context.Background(), customKey{}, "req-43"),
+			opts: []tempura.Option{tempura.WithCorrelationIDs(func(ctx context.Context) tempura.CorrelationIDs {
+				id, _ := ctx.Value(customKey{}).(string)
+				return tempura.CorrelationIDs{RequestID: id}
+			})},
+			expected: tempura.CorrelationIDs{RequestID: "req-43"},
+		},
+		{
+			name:     "none",
+			ctx:      context.Background(),
+			expected: tempura.CorrelationIDs{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			var event tempura.LookupEvent
+			var errEvent tempura.ErrorEvent
+			opts := append([]tempura.Option{
+				tempura.WithLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+				tempura.WithHooks(tempura.Hooks{AfterLookup: func(ctx context.Context, e tempura.LookupEvent) { event = e }}),
+				tempura.WithOnError(func(ctx context.Context, e tempura.ErrorEvent) { errEvent = e }),
+			}, tt.opts...)
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithError(func(key string) (string, bool, error) {
+					return "", false, errUnavailable
+				}),
+			}.BindContext(tt.ctx, opts...)
+
+			_, err := lookup.FuncMapValue("secret.DB_PASS")
+			require.Error(t, err)
+			assert.Equal(t, tt.expected, event.CorrelationIDs)
+			assert.Equal(t, tt.expected, errEvent.CorrelationIDs)
+
+			record := map[string]any{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			assert.Equal(t, tt.expected.TraceID, stringOf(record["trace_id"]))
+			assert.Equal(t, tt.expected.RequestID, stringOf(record["request_id"]))
+		})
+	}
+}
+
+func stringOf(v any) string {
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,229 @@
+package tempura
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// =================================================================================
+// Detailed errors for unresolved args
+// =================================================================================
+
+// MatchFailedError は、どの引数からも値が得られなかったことを、引数ごとの試行の内容と共に表します。
+// 一致した prefix が1つもない場合は ErrMatchFailed に、一致した探索関数が全て値を返さなかった場合は ErrNotFound に errors.Is で一致します。
+//
+// MatchFailedError reports that no value was obtained from any of the args, along with what was attempted for each arg.
+// It matches ErrMatchFailed with errors.Is if no prefix matched at all, and ErrNotFound if every matching lookup function returned no value.
+type MatchFailedError struct {
+	Attempts []Attempt
+
+	redact *keyRedactor
+}
+
+// Attempt は1つの引数に対する試行の内容です。
+//
+// Attempt is what was attempted for a single arg.
+type Attempt struct {
+	Arg string
+	// Matched は、 Arg に一致したものの値を返さなかった prefix です。空の場合は、どの prefix にも一致しなかったことを表します。
+	// Matched is the prefixes that matched Arg but returned no value. If empty, Arg matched no prefix.
+	Matched []Prefix
+	// Suggestion は、登録された prefix と WithKnownKeys で指定されたキーのうち Arg に最も近いものです。見つからない場合は空です。
+	// Suggestion is the closest to Arg among the registered prefixes and the keys specified via WithKnownKeys. It is empty if none is close enough.
+	Suggestion string
+}
+
+func (e MatchFailedError) Error() string {
+	b := &strings.Builder{}
+	if e.Is(ErrNotFound) {
+		b.WriteString(ErrNotFound.Error())
+	} else {
+		b.WriteString(ErrMatchFailed.Error())
+	}
+	for i, a := range e.Attempts {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		arg := e.redact.key(a.Arg)
+		if len(a.Matched) == 0 {
+			fmt.Fprintf(b, "%s matched no prefix", arg)
+		} else {
+			fmt.Fprintf(b, "%s was not found by %v", arg, a.Matched)
+		}
+		if a.Suggestion != "" && e.redact == nil {
+			fmt.Fprintf(b, " (did you mean %s?)", a.Suggestion)
+		}
+	}
+	return b.String()
+}
+
+// Unwrap は、一致したものの値を返さなかった prefix ごとの KeyNotFoundError を返します。
+//
+// Unwrap returns a KeyNotFoundError for each prefix that matched but returned no value.
+func (e MatchFailedError) Unwrap() []error {
+	var errs []error
+	for _, a := range e.Attempts {
+		for _, prefix := range a.Matched {
+			errs = append(errs, KeyNotFoundError{Prefix: prefix, Key: prefix.Strip(a.Arg), redact: e.redact})
+		}
+	}
+	return errs
+}
+
+func (e MatchFailedError) Is(target error) bool {
+	matched := false
+	for _, a := range e.Attempts {
+		matched = matched || len(a.Matched) > 0
+	}
+	switch target {
+	case ErrNotFound:
+		return matched
+	case ErrMatchFailed:
+		return !matched
+	default:
+		return false
+	}
+}
+
+// KeyNotFoundError は、 prefix に一致した探索関数がキーの値を持っていなかったことを表し、 errors.Is で ErrNotFound に一致します。
+// バックエンドの障害ではなくキーが本当に存在しないことを表すため、デプロイのツールなどで両者を異なる方法で扱えます。
+// 探索関数が ErrNotFound に一致するエラーを返した場合も、障害ではなく値が見つからなかったものとして扱われ、次の候補にフォールバックします。
+//
+// KeyNotFoundError reports that the lookup function matching prefix had no value for key, and matches ErrNotFound with errors.Is.
+// It means that the key is genuinely absent rather than that the backend failed, so tools such as deployment pipelines can handle the two differently.
+// If a lookup function returns an error matching ErrNotFound, it is also treated as a missing value rather than a failure and falls back to the next candidate.
+type KeyNotFoundError struct {
+	Prefix Prefix
+	Key    string
+
+	redact *keyRedactor
+}
+
+func (e KeyNotFoundError) Error() string {
+	return fmt.Sprintf("key %s not found by %v", e.redact.key(e.Key), e.Prefix)
+}
+
+func (e KeyNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// WithKnownKeys は、 MatchFailedError の Suggestion の候補として keys (prefix を含む引数) を追加します。
+// 探索関数はキーの一覧を提供しないため、スキーマやマニフェストから読み込んだキーを渡すことで、打ち間違いの候補を示せるようになります。
+//
+// WithKnownKeys adds keys, the args including the prefix, as candidates for Suggestion of MatchFailedError.
+// Lookup functions do not provide lists of keys, so passing keys loaded from a schema or manifest enables suggestions for typos.
+func WithKnownKeys(keys ...string) Option {
+	return func(o *options) {
+		o.knownKeys = append(o.knownKeys, keys...)
+	}
+}
+
+// WithOnMissing は、 FuncMapValue または Resolve が MatchFailedError を返す直前に fn を呼び出し、 fn が true を返した場合はその値を代わりに返します。
+// 最後の手段としての既定値や、フィーチャーフラグによる仮の値を1か所で実装するために使います。
+//
+// WithOnMissing calls fn right before FuncMapValue or Resolve would return a MatchFailedError, and returns its value instead if fn returns true.
+// Use it to implement last-resort defaulting or feature-flagged placeholders in one place.
+//
+//	tempura.WithOnMissing(func(ctx context.Context, args []string) (any, bool) {
+//		if flags.Enabled(ctx, "placeholder-secrets") {
+//			return "placeholder", true
+//		}
+//		return nil, false
+//	})
+func WithOnMissing(fn func(ctx context.Context, args []string) (any, bool)) Option {
+	return func(o *options) {
+		o.onMissing = fn
+	}
+}
+
+// missing は、 WithOnMissing の値、 WithPassThrough の値、または MatchFailedError を返します。
+//
+// missing returns the value of WithOnMissing, the value of WithPassThrough, or a MatchFailedError.
+func (m *MultiLookupContext) missing(ctx context.Context, args []string) (any, error) {
+	if m.opts.onMissing != nil {
+		if val, ok := m.opts.onMissing(ctx, args); ok {
+			return val, nil
+		}
+	}
+	if m.opts.passThrough != nil {
+		return m.opts.passThrough(args), nil
+	}
+	return nil, m.matchFailed(args)
+}
+
+// matchFailed は、 FuncMapValue が ErrMatchFailed または ErrNotFound を返す場合に、その代わりとなる MatchFailedError を組み立てます。
+// エラーの場合にだけ呼び出されるため、探索のホットパスに影響しないよう、一致の判定をやり直します。
+//
+// matchFailed builds the MatchFailedError to return instead of ErrMatchFailed or ErrNotFound from FuncMapValue.
+// It is called only on errors, so it redoes the matching to keep the hot path of lookups untouched.
+func (m *MultiLookupContext) matchFailed(args []string) MatchFailedError {
+	e := MatchFailedError{Attempts: make([]Attempt, len(args)), redact: m.opts.redactor}
+	for i, arg := range args {
+		a := Attempt{Arg: arg}
+		for _, reg := range m.match(arg) {
+			a.Matched = append(a.Matched, reg.prefix)
+		}
+		sort.Slice(a.Matched, func(i, j int) bool { return fmt.Sprint(a.Matched[i]) < fmt.Sprint(a.Matched[j]) })
+		a.Suggestion = m.suggest(arg, len(a.Matched) > 0)
+		e.Attempts[i] = a
+	}
+	return e
+}
+
+// suggest は arg に最も近い候補を返します。 arg が prefix に一致している場合は、キーだけを候補にします。
+//
+// suggest returns the candidate closest to arg. If arg matches a prefix, only keys are candidates.
+func (m *MultiLookupContext) suggest(arg string, matched bool) string {
+	candidates := append([]string(nil), m.opts.knownKeys...)
+	if !matched {
+		// en: Replace the head of arg up to the first separator with each literal prefix, e.g. "ennv.HOME" -> "env.HOME"
+		rest := arg
+		if i := strings.IndexAny(arg, "./"); i >= 0 {
+			rest = arg[i+1:]
+		}
+		for prefix := range m.MultiLookup {
+			if lp, ok := prefix.(LiteralPrefix); ok {
+				candidates = append(candidates, lp.Literal()+rest)
+			}
+		}
+	}
+
+	best, bestDist := "", max(2, len(arg)/4)+1
+	sort.Strings(candidates) // en: deterministic among candidates at the same distance
+	for _, c := range candidates {
+		if c == arg {
+			continue
+		}
+		if d := editDistance(arg, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance は a と b のレーベンシュタイン距離を返します。
+//
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
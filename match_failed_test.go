@@ -0,0 +1,258 @@
+package tempura_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchFailedError(t *testing.T) {
+	t.Parallel()
+
+	missing := func(ctx context.Context, key string) (string, bool, error) {
+		return "", false, nil
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("env"):    tempura.FuncWithContextError(missing),
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(missing),
+		tempura.SlashPrefix("ssm"):  tempura.FuncWithContextError(missing),
+	}.BindContext(context.Background(), tempura.WithKnownKeys("env.DATABASE_URL", "env.HOME"))
+
+	tests := []struct {
+		name     string
+		args     []string
+		attempts []tempura.Attempt
+		is       error
+		message  string
+	}{
+		// ==================== INVALID CASES ====================
+		{
+			name: "typo in the key",
+			args: []string{"env.DATABSE_URL"},
+			attempts: []tempura.Attempt{
+				{Arg: "env.DATABSE_URL", Matched: []tempura.Prefix{tempura.DotPrefix("env")}, Suggestion: "env.DATABASE_URL"},
+			},
+			is:      tempura.ErrNotFound,
+			message: "env.DATABSE_URL was not found by [env] (did you mean env.DATABASE_URL?)",
+		},
+		{
+			name: "typo in the prefix",
+			args: []string{"secrte.API_KEY"},
+			attempts: []tempura.Attempt{
+				{Arg: "secrte.API_KEY", Suggestion: "secret.API_KEY"},
+			},
+			is:      tempura.ErrMatchFailed,
+			message: "secrte.API_KEY matched no prefix (did you mean secret.API_KEY?)",
+		},
+		{
+			name: "matched and unmatched args",
+			args: []string{"vault.X", "secret.API_KEY"},
+			attempts: []tempura.Attempt{
+				{Arg: "vault.X"},
+				{Arg: "secret.API_KEY", Matched: []tempura.Prefix{tempura.DotPrefix("secret")}},
+			},
+			is:      tempura.ErrNotFound,
+			message: "vault.X matched no prefix; secret.API_KEY was not found by [secret]",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := lookup.FuncMapValue(tt.args...)
+			var mfe tempura.MatchFailedError
+			require.True(t, errors.As(err, &mfe))
+			assert.Equal(t, tt.attempts, mfe.Attempts)
+			assert.ErrorIs(t, err, tt.is)
+			assert.ErrorContains(t, err, tt.message)
+
+			_, err = lookup.Resolve(context.Background(), tt.args[len(tt.args)-1])
+			assert.True(t, errors.As(err, &mfe), "Resolve should return MatchFailedError as well")
+		})
+	}
+}
+
+func TestMatchFailedError_WithRedactKeys(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("env"): tempura.Func(func(key string) (string, bool) { return "", false }),
+	}.BindContext(context.Background(), tempura.WithRedactKeys(), tempura.WithKnownKeys("env.CUSTOMER_42"))
+
+	_, err := lookup.FuncMapValue("env.CUSTOMER_43")
+	require.ErrorIs(t, err, tempura.ErrNotFound)
+	assert.NotContains(t, err.Error(), "CUSTOMER_4")
+	assert.ErrorContains(t, err, "sha256:")
+}
+
+func TestKeyNotFoundError(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		switch key {
+		case "FAIL":
+			return "", false, errUnavailable
+		case "ABSENT":
+			return "", false, fmt.Errorf("secret %s does not exist: %w", key, tempura.ErrNotFound) // en: e.g. an adapter of an SDK
+		}
+		return "", false, nil
+	}
+	keyAsValue := func(key string) (string, bool) {
+		return key, true
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"):  tempura.FuncWithContextError(fetchSecret),
+		tempura.DotPrefix("default"): tempura.Func(keyAsValue),
+	}.BindContext(context.Background())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "ErrNotFound from a lookup function falls back",
+			args:     []string{"secret.ABSENT", "default.fallback"},
+			expected: "fallback",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "key genuinely absent",
+			args: []string{"secret.MISSING"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+				var knf tempura.KeyNotFoundError
+				require.True(t, errors.As(err, &knf))
+				assert.Equal(t, tempura.KeyNotFoundError{Prefix: tempura.DotPrefix("secret"), Key: "MISSING"}, knf)
+			},
+		},
+		{
+			name: "ErrNotFound from a lookup function",
+			args: []string{"secret.ABSENT"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+		{
+			name: "backend failed",
+			args: []string{"secret.FAIL"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+				assert.NotErrorIs(t, err, tempura.ErrNotFound)
+				var knf tempura.KeyNotFoundError
+				assert.False(t, errors.As(err, &knf))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestWithOnMissing(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		switch key {
+		case "FAIL":
+			return "", false, errUnavailable
+		case "DB_PASS":
+			return "secret-of-" + key, true, nil
+		}
+		return "", false, nil
+	}
+	var calls [][]string
+	onMissing := func(ctx context.Context, args []string) (any, bool) {
+		calls = append(calls, args)
+		if args[0] == "secret.NO_DEFAULT" {
+			return nil, false
+		}
+		return "placeholder", true
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}.BindContext(context.Background(), tempura.WithOnMissing(onMissing))
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		calls    [][]string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "found",
+			args:     []string{"secret.DB_PASS"},
+			expected: "secret-of-DB_PASS",
+		},
+		{
+			name:     "not found",
+			args:     []string{"secret.MISSING", "secret.MISSING_TOO"},
+			expected: "placeholder",
+			calls:    [][]string{{"secret.MISSING", "secret.MISSING_TOO"}},
+		},
+		{
+			name:     "no prefix matched",
+			args:     []string{"env.HOME"},
+			expected: "placeholder",
+			calls:    [][]string{{"env.HOME"}},
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:  "OnMissing declined",
+			args:  []string{"secret.NO_DEFAULT"},
+			calls: [][]string{{"secret.NO_DEFAULT"}},
+			checkErr: func(t *testing.T, err error) {
+				var mfe tempura.MatchFailedError
+				assert.True(t, errors.As(err, &mfe))
+			},
+		},
+		{
+			name: "provider error is not treated as missing",
+			args: []string{"secret.FAIL"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			val, err := lookup.FuncMapValue(tt.args...)
+			assert.Equal(t, tt.calls, calls)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+
+	val, err := lookup.Resolve(context.Background(), "secret.MISSING")
+	require.NoError(t, err)
+	assert.Equal(t, "placeholder", val, "Resolve should apply OnMissing as well")
+}
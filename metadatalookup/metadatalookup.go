@@ -0,0 +1,205 @@
+// Package metadatalookup は、クラウドのインスタンスメタデータサービス (EC2 IMDSv2, GCE metadata, Azure IMDS) から値を返す探索関数を提供します。
+// VM 上で描画されるテンプレートから、インスタンスの ID、リージョン、タグなどを参照できるようにするためのものです。
+//
+// Package metadatalookup provides lookup functions backed by cloud instance metadata services (EC2 IMDSv2, GCE metadata, Azure IMDS).
+// It lets templates rendered on VMs reference instance identity, region, and tags.
+package metadatalookup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+)
+
+const (
+	DefaultEC2Endpoint   = "http://169.254.169.254"
+	DefaultGCEEndpoint   = "http://metadata.google.internal"
+	DefaultAzureEndpoint = "http://169.254.169.254"
+
+	DefaultTimeout         = 2 * time.Second
+	DefaultMaxResponseSize = 1 << 20
+
+	ec2TokenTTL = 6 * time.Hour
+)
+
+// Config はメタデータサービスへの接続方法を定義します。ゼロ値の場合はそれぞれのデフォルト値が使われます。
+//
+// Config defines how to connect to the metadata service. The defaults are used for zero values.
+type Config struct {
+	// Endpoint はメタデータサービスのベース URL です。主にテストで上書きします。
+	// Endpoint is the base URL of the metadata service, mainly overridden in tests.
+	Endpoint string
+
+	// Client はリクエストに使う HTTP クライアントです。 nil の場合は DefaultTimeout を設定したクライアントが使われます。
+	// Client is the HTTP client used for requests. If nil, a client with DefaultTimeout is used.
+	Client *http.Client
+}
+
+func (c Config) endpoint(fallback string) string {
+	if c.Endpoint != "" {
+		return strings.TrimSuffix(c.Endpoint, "/")
+	}
+	return fallback
+}
+
+func (c Config) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return &http.Client{Timeout: DefaultTimeout}
+}
+
+// EC2 は、prefixを取り除いたキーを /latest/meta-data/ 以下のパスとして IMDSv2 から値を返す探索関数を返します。
+// 例: "instance-id", "placement/region", "tags/instance/Name" (インスタンスメタデータでのタグ公開が必要です)
+//
+// EC2 returns a lookup function that resolves the key, with the prefix removed, as a path under /latest/meta-data/ via IMDSv2.
+// e.g. "instance-id", "placement/region", "tags/instance/Name" (requires tags in instance metadata to be enabled)
+func EC2(cfg Config) tempura.LookupAnyWithContextError {
+	base := cfg.endpoint(DefaultEC2Endpoint)
+	client := cfg.client()
+	token := &ec2Token{endpoint: base, client: client}
+
+	return tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+		tok, err := token.get(ctx)
+		if err != nil {
+			return "", false, err
+		}
+		return get(ctx, client, base+"/latest/meta-data/"+strings.TrimPrefix(key, "/"), map[string]string{
+			"X-aws-ec2-metadata-token": tok,
+		})
+	})
+}
+
+// GCE は、prefixを取り除いたキーを /computeMetadata/v1/ 以下のパスとして値を返す探索関数を返します。
+// 例: "instance/id", "instance/zone", "project/project-id", "instance/attributes/<key>"
+//
+// GCE returns a lookup function that resolves the key, with the prefix removed, as a path under /computeMetadata/v1/.
+// e.g. "instance/id", "instance/zone", "project/project-id", "instance/attributes/<key>"
+func GCE(cfg Config) tempura.LookupAnyWithContextError {
+	base := cfg.endpoint(DefaultGCEEndpoint)
+	client := cfg.client()
+
+	return tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+		return get(ctx, client, base+"/computeMetadata/v1/"+strings.TrimPrefix(key, "/"), map[string]string{
+			"Metadata-Flavor": "Google",
+		})
+	})
+}
+
+// Azure は、prefixを取り除いたキーを /metadata/instance/ 以下のパスとしてテキスト形式で値を返す探索関数を返します。
+// 例: "compute/vmId", "compute/location", "compute/tags"
+//
+// Azure returns a lookup function that resolves the key, with the prefix removed, as a path under /metadata/instance/ in text format.
+// e.g. "compute/vmId", "compute/location", "compute/tags"
+func Azure(cfg Config) tempura.LookupAnyWithContextError {
+	base := cfg.endpoint(DefaultAzureEndpoint)
+	client := cfg.client()
+
+	return tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+		url := base + "/metadata/instance/" + strings.TrimPrefix(key, "/") + "?api-version=2021-02-01&format=text"
+		return get(ctx, client, url, map[string]string{
+			"Metadata": "true",
+		})
+	})
+}
+
+// get はメタデータを取得します。 404 の場合は見つからなかったものとして扱います。
+//
+// get fetches the metadata. 404 is treated as not found.
+func get(ctx context.Context, client *http.Client, url string, header map[string]string) (string, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to request metadata: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, DefaultMaxResponseSize))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return strings.TrimSpace(string(body)), true, nil
+	case http.StatusNotFound:
+		return "", false, nil
+	default:
+		return "", false, StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+}
+
+// ec2Token は IMDSv2 のセッショントークンを有効期限まで使い回します。
+//
+// ec2Token reuses the IMDSv2 session token until it expires.
+type ec2Token struct {
+	endpoint string
+	client   *http.Client
+
+	mu        sync.Mutex
+	value     string
+	expiresAt time.Time
+}
+
+func (t *ec2Token) get(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 期限切れの直前に使われないよう、余裕を持って更新する
+	// en: Refresh with a margin so that the token is not used right before it expires
+	if t.value != "" && time.Now().Add(time.Minute).Before(t.expiresAt) {
+		return t.value, nil
+	}
+
+	url := t.endpoint + "/latest/api/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", strconv.Itoa(int(ec2TokenTTL.Seconds())))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request IMDSv2 token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, DefaultMaxResponseSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read IMDSv2 token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	t.value = strings.TrimSpace(string(body))
+	t.expiresAt = time.Now().Add(ec2TokenTTL)
+	return t.value, nil
+}
+
+// =================================================================================
+// Defined errors that you can handle with errors.Is / errors.As
+// =================================================================================
+
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code from metadata service: %d (%s)", e.StatusCode, e.URL)
+}
@@ -0,0 +1,150 @@
+package metadatalookup_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/metadatalookup"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEC2(t *testing.T) {
+	t.Parallel()
+
+	var tokenRequests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		tokenRequests.Add(1)
+		_, _ = w.Write([]byte("TOKEN"))
+	})
+	mux.HandleFunc("/latest/meta-data/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-aws-ec2-metadata-token") != "TOKEN" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/placement/region":
+			_, _ = w.Write([]byte("ap-northeast-1"))
+		case "/latest/meta-data/tags/instance/Name":
+			_, _ = w.Write([]byte("web-1"))
+		case "/latest/meta-data/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("ec2"):     metadatalookup.EC2(metadatalookup.Config{Endpoint: server.URL}),
+		tempura.DotPrefix("default"): tempura.Func(func(val string) (string, bool) { return val, true }),
+	}.BindContext(context.Background())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "region",
+			args:     []string{"ec2.placement/region"},
+			expected: "ap-northeast-1",
+		},
+		{
+			name:     "tag",
+			args:     []string{"ec2.tags/instance/Name"},
+			expected: "web-1",
+		},
+		{
+			name:     "missing path falls through to the next arg",
+			args:     []string{"ec2.tags/instance/Role", "default.none"},
+			expected: "none",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "server error",
+			args: []string{"ec2.broken"},
+			checkErr: func(t *testing.T, err error) {
+				expected := metadatalookup.StatusError{}
+				assert.ErrorAs(t, err, &expected)
+				assert.Equal(t, http.StatusInternalServerError, expected.StatusCode)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+	assert.Equal(t, int32(1), tokenRequests.Load(), "token should be reused")
+}
+
+func TestGCE(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/computeMetadata/v1/instance/zone" {
+			_, _ = w.Write([]byte("projects/123/zones/asia-northeast1-a\n"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	fn := metadatalookup.GCE(metadatalookup.Config{Endpoint: server.URL})
+
+	val, ok, err := fn(context.Background(), "instance/zone")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "projects/123/zones/asia-northeast1-a", val)
+
+	_, ok, err = fn(context.Background(), "instance/attributes/missing")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAzure(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("format") != "text" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/metadata/instance/compute/location" {
+			_, _ = w.Write([]byte("japaneast"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	fn := metadatalookup.Azure(metadatalookup.Config{Endpoint: server.URL})
+
+	val, ok, err := fn(context.Background(), "compute/location")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "japaneast", val)
+}
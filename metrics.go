@@ -0,0 +1,31 @@
+package tempura
+
+import (
+	"time"
+)
+
+// =================================================================================
+// Metrics of lookups
+// =================================================================================
+
+// Metrics は探索の結果を集計します。 Prometheus による実装は promtempura パッケージにあります。
+// ObserveLookup は探索関数の呼び出しごと、およびキャッシュから解決されるごとに呼び出されます。
+// outcome は found, not_found, error, cache_hit, negative_cache_hit のいずれかで、キャッシュから解決された場合の elapsed は 0 です。
+// キャッシュから解決された引数に一致する prefix がない場合、 prefix は nil です。
+//
+// Metrics aggregates the results of lookups. An implementation with Prometheus is in the promtempura package.
+// ObserveLookup is called for each call of a lookup function and each resolution from a cache.
+// outcome is one of found, not_found, error, cache_hit and negative_cache_hit, and elapsed is 0 for resolutions from a cache.
+// If no prefix matches an arg resolved from a cache, prefix is nil.
+type Metrics interface {
+	ObserveLookup(prefix Prefix, outcome string, elapsed time.Duration)
+}
+
+// WithMetrics は、探索の結果を metrics に記録します。 tempura を通して見えるバックエンドの劣化を監視できるようになります。
+//
+// WithMetrics records the results of lookups to metrics, so that operators can alert on backend degradation observed through tempura.
+func WithMetrics(metrics Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
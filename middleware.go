@@ -0,0 +1,38 @@
+package tempura
+
+import (
+	"context"
+)
+
+// =================================================================================
+// Helpers for middlewares wrapping a LookupFunc
+// =================================================================================
+
+// withContextError は、任意の LookupFunc を LookupAnyWithContextError として呼び出せるように変換します。
+//
+// withContextError converts any LookupFunc so that it can be called as LookupAnyWithContextError.
+func withContextError(typ string, fn LookupFunc) LookupAnyWithContextError {
+	switch fn := fn.(type) {
+	case LookupAny:
+		return func(ctx context.Context, val string) (any, bool, error) {
+			v, ok := fn(val)
+			return v, ok, nil
+		}
+	case LookupAnyWithError:
+		return func(ctx context.Context, val string) (any, bool, error) {
+			return fn(val)
+		}
+	case LookupAnyWithContext:
+		return func(ctx context.Context, val string) (any, bool, error) {
+			v, ok := fn(ctx, val)
+			return v, ok, nil
+		}
+	case LookupAnyWithContextError:
+		return fn
+	default:
+		err := InvalidFunctionError{Type: typ, Func: fn}
+		return func(ctx context.Context, val string) (any, bool, error) {
+			return nil, false, err
+		}
+	}
+}
@@ -2,9 +2,15 @@ package tempura
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // =================================================================================
@@ -19,21 +25,37 @@ type Prefix interface {
 type DotPrefix string
 
 func (p DotPrefix) Match(s string) bool {
-	return strings.HasPrefix(s, fmt.Sprintf("%s.", p))
+	return hasSeparatedPrefix(s, string(p), '.')
 }
 
 func (p DotPrefix) Strip(s string) string {
-	return strings.TrimPrefix(s, fmt.Sprintf("%s.", p))
+	return stripSeparatedPrefix(s, string(p), '.')
 }
 
 type SlashPrefix string
 
 func (p SlashPrefix) Match(s string) bool {
-	return strings.HasPrefix(s, fmt.Sprintf("%s/", p))
+	return hasSeparatedPrefix(s, string(p), '/')
 }
 
 func (p SlashPrefix) Strip(s string) string {
-	return strings.TrimPrefix(s, fmt.Sprintf("%s/", p))
+	return stripSeparatedPrefix(s, string(p), '/')
+}
+
+// hasSeparatedPrefix は strings.HasPrefix(s, prefix+string(sep)) と等価ですが、文字列の連結によるアロケーションを行いません。
+// 探索のたびに全ての引数と prefix の組み合わせで呼び出されるため、ホットパスになります。
+//
+// hasSeparatedPrefix is equivalent to strings.HasPrefix(s, prefix+string(sep)) without allocating the concatenated string.
+// It is on the hot path, being called for every combination of args and prefixes on every lookup.
+func hasSeparatedPrefix(s, prefix string, sep byte) bool {
+	return len(s) > len(prefix) && s[len(prefix)] == sep && strings.HasPrefix(s, prefix)
+}
+
+func stripSeparatedPrefix(s, prefix string, sep byte) string {
+	if !hasSeparatedPrefix(s, prefix, sep) {
+		return s
+	}
+	return s[len(prefix)+1:]
 }
 
 // =================================================================================
@@ -98,29 +120,44 @@ func (fn LookupAnyWithContextError) _isSupportedLookupFunc() {}
 // NOTE: If you want to use a function that takes context.Context, you need to call BindContext(ctx) to generate MultiLookupContext.
 type MultiLookup map[Prefix]LookupFunc
 
+// Validate は登録された全ての探索関数を検査し、見つかった全ての問題を errors.Join でまとめて返します。
+//
+// Validate checks every registered lookup function and returns all the problems found, joined with errors.Join.
 func (m MultiLookup) Validate() error {
 	if len(m) == 0 {
 		return ErrNoFunctionRegistered
 	}
-	for k, v := range m {
+	var errs []error
+	for _, k := range sortedPrefixes(m) {
+		v := m[k]
 		switch v.(type) {
 		case LookupAny, LookupAnyWithError:
-			slog.Debug(
-				fmt.Sprintf("valid function of MultiLookup: %s", k),
-				slog.Any("name", fmt.Sprintf("%s", v)),
-				slog.Any("type", fmt.Sprintf("%T", v)),
-			)
+			slog.Debug("valid function of MultiLookup", slog.Any("prefix", k), slog.String("provider", providerType(v)))
 
 		case LookupAnyWithContext, LookupAnyWithContextError:
 			err := InvalidFunctionError{Type: "MultiLookup", Prefix: k, Func: v}
-			return fmt.Errorf("consider calling BindContext(ctx) to generate MultiLookupContext: %w", err)
+			errs = append(errs, fmt.Errorf("consider calling BindContext(ctx) to generate MultiLookupContext: %w", err))
 
 		default:
-			return InvalidFunctionError{Type: "MultiLookup", Prefix: k, Func: v}
+			errs = append(errs, InvalidFunctionError{Type: "MultiLookup", Prefix: k, Func: v})
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// sortedPrefixes は、エラーの順序が実行ごとに変わらないよう、登録された prefix を文字列表現の順に返します。
+//
+// sortedPrefixes returns the registered prefixes in the order of their string representations, so that the order of errors is stable across runs.
+func sortedPrefixes(m MultiLookup) []Prefix {
+	prefixes := make([]Prefix, 0, len(m))
+	for prefix := range m {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return fmt.Sprintf("%T %v", prefixes[i], prefixes[i]) < fmt.Sprintf("%T %v", prefixes[j], prefixes[j])
+	})
+	return prefixes
 }
 
 func (m MultiLookup) FuncMapValue(args ...string) (any, error) {
@@ -134,21 +171,30 @@ func (m MultiLookup) FuncMapValue(args ...string) (any, error) {
 			matched = true
 
 			suffix := prefix.Strip(arg)
+			enabled := logEnabled(slog.Default(), context.Background(), slog.LevelDebug)
+			var start time.Time
+			if enabled {
+				start = time.Now()
+			}
 			switch fn := fn.(type) {
 			case LookupAny:
-				slog.Debug(fmt.Sprintf("executing LookupAny for %s", arg))
 				val, ok := fn(suffix)
+				if enabled {
+					logLookup(context.Background(), slog.Default(), slog.LevelDebug, prefix, arg, suffix, fn, time.Since(start), lookupResult{ok: ok}, CorrelationIDs{})
+				}
 				if ok {
 					return val, nil
 				}
 
 			case LookupAnyWithError:
-				slog.Debug(fmt.Sprintf("executing LookupAnyWithError for %s", arg))
 				val, ok, err := fn(suffix)
-				if err != nil {
-					return nil, err
+				if enabled {
+					logLookup(context.Background(), slog.Default(), slog.LevelDebug, prefix, arg, suffix, fn, time.Since(start), lookupResult{ok: ok, err: err}, CorrelationIDs{})
 				}
-				if ok {
+				if err != nil && !errors.Is(err, ErrNotFound) {
+					return nil, KeyError{Prefix: prefix, Key: suffix, Provider: providerType(fn), Err: err}
+				}
+				if ok && err == nil {
 					return val, nil
 				}
 
@@ -166,134 +212,690 @@ func (m MultiLookup) FuncMapValue(args ...string) (any, error) {
 	return nil, ErrNotFound
 }
 
-func (m MultiLookup) BindContext(ctx context.Context) *MultiLookupContext {
-	return &MultiLookupContext{
+func (m MultiLookup) BindContext(ctx context.Context, opts ...Option) *MultiLookupContext {
+	mc := &MultiLookupContext{
 		MultiLookup: m,
 		Ctx:         ctx,
+		index:       newPrefixIndex(m),
+		inflight:    &sync.WaitGroup{},
+		readiness:   newReadiness(),
+		stats:       newStats(m),
 	}
+	for _, opt := range opts {
+		opt(&mc.opts)
+	}
+	return mc
 }
 
 // MultiLookupContext は context.Context を受け取る関数を利用できる MultiLookup です。 BindContext(ctx) を呼び出して生成してください。
 //
 // MultiLookupContext is a MultiLookup that can use functions that accept context.Context. Generate it by calling BindContext(ctx).
+//
+// BindContext は登録された prefix を索引付けするため、その後に MultiLookup を変更した場合は再度 BindContext を呼び出してください。
+// NOTE: BindContext indexes the registered prefixes, so call BindContext again if you modify MultiLookup afterwards.
 type MultiLookupContext struct {
 	MultiLookup MultiLookup
 	Ctx         context.Context
+
+	opts      options
+	index     *prefixIndex
+	inflight  *sync.WaitGroup
+	readiness *readiness
+	stats     *stats
+}
+
+// WaitClose は、 FuncMapValue から返った後も実行中の非同期の探索関数が全て終了するまで待ちます。
+// 長時間動作するサービスで、 MultiLookupContext を破棄する前に呼び出すことで goroutine が溜まり続けることを防げます。
+//
+// WaitClose waits until all asynchronous lookup functions still running after FuncMapValue returned have finished.
+// Call it before discarding MultiLookupContext in long-running services so that stragglers do not accumulate.
+func (m *MultiLookupContext) WaitClose() {
+	if m.inflight != nil {
+		m.inflight.Wait()
+	}
 }
 
+// Validate は、 BindContext で生成されたこと、および登録された全ての探索関数を検査し、見つかった全ての問題を errors.Join でまとめて返します。
+//
+// Validate checks that it was generated by BindContext and every registered lookup function, and returns all the problems found, joined with errors.Join.
 func (m *MultiLookupContext) Validate() error {
+	var errs []error
 	if m.Ctx == nil {
-		return fmt.Errorf("consider calling BindContext(ctx): %w", ErrContextUntypedNil)
+		errs = append(errs, fmt.Errorf("consider calling BindContext(ctx): %w", ErrContextUntypedNil))
 	}
 	if len(m.MultiLookup) == 0 {
-		return ErrNoFunctionRegistered
+		return errors.Join(append(errs, ErrNoFunctionRegistered)...)
 	}
-	for prefix, fn := range m.MultiLookup {
+	for _, prefix := range sortedPrefixes(m.MultiLookup) {
+		fn := m.MultiLookup[prefix]
 		switch fn.(type) {
 		case LookupAny, LookupAnyWithError, LookupAnyWithContext, LookupAnyWithContextError:
-			slog.Debug(
-				fmt.Sprintf("valid function of MultiLookupContext: %s", prefix),
-				slog.Any("name", fmt.Sprintf("%s", fn)),
-				slog.Any("type", fmt.Sprintf("%T", fn)),
+			m.logger().LogAttrs(context.Background(), m.logLevel(), "valid function of MultiLookupContext",
+				slog.Any("prefix", prefix),
+				slog.String("provider", providerType(fn)),
 			)
 		default:
-			return InvalidFunctionError{Type: "MultiLookupContext", Prefix: prefix, Func: fn}
+			errs = append(errs, InvalidFunctionError{Type: "MultiLookupContext", Prefix: prefix, Func: fn})
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// lookupResult は1つの引数に対する探索の結果です。
+//
+// lookupResult is the result of looking up a single arg.
+type lookupResult struct {
+	val     any
+	ok      bool
+	err     error
+	matched bool
+	cached  bool
+	// degraded は、 BestEffort の探索関数のエラーが見つからなかったことに変換されたことを表します。一時的な失敗を見つからなかったこととしてキャッシュしないために使います。
+	// degraded reports that an error of a BestEffort lookup function was turned into a miss. It is used to avoid caching transient failures as misses.
+	degraded bool
 }
 
 func (m *MultiLookupContext) FuncMapValue(args ...string) (any, error) {
+	if m.opts.dryRun != nil {
+		return m.dryRun(args)
+	}
+	mc := m
+	var end SpanEnd
+	if m.opts.tracer != nil {
+		var ctx context.Context
+		ctx, end = m.opts.tracer.StartLookup(m.Ctx, m.keyNames(args))
+		mc = m.withContext(ctx)
+	}
+	val, err := mc.funcMapValue(args)
+	if err != nil {
+		val, err = mc.settleError(mc.Ctx, args, err)
+	}
+	if end != nil {
+		end(outcomeOf(lookupResult{ok: err == nil, err: notFoundAsNil(err)}), notFoundAsNil(err))
+	}
+	return val, err
+}
 
-	type result struct {
-		val any
-		ok  bool
-		err error
+// settleError は、 FuncMapValue または Resolve が返そうとしているエラーに WithOnMissing 、 WithErrorTransform 、 WithLenient 、 WithOnError を順に適用します。
+//
+// settleError applies WithOnMissing, WithErrorTransform, WithLenient and WithOnError in order to the error FuncMapValue or Resolve is about to return.
+func (m *MultiLookupContext) settleError(ctx context.Context, args []string, err error) (any, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var val any
+	if err == ErrMatchFailed || err == ErrNotFound {
+		if val, err = m.missing(ctx, args); err == nil {
+			return val, nil
+		}
+	}
+	if m.opts.errorTransform != nil {
+		if val, err = m.opts.errorTransform(ctx, args, err); err == nil {
+			return val, nil
+		}
+	}
+	if m.opts.lenient {
+		return Unresolved{Args: args, Err: err, redact: m.opts.redactor}, nil
 	}
-	results := make([]chan result, 0, len(args))
-	for range args {
-		results = append(results, make(chan result, 1))
+	if len(m.opts.onError) > 0 {
+		m.reportError(ctx, args, err)
 	}
+	return nil, err
+}
 
-	ctx, cancel := context.WithCancel(m.Ctx)
-	defer cancel()
+func (m *MultiLookupContext) funcMapValue(args []string) (any, error) {
+	if m.opts.strict != nil {
+		return m.lookupStrict(m.Ctx, args)
+	}
 
-	// 非同期処理の発火または同期処理実行
-	// en: Fire asynchronous processing or execute synchronous processing
+	// 先頭から、同期的な探索関数だけに一致する引数は goroutine やチャネルを使わずにその場で解決する
+	// en: Resolve leading args that match only synchronous lookup functions in place, without goroutines or channels
 	matched := false
+	for len(args) > 0 {
+		res, inline := m.lookupInline(args[0])
+		if !inline {
+			break
+		}
+		matched = matched || res.matched
+		if res.err != nil {
+			return nil, res.err
+		}
+		if res.ok {
+			return res.val, nil
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		if !matched {
+			return nil, ErrMatchFailed
+		}
+		return nil, ErrNotFound
+	}
+
+	return m.lookupAsync(args, matched)
+}
+
+// lookupTask は、1つの引数とそれに一致した1つの探索関数の組です。
+//
+// lookupTask is a pair of an arg and one lookup function matching it.
+type lookupTask struct {
+	arg    int
+	prefix Prefix
+	fn     LookupFunc
+	res    lookupResult
+	done   bool
+}
+
+type taskDone struct {
+	index int
+	res   lookupResult
+}
+
+// lookupBatch は FuncMapValue の呼び出しごとに必要なバッファで、 sync.Pool で再利用されます。
+// 呼び出しから返った後も動き続ける goroutine は taskDone をチャネルに送るだけで、 lookupBatch には触れません。
+//
+// lookupBatch is the buffer needed for each FuncMapValue call, reused via sync.Pool.
+// Goroutines still running after the call returns only send taskDone to the channel and never touch lookupBatch.
+type lookupBatch struct {
+	tasks []lookupTask
+	regs  []registration
+}
+
+var lookupBatchPool = sync.Pool{
+	New: func() any {
+		return &lookupBatch{}
+	},
+}
+
+// lookupAsync は、 args のそれぞれに一致する探索関数を、 context.Context を受け取るものは並行に実行し、引数の順に結果を確定させます。
+//
+// lookupAsync runs the lookup functions matching each of args, those accepting context.Context concurrently, and settles the results in the order of args.
+func (m *MultiLookupContext) lookupAsync(args []string, matched bool) (any, error) {
+	batch := lookupBatchPool.Get().(*lookupBatch)
+	defer func() {
+		clear(batch.tasks)
+		clear(batch.regs)
+		batch.tasks, batch.regs = batch.tasks[:0], batch.regs[:0]
+		lookupBatchPool.Put(batch)
+	}()
+
+	// 返る時点で実行中の探索関数はキャンセルし、 AsyncWaitForAll の場合は終了を待つ
+	// en: Cancel lookup functions still running on return, and wait for them to finish with AsyncWaitForAll
+	ctx, cancel := context.WithCancel(m.Ctx)
+	g := &errgroup.Group{}
+	defer func() {
+		cancel()
+		if m.opts.asyncPolicy == AsyncWaitForAll {
+			_ = g.Wait() // en: tasks report their results through the channel, never through the group
+		}
+	}()
+
+	// 実行する探索関数を引数の順に洗い出す
+	// en: List the lookup functions to run in the order of args
+	numAsync := 0
 	for index, arg := range args {
-		promise := results[index]
+		if val, ok := m.cacheGet(ctx, arg); ok {
+			m.cacheHit(ctx, arg, outcomeCacheHit)
+			matched = true
+			batch.tasks = append(batch.tasks, lookupTask{arg: index, res: lookupResult{val: val, ok: true, matched: true, cached: true}, done: true})
+			continue
+		}
+		if m.cacheMissed(arg) {
+			m.cacheHit(ctx, arg, outcomeNegativeCacheHit)
+			matched = true
+			batch.tasks = append(batch.tasks, lookupTask{arg: index, res: lookupResult{matched: true, cached: true}, done: true})
+			continue
+		}
 
-		for prefix, fn := range m.MultiLookup {
-			if !prefix.Match(arg) {
-				continue
-			}
+		batch.regs = m.matchInto(arg, batch.regs[:0])
+		for _, reg := range batch.regs {
 			matched = true
-			suffix := prefix.Strip(arg)
+			batch.tasks = append(batch.tasks, lookupTask{arg: index, prefix: reg.prefix, fn: reg.fn})
+			switch reg.fn.(type) {
+			case LookupAnyWithContext, LookupAnyWithContextError:
+				numAsync++
+			}
+		}
+	}
+	if !matched {
+		return nil, ErrMatchFailed
+	}
 
-			switch fn := fn.(type) {
-			case LookupAny:
-				slog.DebugContext(ctx, fmt.Sprintf("executing LookupAny for %s", arg))
-				val, ok := fn(suffix)
-				promise <- result{val: val, ok: ok, err: nil}
-				close(promise)
+	// 非同期処理の発火または同期処理実行
+	// en: Fire asynchronous processing or execute synchronous processing
+	ch := make(chan taskDone, numAsync) // en: buffered so that abandoned goroutines never block
+	for taskIndex := range batch.tasks {
+		task := &batch.tasks[taskIndex]
+		if task.done {
+			continue
+		}
+		arg := args[task.arg]
 
-			case LookupAnyWithError:
-				slog.DebugContext(ctx, fmt.Sprintf("executing LookupAnyWithError for %s", arg))
-				val, ok, err := fn(suffix)
-				promise <- result{val: val, ok: ok, err: err}
-				close(promise)
-
-			case LookupAnyWithContext:
-				slog.DebugContext(ctx, fmt.Sprintf("executing LookupAnyWithContext for %s", arg))
-				go func() {
-					val, ok := fn(ctx, suffix)
-					promise <- result{val: val, ok: ok, err: nil}
-					close(promise)
-				}()
-
-			case LookupAnyWithContextError:
-				slog.DebugContext(ctx, fmt.Sprintf("executing LookupAnyWithContextError for %s", arg))
-				go func() {
-					val, ok, err := fn(ctx, suffix)
-					promise <- result{val: val, ok: ok, err: err}
-					close(promise)
-				}()
+		switch task.fn.(type) {
+		case LookupAnyWithContext, LookupAnyWithContextError:
+			taskIndex, prefix, fn := taskIndex, task.prefix, task.fn
+			m.track()
+			g.Go(func() error {
+				defer m.untrack()
+				release, err := m.acquire(ctx, prefix)
+				if err != nil {
+					ch <- taskDone{index: taskIndex, res: lookupResult{err: err, matched: true}}
+					return nil
+				}
+				defer release()
+				ch <- taskDone{index: taskIndex, res: m.runTask(ctx, prefix, fn, arg)}
+				return nil
+			})
 
-			default:
-				err := InvalidFunctionError{Type: "MultiLookupContext", Prefix: prefix, Func: fn}
-				return nil, fmt.Errorf("unexpected error! it might be a bug: %w", err)
+		default:
+			task.res, task.done = m.runTask(ctx, task.prefix, task.fn, arg), true
+		}
+	}
+
+	// 引数の順、同じ引数の中では一致した探索関数の順に結果を確定させる。最初に確定した値が返ると、残りは defer でキャンセルされる
+	// en: Settle the results in the order of args, and of matching lookup functions within the same arg. Once the first value is settled, the rest are canceled by defer
+	degraded := false
+	for k := range batch.tasks {
+		for !batch.tasks[k].done {
+			d := <-ch
+			batch.tasks[d.index].res, batch.tasks[d.index].done = d.res, true
+		}
+
+		task := batch.tasks[k]
+		if k == 0 || batch.tasks[k-1].arg != task.arg {
+			degraded = false
+		}
+		degraded = degraded || task.res.degraded
+		if task.res.err != nil {
+			return nil, task.res.err
+		}
+		if task.res.ok {
+			if !task.res.cached {
+				m.cacheSet(ctx, args[task.arg], task.res.val)
 			}
+			m.recordProvenance(ctx, args[task.arg], task.prefix, task.fn, task.res.cached)
+			return task.res.val, nil
+		}
+		lastOfArg := k == len(batch.tasks)-1 || batch.tasks[k+1].arg != task.arg
+		if lastOfArg && !task.res.cached && !degraded {
+			m.cacheMiss(args[task.arg])
 		}
+	}
+
+	return nil, ErrNotFound
+}
 
+// runTask は runTaskOnce を実行します。 ctx が描画のキャッシュ (WithRenderCache) を持つ場合は、実行中の同じ prefix と arg の呼び出しの結果を待って共有します。
+//
+// runTask runs runTaskOnce. If ctx carries a render cache (WithRenderCache), it waits for and shares the result of a call in flight for the same prefix and arg.
+func (m *MultiLookupContext) runTask(ctx context.Context, prefix Prefix, fn LookupFunc, arg string) lookupResult {
+	rc, ok := renderCacheOf(ctx)
+	if !ok {
+		return m.runTaskOnce(ctx, prefix, fn, arg)
 	}
-	if !matched {
-		return nil, ErrMatchFailed
+	key := renderFlightKey{prefix: prefix, arg: arg}
+	f, leader := rc.flight(key)
+	if leader {
+		res := m.runTaskOnce(ctx, prefix, fn, arg)
+		rc.land(key, f, res)
+		return res
+	}
+
+	start := time.Now()
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		err := contextError(prefix, time.Since(start), fmt.Errorf("waiting for a concurrent lookup: %w", ctx.Err()))
+		return lookupResult{err: KeyError{Prefix: prefix, Key: prefix.Strip(arg), Provider: providerType(fn), Err: err, redact: m.opts.redactor}, matched: true}
+	}
+	if f.res.err != nil && ctx.Err() == nil && (errors.Is(f.res.err, context.Canceled) || errors.Is(f.res.err, context.DeadlineExceeded)) {
+		return m.runTaskOnce(ctx, prefix, fn, arg) // en: the call was canceled along with the lookup that started it
+	}
+	return f.res
+}
+
+// runTaskOnce は、 arg から prefix を取り除いて fn を1回実行します。探索関数の型による分岐とエラーの扱いはここに集約されます。
+//
+// runTaskOnce runs fn once with prefix stripped from arg. Branching on the type of lookup functions and handling their errors are centralized here.
+func (m *MultiLookupContext) runTaskOnce(ctx context.Context, prefix Prefix, fn LookupFunc, arg string) lookupResult {
+	suffix := prefix.Strip(arg)
+	enabled := m.logEnabled(ctx)
+	start := time.Now()
+	var end SpanEnd
+	if m.opts.tracer != nil {
+		ctx, end = m.opts.tracer.StartProvider(ctx, prefix, m.keyName(suffix), providerType(fn))
+	}
+
+	res := lookupResult{matched: true}
+	if err := m.beforeLookup(ctx, prefix, suffix); err != nil {
+		res.err = err
+	} else {
+		res = m.safeCallLookup(ctx, prefix, fn, suffix)
+	}
+	if res.err != nil && errors.Is(res.err, ErrNotFound) {
+		res = lookupResult{matched: true} // en: absence reported as an error by adapters of SDKs
+	}
+	if res.ok && (m.opts.htmlSafe || m.opts.trusted != nil) {
+		res = m.checkContent(prefix, res)
+	}
+	elapsed := time.Since(start)
+
+	m.stats.record(prefix, res, elapsed)
+	if enabled {
+		logLookup(ctx, m.logger(), m.logLevel(), prefix, m.keyName(arg), m.keyName(suffix), fn, elapsed, res, m.correlationIDs(ctx))
+	}
+	if m.opts.slowLookup > 0 && elapsed > m.opts.slowLookup {
+		m.slowLookup(ctx, prefix, suffix, fn, elapsed)
 	}
+	if m.opts.metrics != nil {
+		m.opts.metrics.ObserveLookup(prefix, outcomeOf(res), elapsed)
+	}
+	if len(m.opts.hooks) > 0 {
+		m.afterLookup(ctx, LookupEvent{Prefix: prefix, Arg: arg, Key: suffix, Provider: providerType(fn), Duration: elapsed, Found: res.ok, Err: res.err, CorrelationIDs: m.correlationIDs(ctx)})
+	}
+	if len(m.opts.audits) > 0 {
+		m.recordAudit(ctx, AuditEvent{Prefix: prefix, Arg: arg, Key: suffix, Provider: providerType(fn), Outcome: outcomeOf(res), Err: res.err})
+	}
+	if end != nil {
+		end(outcomeOf(res), res.err)
+	}
+	if res.err != nil && isBestEffort(res.err) {
+		return lookupResult{matched: true, degraded: true}
+	}
+	if res.err != nil {
+		err := contextError(prefix, elapsed, res.err)
+		res.err = KeyError{Prefix: prefix, Key: suffix, Provider: providerType(fn), Err: err, redact: m.opts.redactor}
+	}
+	return res
+}
+
+// callLookup は、 fn を型に応じて呼び出します。
+//
+// callLookup calls fn according to its type.
+func callLookup(ctx context.Context, prefix Prefix, fn LookupFunc, key string) lookupResult {
+	switch fn := fn.(type) {
+	case LookupAny:
+		val, ok := fn(key)
+		return lookupResult{val: val, ok: ok, matched: true}
+
+	case LookupAnyWithError:
+		val, ok, err := fn(key)
+		return lookupResult{val: val, ok: ok, err: err, matched: true}
+
+	case LookupAnyWithContext:
+		val, ok := fn(ctx, key)
+		return lookupResult{val: val, ok: ok, matched: true}
+
+	case LookupAnyWithContextError:
+		val, ok, err := fn(ctx, key)
+		return lookupResult{val: val, ok: ok, err: err, matched: true}
+
+	default:
+		err := InvalidFunctionError{Type: "MultiLookupContext", Prefix: prefix, Func: fn}
+		return lookupResult{err: fmt.Errorf("unexpected error! it might be a bug: %w", err), matched: true}
+	}
+}
+
+func (m *MultiLookupContext) track() {
+	if m.inflight != nil {
+		m.inflight.Add(1)
+	}
+}
+
+func (m *MultiLookupContext) untrack() {
+	if m.inflight != nil {
+		m.inflight.Done()
+	}
+}
+
+// acquire は WithMaxConcurrency の枠を1つ確保します。枠が空くより先に ctx が終了した場合はエラーを返します。
+//
+// acquire takes a slot of WithMaxConcurrency for the lookup function of prefix. If ctx is done before a slot becomes available, it returns an error.
+func (m *MultiLookupContext) acquire(ctx context.Context, prefix Prefix) (release func(), err error) {
+	if m.opts.semaphore == nil {
+		return func() {}, nil
+	}
+	start := time.Now()
+	select {
+	case m.opts.semaphore <- struct{}{}:
+		return func() { <-m.opts.semaphore }, nil
+	case <-ctx.Done():
+		return nil, contextError(prefix, time.Since(start), fmt.Errorf("waiting for a concurrency slot: %w", ctx.Err()))
+	}
+}
+
+// lookupInline は、 arg がキャッシュにあるか、同期的な探索関数だけに一致する場合に、その場で解決して true を返します。
+// context.Context を受け取る探索関数に一致する場合は、何もせずに false を返します。
+//
+// lookupInline resolves arg in place and returns true if arg is cached or matches only synchronous lookup functions.
+// If arg matches a lookup function that accepts context.Context, it does nothing and returns false.
+func (m *MultiLookupContext) lookupInline(arg string) (lookupResult, bool) {
+	if val, ok := m.cacheGet(m.Ctx, arg); ok {
+		m.cacheHit(m.Ctx, arg, outcomeCacheHit)
+		m.recordProvenance(m.Ctx, arg, nil, nil, true)
+		return lookupResult{val: val, ok: true, matched: true, cached: true}, true
+	}
+	if m.cacheMissed(arg) {
+		m.cacheHit(m.Ctx, arg, outcomeNegativeCacheHit)
+		return lookupResult{matched: true, cached: true}, true
+	}
+
+	var buf [4]registration
+	matches := m.matchInto(arg, buf[:0])
+	for _, reg := range matches {
+		switch reg.fn.(type) {
+		case LookupAny, LookupAnyWithError:
+		default:
+			return lookupResult{}, false
+		}
+	}
+
+	degraded := false
+	for _, reg := range matches {
+		res := m.runTask(m.Ctx, reg.prefix, reg.fn, arg)
+		if res.err != nil {
+			return res, true
+		}
+		if res.ok {
+			m.cacheSet(m.Ctx, arg, res.val)
+			m.recordProvenance(m.Ctx, arg, reg.prefix, reg.fn, false)
+			return res, true
+		}
+		degraded = degraded || res.degraded
+	}
+
+	if len(matches) > 0 && !degraded {
+		m.cacheMiss(arg)
+	}
+	return lookupResult{matched: len(matches) > 0}, true
+}
+
+// match は arg に一致する登録を返します。 BindContext を経由せずに生成された場合は、全ての prefix を1つずつ照合します。
+//
+// match returns the registrations matching arg. If generated without BindContext, it matches every prefix one by one.
+func (m *MultiLookupContext) match(arg string) []registration {
+	return m.matchInto(arg, nil)
+}
+
+func (m *MultiLookupContext) matchInto(arg string, dst []registration) []registration {
+	if m.index != nil {
+		return m.index.match(arg, dst)
+	}
+
+	for prefix, fn := range m.MultiLookup {
+		if prefix.Match(arg) {
+			dst = append(dst, registration{prefix: prefix, fn: fn})
+		}
+	}
+	return dst
+}
+
+// cacheGet は、描画ごとのキャッシュ、 WithCache で登録されたキャッシュの順に値を探します。
+//
+// cacheGet looks for the value in the per-render cache, then in the cache registered via WithCache.
+func (m *MultiLookupContext) cacheGet(ctx context.Context, arg string) (any, bool) {
+	renderCache, hasRenderCache := renderCacheFrom(ctx)
+	if hasRenderCache {
+		if val, ok := renderCache.Get(arg); ok {
+			return val, true
+		}
+	}
+	if sc, ok := m.opts.cache.(StaleCache); ok {
+		val, revalidate, ok := sc.GetStale(arg)
+		if !ok {
+			return nil, false
+		}
+		if revalidate {
+			m.revalidate(ctx, arg)
+		}
+		if hasRenderCache {
+			renderCache.Set(arg, val)
+		}
+		return val, true
+	}
+	if m.opts.cache != nil {
+		if val, ok := m.opts.cache.Get(arg); ok {
+			if hasRenderCache {
+				renderCache.Set(arg, val)
+			}
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// revalidate は、 StaleCache が期限切れの値を返した arg をバックグラウンドで再取得し、キャッシュを更新します。
+// 描画が終わった後も再取得を続けられるよう、 context.Context のキャンセルは引き継ぎません。
+//
+// revalidate refreshes arg, for which StaleCache returned an expired value, in the background and updates the cache.
+// It does not inherit the cancellation of context.Context, so that the refresh can continue after the render finishes.
+func (m *MultiLookupContext) revalidate(ctx context.Context, arg string) {
+	if ctx == nil {
+		ctx = context.Background() // en: MultiLookupContext may be generated without BindContext
+	}
+	ctx = context.WithoutCancel(ctx)
+	regs := m.match(arg)
+
+	m.track()
+	go func() {
+		defer m.untrack()
+		release, err := m.acquire(ctx, nil)
+		if err != nil {
+			return
+		}
+		defer release()
 
-	for _, promise := range results {
-		select {
-		case res := <-promise:
+		for _, reg := range regs {
+			res := m.runTaskOnce(ctx, reg.prefix, reg.fn, arg)
 			if res.err != nil {
-				return nil, res.err
+				m.logger().LogAttrs(ctx, slog.LevelWarn, "failed to revalidate", slog.String("arg", m.keyName(arg)), slog.Any("error", res.err))
+				return
 			}
 			if res.ok {
-				return res.val, nil
+				m.cacheStore(ctx, arg, res.val)
+				return
+			}
+			if res.degraded {
+				return // en: keep serving the stale value rather than dropping it on a transient failure
 			}
 		}
+		m.opts.cache.Invalidate(arg) // en: not found anymore
+	}()
+}
+
+func (m *MultiLookupContext) cacheSet(ctx context.Context, arg string, val any) {
+	if renderCache, ok := renderCacheFrom(ctx); ok {
+		renderCache.Set(arg, val)
 	}
+	if m.opts.cache != nil {
+		m.cacheStore(ctx, arg, val)
+	}
+}
 
-	return nil, ErrNotFound
+// errorCache は、 DiskCache のように書き込みに失敗しうるキャッシュです。失敗は WithLogger のロガーに出力されます。
+//
+// errorCache is a cache whose writes may fail, such as DiskCache. Failures are logged with the logger of WithLogger.
+type errorCache interface {
+	setErr(key string, val any) error
+}
+
+// cacheStore は WithCache で登録されたキャッシュに val を書き込みます。
+//
+// cacheStore writes val to the cache registered via WithCache.
+func (m *MultiLookupContext) cacheStore(ctx context.Context, arg string, val any) {
+	ec, ok := m.opts.cache.(errorCache)
+	if !ok {
+		m.opts.cache.Set(arg, val)
+		return
+	}
+	if err := ec.setErr(arg, val); err != nil {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		m.logger().LogAttrs(ctx, slog.LevelWarn, "failed to write to the cache", slog.String("arg", m.keyName(arg)), slog.Any("error", err))
+	}
+}
+
+// cacheMissed は、 WithCache で登録されたキャッシュが NegativeCache であれば、見つからなかったことが記録されているかを返します。
+//
+// cacheMissed reports whether a miss is recorded, if the cache registered via WithCache is a NegativeCache.
+func (m *MultiLookupContext) cacheMissed(arg string) bool {
+	if nc, ok := m.opts.cache.(NegativeCache); ok {
+		return nc.GetMiss(arg)
+	}
+	return false
+}
+
+func (m *MultiLookupContext) cacheMiss(arg string) {
+	if nc, ok := m.opts.cache.(NegativeCache); ok {
+		nc.SetMiss(arg)
+	}
+}
+
+// logger は WithLogger で指定されたロガーを返します。指定されていない場合は slog.Default() です。
+//
+// logger returns the logger specified via WithLogger, or slog.Default() if not specified.
+func (m *MultiLookupContext) logger() *slog.Logger {
+	if m.opts.logger != nil {
+		return m.opts.logger
+	}
+	return slog.Default()
+}
+
+// logLevel は WithLogLevel で指定されたレベルを返します。指定されていない場合は slog.LevelDebug です。
+//
+// logLevel returns the level specified via WithLogLevel, or slog.LevelDebug if not specified.
+func (m *MultiLookupContext) logLevel() slog.Level {
+	if m.opts.logLevel != nil {
+		return m.opts.logLevel.Level()
+	}
+	return slog.LevelDebug
+}
+
+func (m *MultiLookupContext) logEnabled(ctx context.Context) bool {
+	return logEnabled(m.logger(), ctx, m.logLevel())
 }
 
 // =================================================================================
 // Defined errors that you can handle with errors.Is / errors.As
 // =================================================================================
 
+// tempura がエラーを包む箇所 (KeyError 、 TimeoutError 、 Preload 、 RenderAll 、ミドルウェアなど) では、常に %w または Unwrap を使います。
+// そのため、探索関数が返した SDK のエラーは、 errors.Is / errors.As で常に取り出すことができ、スロットリングや権限エラーによって処理を分けられます。
+//
+// Wherever tempura wraps errors, such as KeyError, TimeoutError, Preload, RenderAll and middlewares, it always uses %w or Unwrap.
+// So SDK errors returned by lookup functions always remain reachable via errors.Is / errors.As, letting callers branch on throttling or permission errors.
+
 var ErrNoFunctionRegistered = fmt.Errorf("no function registered")
 var ErrContextUntypedNil = fmt.Errorf("context.Context is untyped nil")
 var ErrMatchFailed = fmt.Errorf("failed to match between args and prefixes")
+
+// ErrNotFound は、キーが存在しないことを表します。バックエンドの障害を表す探索関数のエラーとは区別されます。
+// 探索関数は、値がないことをこのエラー (または KeyNotFoundError) を返すことで伝えることもできます。
+//
+// ErrNotFound reports that a key is absent, as distinguished from errors of lookup functions reporting backend failures.
+// Lookup functions may also report the absence of a value by returning this error or a KeyNotFoundError.
 var ErrNotFound = fmt.Errorf("not found: none of the lookup functions returned true as the second return value")
 
 type InvalidFunctionError struct {
@@ -305,3 +907,82 @@ type InvalidFunctionError struct {
 func (e InvalidFunctionError) Error() string {
 	return fmt.Sprintf("invalid function of %s: %+v with type %T", e.Type, e.Prefix, e.Func)
 }
+
+// KeyError は、探索関数が返したエラーを、それを返した prefix 、 prefix を取り除いたキー、探索関数の型と共に表します。
+// 探索関数が返したエラーは全て KeyError に包まれるため、文字列を解析することなく errors.As で対処に必要な情報が得られます。
+//
+// KeyError represents an error returned by a lookup function, along with the prefix, the key with the prefix removed and the type of the lookup function.
+// Every error returned by lookup functions is wrapped in a KeyError, so errors.As gives enough context to produce actionable messages without parsing strings.
+type KeyError struct {
+	Prefix   Prefix
+	Key      string
+	Provider string
+	Err      error
+
+	redact *keyRedactor
+}
+
+func (e KeyError) Error() string {
+	return fmt.Sprintf("failed to look up %s by %v (%s): %v", e.redact.key(e.Key), e.Prefix, e.Provider, e.Err)
+}
+
+func (e KeyError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError は、 context.Context の期限切れによって失敗した探索を、その prefix と経過時間と共に表します。
+// 上流の再試行の処理が、バックエンドの本当の障害と区別できるようにするためのものです。 errors.Is で context.DeadlineExceeded に一致します。
+//
+// TimeoutError represents a lookup that failed due to the deadline of context.Context, along with its prefix and the elapsed time.
+// It lets retry logic upstream distinguish it from real backend failures. It matches context.DeadlineExceeded with errors.Is.
+type TimeoutError struct {
+	Prefix  Prefix
+	Elapsed time.Duration
+	Err     error
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s: %v", e.Elapsed, e.Err)
+}
+
+func (e TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout は net.Error などと同様に、タイムアウトであることを表します。
+//
+// Timeout reports that it is a timeout, as net.Error and others do.
+func (e TimeoutError) Timeout() bool {
+	return true
+}
+
+// CanceledError は、 context.Context のキャンセルによって失敗した探索を、その prefix と経過時間と共に表します。 errors.Is で context.Canceled に一致します。
+//
+// CanceledError represents a lookup that failed due to the cancellation of context.Context, along with its prefix and the elapsed time. It matches context.Canceled with errors.Is.
+type CanceledError struct {
+	Prefix  Prefix
+	Elapsed time.Duration
+	Err     error
+}
+
+func (e CanceledError) Error() string {
+	return fmt.Sprintf("canceled after %s: %v", e.Elapsed, e.Err)
+}
+
+func (e CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// contextError は、 err が context.Context の終了によるものであれば TimeoutError または CanceledError で包みます。
+//
+// contextError wraps err in a TimeoutError or CanceledError if it is caused by the end of context.Context.
+func contextError(prefix Prefix, elapsed time.Duration, err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return TimeoutError{Prefix: prefix, Elapsed: elapsed, Err: err}
+	case errors.Is(err, context.Canceled):
+		return CanceledError{Prefix: prefix, Elapsed: elapsed, Err: err}
+	default:
+		return err
+	}
+}
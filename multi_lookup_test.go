@@ -2,14 +2,58 @@ package tempura_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/ebi-yade/go-tempura"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+func TestPrefix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		prefix   tempura.Prefix
+		arg      string
+		match    bool
+		stripped string
+	}{
+		{name: "dot", prefix: tempura.DotPrefix("env"), arg: "env.HOME", match: true, stripped: "HOME"},
+		{name: "dot with empty key", prefix: tempura.DotPrefix("env"), arg: "env.", match: true, stripped: ""},
+		{name: "dot with nested key", prefix: tempura.DotPrefix("env"), arg: "env.a.b", match: true, stripped: "a.b"},
+		{name: "dot without separator", prefix: tempura.DotPrefix("env"), arg: "env", match: false, stripped: "env"},
+		{name: "dot with longer name", prefix: tempura.DotPrefix("env"), arg: "environ.HOME", match: false, stripped: "environ.HOME"},
+		{name: "dot with other separator", prefix: tempura.DotPrefix("env"), arg: "env/HOME", match: false, stripped: "env/HOME"},
+		{name: "slash", prefix: tempura.SlashPrefix("env"), arg: "env/HOME", match: true, stripped: "HOME"},
+		{name: "slash with other separator", prefix: tempura.SlashPrefix("env"), arg: "env.HOME", match: false, stripped: "env.HOME"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, tt.prefix.Match(tt.arg))
+			assert.Equal(t, tt.stripped, tt.prefix.Strip(tt.arg))
+		})
+	}
+}
+
+func TestPrefix_Allocs(t *testing.T) {
+	prefixes := []tempura.Prefix{tempura.DotPrefix("env"), tempura.SlashPrefix("env")}
+	for _, prefix := range prefixes {
+		allocs := testing.AllocsPerRun(100, func() {
+			if prefix.Match("env.HOME") || prefix.Match("env/HOME") {
+				_ = prefix.Strip("env.HOME")
+				_ = prefix.Strip("env/HOME")
+			}
+		})
+		assert.Zero(t, allocs, "%T should not allocate", prefix)
+	}
+}
+
 func TestMultiLookup_Validate(t *testing.T) {
 	t.Parallel()
 
@@ -71,6 +115,27 @@ func TestMultiLookup_Validate(t *testing.T) {
 				assert.Equal(t, tempura.DotPrefix("secret"), expected.Prefix, "Prefix mismatch")
 			},
 		},
+		{
+			name: "reports every invalid function",
+			receiver: &tempura.MultiLookup{
+				tempura.DotPrefix("env"):    tempura.Func(os.LookupEnv),
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+				tempura.DotPrefix("vault"):  tempura.FuncWithContextError(fetchSecret),
+				tempura.DotPrefix("raw"):    nil,
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.Error(t, err)
+				joined, ok := err.(interface{ Unwrap() []error })
+				require.True(t, ok, "errors should be joined")
+				var prefixes []tempura.Prefix
+				for _, err := range joined.Unwrap() {
+					expected := tempura.InvalidFunctionError{}
+					require.ErrorAs(t, err, &expected)
+					prefixes = append(prefixes, expected.Prefix)
+				}
+				assert.Equal(t, []tempura.Prefix{tempura.DotPrefix("raw"), tempura.DotPrefix("secret"), tempura.DotPrefix("vault")}, prefixes, "sorted by prefix")
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -143,6 +208,22 @@ func TestMultiLookupContext_Validate(t *testing.T) {
 				assert.ErrorIs(t, err, tempura.ErrContextUntypedNil)
 			},
 		},
+		{
+			name: "reports every problem",
+			receiver: &tempura.MultiLookupContext{
+				MultiLookup: tempura.MultiLookup{
+					tempura.DotPrefix("env"):    tempura.Func(os.LookupEnv),
+					tempura.DotPrefix("raw"):    nil,
+					tempura.DotPrefix("rawer"):  nil,
+					tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+				},
+			},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrContextUntypedNil)
+				assert.ErrorContains(t, err, "invalid function of MultiLookupContext: raw ")
+				assert.ErrorContains(t, err, "invalid function of MultiLookupContext: rawer ")
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -152,3 +233,320 @@ func TestMultiLookupContext_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestMultiLookupContext_FuncMapValue(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		return "", false, nil
+	}
+	keyAsValue := func(val string) (string, bool) {
+		return val, true
+	}
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"):  tempura.FuncWithContextError(fetchSecret),
+		tempura.DotPrefix("default"): tempura.Func(keyAsValue),
+	}.BindContext(context.TODO())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "unmatched arg is skipped",
+			args:     []string{"typo.KEY", "default.fallback"},
+			expected: "fallback",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "unmatched arg after a miss",
+			args: []string{"secret.KEY", "typo.KEY"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+		{
+			name: "no arg matched",
+			args: []string{"typo.KEY"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrMatchFailed)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestMultiLookupContext_FuncMapValue_Async(t *testing.T) {
+	t.Parallel()
+
+	delayed := func(delay time.Duration, val string, ok bool) tempura.LookupAnyWithContextError {
+		return tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+			select {
+			case <-time.After(delay):
+				return val, ok, nil
+			case <-ctx.Done():
+				return "", false, ctx.Err()
+			}
+		})
+	}
+	failing := tempura.FuncWithContextError(func(ctx context.Context, key string) (string, bool, error) {
+		return "", false, fmt.Errorf("backend is down")
+	})
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("slow"):      delayed(50*time.Millisecond, "slow", true),
+		tempura.DotPrefix("fast"):      delayed(0, "fast", true),
+		tempura.DotPrefix("miss"):      delayed(0, "", false),
+		tempura.DotPrefix("miss.deep"): delayed(10*time.Millisecond, "deep", true),
+		tempura.DotPrefix("fail"):      failing,
+		tempura.DotPrefix("default"):   tempura.Func(func(val string) (string, bool) { return val, true }),
+	}.BindContext(context.TODO())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "earlier arg wins even if slower", args: []string{"slow.KEY", "fast.KEY"}, expected: "slow"},
+		{name: "miss falls through to the next arg", args: []string{"miss.KEY", "fast.KEY"}, expected: "fast"},
+		{name: "synchronous arg after asynchronous ones", args: []string{"miss.KEY", "default.fallback"}, expected: "fallback"},
+		{name: "nested prefixes matching the same arg", args: []string{"miss.deep.KEY"}, expected: "deep"},
+		// ==================== INVALID CASES ====================
+		{
+			name: "error of an earlier arg",
+			args: []string{"fail.KEY", "fast.KEY"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "backend is down")
+			},
+		},
+		{
+			name: "all missed",
+			args: []string{"miss.A", "miss.B"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestFuncMapValue_Allocs(t *testing.T) {
+	var home any = "/home/tempura" // en: boxed in advance so that the lookup function itself does not allocate
+	lookupHome := tempura.LookupAny(func(key string) (any, bool) {
+		return home, key == "HOME"
+	})
+	args := []string{"env.HOME"}
+
+	sync := tempura.MultiLookup{
+		tempura.DotPrefix("env"): lookupHome,
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = sync.FuncMapValue(args...)
+	})
+	assert.Zero(t, allocs, "MultiLookup should not allocate")
+
+	withContext := sync.BindContext(context.TODO())
+	allocs = testing.AllocsPerRun(100, func() {
+		_, _ = withContext.FuncMapValue(args...)
+	})
+	assert.Zero(t, allocs, "MultiLookupContext should not allocate for synchronous functions")
+}
+
+func BenchmarkMultiLookupContext_FuncMapValue_Async(b *testing.B) {
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		return "XXXXXXXX", key == "C", nil
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}.BindContext(context.Background())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = lookup.FuncMapValue("secret.A", "secret.B", "secret.C")
+	}
+}
+
+func TestKeyError(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		return "", false, errUnavailable
+	}
+	parseEnv := func(key string) (int, bool, error) {
+		return 0, false, fmt.Errorf("not a number")
+	}
+
+	tests := []struct {
+		name     string
+		opts     []tempura.Option
+		args     []string
+		expected tempura.KeyError
+		message  string
+	}{
+		// ==================== INVALID CASES ====================
+		{
+			name:     "asynchronous lookup function",
+			args:     []string{"secret.DB_PASS"},
+			expected: tempura.KeyError{Prefix: tempura.DotPrefix("secret"), Key: "DB_PASS", Provider: "LookupAnyWithContextError", Err: errUnavailable},
+			message:  "failed to look up DB_PASS by secret (LookupAnyWithContextError): service unavailable",
+		},
+		{
+			name:     "synchronous lookup function",
+			args:     []string{"num.PORT"},
+			expected: tempura.KeyError{Prefix: tempura.DotPrefix("num"), Key: "PORT", Provider: "LookupAnyWithError", Err: fmt.Errorf("not a number")},
+			message:  "failed to look up PORT by num (LookupAnyWithError): not a number",
+		},
+		{
+			name:     "key name redacted",
+			opts:     []tempura.Option{tempura.WithRedactKeys()},
+			args:     []string{"secret.DB_PASS"},
+			expected: tempura.KeyError{Prefix: tempura.DotPrefix("secret"), Key: "DB_PASS", Provider: "LookupAnyWithContextError", Err: errUnavailable},
+			message:  "failed to look up sha256:",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+				tempura.DotPrefix("num"):    tempura.FuncWithError(parseEnv),
+			}.BindContext(context.Background(), tt.opts...)
+
+			_, err := lookup.FuncMapValue(tt.args...)
+			var ke tempura.KeyError
+			if assert.True(t, errors.As(err, &ke)) {
+				assert.Equal(t, tt.expected.Prefix, ke.Prefix)
+				assert.Equal(t, tt.expected.Key, ke.Key)
+				assert.Equal(t, tt.expected.Provider, ke.Provider)
+				assert.EqualError(t, ke.Err, tt.expected.Err.Error())
+			}
+			assert.ErrorContains(t, err, tt.message)
+		})
+	}
+}
+
+func TestTimeoutError_CanceledError(t *testing.T) {
+	t.Parallel()
+
+	waitDone := func(ctx context.Context, key string) (string, bool, error) {
+		<-ctx.Done()
+		return "", false, fmt.Errorf("failed to fetch %s: %w", key, ctx.Err())
+	}
+
+	tests := []struct {
+		name     string
+		ctx      func() (context.Context, context.CancelFunc)
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== INVALID CASES ====================
+		{
+			name: "deadline exceeded",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+				var te tempura.TimeoutError
+				if assert.True(t, errors.As(err, &te)) {
+					assert.Equal(t, tempura.DotPrefix("secret"), te.Prefix)
+					assert.GreaterOrEqual(t, te.Elapsed, 10*time.Millisecond) // en: the timer starts before the lookup
+					assert.True(t, te.Timeout())
+				}
+				var ce tempura.CanceledError
+				assert.False(t, errors.As(err, &ce))
+			},
+		},
+		{
+			name: "canceled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, context.Canceled)
+				var ce tempura.CanceledError
+				if assert.True(t, errors.As(err, &ce)) {
+					assert.Equal(t, tempura.DotPrefix("secret"), ce.Prefix)
+					assert.GreaterOrEqual(t, ce.Elapsed, 10*time.Millisecond) // en: the timer starts before the lookup
+				}
+				var te tempura.TimeoutError
+				assert.False(t, errors.As(err, &te))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(waitDone),
+			}.BindContext(ctx)
+
+			_, err := lookup.FuncMapValue("secret.DB_PASS")
+			tt.checkErr(t, err)
+		})
+	}
+}
+
+func TestTimeoutError_WaitingForConcurrencySlot(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	defer close(release)
+	block := func(ctx context.Context, key string) (string, bool, error) {
+		<-release
+		return key, true, nil
+	}
+	limit := tempura.WithMaxConcurrency(1)
+	m := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(block),
+	}
+	go func() { _, _ = m.BindContext(context.Background(), limit).FuncMapValue("secret.A") }()
+	time.Sleep(10 * time.Millisecond) // en: let the first lookup take the only slot
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := m.BindContext(ctx, limit).Resolve(ctx, "secret.B")
+	var te tempura.TimeoutError
+	if assert.True(t, errors.As(err, &te)) {
+		assert.Equal(t, tempura.DotPrefix("secret"), te.Prefix)
+	}
+	assert.ErrorContains(t, err, "waiting for a concurrency slot")
+}
@@ -0,0 +1,91 @@
+package tempura
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// =================================================================================
+// Rendering in multiple passes
+// =================================================================================
+
+// ErrRenderCycle は、複数回の描画で、以前の描画と同じテンプレートが再び現れたことを表します。
+//
+// ErrRenderCycle represents that the same template as an earlier pass appeared again while rendering in multiple passes.
+var ErrRenderCycle = fmt.Errorf("render cycle detected")
+
+// ErrTooManyPasses は、 WithRenderPasses で指定した回数の描画の後も、結果にアクションが残っていたことを表します。
+//
+// ErrTooManyPasses represents that the result still has actions after the number of passes specified in WithRenderPasses.
+var ErrTooManyPasses = fmt.Errorf("too many render passes")
+
+// WithRenderPasses は、 Render や RenderDelims などの描画関数が、描画した結果を再びテンプレートとして解析し、アクションがなくなるまで最大 n 回まで描画するようにします。
+// 探索した値そのものが別のキーを参照するテンプレートの式を含む場合に使います。
+// 同じテンプレートが再び現れた場合は ErrRenderCycle を、 n 回の描画の後もアクションが残っている場合は ErrTooManyPasses を返します。
+// 2回目以降の描画のエラーの位置は、その前の描画の結果の中の位置です。 n が 1 以下の場合は1回だけ描画します (既定)。
+//
+// WithRenderPasses makes rendering functions such as Render and RenderDelims parse the result as a template again and render it, up to n passes until no actions remain.
+// Use it when looked-up values themselves contain template expressions referring to other keys.
+// It returns ErrRenderCycle if the same template appears again, and ErrTooManyPasses if actions remain after n passes.
+// Positions of errors in later passes refer to the result of the previous pass. If n is 1 or less, it renders only once (default).
+//
+//	// en: "dsn" resolves to `postgres://app:{{ lookup "secret.DB_PASS" }}@db/app`
+//	dsn, err := tempura.RenderString(ctx, `{{ lookup "config.dsn" }}`, lookup, nil, tempura.WithRenderPasses(3))
+func WithRenderPasses(n int) Option {
+	return func(o *options) {
+		o.renderPasses = n
+	}
+}
+
+// renderPasses は、 text を最大 m.opts.renderPasses 回まで繰り返し描画し、アクションのなくなった結果を w に書き込みます。
+//
+// renderPasses renders text repeatedly up to m.opts.renderPasses passes, and writes the result without actions to w.
+func (m *MultiLookupContext) renderPasses(w io.Writer, left, right, text string, data any) error {
+	seen := make(map[string]bool)
+	for pass := 1; ; pass++ {
+		t, err := RegisterTo(template.New("").Delims(left, right), DefaultFuncName, m).Parse(text)
+		if err != nil {
+			if pass == 1 {
+				return fmt.Errorf("failed to parse template: %w", err)
+			}
+			return fmt.Errorf("failed to parse the result of pass %d: %w", pass-1, err)
+		}
+		if isPlainText(t) {
+			_, err := io.WriteString(w, text)
+			return err
+		}
+		if pass > m.opts.renderPasses {
+			return fmt.Errorf("%w: actions remain after %d passes", ErrTooManyPasses, m.opts.renderPasses)
+		}
+		if seen[text] {
+			return fmt.Errorf("%w: the result of pass %d appeared in an earlier pass", ErrRenderCycle, pass-1)
+		}
+		seen[text] = true
+
+		b := &strings.Builder{}
+		if err := t.Execute(b, data); err != nil {
+			return AnnotateTemplateError(err)
+		}
+		text = b.String()
+	}
+}
+
+// isPlainText は、 t に関連付けられた全てのテンプレートがテキストだけからなるかを返します。
+//
+// isPlainText reports whether all templates associated with t consist of text only.
+func isPlainText(t *template.Template) bool {
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil {
+			continue
+		}
+		for _, node := range tmpl.Tree.Root.Nodes {
+			if node.Type() != parse.NodeText {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package tempura_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRenderPasses(t *testing.T) {
+	t.Parallel()
+
+	configs := map[string]string{
+		"dsn":   `postgres://app:{{ lookup "secret.DB_PASS" }}@db/app`,
+		"alias": `{{ lookup "config.dsn" }}`,
+		"ping":  `{{ lookup "config.pong" }}`,
+		"pong":  `{{ lookup "config.ping" }}`,
+		"plain": "{{`{{ not an action }}`}}",
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("config"): tempura.Func(func(key string) (string, bool) {
+			val, ok := configs[key]
+			return val, ok
+		}),
+		tempura.DotPrefix("secret"): tempura.Func(func(key string) (string, bool) { return "secret-of-" + key, true }),
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		passes   int
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "single pass", text: `{{ lookup "config.dsn" }}`, passes: 1, expected: configs["dsn"]},
+		{name: "two passes", text: `{{ lookup "config.dsn" }}`, passes: 2, expected: "postgres://app:secret-of-DB_PASS@db/app"},
+		{name: "fewer passes than allowed", text: `{{ lookup "config.alias" }}`, passes: 5, expected: "postgres://app:secret-of-DB_PASS@db/app"},
+		{name: "plain text", text: "user: app", passes: 2, expected: "user: app"},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "too many passes",
+			text:   `{{ lookup "config.alias" }}`,
+			passes: 2,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrTooManyPasses)
+			},
+		},
+		{
+			name:   "cycle",
+			text:   `{{ lookup "config.ping" }}`,
+			passes: 10,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrRenderCycle)
+			},
+		},
+		{
+			name:   "result not parsable",
+			text:   `{{ lookup "config.plain" }}`,
+			passes: 2,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "failed to parse the result of pass 2")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := tempura.RenderString(context.Background(), tt.text, lookup, nil, tempura.WithRenderPasses(tt.passes))
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, s)
+		})
+	}
+}
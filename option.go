@@ -0,0 +1,151 @@
+package tempura
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// =================================================================================
+// Options for MultiLookupContext
+// =================================================================================
+
+// Option は BindContext(ctx, opts...) で MultiLookupContext の振る舞いを変更します。
+//
+// Option changes the behavior of MultiLookupContext via BindContext(ctx, opts...).
+type Option func(*options)
+
+type options struct {
+	cache          Cache
+	semaphore      chan struct{}
+	asyncPolicy    AsyncPolicy
+	initializers   []func(ctx context.Context) error
+	logger         *slog.Logger
+	logLevel       slog.Leveler
+	tracer         Tracer
+	metrics        Metrics
+	hooks          []Hooks
+	redactor       *keyRedactor
+	audits         []audit
+	slowLookup     time.Duration
+	onError        []func(ctx context.Context, e ErrorEvent)
+	correlation    func(ctx context.Context) CorrelationIDs
+	dryRun         *DryRunReport
+	knownKeys      []string
+	onMissing      func(ctx context.Context, args []string) (any, bool)
+	errorTransform func(ctx context.Context, args []string, err error) (any, error)
+	lenient        bool
+	panicStack     bool
+	strict         *strictMatch
+	htmlSafe       bool
+	trusted        map[Prefix]ContentType
+	renderPasses   int
+	passThrough    func(args []string) string
+	outputCheck    func(out []byte) error
+}
+
+// WithCache は、見つかった値を引数 (prefix を含むキー) ごとに cache に保持し、以降の探索で再利用します。
+//
+// WithCache memoizes found values in cache per arg (the key including the prefix) and reuses them in subsequent lookups.
+func WithCache(cache Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithMaxConcurrency は、 context.Context を受け取る探索関数が同時に実行される数を n までに制限します。
+// 制限は Option の値ごとに共有されるため、描画ごとに BindContext する場合も同じ Option を渡すことで、全ての描画をまたいで制限できます。
+// 描画が集中したときに、 Vault などのバックエンドへ数百の接続を並行して開いてしまうことを防ぎます。
+//
+// WithMaxConcurrency limits the number of lookup functions accepting context.Context that run simultaneously to n.
+// The limit is shared per Option value, so passing the same Option to BindContext for each render limits across all renders.
+// It keeps a burst of template executions from opening hundreds of parallel connections to backends such as Vault.
+// n が 0 以下の場合は制限しません。 / If n is zero or negative, no limit is applied.
+//
+//	limit := tempura.WithMaxConcurrency(8)
+//	lookup := secrets.BindContext(ctx, limit) // en: per render
+func WithMaxConcurrency(n int) Option {
+	if n <= 0 {
+		return func(o *options) {
+			o.semaphore = nil
+		}
+	}
+	semaphore := make(chan struct{}, n)
+	return func(o *options) {
+		o.semaphore = semaphore
+	}
+}
+
+// AsyncPolicy は、 FuncMapValue が返った時点でまだ実行中の非同期の探索関数をどう扱うかを表します。
+//
+// AsyncPolicy represents how to treat asynchronous lookup functions still running when FuncMapValue returns.
+type AsyncPolicy int
+
+const (
+	// AsyncDetach は、実行中の探索関数の context.Context をキャンセルし、終了を待たずに返ります (既定)。
+	// 残った goroutine は MultiLookupContext.WaitClose で待つことができます。
+	//
+	// AsyncDetach cancels the context.Context of running lookup functions and returns without waiting for them (default).
+	// The remaining goroutines can be drained with MultiLookupContext.WaitClose.
+	AsyncDetach AsyncPolicy = iota
+
+	// AsyncWaitForAll は、実行中の探索関数の context.Context をキャンセルし、全てが終了するまで待ってから返ります。
+	//
+	// AsyncWaitForAll cancels the context.Context of running lookup functions and waits for all of them to finish before returning.
+	AsyncWaitForAll
+)
+
+// WithAsyncPolicy は、後続の引数のために起動され、結果が不要になった非同期の探索関数の扱いを policy に変更します。
+//
+// WithAsyncPolicy changes how asynchronous lookup functions fired for later args, whose results turn out to be unneeded, are treated.
+func WithAsyncPolicy(policy AsyncPolicy) Option {
+	return func(o *options) {
+		o.asyncPolicy = policy
+	}
+}
+
+// WithInitializer は、 MultiLookupContext.Start でキーの先読みより前に実行する、探索関数の初期化処理 (クライアントの生成や認証など) を登録します。
+//
+// WithInitializer registers initialization of lookup functions, such as creating clients or authenticating, run by MultiLookupContext.Start before preloading keys.
+func WithInitializer(init func(ctx context.Context) error) Option {
+	return func(o *options) {
+		o.initializers = append(o.initializers, init)
+	}
+}
+
+// WithLogger は、 MultiLookupContext のログの出力先を logger に変更します。指定しない場合は slog.Default() に出力されます。
+// tempura を組み込むライブラリが、プロセス全体の状態を変えずにログを振り分けたり抑制したりできます。
+// MultiLookup は常に slog.Default() に出力するため、ロガーを指定したい場合は BindContext を使ってください。
+//
+// WithLogger routes the logs of MultiLookupContext to logger. Without it, logs go to slog.Default().
+// It lets libraries embedding tempura route or silence its logs without touching process-global state.
+// MultiLookup always logs to slog.Default(), so use BindContext to specify a logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithLogLevel は、探索の記録を出力するレベルを level に変更します。指定しない場合は slog.LevelDebug です。
+// 探索ごとに prefix, key, provider (探索関数の型), duration, outcome (found, not_found, error, cache_hit, negative_cache_hit) を属性として持つ記録が出力されるため、本番環境のログ基盤で絞り込みや集計ができます。
+// 値そのものは出力されません。
+//
+// WithLogLevel changes the level at which lookups are logged to level. Without it, the level is slog.LevelDebug.
+// A record is emitted per lookup with prefix, key, provider (the type of the lookup function), duration and outcome (found, not_found, error, cache_hit, negative_cache_hit) as attributes, so production log pipelines can filter and aggregate lookup activity.
+// Values themselves are never logged.
+func WithLogLevel(level slog.Leveler) Option {
+	return func(o *options) {
+		o.logLevel = level
+	}
+}
+
+// WithSlowLookupThreshold は、探索関数の呼び出しに threshold より長くかかった場合に、 prefix, key, provider, duration を属性として持つ警告を出力します。
+// 警告は WithLogLevel に関係なく slog.LevelWarn で WithLogger のロガーに出力されるため、遅いバックエンドに障害になる前に気付くことができます。
+//
+// WithSlowLookupThreshold emits a warning with prefix, key, provider and duration as attributes when a call of a lookup function takes longer than threshold.
+// Warnings go to the logger of WithLogger at slog.LevelWarn regardless of WithLogLevel, so slow backends are noticed before they turn into outages.
+func WithSlowLookupThreshold(threshold time.Duration) Option {
+	return func(o *options) {
+		o.slowLookup = threshold
+	}
+}
@@ -0,0 +1,147 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestWithMaxConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var running, peak atomic.Int32
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return "secret-of-" + key, true, nil
+	}
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}
+
+	limit := tempura.WithMaxConcurrency(2)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// en: BindContext per render, sharing the same limit
+			lookup := secrets.BindContext(context.Background(), limit)
+			_, err := lookup.FuncMapValue("secret.A", "secret.B")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, peak.Load(), int32(2), "concurrency should be limited across renders")
+	assert.Equal(t, int32(2), peak.Load())
+}
+
+func TestWithMaxConcurrency_Canceled(t *testing.T) {
+	t.Parallel()
+
+	block := make(chan struct{})
+	defer close(block)
+	blocking := func(ctx context.Context, key string) (string, bool, error) {
+		<-block
+		return "", false, nil
+	}
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(blocking),
+	}
+	limit := tempura.WithMaxConcurrency(1)
+
+	go func() {
+		_, _ = secrets.BindContext(context.Background(), limit).FuncMapValue("secret.A")
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := secrets.BindContext(ctx, limit).FuncMapValue("secret.B")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWithAsyncPolicy(t *testing.T) {
+	// en: not parallel, so that goleak sees only the goroutines of this test
+
+	const delay = 50 * time.Millisecond
+	fast := func(ctx context.Context, key string) (string, bool, error) {
+		return "fast:" + key, true, nil
+	}
+	stubborn := func(ctx context.Context, key string) (string, bool, error) {
+		time.Sleep(delay) // en: ignores cancellation
+		return "slow:" + key, true, nil
+	}
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("fast"):     tempura.FuncWithContextError(fast),
+		tempura.DotPrefix("stubborn"): tempura.FuncWithContextError(stubborn),
+	}
+
+	tests := []struct {
+		name        string
+		policy      tempura.AsyncPolicy
+		waitClose   bool
+		minDuration time.Duration
+		maxDuration time.Duration
+	}{
+		// ==================== VALID CASES ====================
+		{name: "wait for all on return", policy: tempura.AsyncWaitForAll, minDuration: delay},
+		{name: "detach and drain with WaitClose", policy: tempura.AsyncDetach, waitClose: true, maxDuration: delay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+			lookup := secrets.BindContext(context.Background(), tempura.WithAsyncPolicy(tt.policy))
+			start := time.Now()
+			val, err := lookup.FuncMapValue("fast.A", "stubborn.B", "stubborn.C")
+			require.NoError(t, err)
+			assert.Equal(t, "fast:A", val)
+			elapsed := time.Since(start)
+			assert.GreaterOrEqual(t, elapsed, tt.minDuration)
+			if tt.maxDuration > 0 {
+				assert.Less(t, elapsed, tt.maxDuration)
+			}
+
+			if tt.waitClose {
+				lookup.WaitClose()
+			}
+		})
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		return "secret-of-" + key, true, nil
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}.BindContext(context.Background(), tempura.WithLogger(logger))
+
+	val, err := lookup.FuncMapValue("secret.DB_PASS")
+	require.NoError(t, err)
+	assert.Equal(t, "secret-of-DB_PASS", val)
+	assert.Contains(t, buf.String(), "arg=secret.DB_PASS")
+}
@@ -0,0 +1,86 @@
+// Package oteltempura は、 OpenTelemetry のスパンを作成する tempura.Tracer を提供します。
+// FuncMapValue の呼び出しごとにスパンを、探索関数の呼び出しごとにその子スパンを作成します。
+// prefix やキーは属性として記録しますが、値は記録しません。
+//
+// Package oteltempura provides a tempura.Tracer creating OpenTelemetry spans.
+// It creates a span per FuncMapValue call and a child span per call of a lookup function.
+// Prefixes and keys are recorded as attributes, but values never are.
+//
+//	lookup := secrets.BindContext(ctx, tempura.WithTracer(oteltempura.NewTracer(nil)))
+package oteltempura
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ebi-yade/go-tempura"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/ebi-yade/go-tempura/oteltempura"
+
+// Attribute keys recorded on spans.
+const (
+	ArgsKey     = attribute.Key("tempura.args")
+	PrefixKey   = attribute.Key("tempura.prefix")
+	KeyKey      = attribute.Key("tempura.key")
+	ProviderKey = attribute.Key("tempura.provider")
+	OutcomeKey  = attribute.Key("tempura.outcome")
+)
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer は、 tp でスパンを作成する tempura.Tracer を返します。 tp が nil の場合はグローバルな TracerProvider を使います。
+//
+// NewTracer returns a tempura.Tracer creating spans with tp. If tp is nil, the global TracerProvider is used.
+func NewTracer(tp trace.TracerProvider) tempura.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+func (t tracer) StartLookup(ctx context.Context, args []string) (context.Context, tempura.SpanEnd) {
+	ctx, span := t.tracer.Start(ctx, "tempura.FuncMapValue", trace.WithAttributes(ArgsKey.StringSlice(args)))
+	return ctx, end(span)
+}
+
+func (t tracer) StartProvider(ctx context.Context, prefix tempura.Prefix, key string, provider string) (context.Context, tempura.SpanEnd) {
+	ctx, span := t.tracer.Start(ctx, "tempura.lookup "+fmt.Sprint(prefix), trace.WithAttributes(
+		PrefixKey.String(fmt.Sprint(prefix)),
+		KeyKey.String(key),
+		ProviderKey.String(provider),
+	))
+	return ctx, end(span)
+}
+
+func end(span trace.Span) tempura.SpanEnd {
+	return func(outcome string, err error) {
+		span.SetAttributes(OutcomeKey.String(outcome))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// CorrelationIDs は、 ctx のスパンのトレース ID を tempura.CorrelationIDs として返します。
+// tempura.WithCorrelationIDs に渡すことで、探索のログとフックのイベントに OpenTelemetry のトレース ID が含まれます。
+//
+// CorrelationIDs returns the trace ID of the span in ctx as tempura.CorrelationIDs.
+// Passing it to tempura.WithCorrelationIDs includes the trace ID of OpenTelemetry in the logs and hook events of lookups.
+//
+//	lookup := secrets.BindContext(ctx, tempura.WithCorrelationIDs(oteltempura.CorrelationIDs))
+func CorrelationIDs(ctx context.Context) tempura.CorrelationIDs {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return tempura.CorrelationIDs{}
+	}
+	return tempura.CorrelationIDs{TraceID: sc.TraceID().String()}
+}
@@ -0,0 +1,111 @@
+package oteltempura_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/oteltempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errUnavailable = fmt.Errorf("service unavailable")
+
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	out := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		out[kv.Key] = kv.Value
+	}
+	return out
+}
+
+func TestNewTracer(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		// en: providers receive the context of their own span
+		assert.True(t, trace.SpanContextFromContext(ctx).IsValid())
+		if key == "FAIL" {
+			return "", false, errUnavailable
+		}
+		return "secret-of-" + key, key != "MISSING", nil
+	}
+
+	tests := []struct {
+		name             string
+		args             []string
+		expectedOutcomes []string
+		expectedStatus   codes.Code
+	}{
+		// ==================== VALID CASES ====================
+		{name: "found", args: []string{"secret.DB_PASS"}, expectedOutcomes: []string{"found", "found"}, expectedStatus: codes.Unset},
+		{name: "not found", args: []string{"secret.MISSING"}, expectedOutcomes: []string{"not_found", "not_found"}, expectedStatus: codes.Unset},
+		// ==================== INVALID CASES ====================
+		{name: "error", args: []string{"secret.FAIL"}, expectedOutcomes: []string{"error", "error"}, expectedStatus: codes.Error},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+			}.BindContext(context.Background(), tempura.WithTracer(oteltempura.NewTracer(tp)))
+
+			_, _ = lookup.FuncMapValue(tt.args...)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 2)
+			child, parent := spans[0], spans[1]
+			assert.Equal(t, parent.SpanContext().SpanID(), child.Parent().SpanID())
+
+			assert.Equal(t, "tempura.FuncMapValue", parent.Name())
+			assert.Equal(t, tt.args, attrs(parent)[oteltempura.ArgsKey].AsStringSlice())
+			assert.Equal(t, tt.expectedOutcomes[0], attrs(parent)[oteltempura.OutcomeKey].AsString())
+			assert.Equal(t, tt.expectedStatus, parent.Status().Code)
+
+			childAttrs := attrs(child)
+			assert.Equal(t, "secret", childAttrs[oteltempura.PrefixKey].AsString())
+			assert.Equal(t, "LookupAnyWithContextError", childAttrs[oteltempura.ProviderKey].AsString())
+			assert.Equal(t, tt.expectedOutcomes[1], childAttrs[oteltempura.OutcomeKey].AsString())
+			assert.Equal(t, tt.expectedStatus, child.Status().Code)
+			for _, kv := range append(parent.Attributes(), child.Attributes()...) {
+				assert.NotContains(t, kv.Value.Emit(), "secret-of-", "values should never be recorded")
+			}
+		})
+	}
+}
+
+func TestCorrelationIDs(t *testing.T) {
+	t.Parallel()
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(tracetest.NewSpanRecorder()))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	var events []tempura.LookupEvent
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.Func(func(key string) (string, bool) {
+			return "secret-of-" + key, true
+		}),
+	}.BindContext(ctx, tempura.WithCorrelationIDs(oteltempura.CorrelationIDs), tempura.WithHooks(tempura.Hooks{
+		AfterLookup: func(ctx context.Context, e tempura.LookupEvent) { events = append(events, e) },
+	}))
+
+	_, err := lookup.FuncMapValue("secret.DB_PASS")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, tempura.CorrelationIDs{TraceID: span.SpanContext().TraceID().String()}, events[0].CorrelationIDs)
+
+	assert.Equal(t, tempura.CorrelationIDs{}, oteltempura.CorrelationIDs(context.Background()))
+}
@@ -0,0 +1,140 @@
+package tempura
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// =================================================================================
+// Validity checks of rendered outputs
+// =================================================================================
+
+// OutputError は、描画した結果が YAML や JSON として正しくないことを、その位置と共に表します。
+// 結果には秘密情報が含まれうるため、該当する箇所の内容は含みません。
+//
+// OutputError represents that a rendered output is not valid as YAML or JSON, along with the position.
+// Outputs may contain secrets, so it never includes the content at the position.
+type OutputError struct {
+	Format string
+	// Line と Column は1から始まります。 Column は分からない場合に 0 です。
+	// Line and Column are 1-based. Column is 0 if unknown.
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e OutputError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("rendered output is not valid %s at line %d: %v", e.Format, e.Line, e.Err)
+	}
+	return fmt.Sprintf("rendered output is not valid %s at %d:%d: %v", e.Format, e.Line, e.Column, e.Err)
+}
+
+func (e OutputError) Unwrap() error {
+	return e.Err
+}
+
+// WithOutputCheck は、 Render や RenderDir などの描画関数が、描画した結果を w に書き込む前に check で検査し、エラーであれば何も書き込まずに返すようにします。
+// CheckYAML や CheckJSON と組み合わせると、引用符で囲まれていない秘密情報が YAML を壊すといったよくある問題を描画の時点で見つけられます。
+//
+// WithOutputCheck makes rendering functions such as Render and RenderDir check the rendered output with check before writing it to w, and return the error without writing anything if it fails.
+// Combined with CheckYAML or CheckJSON, it catches the classic "unquoted secret broke the YAML" bug at render time.
+//
+//	err := tempura.Render(ctx, w, text, secrets, nil, tempura.WithOutputCheck(tempura.CheckYAML))
+func WithOutputCheck(check func(out []byte) error) Option {
+	return func(o *options) {
+		o.outputCheck = check
+	}
+}
+
+// yamlErrorLine は、 yaml.v3 がエラーに付ける行番号の形式です。
+//
+// yamlErrorLine is the format of line numbers yaml.v3 gives to errors.
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// CheckYAML は、 out が YAML として正しいかを検査します。 --- で区切られた複数のドキュメントも扱えます。
+// 正しくない場合は、行番号と共に OutputError を返します。
+//
+// CheckYAML checks whether out is valid YAML. It handles multiple documents separated by ---.
+// If invalid, it returns an OutputError with the line number.
+func CheckYAML(out []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(out))
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			e := OutputError{Format: "YAML", Err: err}
+			if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+				e.Line, _ = strconv.Atoi(match[1])
+				e.Err = errors.New(match[2])
+			}
+			return e
+		}
+	}
+}
+
+// CheckJSON は、 out が JSON として正しいかを検査します。 JSON Lines のように連続した複数の値も扱えます。
+// 正しくない場合は、行と列の番号と共に OutputError を返します。
+//
+// CheckJSON checks whether out is valid JSON. It handles multiple consecutive values such as JSON Lines.
+// If invalid, it returns an OutputError with the line and column numbers.
+func CheckJSON(out []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var val json.RawMessage
+		err := dec.Decode(&val)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			offset := len(out) // en: io.ErrUnexpectedEOF and the like point at the end
+			var se *json.SyntaxError
+			if errors.As(err, &se) {
+				offset = int(se.Offset)
+			}
+			line, column := position(out, offset)
+			return OutputError{Format: "JSON", Line: line, Column: column, Err: err}
+		}
+	}
+}
+
+// position は、 out の offset バイト目の行と列の番号を返します。
+//
+// position returns the line and column numbers of the byte at offset in out.
+func position(out []byte, offset int) (line, column int) {
+	if offset > len(out) {
+		offset = len(out)
+	}
+	before := out[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = offset - (bytes.LastIndexByte(before, '\n') + 1)
+	if column == 0 {
+		column = 1
+	}
+	return line, column
+}
+
+// renderChecked は、描画した結果を WithOutputCheck で検査してから w に書き込みます。
+//
+// renderChecked checks the rendered output with WithOutputCheck before writing it to w.
+func (m *MultiLookupContext) renderChecked(w io.Writer, left, right, tmplText string, data any) error {
+	b := &bytes.Buffer{}
+	if err := m.execute(b, left, right, tmplText, data); err != nil {
+		return err
+	}
+	if err := m.opts.outputCheck(b.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
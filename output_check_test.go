@@ -0,0 +1,101 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithOutputCheck(t *testing.T) {
+	t.Parallel()
+
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.Func(func(key string) (string, bool) {
+			return map[string]string{"PLAIN": "p4ss", "COLON": "p: ss", "QUOTE": `p"ss`}[key], true
+		}),
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		check    func(out []byte) error
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "valid YAML",
+			text:     "user: app\npass: {{ lookup \"secret.PLAIN\" }}\n",
+			check:    tempura.CheckYAML,
+			expected: "user: app\npass: p4ss\n",
+		},
+		{
+			name:     "valid YAML documents",
+			text:     "a: 1\n---\npass: {{ lookup \"secret.PLAIN\" }}\n",
+			check:    tempura.CheckYAML,
+			expected: "a: 1\n---\npass: p4ss\n",
+		},
+		{
+			name:     "valid JSON lines",
+			text:     "{\"a\": 1}\n{\"pass\": \"{{ lookup \"secret.PLAIN\" }}\"}\n",
+			check:    tempura.CheckJSON,
+			expected: "{\"a\": 1}\n{\"pass\": \"p4ss\"}\n",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:  "invalid YAML",
+			text:  "a: 1\n---\nuser: app\npass: {{ lookup \"secret.COLON\" }}\n",
+			check: tempura.CheckYAML,
+			checkErr: func(t *testing.T, err error) {
+				var oe tempura.OutputError
+				require.ErrorAs(t, err, &oe)
+				assert.Equal(t, "YAML", oe.Format)
+				assert.Equal(t, 4, oe.Line)
+				assert.NotContains(t, err.Error(), "p: ss")
+			},
+		},
+		{
+			name:  "invalid JSON",
+			text:  "{\n  \"pass\": \"{{ lookup \"secret.QUOTE\" }}\"\n}\n",
+			check: tempura.CheckJSON,
+			checkErr: func(t *testing.T, err error) {
+				var oe tempura.OutputError
+				require.ErrorAs(t, err, &oe)
+				assert.Equal(t, "JSON", oe.Format)
+				assert.Equal(t, 2, oe.Line)
+				assert.Equal(t, 14, oe.Column)
+			},
+		},
+		{
+			name:  "truncated JSON",
+			text:  "{\n  \"pass\": \"{{ lookup \"secret.PLAIN\" }}\"\n",
+			check: tempura.CheckJSON,
+			checkErr: func(t *testing.T, err error) {
+				var oe tempura.OutputError
+				require.ErrorAs(t, err, &oe)
+				assert.Equal(t, 3, oe.Line)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			err := tempura.Render(context.Background(), buf, tt.text, secrets, nil, tempura.WithOutputCheck(tt.check))
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				assert.Empty(t, buf.String(), "nothing is written on failure")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
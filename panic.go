@@ -0,0 +1,66 @@
+package tempura
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// =================================================================================
+// Recovery from panics of lookup functions
+// =================================================================================
+
+// PanicError は、探索関数の panic から回復したことを、その prefix と panic の値と共に表します。
+// 探索関数が panic しても描画のプロセス全体が止まることはなく、 PanicError を包んだ KeyError が探索の結果になります。
+//
+// PanicError represents a recovery from a panic of a lookup function, along with its prefix and the value of the panic.
+// A panic of a lookup function never brings down the whole rendering process; a KeyError wrapping a PanicError becomes the result of the lookup.
+type PanicError struct {
+	Prefix Prefix
+	Value  any
+	// Stack は panic した goroutine のスタックトレースです。 WithPanicStack を指定した場合のみ記録されます。
+	// Stack is the stack trace of the goroutine that panicked. It is captured only if WithPanicStack is specified.
+	Stack []byte
+}
+
+func (e PanicError) Error() string {
+	if len(e.Stack) == 0 {
+		return fmt.Sprintf("lookup function panicked: %v", e.Value)
+	}
+	return fmt.Sprintf("lookup function panicked: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap は、 panic の値が error の場合にそれを返します。
+//
+// Unwrap returns the value of the panic if it is an error.
+func (e PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithPanicStack は、探索関数の panic から回復した際にスタックトレースを PanicError に記録します。
+// デバッガを使って再実行することなく問題のある探索関数を特定するためのデバッグ用のオプションで、エラーの文字列にもスタックトレースが含まれます。
+//
+// WithPanicStack captures the stack trace in PanicError when recovering from a panic of a lookup function.
+// It is a debugging option to identify the offending lookup function without re-running with a debugger, and the error string includes the stack trace as well.
+func WithPanicStack() Option {
+	return func(o *options) {
+		o.panicStack = true
+	}
+}
+
+// safeCallLookup は callLookup を呼び出し、 fn の panic を PanicError に変換します。
+//
+// safeCallLookup calls callLookup, converting a panic of fn into a PanicError.
+func (m *MultiLookupContext) safeCallLookup(ctx context.Context, prefix Prefix, fn LookupFunc, key string) (res lookupResult) {
+	defer func() {
+		if v := recover(); v != nil {
+			e := PanicError{Prefix: prefix, Value: v}
+			if m.opts.panicStack {
+				e.Stack = debug.Stack()
+			}
+			res = lookupResult{err: e, matched: true}
+		}
+	}()
+	return callLookup(ctx, prefix, fn, key)
+}
@@ -0,0 +1,84 @@
+package tempura_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPanicError(t *testing.T) {
+	t.Parallel()
+
+	errBroken := errors.New("broken")
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		switch key {
+		case "NIL_MAP":
+			var m map[string]string
+			m[key] = "boom"
+		case "ERROR":
+			panic(errBroken)
+		}
+		return "p@ssword!", true, nil
+	}
+
+	tests := []struct {
+		name      string
+		opts      []tempura.Option
+		arg       string
+		withStack bool
+		checkErr  func(t *testing.T, err error)
+	}{
+		// ==================== INVALID CASES ====================
+		{
+			name: "runtime error",
+			arg:  "secret.NIL_MAP",
+			checkErr: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "failed to look up NIL_MAP by secret (LookupAnyWithContextError): lookup function panicked: assignment to entry in nil map")
+			},
+		},
+		{
+			name: "panic with an error",
+			arg:  "secret.ERROR",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errBroken)
+			},
+		},
+		{
+			name:      "with stack",
+			opts:      []tempura.Option{tempura.WithPanicStack()},
+			arg:       "secret.NIL_MAP",
+			withStack: true,
+			checkErr: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "panic_test.go")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+			}.BindContext(context.Background(), tt.opts...)
+
+			_, err := lookup.FuncMapValue(tt.arg)
+			require.Error(t, err)
+
+			var pe tempura.PanicError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, tempura.DotPrefix("secret"), pe.Prefix)
+			if tt.withStack {
+				assert.Contains(t, string(pe.Stack), "panic_test.go")
+			} else {
+				assert.Empty(t, pe.Stack)
+			}
+			tt.checkErr(t, err)
+		})
+	}
+}
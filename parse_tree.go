@@ -0,0 +1,101 @@
+package tempura
+
+import (
+	"text/template"
+	"text/template/parse"
+)
+
+// =================================================================================
+// Static analysis of parsed templates
+// =================================================================================
+
+// lookupCalls は、 t に関連付けられた全てのテンプレートから、 name という関数の呼び出しに渡された文字列リテラルの引数の列を集めます。
+// 変数やパイプラインなど、実行するまで値が決まらない引数を含む呼び出しは、リテラルの引数だけでは実際の探索と異なるため無視されます。
+//
+// lookupCalls collects the lists of string literal args passed to calls of the function name in all templates associated with t.
+// Calls with args whose values are unknown until execution, such as variables and preceding commands of pipelines, are ignored, since the literal args alone differ from the actual lookups.
+func lookupCalls(t *template.Template, name string) [][]string {
+	var calls [][]string
+	seen := make(map[string]bool)
+	walkLookupCalls(t, name, func(_ *template.Template, cmd *parse.CommandNode, piped bool) {
+		if piped {
+			return // en: the output of the preceding command is passed as the last arg
+		}
+		args := make([]string, 0, len(cmd.Args)-1)
+		signature := ""
+		for _, arg := range cmd.Args[1:] {
+			s, ok := arg.(*parse.StringNode)
+			if !ok {
+				return
+			}
+			args = append(args, s.Text)
+			signature += s.Text + "\x00"
+		}
+		if len(args) == 0 || seen[signature] {
+			return
+		}
+		seen[signature] = true
+		calls = append(calls, args)
+	})
+	return calls
+}
+
+// walkLookupCalls は、 t に関連付けられた全てのテンプレートから name という関数の呼び出しを探し、それを含むテンプレートと共に fn を呼び出します。
+// piped は、呼び出しがパイプラインで他のコマンドに続き、その出力を最後の引数として受け取る場合に true です。
+//
+// walkLookupCalls finds calls of the function name in all templates associated with t, and calls fn with each of them and the template containing it.
+// piped is true if the call follows another command in a pipeline, and receives its output as the last arg.
+func walkLookupCalls(t *template.Template, name string, fn func(tmpl *template.Template, cmd *parse.CommandNode, piped bool)) {
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil {
+			continue
+		}
+		walkCommands(tmpl.Tree.Root, func(cmd *parse.CommandNode, piped bool) {
+			if len(cmd.Args) == 0 {
+				return
+			}
+			if ident, ok := cmd.Args[0].(*parse.IdentifierNode); !ok || ident.Ident != name {
+				return
+			}
+			fn(tmpl, cmd, piped)
+		})
+	}
+}
+
+func walkCommands(node parse.Node, fn func(cmd *parse.CommandNode, piped bool)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkCommands(child, fn)
+		}
+	case *parse.ActionNode:
+		walkCommands(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for i, cmd := range n.Cmds {
+			fn(cmd, i > 0)
+			for _, arg := range cmd.Args {
+				walkCommands(arg, fn)
+			}
+		}
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkCommands(n.Pipe, fn)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, fn func(cmd *parse.CommandNode, piped bool)) {
+	walkCommands(n.Pipe, fn)
+	walkCommands(n.List, fn)
+	walkCommands(n.ElseList, fn)
+}
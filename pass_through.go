@@ -0,0 +1,55 @@
+package tempura
+
+import (
+	"strconv"
+	"strings"
+)
+
+// =================================================================================
+// Pass-through of unresolved placeholders
+// =================================================================================
+
+// WithPassThrough は、どの引数からも値が得られなかった場合に、エラーを返す代わりに format(args) の結果を値として返します。
+// PassThroughDollar や PassThroughAction を使うと、解決できなかった箇所を元の式のまま出力に残せるため、後段のツールが残りを埋める多段のパイプラインで部分的に解決できます。
+// WithOnMissing の後に適用されます。探索関数のエラーはこれまで通りエラーとして返されます。
+//
+// WithPassThrough makes a lookup with no value from any of the args return the result of format(args) as the value instead of an error.
+// With PassThroughDollar or PassThroughAction, unresolved placeholders are left verbatim in the output, enabling partial resolution in multi-stage pipelines where a later tool fills the rest.
+// It is applied after WithOnMissing. Errors of lookup functions are still returned as errors.
+//
+//	// en: {{ lookup "env.FOO" }} renders as ${env.FOO} if env.FOO is not found
+//	err := tempura.Render(ctx, w, text, lookup, nil, tempura.WithPassThrough(tempura.PassThroughDollar))
+func WithPassThrough(format func(args []string) string) Option {
+	return func(o *options) {
+		o.passThrough = format
+	}
+}
+
+// PassThroughDollar は、 WithPassThrough のための書式で、最初の引数を envsubst や Expand の ${env.FOO} という形で返します。
+//
+// PassThroughDollar is a format for WithPassThrough that returns the first arg in the form ${env.FOO} of envsubst and Expand.
+func PassThroughDollar(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return "${" + args[0] + "}"
+}
+
+// PassThroughAction は、 WithPassThrough のための書式で、引数を name という関数の呼び出し {{ name "env.FOO" }} として返します。
+// 後段で同じテンプレートを別の探索関数で再び描画する場合に使います。
+//
+// PassThroughAction returns a format for WithPassThrough that returns the args as a call of the function name, {{ name "env.FOO" }}.
+// Use it when a later stage renders the same template again with other lookup functions.
+func PassThroughAction(name string) func(args []string) string {
+	return func(args []string) string {
+		b := &strings.Builder{}
+		b.WriteString("{{ ")
+		b.WriteString(name)
+		for _, arg := range args {
+			b.WriteByte(' ')
+			b.WriteString(strconv.Quote(arg))
+		}
+		b.WriteString(" }}")
+		return b.String()
+	}
+}
@@ -0,0 +1,57 @@
+package tempura_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPassThrough(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("env"): tempura.Func(func(key string) (string, bool) { return "env-of-" + key, key != "MISSING" }),
+		tempura.DotPrefix("secret"): tempura.FuncWithError(func(key string) (string, bool, error) {
+			return "", false, errUnavailable
+		}),
+	}
+	text := `{{ lookup "env.HOST" }} {{ lookup "env.MISSING" "vault.FOO" }}`
+
+	tests := []struct {
+		name     string
+		text     string
+		format   func(args []string) string
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "dollar", text: text, format: tempura.PassThroughDollar, expected: "env-of-HOST ${env.MISSING}"},
+		{name: "action", text: text, format: tempura.PassThroughAction("tpl"), expected: `env-of-HOST {{ tpl "env.MISSING" "vault.FOO" }}`},
+		// ==================== INVALID CASES ====================
+		{
+			name:   "lookup error",
+			text:   `{{ lookup "secret.DB_PASS" }}`,
+			format: tempura.PassThroughDollar,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := tempura.RenderString(context.Background(), tt.text, lookup, nil, tempura.WithPassThrough(tt.format))
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, s)
+		})
+	}
+}
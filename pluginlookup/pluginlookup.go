@@ -0,0 +1,506 @@
+// Package pluginlookup は、外部のプロバイダのプロセスと標準入出力で JSON をやり取りする探索関数を提供します。
+// tempura が全ての SDK をリンクすることなく、第三者がプロバイダを実行ファイルとして配布できるようにするためのものです。
+//
+// Package pluginlookup provides a lookup function that exchanges JSON with an external provider process over stdio.
+// It lets third parties ship providers as executables, without tempura linking every SDK.
+//
+// # Protocol
+//
+// プラグインは起動すると、まず1行のハンドシェイクを標準出力に書き込みます。
+// その後、標準入力から1行に1つのリクエストを読み、同じ id のレスポンスを1行ずつ標準出力に書き込みます。
+// レスポンスの順序はリクエストの順序と異なっても構いません。標準入力が閉じられたら終了します。標準エラー出力はログに使えます。
+//
+// On start, a plugin first writes a handshake line to stdout.
+// It then reads one request per line from stdin, and writes a response with the same id per line to stdout.
+// Responses may be written in a different order from the requests. It exits when stdin is closed. Stderr can be used for logs.
+//
+//	{"protocol":1,"description":"secrets in Foo"}   // en: handshake
+//	{"id":1,"key":"db/password"}                    // en: request
+//	{"id":1,"found":true,"value":"p4ss"}            // en: response
+//	{"id":2,"found":false}
+//	{"id":3,"error":"permission denied"}
+//
+// Go で書くプラグインは Serve を使えます。
+//
+// Plugins written in Go can use Serve.
+//
+//	func main() {
+//		info := pluginlookup.Info{Protocol: pluginlookup.ProtocolVersion, Description: "secrets in Foo"}
+//		if err := pluginlookup.Serve(context.Background(), os.Stdin, os.Stdout, info, lookupFoo); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+package pluginlookup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+)
+
+const (
+	// ProtocolVersion はこのパッケージが話すプロトコルのバージョンです。
+	// ProtocolVersion is the version of the protocol this package speaks.
+	ProtocolVersion = 1
+
+	// CommandPrefix は Discover が探すプラグインの実行ファイルの名前の prefix です。
+	// CommandPrefix is the prefix of the names of plugin executables Discover looks for.
+	CommandPrefix = "tempura-provider-"
+
+	DefaultTimeout = 10 * time.Second
+)
+
+// Config はプラグインの実行ファイルとその実行条件を定義します。
+//
+// Config defines the plugin executable and how it is run.
+type Config struct {
+	// Command はプラグインの実行ファイルのパスです。パス区切りを含まない場合は PATH から探します。
+	// Command is the path of the plugin executable. It is looked up in PATH if it contains no path separators.
+	Command string
+
+	// Args はプラグインに渡す引数です。
+	// Args is the arguments passed to the plugin.
+	Args []string
+
+	// Env は、親プロセスから引き継ぐ環境変数に加えて渡す "KEY=value" 形式の環境変数です。
+	// プラグインは SDK の認証情報を環境変数から読むことが多いため、 execlookup と異なり環境変数を引き継ぎます。
+	// Env is the environment variables in the form of "KEY=value" passed in addition to those inherited from the parent process.
+	// Unlike execlookup, the environment is inherited, since plugins often read credentials of SDKs from it.
+	Env []string
+
+	// Timeout はハンドシェイクと1回の探索の制限時間です。0 の場合は DefaultTimeout が使われます。
+	// Timeout limits the handshake and a single lookup. DefaultTimeout is used if it is 0.
+	Timeout time.Duration
+
+	// Stderr はプラグインの標準エラー出力の書き込み先です。 nil の場合は捨てられます。
+	// Stderr is where the standard error of the plugin is written. It is discarded if nil.
+	Stderr io.Writer
+}
+
+// Info はプラグインがハンドシェイクで返す情報です。
+//
+// Info is what a plugin returns in the handshake.
+type Info struct {
+	Protocol    int    `json:"protocol"`
+	Description string `json:"description,omitempty"`
+}
+
+// Request と Response は、プロトコルで1行ずつやり取りされるメッセージです。 wasmlookup など、他の実行方法でもプロトコルを共有するために公開しています。
+//
+// Request and Response are the messages exchanged per line in the protocol. They are exported to share the protocol with other runtimes, such as wasmlookup.
+type Request struct {
+	ID  uint64 `json:"id"`
+	Key string `json:"key"`
+}
+
+type Response struct {
+	ID    uint64          `json:"id"`
+	Found bool            `json:"found,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// Plugin は、プラグインのプロセスを探索をまたいで使い回します。プロセスは最初の探索で起動し、終了していた場合は次の探索で起動し直します。
+//
+// Plugin reuses the plugin process across lookups. The process is started on the first lookup, and started again on the next lookup if it has exited.
+type Plugin struct {
+	cfg     Config
+	timeout time.Duration
+
+	mu     sync.Mutex
+	proc   *process
+	closed bool
+}
+
+func New(cfg Config) *Plugin {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Plugin{cfg: cfg, timeout: timeout}
+}
+
+// Lookup は、prefixを取り除いたキーをプラグインに問い合わせる探索関数を返します。
+// 値の数値は json.Number として返されるため、テンプレートでは書かれたとおりに出力されます。
+//
+// Lookup returns a lookup function that asks the plugin for the key with the prefix removed.
+// Numbers in values are returned as json.Number, so they are output in templates as written.
+func (p *Plugin) Lookup() tempura.LookupAnyWithContextError {
+	return func(ctx context.Context, key string) (any, bool, error) {
+		proc, err := p.process(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		return proc.call(ctx, key, p.timeout)
+	}
+}
+
+// Info は、必要であればプラグインを起動して、ハンドシェイクで返された情報を返します。プラグインが使えるかの確認に使えます。
+//
+// Info starts the plugin if needed, and returns what it returned in the handshake. It can be used to check whether the plugin is usable.
+func (p *Plugin) Info(ctx context.Context) (Info, error) {
+	proc, err := p.process(ctx)
+	if err != nil {
+		return Info{}, err
+	}
+	return proc.info, nil
+}
+
+// Close はプラグインの標準入力を閉じ、 Timeout 以内に終了しない場合は強制的に終了させます。 Close の後の探索は ErrClosed を返します。
+//
+// Close closes the standard input of the plugin, and kills it if it does not exit within Timeout. Lookups after Close return ErrClosed.
+func (p *Plugin) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.proc == nil {
+		return nil
+	}
+	p.proc.stdin.Close()
+	select {
+	case <-p.proc.exited:
+	case <-time.After(p.timeout):
+		_ = p.proc.cmd.Process.Kill()
+		<-p.proc.exited
+	}
+	return nil
+}
+
+func (p *Plugin) process(ctx context.Context) (*process, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrClosed
+	}
+	if p.proc != nil && p.proc.alive() {
+		return p.proc, nil
+	}
+	proc, err := start(ctx, p.cfg, p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	p.proc = proc
+	return proc, nil
+}
+
+// process は起動したプラグインのプロセスで、応答待ちのリクエストを id で管理します。
+//
+// process is a started plugin process, managing requests waiting for responses by id.
+type process struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	info   Info
+	exited chan struct{}
+
+	wmu sync.Mutex // en: serializes writes so that concurrent requests never interleave
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan Response
+	err     error // en: set once the plugin exits or breaks the protocol
+}
+
+func start(ctx context.Context, cfg Config, timeout time.Duration) (*process, error) {
+	name := filepath.Base(cfg.Command)
+	cmd := exec.Command(cfg.Command, cfg.Args...) // en: not bound to ctx, since the process outlives the lookup
+	cmd.Env = append(os.Environ(), cfg.Env...)
+	cmd.Stderr = cfg.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", name, err)
+	}
+	proc := &process{
+		name:    name,
+		cmd:     cmd,
+		stdin:   stdin,
+		exited:  make(chan struct{}),
+		pending: make(map[uint64]chan Response),
+	}
+
+	dec := json.NewDecoder(stdout)
+	handshake := make(chan error, 1)
+	go func() { handshake <- dec.Decode(&proc.info) }()
+	abort := func(err error) (*process, error) {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, err
+	}
+	select {
+	case err := <-handshake:
+		if err != nil {
+			return abort(fmt.Errorf("plugin %s: failed to read handshake: %w: %v", name, ErrProtocol, err))
+		}
+	case <-time.After(timeout):
+		return abort(fmt.Errorf("plugin %s: no handshake within %v: %w", name, timeout, ErrProtocol))
+	case <-ctx.Done():
+		return abort(ctx.Err())
+	}
+	if proc.info.Protocol != ProtocolVersion {
+		return abort(fmt.Errorf("plugin %s speaks protocol %d, not %d: %w", name, proc.info.Protocol, ProtocolVersion, ErrProtocol))
+	}
+
+	go proc.readLoop(dec)
+	return proc, nil
+}
+
+// readLoop はレスポンスを読み、待っているリクエストに渡します。プラグインが終了するかプロトコルに違反すると、待っている全てのリクエストを失敗させます。
+//
+// readLoop reads responses and hands them to the waiting requests. Once the plugin exits or breaks the protocol, it fails all the waiting requests.
+func (p *process) readLoop(dec *json.Decoder) {
+	defer close(p.exited)
+	for {
+		var res Response
+		if err := dec.Decode(&res); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("plugin %s exited", p.name)
+			} else {
+				err = fmt.Errorf("plugin %s: %w: %v", p.name, ErrProtocol, err)
+				_ = p.cmd.Process.Kill()
+			}
+			p.fail(err)
+			_ = p.cmd.Wait()
+			return
+		}
+		p.mu.Lock()
+		ch, ok := p.pending[res.ID]
+		delete(p.pending, res.ID)
+		p.mu.Unlock()
+		if ok {
+			ch <- res
+		}
+	}
+}
+
+func (p *process) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+	for id, ch := range p.pending {
+		close(ch)
+		delete(p.pending, id)
+	}
+}
+
+func (p *process) alive() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err == nil
+}
+
+func (p *process) call(ctx context.Context, key string, timeout time.Duration) (any, bool, error) {
+	ch := make(chan Response, 1)
+	p.mu.Lock()
+	if p.err != nil {
+		p.mu.Unlock()
+		return nil, false, p.err
+	}
+	p.nextID++
+	id := p.nextID
+	p.pending[id] = ch
+	p.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	// 書き込みは、標準入力を読まないプラグインでパイプが詰まるとブロックし続けるため、タイムアウトと ctx を待ちながら別の goroutine で行う
+	// en: Write on another goroutine while waiting for the timeout and ctx, since the write blocks forever once a plugin that stops reading stdin fills the pipe
+	b, _ := json.Marshal(Request{ID: id, Key: key})
+	written := make(chan error, 1)
+	go func() {
+		p.wmu.Lock()
+		defer p.wmu.Unlock()
+		_, err := p.stdin.Write(append(b, '\n'))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		if err != nil {
+			p.abandon(id)
+			return nil, false, fmt.Errorf("plugin %s: failed to write request: %w", p.name, err)
+		}
+	case <-timer.C:
+		p.abandon(id)
+		err := fmt.Errorf("plugin %s: request not read within %v: %w", p.name, timeout, context.DeadlineExceeded)
+		// en: a plugin not reading requests is stuck; it is restarted by the next lookup
+		p.fail(err)
+		_ = p.cmd.Process.Kill()
+		return nil, false, err
+	case <-ctx.Done():
+		p.abandon(id)
+		return nil, false, ctx.Err()
+	}
+
+	var res Response
+	var ok bool
+	select {
+	case res, ok = <-ch:
+	case <-timer.C:
+		p.abandon(id)
+		return nil, false, fmt.Errorf("plugin %s: no response within %v: %w", p.name, timeout, context.DeadlineExceeded)
+	case <-ctx.Done():
+		p.abandon(id)
+		return nil, false, ctx.Err()
+	}
+	if !ok {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return nil, false, p.err
+	}
+
+	val, found, err := res.Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("plugin %s: %w", p.name, err)
+	}
+	return val, found, nil
+}
+
+// Result は、レスポンスを探索関数の戻り値に変換します。数値は json.Number になります。
+//
+// Result converts the response into the return values of a lookup function. Numbers become json.Number.
+func (r Response) Result() (any, bool, error) {
+	if r.Error != "" {
+		return nil, false, errors.New(r.Error)
+	}
+	if !r.Found {
+		return nil, false, nil
+	}
+	var val any
+	if len(r.Value) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(r.Value))
+		dec.UseNumber()
+		if err := dec.Decode(&val); err != nil {
+			return nil, false, fmt.Errorf("invalid value: %w: %v", ErrProtocol, err)
+		}
+	}
+	return val, true, nil
+}
+
+func (p *process) abandon(id uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, id)
+}
+
+// =================================================================================
+// Serving and discovering plugins
+// =================================================================================
+
+// Serve は、 r からリクエストを読み、 lookup で解決したレスポンスを w に書き込みます。 Go でプラグインを書くためのものです。
+// リクエストは並行に処理されます。 r が閉じられると、処理中のリクエストを待ってから nil を返します。
+//
+// Serve reads requests from r, and writes responses resolved by lookup to w. It is for writing plugins in Go.
+// Requests are handled concurrently. When r is closed, it waits for the requests in progress and returns nil.
+func Serve(ctx context.Context, r io.Reader, w io.Writer, info Info, lookup tempura.LookupAnyWithContextError) error {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	write := func(v any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return enc.Encode(v)
+	}
+	if err := write(info); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	dec := json.NewDecoder(r)
+	for {
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("%w: %v", ErrProtocol, err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := Response{ID: req.ID}
+			val, found, err := lookup(ctx, req.Key)
+			switch {
+			case err != nil:
+				res.Error = err.Error()
+			case found:
+				b, err := json.Marshal(val)
+				if err != nil {
+					res.Error = fmt.Sprintf("failed to encode value: %v", err)
+					break
+				}
+				res.Found, res.Value = true, b
+			}
+			_ = write(res)
+		}()
+	}
+}
+
+// Discover は、 PATH のディレクトリから名前が CommandPrefix で始まる実行ファイルを探し、 CommandPrefix を取り除いた名前とパスの対応を返します。
+// exec.LookPath と同様に、同じ名前の場合は PATH で先に現れるものが優先されます。
+// 実行ファイルへのシンボリックリンクは辿り、カレントディレクトリの実行ファイルを実行しないよう、 PATH の空や相対パスのディレクトリは飛ばします。
+//
+// Discover looks for executables whose names start with CommandPrefix in the directories of PATH, and returns their names without CommandPrefix mapped to their paths.
+// As with exec.LookPath, the one appearing first in PATH wins for the same name.
+// Symlinks to executables are followed, and empty and relative directories in PATH are skipped, so that executables in the current directory are never run.
+func Discover() map[string]string {
+	found := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if !filepath.IsAbs(dir) {
+			continue // en: as exec.LookPath does, never run executables in the current directory
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), CommandPrefix)
+			if !ok || name == "" || !executable(filepath.Join(dir, entry.Name())) {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if _, ok := found[name]; !ok {
+				found[name] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+	return found
+}
+
+func executable(path string) bool {
+	info, err := os.Stat(path) // en: follow symlinks, the usual layout of package managers
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(path), ".exe")
+	}
+	return info.Mode().Perm()&0o111 != 0
+}
+
+// =================================================================================
+// Defined errors that you can handle with errors.Is / errors.As
+// =================================================================================
+
+var ErrProtocol = fmt.Errorf("protocol violation")
+var ErrClosed = fmt.Errorf("plugin closed")
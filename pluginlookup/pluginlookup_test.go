@@ -0,0 +1,242 @@
+package pluginlookup_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/pluginlookup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain は、 TEMPURA_TEST_PLUGIN が設定されている場合、テストの実行ファイルをプラグインとして動かします。
+//
+// TestMain runs the test executable as a plugin if TEMPURA_TEST_PLUGIN is set.
+func TestMain(m *testing.M) {
+	switch os.Getenv("TEMPURA_TEST_PLUGIN") {
+	case "":
+		os.Exit(m.Run())
+	case "serve":
+		info := pluginlookup.Info{Protocol: pluginlookup.ProtocolVersion, Description: "test plugin"}
+		lookup := tempura.FuncWithContextError(func(ctx context.Context, key string) (any, bool, error) {
+			switch key {
+			case "DB_PASS":
+				return "p4ss", true, nil
+			case "PORT":
+				return 5432, true, nil
+			case "SLOW":
+				time.Sleep(time.Second)
+				return "slow", true, nil
+			case "FAIL":
+				return nil, false, errors.New("permission denied")
+			case "EXIT":
+				os.Exit(1)
+			}
+			return nil, false, nil
+		})
+		if err := pluginlookup.Serve(context.Background(), os.Stdin, os.Stdout, info, lookup); err != nil {
+			os.Exit(1)
+		}
+	case "old":
+		fmt.Println(`{"protocol":0}`)
+	case "silent":
+		time.Sleep(time.Minute)
+	case "deaf":
+		_ = json.NewEncoder(os.Stdout).Encode(pluginlookup.Info{Protocol: pluginlookup.ProtocolVersion})
+		time.Sleep(time.Minute) // en: never reads stdin
+	}
+	os.Exit(0)
+}
+
+func newPlugin(t *testing.T, mode string) *pluginlookup.Plugin {
+	t.Helper()
+
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	p := pluginlookup.New(pluginlookup.Config{
+		Command: exe,
+		Env:     []string{"TEMPURA_TEST_PLUGIN=" + mode},
+		Timeout: 500 * time.Millisecond,
+	})
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestPlugin_Lookup(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("foo"): newPlugin(t, "serve").Lookup(),
+	}.BindContext(context.Background())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "string",
+			args:     []string{"foo.DB_PASS"},
+			expected: "p4ss",
+		},
+		{
+			name:     "number",
+			args:     []string{"foo.PORT"},
+			expected: json.Number("5432"),
+		},
+		{
+			name:     "fallback",
+			args:     []string{"foo.MISSING", "foo.DB_PASS"},
+			expected: "p4ss",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "not found",
+			args: []string{"foo.MISSING"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+		{
+			name: "plugin error",
+			args: []string{"foo.FAIL"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "permission denied")
+			},
+		},
+		{
+			name: "timeout",
+			args: []string{"foo.SLOW"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestPlugin_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	lookup := newPlugin(t, "serve").Lookup()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, found, err := lookup(context.Background(), "DB_PASS")
+			assert.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, "p4ss", val)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPlugin_Restart(t *testing.T) {
+	t.Parallel()
+
+	lookup := newPlugin(t, "serve").Lookup()
+	_, _, err := lookup(context.Background(), "EXIT")
+	assert.ErrorContains(t, err, "exited")
+
+	val, found, err := lookup(context.Background(), "DB_PASS")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "p4ss", val)
+}
+
+func TestPlugin_NotReading(t *testing.T) {
+	t.Parallel()
+
+	lookup := newPlugin(t, "deaf").Lookup()
+	key := strings.Repeat("K", 1<<20) // en: larger than the pipe buffer
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		_, _, err := lookup(context.Background(), key)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	}
+}
+
+func TestPlugin_Info(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serve", func(t *testing.T) {
+		t.Parallel()
+
+		info, err := newPlugin(t, "serve").Info(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, pluginlookup.Info{Protocol: pluginlookup.ProtocolVersion, Description: "test plugin"}, info)
+	})
+
+	t.Run("unsupported protocol", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newPlugin(t, "old").Info(context.Background())
+		assert.ErrorIs(t, err, pluginlookup.ErrProtocol)
+	})
+
+	t.Run("no handshake", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newPlugin(t, "silent").Info(context.Background())
+		assert.ErrorIs(t, err, pluginlookup.ErrProtocol)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		t.Parallel()
+
+		p := newPlugin(t, "serve")
+		require.NoError(t, p.Close())
+		_, err := p.Info(context.Background())
+		assert.ErrorIs(t, err, pluginlookup.ErrClosed)
+	})
+}
+
+func TestDiscover(t *testing.T) {
+	dir, shadowed, cellar := t.TempDir(), t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tempura-provider-foo"), []byte("#!/bin/sh\n"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(cellar, "tempura-provider-bar"), []byte("#!/bin/sh\n"), 0o755))
+	require.NoError(t, os.Symlink(filepath.Join(cellar, "tempura-provider-bar"), filepath.Join(dir, "tempura-provider-bar")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tempura-provider-data"), []byte("{}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other"), []byte("#!/bin/sh\n"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(shadowed, "tempura-provider-foo"), []byte("#!/bin/sh\n"), 0o755))
+	local := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(local, "tempura-provider-local"), []byte("#!/bin/sh\n"), 0o755))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	relative, err := filepath.Rel(wd, local)
+	require.NoError(t, err)
+	t.Setenv("PATH", strings.Join([]string{dir, shadowed, "", relative}, string(filepath.ListSeparator)))
+
+	assert.Equal(t, map[string]string{
+		"foo": filepath.Join(dir, "tempura-provider-foo"),
+		"bar": filepath.Join(dir, "tempura-provider-bar"),
+	}, pluginlookup.Discover())
+}
@@ -0,0 +1,61 @@
+// Package pongotempura は、 tempura の探索を Django 風のテンプレートエンジンである flosch/pongo2 の関数とフィルターとして提供します。
+// pongo2 のテンプレートも、 text/template のコードと同じ探索関数の登録で秘密情報や環境変数を解決できます。
+//
+// Package pongotempura provides lookups of tempura as a function and a filter of flosch/pongo2, a Django-style template engine.
+// pongo2 templates can resolve secrets and environment values through the same registrations of lookup functions as text/template code.
+//
+//	tpl := pongo2.Must(pongo2.FromString(`db_pass: {{ secret("vault.DB_PASS", "env.DB_PASS") }}`))
+//	s, err := tpl.Execute(pongotempura.Context("secret", secrets.BindContext(ctx)))
+package pongotempura
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/flosch/pongo2/v6"
+)
+
+// Lookup は、 tempura.MultiLookup と *tempura.MultiLookupContext を表します。
+//
+// Lookup represents tempura.MultiLookup and *tempura.MultiLookupContext.
+type Lookup interface {
+	FuncMapValue(args ...string) (any, error)
+}
+
+// Context は、 m の FuncMapValue を name という関数として持つ pongo2.Context を返します。
+// 描画ごとに BindContext した *tempura.MultiLookupContext を渡せます。他の変数と合わせる場合は pongo2.Context.Update を使ってください。
+//
+// Context returns a pongo2.Context with FuncMapValue of m as the function name.
+// A *tempura.MultiLookupContext bound per render can be passed. Use pongo2.Context.Update to combine it with other variables.
+func Context(name string, m Lookup) pongo2.Context {
+	return pongo2.Context{name: m.FuncMapValue}
+}
+
+// Filter は、入力をキーとして m で解決する pongo2.FilterFunction を返します。
+// キーが見つからない場合やどの prefix にも一致しない場合は、フィルターの引数があればその値を返します。
+// pongo2 のフィルターはプロセス全体で共有されるため、 m には描画をまたいで使える tempura.MultiLookup などを渡してください。
+//
+// Filter returns a pongo2.FilterFunction resolving the input as a key with m.
+// If the key is not found or matches no prefix, it returns the parameter of the filter if given.
+// Filters of pongo2 are shared process-wide, so pass m usable across renders, such as a tempura.MultiLookup.
+//
+//	pongo2.RegisterFilter("lookup", pongotempura.Filter(envs))
+//	tpl := pongo2.Must(pongo2.FromString(`host: {{ "env.DB_HOST"|lookup:"localhost" }}`))
+func Filter(m Lookup) pongo2.FilterFunction {
+	return func(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+		key := in.String()
+		val, err := m.FuncMapValue(key)
+		if err != nil {
+			if !param.IsNil() && isMissing(err) {
+				return param, nil
+			}
+			return nil, &pongo2.Error{Sender: "filter:tempura", OrigError: fmt.Errorf("failed to resolve %q: %w", key, err)}
+		}
+		return pongo2.AsValue(val), nil
+	}
+}
+
+func isMissing(err error) bool {
+	return errors.Is(err, tempura.ErrNotFound) || errors.Is(err, tempura.ErrMatchFailed)
+}
@@ -0,0 +1,100 @@
+package pongotempura_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/pongotempura"
+	"github.com/flosch/pongo2/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var lookup = tempura.MultiLookup{
+	tempura.DotPrefix("env"): tempura.Func(func(key string) (string, bool) { return "env-of-" + key, key != "MISSING" }),
+}
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "function", text: `{{ secret("env.HOST") }}`, expected: "env-of-HOST"},
+		{name: "fallback", text: `{{ secret("env.MISSING", "env.HOST") }}`, expected: "env-of-HOST"},
+		// ==================== INVALID CASES ====================
+		{
+			name: "not found",
+			text: `{{ secret("env.MISSING") }}`,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, tempura.ErrNotFound.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tpl, err := pongo2.FromString(tt.text)
+			require.NoError(t, err)
+			s, err := tpl.Execute(pongotempura.Context("secret", lookup.BindContext(context.Background())))
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, s)
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	set := pongo2.NewSet("filter", pongo2.DefaultLoader)
+	require.NoError(t, pongo2.RegisterFilter("tempura_test_lookup", pongotempura.Filter(lookup)))
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "filter", text: `{{ "env.HOST"|tempura_test_lookup }}`, expected: "env-of-HOST"},
+		{name: "default unused", text: `{{ "env.HOST"|tempura_test_lookup:"localhost" }}`, expected: "env-of-HOST"},
+		{name: "default for not found", text: `{{ "env.MISSING"|tempura_test_lookup:"localhost" }}`, expected: "localhost"},
+		// ==================== INVALID CASES ====================
+		{
+			name: "not found",
+			text: `{{ "env.MISSING"|tempura_test_lookup }}`,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, tempura.ErrNotFound.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tpl, err := set.FromString(tt.text)
+			require.NoError(t, err)
+			s, err := tpl.Execute(nil)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, s)
+		})
+	}
+}
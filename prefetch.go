@@ -0,0 +1,25 @@
+package tempura
+
+import (
+	"context"
+	"text/template"
+)
+
+// Prefetch は、解析済みのテンプレート t から name という関数の呼び出しを全て抽出し、 Execute の前にそれらを並行に解決してキャッシュに載せます。
+// 描画中に逐次行われる N 回のリモート呼び出しを、1回の並行なウォームアップにまとめることができます。
+// キャッシュ (WithCache または ctx の WithRenderCache) が無い場合、解決した値は再利用されません。
+//
+// Prefetch extracts every call of the function name from the parsed template t, and resolves them concurrently before Execute to prime the caches.
+// It collapses N sequential remote calls during render into one parallel warm-up phase.
+// Without a cache (WithCache or WithRenderCache of ctx), the resolved values are not reused.
+//
+//	ctx = tempura.WithRenderCache(ctx)
+//	lookup := secrets.BindContext(ctx)
+//	tpl := template.Must(template.New("").Funcs(template.FuncMap{"secret": lookup.FuncMapValue}).Parse(text))
+//	if err := lookup.Prefetch(ctx, tpl, "secret"); err != nil {
+//		return err
+//	}
+//	return tpl.Execute(w, data)
+func (m *MultiLookupContext) Prefetch(ctx context.Context, t *template.Template, name string) error {
+	return m.warm(ctx, lookupCalls(t, name))
+}
@@ -0,0 +1,88 @@
+package tempura_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiLookupContext_Prefetch(t *testing.T) {
+	t.Parallel()
+
+	mu := &sync.Mutex{}
+	var fetched []string
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetched = append(fetched, key)
+		return "secret-of-" + key, key != "MISSING", nil
+	}
+	keyAsValue := func(val string) (string, bool) {
+		return val, true
+	}
+
+	ctx := tempura.WithRenderCache(context.Background())
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"):  tempura.FuncWithContextError(fetchSecret),
+		tempura.DotPrefix("default"): tempura.Func(keyAsValue),
+	}.BindContext(ctx)
+
+	const text = `{{ define "db" }}{{ secret "secret.DB_PASS" }}{{ end -}}
+{{ secret "secret.API_KEY" }}
+{{ if .Enabled }}{{ secret "secret.MISSING" "default.none" }}{{ end }}
+{{ range .Items }}{{ secret "secret.API_KEY" }}{{ end }}
+{{ template "db" }}`
+	tpl := template.Must(template.New("root").Funcs(template.FuncMap{
+		"secret": lookup.FuncMapValue,
+	}).Parse(text))
+
+	require.NoError(t, lookup.Prefetch(ctx, tpl, "secret"))
+	assert.ElementsMatch(t, []string{"DB_PASS", "API_KEY", "MISSING"}, fetched)
+
+	start := len(fetched)
+	buf := &strings.Builder{}
+	require.NoError(t, tpl.Execute(buf, map[string]any{"Enabled": true, "Items": []int{1, 2}}))
+	assert.Equal(t, "secret-of-API_KEY\nnone\nsecret-of-API_KEYsecret-of-API_KEY\nsecret-of-DB_PASS", buf.String())
+	assert.Equal(t, []string{"MISSING"}, fetched[start:], "only the missing key should be fetched again")
+}
+
+func TestMultiLookupContext_Prefetch_Error(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("env"): tempura.Func(func(string) (string, bool) { return "", false }),
+	}.BindContext(context.Background(), tempura.WithCache(tempura.NewTTLCache(time.Minute)))
+
+	tpl := template.Must(template.New("").Funcs(template.FuncMap{
+		"env": lookup.FuncMapValue,
+	}).Parse(`{{ env "env.A" }}{{ env "evn.B" }}`))
+
+	err := lookup.Prefetch(context.Background(), tpl, "env")
+	assert.ErrorIs(t, err, tempura.ErrNotFound)
+	assert.ErrorIs(t, err, tempura.ErrMatchFailed)
+}
+
+func TestMultiLookupContext_Prefetch_DynamicArgs(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"):  tempura.Func(func(key string) (string, bool) { return "secret-of-" + key, key != "MISSING" }),
+		tempura.DotPrefix("default"): tempura.Func(func(key string) (string, bool) { return key, true }),
+	}.BindContext(context.Background())
+
+	tpl := template.Must(template.New("").Funcs(template.FuncMap{
+		"secret": lookup.FuncMapValue,
+	}).Parse(`{{ secret "secret.MISSING" .Fallback }} {{ "default.piped" | secret "secret.MISSING" }} {{ secret "secret.API_KEY" }}`))
+
+	require.NoError(t, lookup.Prefetch(context.Background(), tpl, "secret"), "calls with dynamic args should not be warmed by their literal args alone")
+	buf := &strings.Builder{}
+	require.NoError(t, tpl.Execute(buf, map[string]any{"Fallback": "default.data"}))
+	assert.Equal(t, "data piped secret-of-API_KEY", buf.String())
+}
@@ -0,0 +1,129 @@
+package tempura
+
+import "strings"
+
+// =================================================================================
+// Radix-tree matcher for large prefix sets
+// =================================================================================
+
+// LiteralPrefix は、一致判定がリテラルな文字列の前方一致だけで決まる Prefix です。
+// LiteralPrefix を満たす prefix は、 BindContext 時に基数木に索引付けされ、登録数に比例しない時間で照合されます。
+// 正規表現や glob などそれ以外の Prefix は、従来通り1つずつ Match で照合されます。
+//
+// LiteralPrefix is a Prefix whose matching is determined solely by a literal string prefix.
+// Prefixes satisfying LiteralPrefix are indexed in a radix tree at BindContext and matched in time independent of the number of registrations.
+// Other Prefixes, such as regex or glob matchers, are still matched one by one with Match.
+type LiteralPrefix interface {
+	Prefix
+	// Literal は区切り文字を含む前方一致の文字列を返します。例: DotPrefix("env") は "env." を返します。
+	// Literal returns the literal text including the separator. e.g. DotPrefix("env") returns "env.".
+	Literal() string
+}
+
+func (p DotPrefix) Literal() string {
+	return string(p) + "."
+}
+
+func (p SlashPrefix) Literal() string {
+	return string(p) + "/"
+}
+
+type registration struct {
+	prefix Prefix
+	fn     LookupFunc
+}
+
+// prefixIndex は、 LiteralPrefix を基数木に、それ以外の Prefix をスライスに保持します。
+//
+// prefixIndex holds LiteralPrefixes in a radix tree and the other Prefixes in a slice.
+type prefixIndex struct {
+	root    radixNode
+	dynamic []registration
+}
+
+type radixNode struct {
+	label    string
+	children []*radixNode
+	regs     []registration
+}
+
+func newPrefixIndex(m MultiLookup) *prefixIndex {
+	idx := &prefixIndex{}
+	for prefix, fn := range m {
+		reg := registration{prefix: prefix, fn: fn}
+		if lp, ok := prefix.(LiteralPrefix); ok {
+			idx.root.insert(lp.Literal(), reg)
+		} else {
+			idx.dynamic = append(idx.dynamic, reg)
+		}
+	}
+	return idx
+}
+
+func (n *radixNode) insert(key string, reg registration) {
+	for {
+		if key == "" {
+			n.regs = append(n.regs, reg)
+			return
+		}
+
+		var child *radixNode
+		for _, c := range n.children {
+			if c.label[0] == key[0] {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			n.children = append(n.children, &radixNode{label: key, regs: []registration{reg}})
+			return
+		}
+
+		common := commonPrefixLen(child.label, key)
+		if common < len(child.label) {
+			// en: Split the edge so that the common part becomes its own node
+			split := &radixNode{label: child.label[common:], children: child.children, regs: child.regs}
+			child.label = child.label[:common]
+			child.children = []*radixNode{split}
+			child.regs = nil
+		}
+		n, key = child, key[common:]
+	}
+}
+
+// match は arg に一致する全ての登録を、短い prefix から順に dst に追加して返します。索引付けされていない Prefix は最後に追加されます。
+//
+// match appends all registrations matching arg to dst, shorter prefixes first, and returns it. Prefixes not indexed come last.
+func (idx *prefixIndex) match(arg string, dst []registration) []registration {
+	n, rest := &idx.root, arg
+	for {
+		dst = append(dst, n.regs...)
+
+		var next *radixNode
+		for _, c := range n.children {
+			if strings.HasPrefix(rest, c.label) {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		n, rest = next, rest[len(next.label):]
+	}
+
+	for _, reg := range idx.dynamic {
+		if reg.prefix.Match(arg) {
+			dst = append(dst, reg)
+		}
+	}
+	return dst
+}
+
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
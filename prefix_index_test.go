@@ -0,0 +1,103 @@
+package tempura_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+)
+
+// regexPrefix is a Prefix that cannot be indexed.
+type regexPrefix struct {
+	re *regexp.Regexp
+}
+
+func (p regexPrefix) Match(s string) bool {
+	return p.re.MatchString(s)
+}
+
+func (p regexPrefix) Strip(s string) string {
+	return p.re.ReplaceAllString(s, "")
+}
+
+func TestMultiLookupContext_PrefixIndex(t *testing.T) {
+	t.Parallel()
+
+	named := func(name string) tempura.LookupAny {
+		return tempura.Func(func(key string) (string, bool) {
+			return name + ":" + key, true
+		})
+	}
+	never := tempura.Func(func(key string) (string, bool) {
+		return "", false
+	})
+
+	m := tempura.MultiLookup{
+		tempura.DotPrefix("env"):                     named("dot-env"),
+		tempura.SlashPrefix("env"):                   named("slash-env"),
+		tempura.DotPrefix("environment"):             named("dot-environment"),
+		tempura.DotPrefix("app"):                     never,
+		tempura.DotPrefix("app.db"):                  named("dot-app.db"),
+		regexPrefix{regexp.MustCompile(`^v[0-9]+:`)}: named("regex"),
+	}
+	for i := 0; i < 200; i++ {
+		m[tempura.DotPrefix(fmt.Sprintf("p%03d", i))] = named(fmt.Sprintf("p%03d", i))
+	}
+	lookup := m.BindContext(context.TODO())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "dot", args: []string{"env.HOME"}, expected: "dot-env:HOME"},
+		{name: "slash", args: []string{"env/HOME"}, expected: "slash-env:HOME"},
+		{name: "sharing the head of another prefix", args: []string{"environment.HOME"}, expected: "dot-environment:HOME"},
+		{name: "nested prefixes", args: []string{"app.db.HOST"}, expected: "dot-app.db:HOST"},
+		{name: "one of many prefixes", args: []string{"p123.KEY"}, expected: "p123:KEY"},
+		{name: "prefix that is not indexed", args: []string{"v2:KEY"}, expected: "regex:KEY"},
+		// ==================== INVALID CASES ====================
+		{
+			name: "no prefix matched",
+			args: []string{"envi.HOME", "p1234.KEY", "p12.KEY"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrMatchFailed)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func BenchmarkMultiLookupContext_FuncMapValue_ManyPrefixes(b *testing.B) {
+	m := tempura.MultiLookup{}
+	for i := 0; i < 500; i++ {
+		m[tempura.DotPrefix(fmt.Sprintf("provider%03d", i))] = tempura.Func(os.LookupEnv)
+	}
+	m[tempura.DotPrefix("env")] = tempura.Func(func(key string) (string, bool) {
+		return key, true
+	})
+	lookup := m.BindContext(context.Background())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = lookup.FuncMapValue("env.HOME")
+	}
+}
@@ -0,0 +1,65 @@
+package tempura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Preload は keys をそれぞれ1つの引数として並行に解決し、キャッシュ (WithCache および ctx の WithRenderCache) に載せます。
+// サーバーの起動時に呼び出すことで、リクエストの途中ではなく起動時に設定の不足を検出できます。
+// 解決できなかった全てのキーのエラーを errors.Join でまとめて返します。
+//
+// Preload resolves each of keys as a single arg concurrently and primes the caches (WithCache and WithRenderCache of ctx).
+// Calling it at server startup makes missing configuration fail fast instead of failing mid-request.
+// It returns the errors of all keys that could not be resolved, joined by errors.Join.
+func (m *MultiLookupContext) Preload(ctx context.Context, keys ...string) error {
+	calls := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		calls = append(calls, []string{key})
+	}
+	return m.warm(ctx, calls)
+}
+
+// warm は、それぞれの引数の列を1回の FuncMapValue 呼び出しとして並行に解決します。
+//
+// warm resolves each list of args concurrently as a single FuncMapValue call.
+func (m *MultiLookupContext) warm(ctx context.Context, calls [][]string) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	mc := m.withContext(ctx)
+	errs := make([]error, len(calls))
+	wg := &sync.WaitGroup{}
+	for index, args := range calls {
+		index, args := index, args
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := mc.FuncMapValue(args...); err != nil {
+				errs[index] = fmt.Errorf("failed to preload %s: %w", strings.Join(m.keyNames(args), " "), err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// withContext は、登録やオプションを共有したまま ctx に結び付け直した MultiLookupContext を返します。
+//
+// withContext returns a MultiLookupContext rebound to ctx, sharing the registrations and options.
+func (m *MultiLookupContext) withContext(ctx context.Context) *MultiLookupContext {
+	return &MultiLookupContext{
+		MultiLookup: m.MultiLookup,
+		Ctx:         ctx,
+		opts:        m.opts,
+		index:       m.index,
+		inflight:    m.inflight,
+		readiness:   m.readiness,
+		stats:       m.stats,
+	}
+}
@@ -0,0 +1,49 @@
+package tempura_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiLookupContext_Preload(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		calls.Add(1)
+		if key == "MISSING" {
+			return "", false, nil
+		}
+		return "secret-of-" + key, true, nil
+	}
+
+	ctx := context.Background()
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}.BindContext(ctx, tempura.WithCache(tempura.NewTTLCache(time.Minute)))
+
+	t.Run("primes the cache", func(t *testing.T) {
+		err := lookup.Preload(ctx, "secret.A", "secret.B")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+
+		val, err := lookup.FuncMapValue("secret.A")
+		assert.NoError(t, err)
+		assert.Equal(t, "secret-of-A", val)
+		assert.Equal(t, int32(2), calls.Load(), "preloaded key should come from the cache")
+	})
+
+	t.Run("reports every unresolvable key", func(t *testing.T) {
+		err := lookup.Preload(ctx, "secret.MISSING", "env.HOME", "secret.C")
+		assert.ErrorIs(t, err, tempura.ErrNotFound)
+		assert.ErrorIs(t, err, tempura.ErrMatchFailed)
+		assert.ErrorContains(t, err, "secret.MISSING")
+		assert.ErrorContains(t, err, "env.HOME")
+		assert.NotContains(t, err.Error(), "secret.C")
+	})
+}
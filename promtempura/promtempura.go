@@ -0,0 +1,67 @@
+// Package promtempura は、探索の結果を Prometheus のメトリクスとして公開する tempura.Metrics を提供します。
+//
+// Package promtempura provides a tempura.Metrics exposing the results of lookups as Prometheus metrics.
+//
+//	metrics := promtempura.New(prometheus.DefaultRegisterer)
+//	lookup := secrets.BindContext(ctx, tempura.WithMetrics(metrics))
+//
+// 以下のメトリクスが prefix ごとに記録されます。
+// The following metrics are recorded per prefix:
+//
+//   - tempura_lookups_total{prefix, outcome}: outcome は found, not_found, error, cache_hit, negative_cache_hit / outcome is one of found, not_found, error, cache_hit and negative_cache_hit
+//   - tempura_lookup_duration_seconds{prefix}: 探索関数の呼び出しにかかった時間 (キャッシュからの解決を除く) / durations of calls of lookup functions, excluding resolutions from caches
+package promtempura
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics は Prometheus のメトリクスを記録する tempura.Metrics です。
+//
+// Metrics is a tempura.Metrics recording Prometheus metrics.
+type Metrics struct {
+	lookups  *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+var _ tempura.Metrics = (*Metrics)(nil)
+
+// New は、メトリクスを reg に登録した Metrics を返します。 reg が nil の場合は登録しません。
+//
+// New returns Metrics whose collectors are registered to reg. If reg is nil, they are not registered.
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tempura",
+			Name:      "lookups_total",
+			Help:      "Number of lookups by prefix and outcome.",
+		}, []string{"prefix", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "tempura",
+			Name:      "lookup_duration_seconds",
+			Help:      "Duration of calls of lookup functions by prefix.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"prefix"}),
+	}
+	if reg != nil {
+		reg.MustRegister(m.lookups, m.duration)
+	}
+	return m
+}
+
+func (m *Metrics) ObserveLookup(prefix tempura.Prefix, outcome string, elapsed time.Duration) {
+	label := ""
+	if prefix != nil {
+		label = fmt.Sprint(prefix)
+	}
+	m.lookups.WithLabelValues(label, outcome).Inc()
+	switch outcome {
+	case "cache_hit", "negative_cache_hit":
+	default:
+		m.duration.WithLabelValues(label).Observe(elapsed.Seconds())
+	}
+}
@@ -0,0 +1,54 @@
+package promtempura_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/promtempura"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errUnavailable = fmt.Errorf("service unavailable")
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	reg := prometheus.NewPedanticRegistry()
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		if key == "FAIL" {
+			return "", false, errUnavailable
+		}
+		return "secret-of-" + key, key != "MISSING", nil
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}.BindContext(context.Background(),
+		tempura.WithMetrics(promtempura.New(reg)),
+		tempura.WithCache(tempura.NewTTLCache(time.Minute)),
+	)
+
+	for _, arg := range []string{"secret.DB_PASS", "secret.DB_PASS", "secret.MISSING", "secret.FAIL"} {
+		_, _ = lookup.FuncMapValue(arg)
+	}
+
+	expected := `
+# HELP tempura_lookups_total Number of lookups by prefix and outcome.
+# TYPE tempura_lookups_total counter
+tempura_lookups_total{outcome="cache_hit",prefix="secret"} 1
+tempura_lookups_total{outcome="error",prefix="secret"} 1
+tempura_lookups_total{outcome="found",prefix="secret"} 1
+tempura_lookups_total{outcome="not_found",prefix="secret"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "tempura_lookups_total"))
+
+	count, err := testutil.GatherAndCount(reg, "tempura_lookup_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "one histogram for the prefix")
+}
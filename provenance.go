@@ -0,0 +1,97 @@
+package tempura
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// =================================================================================
+// Provenance of resolved values scoped to a context
+// =================================================================================
+
+type provenanceKey struct{}
+
+// Provenance は、1つの引数の値をどこから得たかの記録です。値そのものは含みません。
+//
+// Provenance is a record of where the value of an arg came from. It never contains the value itself.
+type Provenance struct {
+	Arg    string
+	Prefix Prefix
+	// Provider は値を返した探索関数の型です。キャッシュから解決された場合は空です。
+	// Provider is the type of the lookup function that returned the value. It is empty if resolved from a cache.
+	Provider string
+	Cached   bool
+	// FetchedAt は探索関数が値を返した時刻です。この描画より前にキャッシュに載った値の場合は、取得した時刻が分からないためゼロ値です。
+	// FetchedAt is when the lookup function returned the value. It is the zero value for values cached before this render, since when they were fetched is unknown.
+	FetchedAt time.Time
+}
+
+// ProvenanceReport は、描画の中で解決された引数ごとの Provenance を集めます。
+//
+// ProvenanceReport collects the Provenance of each arg resolved during a render.
+type ProvenanceReport struct {
+	mu      sync.Mutex
+	entries []Provenance
+	index   map[string]int
+}
+
+// WithProvenance は、描画の中で解決された値の出所を記録する ProvenanceReport を ctx に持たせます。
+// 描画ごとにこの ctx で BindContext(ctx) し、描画の後に Entries を呼び出すことで、生成された設定のコンプライアンスレビューに必要な記録が得られます。
+//
+// WithProvenance returns a ctx carrying a ProvenanceReport that records where the values resolved during a render came from.
+// Call BindContext(ctx) with this ctx for each render and call Entries after it to get the records needed for compliance reviews of generated configuration.
+//
+//	ctx, report := tempura.WithProvenance(ctx)
+//	lookup := secrets.BindContext(ctx)
+//	tpl.Funcs(template.FuncMap{"secret": lookup.FuncMapValue}).Execute(w, data)
+//	for _, p := range report.Entries() { ... }
+func WithProvenance(ctx context.Context) (context.Context, *ProvenanceReport) {
+	report := &ProvenanceReport{index: make(map[string]int)}
+	return context.WithValue(ctx, provenanceKey{}, report), report
+}
+
+// Entries は、解決された引数ごとの Provenance を最初に解決された順に返します。
+//
+// Entries returns the Provenance of each resolved arg in the order they were first resolved.
+func (r *ProvenanceReport) Entries() []Provenance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Provenance(nil), r.entries...)
+}
+
+func (r *ProvenanceReport) record(p Provenance) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.index[p.Arg]; ok {
+		return // en: keep the first record, which tells when the value was actually fetched
+	}
+	r.index[p.Arg] = len(r.entries)
+	r.entries = append(r.entries, p)
+}
+
+// recordProvenance は、 ctx が ProvenanceReport を持つ場合に、 arg の値の出所を記録します。
+//
+// recordProvenance records where the value of arg came from, if ctx carries a ProvenanceReport.
+func (m *MultiLookupContext) recordProvenance(ctx context.Context, arg string, prefix Prefix, fn LookupFunc, cached bool) {
+	if ctx == nil {
+		return
+	}
+	report, ok := ctx.Value(provenanceKey{}).(*ProvenanceReport)
+	if !ok {
+		return
+	}
+
+	p := Provenance{Arg: arg, Prefix: prefix, Cached: cached}
+	if cached {
+		var buf [4]registration
+		if regs := m.matchInto(arg, buf[:0]); len(regs) > 0 {
+			p.Prefix = regs[0].prefix
+		}
+	} else {
+		p.Provider, p.FetchedAt = providerType(fn), time.Now()
+	}
+	report.record(p)
+}
@@ -0,0 +1,51 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithProvenance(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		return "secret-of-" + key, key != "MISSING", nil
+	}
+	cache := tempura.NewTTLCache(time.Minute)
+	cache.Set("secret.CACHED", "cached")
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+		tempura.DotPrefix("env"):    tempura.Func(func(key string) (string, bool) { return "env-of-" + key, true }),
+	}
+
+	ctx, report := tempura.WithProvenance(context.Background())
+	lookup := secrets.BindContext(tempura.WithRenderCache(ctx), tempura.WithCache(cache))
+	tpl := template.Must(template.New("config").Funcs(template.FuncMap{"lookup": lookup.FuncMapValue}).Parse(
+		`{{ lookup "secret.DB_PASS" }} {{ lookup "secret.MISSING" "env.HOME" }} {{ lookup "secret.CACHED" }} {{ lookup "secret.DB_PASS" }}`,
+	))
+	before := time.Now()
+	require.NoError(t, tpl.Execute(&bytes.Buffer{}, nil))
+
+	entries := report.Entries()
+	require.Len(t, entries, 3)
+	for i := range entries {
+		if entries[i].Cached {
+			assert.True(t, entries[i].FetchedAt.IsZero())
+		} else {
+			assert.False(t, entries[i].FetchedAt.Before(before))
+		}
+		entries[i].FetchedAt = time.Time{}
+	}
+	assert.Equal(t, []tempura.Provenance{
+		{Arg: "secret.DB_PASS", Prefix: tempura.DotPrefix("secret"), Provider: "LookupAnyWithContextError"},
+		{Arg: "env.HOME", Prefix: tempura.DotPrefix("env"), Provider: "LookupAny"},
+		{Arg: "secret.CACHED", Prefix: tempura.DotPrefix("secret"), Cached: true},
+	}, entries)
+}
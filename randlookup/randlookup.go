@@ -0,0 +1,141 @@
+// Package randlookup は、UUID やトークンなどのランダムな値を生成する探索関数を提供します。
+//
+// Package randlookup provides a lookup function that generates random values such as UUIDs and tokens.
+package randlookup
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ebi-yade/go-tempura"
+)
+
+const alnum = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// MaxLength は、 "hex:N" と "alnum:N" の N の上限です。テンプレートを書ける利用者が巨大なメモリの確保を引き起こせないようにします。
+//
+// MaxLength is the upper limit of N of "hex:N" and "alnum:N". It keeps those who can write templates from forcing huge allocations.
+const MaxLength = 4096
+
+// New は、prefixを取り除いたキーに応じて src から読み出したランダムな値を返す探索関数を返します。
+// src が nil の場合は crypto/rand.Reader が使われます。
+//
+//   - "uuid": UUID version 4
+//   - "hex:N": N 文字の16進数文字列 / a hexadecimal string of N characters
+//   - "alnum:N": N 文字の英数字文字列 / an alphanumeric string of N characters
+//
+// N は MaxLength 以下でなければなりません。
+// N must not exceed MaxLength.
+//
+// New returns a lookup function that returns a random value read from src depending on the key with the prefix removed.
+// If src is nil, crypto/rand.Reader is used.
+func New(src io.Reader) tempura.LookupAnyWithError {
+	if src == nil {
+		src = rand.Reader
+	}
+
+	return tempura.FuncWithError(func(key string) (string, bool, error) {
+		kind, arg, _ := strings.Cut(key, ":")
+		switch kind {
+		case "uuid":
+			val, err := uuid(src)
+			return val, err == nil, err
+
+		case "hex":
+			n, err := parseLength(key, arg)
+			if err != nil {
+				return "", false, err
+			}
+			val, err := randomHex(src, n)
+			return val, err == nil, err
+
+		case "alnum":
+			n, err := parseLength(key, arg)
+			if err != nil {
+				return "", false, err
+			}
+			val, err := randomAlnum(src, n)
+			return val, err == nil, err
+		}
+
+		return "", false, nil
+	})
+}
+
+// Seeded は seed から決定的なバイト列を生成します。テスト用途であり、秘密情報の生成には使わないでください。
+//
+// Seeded generates a deterministic byte sequence from seed. It is meant for tests: never use it to generate secrets.
+func Seeded(seed int64) io.Reader {
+	return &lockedReader{r: mathrand.New(mathrand.NewSource(seed))}
+}
+
+type lockedReader struct {
+	mu sync.Mutex
+	r  io.Reader
+}
+
+func (l *lockedReader) Read(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Read(p)
+}
+
+func parseLength(key, arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid length in %q: must be a positive integer", key)
+	}
+	if n > MaxLength {
+		return 0, fmt.Errorf("invalid length in %q: must not exceed %d", key, MaxLength)
+	}
+	return n, nil
+}
+
+func uuid(src io.Reader) (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(src, b); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+func randomHex(src io.Reader, n int) (string, error) {
+	b := make([]byte, (n+1)/2)
+	if _, err := io.ReadFull(src, b); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return hex.EncodeToString(b)[:n], nil
+}
+
+func randomAlnum(src io.Reader, n int) (string, error) {
+	// 偏りをなくすため、 len(alnum) の倍数に収まらないバイトは捨てる
+	// en: Reject bytes beyond the largest multiple of len(alnum) to avoid modulo bias
+	const limit = 256 - 256%len(alnum)
+
+	out := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(out) < n {
+		if _, err := io.ReadFull(src, buf); err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			out = append(out, alnum[int(b)%len(alnum)])
+			if len(out) == n {
+				break
+			}
+		}
+	}
+	return string(out), nil
+}
@@ -0,0 +1,96 @@
+package randlookup_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/randlookup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("rand"): randlookup.New(nil),
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		pattern  string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:    "uuid",
+			args:    []string{"rand.uuid"},
+			pattern: `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+		},
+		{
+			name:    "hex",
+			args:    []string{"rand.hex:32"},
+			pattern: `^[0-9a-f]{32}$`,
+		},
+		{
+			name:    "hex with odd length",
+			args:    []string{"rand.hex:7"},
+			pattern: `^[0-9a-f]{7}$`,
+		},
+		{
+			name:    "alnum",
+			args:    []string{"rand.alnum:16"},
+			pattern: `^[0-9A-Za-z]{16}$`,
+		},
+
+		// ==================== INVALID CASES ====================
+		{
+			name: "invalid length",
+			args: []string{"rand.hex:abc"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "invalid length")
+			},
+		},
+		{
+			name: "too long",
+			args: []string{"rand.alnum:1000000000"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "must not exceed 4096")
+			},
+		},
+		{
+			name: "unknown kind",
+			args: []string{"rand.uuuid"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Regexp(t, regexp.MustCompile(tt.pattern), val)
+		})
+	}
+}
+
+func TestSeeded(t *testing.T) {
+	t.Parallel()
+
+	first, ok, err := randlookup.New(randlookup.Seeded(42))("alnum:24")
+	require.NoError(t, err)
+	require.True(t, ok)
+	second, _, _ := randlookup.New(randlookup.Seeded(42))("alnum:24")
+	other, _, _ := randlookup.New(randlookup.Seeded(43))("alnum:24")
+
+	assert.Equal(t, first, second, "same seed should generate the same value")
+	assert.NotEqual(t, first, other, "different seeds should generate different values")
+}
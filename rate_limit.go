@@ -0,0 +1,38 @@
+package tempura
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimit は、 fn の呼び出しをトークンバケットで制限するミドルウェアです。
+// 1秒あたり limit 回、最大 burst 回まで連続で呼び出すことができ、それを超える呼び出しはトークンが補充されるまで待機します。
+// 待機中に ctx が終了した場合や、待機が ctx の期限を超える場合は、 TimeoutError または CanceledError になります。
+// prefix ごとに RateLimit で包むことで、 AWS SSM のように TPS の制限が厳しい API へのリクエストを、スロットリングエラーになる前に tempura の中で抑えられます。
+//
+// RateLimit is a middleware that throttles calls of fn with a token bucket.
+// It allows limit calls per second with bursts of up to burst calls, and further calls wait until a token is refilled.
+// If ctx is done while waiting, or waiting would exceed the deadline of ctx, the lookup fails with a TimeoutError or a CanceledError.
+// Wrapping each prefix with RateLimit keeps requests against APIs with strict TPS limits, such as AWS SSM, throttled inside tempura before they fail with throttling errors.
+//
+//	lookup := tempura.MultiLookup{
+//		tempura.DotPrefix("ssm"): tempura.RateLimit(ssmLookup, 10, 5),
+//	}.BindContext(ctx)
+func RateLimit(fn LookupFunc, limit rate.Limit, burst int) LookupAnyWithContextError {
+	limiter := rate.NewLimiter(limit, burst)
+	next := withContextError("RateLimit", fn)
+	return func(ctx context.Context, val string) (any, bool, error) {
+		if err := limiter.Wait(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil, false, ctx.Err() // en: classified as TimeoutError or CanceledError
+			}
+			if _, ok := ctx.Deadline(); ok {
+				return nil, false, fmt.Errorf("rate limit exceeded: %w: %v", context.DeadlineExceeded, err) // en: waiting would exceed the deadline
+			}
+			return nil, false, fmt.Errorf("rate limit exceeded: %w", err)
+		}
+		return next(ctx, val)
+	}
+}
@@ -0,0 +1,107 @@
+package tempura_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	ssm := tempura.Func(func(key string) (string, bool) {
+		calls.Add(1)
+		return "ssm:" + key, true
+	})
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("ssm"): tempura.RateLimit(ssm, rate.Every(50*time.Millisecond), 1),
+	}.BindContext(context.Background())
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		val, err := lookup.FuncMapValue("ssm.KEY")
+		require.NoError(t, err)
+		assert.Equal(t, "ssm:KEY", val)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond, "the 2nd and 3rd calls should wait for tokens")
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestRateLimit_Invalid(t *testing.T) {
+	t.Parallel()
+
+	ssm := tempura.Func(func(key string) (string, bool) {
+		return "ssm:" + key, true
+	})
+
+	tests := []struct {
+		name        string
+		fn          tempura.LookupFunc
+		timeout     time.Duration
+		cancelAfter time.Duration
+		checkErr    func(t *testing.T, err error)
+	}{
+		// ==================== INVALID CASES ====================
+		{
+			name:    "waiting would exceed the deadline",
+			fn:      ssm,
+			timeout: 10 * time.Millisecond,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "rate limit exceeded")
+				assert.ErrorAs(t, err, &tempura.TimeoutError{})
+			},
+		},
+		{
+			name:        "canceled while waiting",
+			fn:          ssm,
+			cancelAfter: 20 * time.Millisecond,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorAs(t, err, &tempura.CanceledError{})
+				assert.ErrorIs(t, err, context.Canceled)
+			},
+		},
+		{
+			name:    "unsupported function",
+			fn:      nil,
+			timeout: time.Second,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorAs(t, err, &tempura.InvalidFunctionError{})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			limited := tempura.RateLimit(tt.fn, rate.Every(time.Hour), 1)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.timeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			if tt.cancelAfter > 0 {
+				time.AfterFunc(tt.cancelAfter, cancel)
+			}
+			lookup := tempura.MultiLookup{tempura.DotPrefix("ssm"): limited}.BindContext(ctx)
+
+			_, err := lookup.FuncMapValue("ssm.FIRST")
+			if tt.fn == nil {
+				tt.checkErr(t, err)
+				return
+			}
+			require.NoError(t, err)
+			_, err = lookup.FuncMapValue("ssm.SECOND")
+			tt.checkErr(t, err)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Package raymondtempura は、 tempura の探索を Handlebars (Mustache) の Go 実装である aymerick/raymond のヘルパーとして提供します。
+// Go のテンプレートに慣れていないメンバーも Handlebars の構文のまま書き、値の解決は tempura に任せられます。
+//
+// Package raymondtempura provides lookups of tempura as a helper of aymerick/raymond, a Go implementation of Handlebars (Mustache).
+// Template authors unfamiliar with Go templates can keep the Handlebars syntax while resolution stays in tempura.
+//
+//	tpl := raymond.MustParse(`db_pass: {{secret "vault.DB_PASS"}} db_host: {{secret "env.DB_HOST" default="localhost"}}`)
+//	raymondtempura.Register(tpl, "secret", secrets.BindContext(ctx))
+//	s, err := tpl.Exec(nil)
+package raymondtempura
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aymerick/raymond"
+	"github.com/ebi-yade/go-tempura"
+)
+
+// Lookup は、 tempura.MultiLookup と *tempura.MultiLookupContext を表します。
+//
+// Lookup represents tempura.MultiLookup and *tempura.MultiLookupContext.
+type Lookup interface {
+	FuncMapValue(args ...string) (any, error)
+}
+
+// Helper は、1つのキーを m で解決する raymond のヘルパーを返します。
+// キーが見つからない場合やどの prefix にも一致しない場合は、ハッシュ引数 default があればその値を返します。
+// 解決できなかったキーのエラーは、 raymond.Template.Exec の戻り値として返されます。
+//
+// Helper returns a helper of raymond resolving a single key with m.
+// If the key is not found or matches no prefix, it returns the value of the hash argument default if given.
+// Errors of keys that could not be resolved are returned by raymond.Template.Exec.
+func Helper(m Lookup) func(key string, options *raymond.Options) any {
+	return func(key string, options *raymond.Options) any {
+		val, err := m.FuncMapValue(key)
+		if err != nil {
+			if def, ok := options.Hash()["default"]; ok && isMissing(err) {
+				return def
+			}
+			// en: raymond turns panics with errors into the errors returned by Exec
+			panic(fmt.Errorf("failed to resolve %q: %w", key, err))
+		}
+		return val
+	}
+}
+
+// Register は、 Helper(m) を tpl に name というヘルパーとして登録し、 tpl を返します。
+//
+// Register registers Helper(m) to tpl as the helper name, and returns tpl.
+func Register(tpl *raymond.Template, name string, m Lookup) *raymond.Template {
+	tpl.RegisterHelper(name, Helper(m))
+	return tpl
+}
+
+func isMissing(err error) bool {
+	return errors.Is(err, tempura.ErrNotFound) || errors.Is(err, tempura.ErrMatchFailed)
+}
@@ -0,0 +1,58 @@
+package raymondtempura_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aymerick/raymond"
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/raymondtempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("env"): tempura.Func(func(key string) (string, bool) { return "env-of-" + key, key != "MISSING" }),
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "helper", text: `{{#if true}}{{secret "env.HOST"}}{{/if}}`, expected: "env-of-HOST"},
+		{name: "default unused", text: `{{secret "env.HOST" default="localhost"}}`, expected: "env-of-HOST"},
+		{name: "default for not found", text: `{{secret "env.MISSING" default="localhost"}}`, expected: "localhost"},
+		{name: "default for no prefix matched", text: `{{secret "vault.HOST" default="localhost"}}`, expected: "localhost"},
+		// ==================== INVALID CASES ====================
+		{
+			name: "not found",
+			text: `{{secret "env.MISSING"}}`,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tpl, err := raymond.Parse(tt.text)
+			require.NoError(t, err)
+			s, err := raymondtempura.Register(tpl, "secret", lookup.BindContext(context.Background())).Exec(nil)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, s)
+		})
+	}
+}
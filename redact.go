@@ -0,0 +1,110 @@
+package tempura
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// =================================================================================
+// Redaction of key names
+// =================================================================================
+
+// WithRedactKeys は、ログ、エラー、スパン、 Explain に含まれるキーの名前を、その SHA-256 の先頭 12 桁に置き換えます。
+// 値そのものはこのオプションに関係なく出力されませんが、キーの名前 (kms の暗号文や顧客ごとのパスなど) 自体が機密である環境のために使います。
+// 同じキーは常に同じ文字列に置き換えられるため、記録どうしを突き合わせることはできます。
+// Hooks と WithProvenance はプログラムから使う API のため、キーの名前をそのまま受け取ります。
+// 探索関数が返したエラーの文字列は置き換えられないため、探索関数自身がキーや値をエラーに含めないようにしてください。
+// 置き換えの方法を変える場合は WithKeyRedactor を使ってください。
+//
+// WithRedactKeys replaces key names in logs, errors, spans and Explain with the first 12 hex digits of their SHA-256.
+// Values themselves are never emitted regardless of this option; use it in environments where the key name itself, such as a kms ciphertext or a per-customer path, is sensitive.
+// The same key is always replaced with the same string, so records can still be correlated.
+// Hooks and WithProvenance are programmatic APIs and receive key names as they are.
+// Error strings returned by lookup functions are not rewritten, so lookup functions themselves must keep keys and values out of their errors.
+// To change how keys are replaced, use WithKeyRedactor.
+//
+//	lookup := secrets.BindContext(ctx, tempura.WithRedactKeys()) // en: arg=sha256:5e884898da28
+func WithRedactKeys() Option {
+	return WithKeyRedactor(HashKey)
+}
+
+// KeyRedactor は、キーの名前を出力に含めてよい文字列に置き換えます。
+//
+// KeyRedactor replaces a key name with a string that may be included in outputs.
+type KeyRedactor func(key string) string
+
+// WithKeyRedactor は WithRedactKeys と同様に、ログ、エラー、スパン、 Explain に含まれるキーの名前を redactor で置き換えます。
+// エラーの文字列のキーの名前は置き換えられますが、 KeyError などの構造化されたフィールドにはそのままの値が残るため、権限のある利用者は errors.As で取り出せます。
+//
+// WithKeyRedactor replaces key names in logs, errors, spans and Explain with redactor, as WithRedactKeys does.
+// Key names in error strings are replaced, but structured fields such as those of KeyError keep them as they are, so that authorized consumers can retrieve them with errors.As.
+//
+//	lookup := secrets.BindContext(ctx, tempura.WithKeyRedactor(tempura.TruncateKey(10))) // en: arg=secret.CUS...
+func WithKeyRedactor(redactor KeyRedactor) Option {
+	r := &keyRedactor{fn: redactor}
+	return func(o *options) {
+		o.redactor = r
+	}
+}
+
+// HashKey は、キーの名前を "sha256:" とその SHA-256 の先頭 12 桁に置き換えます。
+//
+// HashKey replaces a key name with "sha256:" followed by the first 12 hex digits of its SHA-256.
+func HashKey(key string) string {
+	return redactKey(key)
+}
+
+// TruncateKey は、キーの名前の先頭 n バイトだけを残して "..." を続ける KeyRedactor を返します。
+//
+// TruncateKey returns a KeyRedactor that keeps only the first n bytes of a key name followed by "...".
+func TruncateKey(n int) KeyRedactor {
+	return func(key string) string {
+		if len(key) <= n {
+			return key
+		}
+		return key[:n] + "..."
+	}
+}
+
+// keyRedactor は、エラーの値が比較可能なままでいられるよう、 KeyRedactor をポインタ越しに保持します。
+//
+// keyRedactor holds a KeyRedactor behind a pointer, so that error values stay comparable.
+type keyRedactor struct {
+	fn KeyRedactor
+}
+
+// key は、 r が nil の場合は key をそのまま返します。
+//
+// key returns key as is if r is nil.
+func (r *keyRedactor) key(key string) string {
+	if r == nil {
+		return key
+	}
+	return r.fn(key)
+}
+
+// keyName は、出力に含めるキーの名前を返します。
+//
+// keyName returns the name of key to be included in outputs.
+func (m *MultiLookupContext) keyName(key string) string {
+	return m.opts.redactor.key(key)
+}
+
+// keyNames は keyName を args のそれぞれに適用します。キーの名前を置き換えない場合は args をそのまま返します。
+//
+// keyNames applies keyName to each of args. Without redaction of key names, it returns args as is.
+func (m *MultiLookupContext) keyNames(args []string) []string {
+	if m.opts.redactor == nil {
+		return args
+	}
+	names := make([]string, len(args))
+	for i, arg := range args {
+		names[i] = m.opts.redactor.key(arg)
+	}
+	return names
+}
+
+func redactKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
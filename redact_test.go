@@ -0,0 +1,189 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTracer records everything passed to it as strings.
+type recordingTracer struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (r *recordingTracer) record(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTracer) StartLookup(ctx context.Context, args []string) (context.Context, tempura.SpanEnd) {
+	r.record("lookup %v", args)
+	return ctx, func(outcome string, err error) { r.record("end %s %v", outcome, err) }
+}
+
+func (r *recordingTracer) StartProvider(ctx context.Context, prefix tempura.Prefix, key string, provider string) (context.Context, tempura.SpanEnd) {
+	r.record("provider %v %s %s", prefix, key, provider)
+	return ctx, func(outcome string, err error) { r.record("end %s %v", outcome, err) }
+}
+
+func TestWithRedactKeys(t *testing.T) {
+	t.Parallel()
+
+	const sentinel = "SENTINEL-p@ssw0rd"
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		if key == "FAIL_CUSTOMER_42" {
+			return "", false, errUnavailable
+		}
+		return sentinel, true, nil
+	}
+	hashed := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	}
+
+	tests := []struct {
+		name       string
+		opts       []tempura.Option
+		present    []string
+		notPresent []string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:       "values are never emitted",
+			present:    []string{"DB_PASS_CUSTOMER_42", "FAIL_CUSTOMER_42"},
+			notPresent: []string{sentinel},
+		},
+		{
+			name:       "key names are hashed",
+			opts:       []tempura.Option{tempura.WithRedactKeys()},
+			present:    []string{hashed("secret.DB_PASS_CUSTOMER_42"), hashed("DB_PASS_CUSTOMER_42"), hashed("secret.FAIL_CUSTOMER_42")},
+			notPresent: []string{sentinel, "CUSTOMER_42"},
+		},
+		{
+			name:       "key names are truncated",
+			opts:       []tempura.Option{tempura.WithKeyRedactor(tempura.TruncateKey(10))},
+			present:    []string{"secret.DB_...", "DB_PASS_CU...", "secret.FAI..."},
+			notPresent: []string{sentinel, "CUSTOMER_42"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			tracer := &recordingTracer{}
+			opts := append([]tempura.Option{
+				tempura.WithLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+				tempura.WithTracer(tracer),
+				tempura.WithCache(tempura.NewTTLCache(time.Minute)),
+			}, tt.opts...)
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+			}.BindContext(context.Background(), opts...)
+
+			outputs := &strings.Builder{}
+			for i := 0; i < 2; i++ { // en: the second call hits the cache
+				val, err := lookup.FuncMapValue("secret.DB_PASS_CUSTOMER_42")
+				require.NoError(t, err)
+				require.Equal(t, sentinel, val)
+			}
+			err := lookup.Preload(context.Background(), "secret.FAIL_CUSTOMER_42")
+			require.Error(t, err)
+			outputs.WriteString(err.Error())
+
+			e, err := lookup.Explain(context.Background(), "secret.DB_PASS_CUSTOMER_42")
+			require.NoError(t, err)
+			outputs.WriteString(e.String())
+			b, err := json.Marshal(e)
+			require.NoError(t, err)
+			outputs.Write(b)
+
+			outputs.Write(buf.Bytes())
+			outputs.WriteString(strings.Join(tracer.records, "\n"))
+
+			for _, s := range tt.present {
+				assert.Contains(t, outputs.String(), s)
+			}
+			for _, s := range tt.notPresent {
+				assert.NotContains(t, outputs.String(), s)
+			}
+		})
+	}
+}
+
+func TestWithKeyRedactor_StructuredFields(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		if key == "FAIL_CUSTOMER_42" {
+			return "", false, errUnavailable
+		}
+		return "", false, nil
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}.BindContext(context.Background(), tempura.WithKeyRedactor(func(key string) string { return "<key>" }))
+
+	tests := []struct {
+		name   string
+		args   []string
+		errStr string
+		check  func(t *testing.T, err error)
+	}{
+		// ==================== INVALID CASES ====================
+		{
+			name:   "KeyError keeps the key",
+			args:   []string{"secret.FAIL_CUSTOMER_42"},
+			errStr: "failed to look up <key> by secret (LookupAnyWithContextError): " + errUnavailable.Error(),
+			check: func(t *testing.T, err error) {
+				var ke tempura.KeyError
+				require.ErrorAs(t, err, &ke)
+				assert.Equal(t, "FAIL_CUSTOMER_42", ke.Key)
+			},
+		},
+		{
+			name:   "MatchFailedError keeps the args",
+			args:   []string{"secret.DB_PASS_CUSTOMER_42"},
+			errStr: tempura.ErrNotFound.Error() + ": <key> was not found by [secret]",
+			check: func(t *testing.T, err error) {
+				var me tempura.MatchFailedError
+				require.ErrorAs(t, err, &me)
+				require.Len(t, me.Attempts, 1)
+				assert.Equal(t, "secret.DB_PASS_CUSTOMER_42", me.Attempts[0].Arg)
+
+				var ke tempura.KeyNotFoundError
+				require.ErrorAs(t, err, &ke)
+				assert.Equal(t, "DB_PASS_CUSTOMER_42", ke.Key)
+				assert.NotContains(t, ke.Error(), "CUSTOMER_42")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := lookup.FuncMapValue(tt.args...)
+			require.Error(t, err)
+			assert.Equal(t, tt.errStr, err.Error())
+			tt.check(t, err)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package tempura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/template"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// RenderJob は RenderAll で描画する1つのテンプレートです。
+//
+// RenderJob is a single template rendered by RenderAll.
+type RenderJob struct {
+	// Template は描画するテンプレートです。 RenderAll は複製してから関数を登録するため、元のテンプレートは変更されません。
+	// Template is the template to render. RenderAll registers the function on a clone, so the original is never modified.
+	Template *template.Template
+	Data     any
+	Out      io.Writer
+}
+
+// RenderAll は、 jobs を最大 workers 個ずつ並行に描画します。
+// 全てのテンプレートは1つの描画キャッシュ (WithRenderCache) を共有するため、同じ元データから多数の設定ファイルを生成する場合も、それぞれのキーは一度だけ解決されます。
+// テンプレートの name という関数は、このキャッシュに結び付けられた FuncMapValue に置き換えられます。
+// workers が 0 以下の場合は全てを同時に描画します。描画に失敗した全てのテンプレートのエラーを、位置を TemplateError で添えて errors.Join でまとめて返します。
+//
+// RenderAll renders jobs concurrently, up to workers at a time.
+// All templates share a single render cache (WithRenderCache), so each key is resolved only once even when generating dozens of config files from the same source data.
+// The function name in the templates is replaced by FuncMapValue bound to this cache.
+// If workers is zero or negative, all jobs are rendered at once. It returns the errors of all templates that failed to render, with their positions attached as TemplateError, joined by errors.Join.
+func (m *MultiLookupContext) RenderAll(ctx context.Context, name string, jobs []RenderJob, workers int) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	mc := m.withContext(WithRenderCache(ctx))
+	funcs := mc.FuncMap(name)
+
+	g := &errgroup.Group{}
+	if workers > 0 {
+		g.SetLimit(workers)
+	}
+	errs := make([]error, len(jobs))
+	for index, job := range jobs {
+		index, job := index, job
+		g.Go(func() error {
+			t, err := job.Template.Clone()
+			if err != nil {
+				errs[index] = fmt.Errorf("failed to clone %s: %w", job.Template.Name(), err)
+				return nil
+			}
+			if err := t.Funcs(funcs).Execute(job.Out, job.Data); err != nil {
+				errs[index] = fmt.Errorf("failed to render %s: %w", job.Template.Name(), AnnotateTemplateError(err))
+			}
+			return nil
+		})
+	}
+	_ = g.Wait() // en: errors are collected per job so that one failure doesn't hide the others
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,101 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiLookupContext_RenderAll(t *testing.T) {
+	t.Parallel()
+
+	var calls, running, peak atomic.Int32
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		calls.Add(1)
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if key == "FAIL" {
+			return "", false, errUnavailable
+		}
+		return "secret-of-" + key, true, nil
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}.BindContext(context.Background())
+
+	// en: the placeholder function is replaced by RenderAll
+	placeholder := template.FuncMap{"secret": func(args ...string) (any, error) { return nil, nil }}
+	shared := template.Must(template.New("shared").Funcs(placeholder).Parse(`{{ secret "secret.DB_PASS" }}`))
+
+	tests := []struct {
+		name      string
+		templates []string
+		workers   int
+		expected  []string
+		checkErr  func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:      "templates sharing keys",
+			templates: []string{`a={{ secret "secret.DB_PASS" }}`, `b={{ secret "secret.DB_PASS" }}`, `c={{ secret "secret.API_KEY" }}`},
+			workers:   4,
+			expected:  []string{"a=secret-of-DB_PASS", "b=secret-of-DB_PASS", "c=secret-of-API_KEY"},
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name:      "failing template does not hide the others",
+			templates: []string{`{{ secret "secret.FAIL" }}`, `ok={{ secret "secret.OK" }}`},
+			workers:   2,
+			expected:  []string{"", "ok=secret-of-OK"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+				assert.ErrorContains(t, err, "failed to render job0")
+				assert.ErrorContains(t, err, `job0:1:3: template "job0" at {{ secret "secret.FAIL" }}`)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			peak.Store(0)
+			jobs := make([]tempura.RenderJob, len(tt.templates)+1)
+			outs := make([]*bytes.Buffer, len(jobs))
+			for i := range jobs {
+				outs[i] = &bytes.Buffer{}
+				tmpl := shared
+				if i < len(tt.templates) {
+					tmpl = template.Must(template.New(fmt.Sprintf("job%d", i)).Funcs(placeholder).Parse(tt.templates[i]))
+				}
+				jobs[i] = tempura.RenderJob{Template: tmpl, Out: outs[i]}
+			}
+
+			err := lookup.RenderAll(context.Background(), "secret", jobs, tt.workers)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, int32(2), calls.Load(), "each key should be resolved once per batch")
+			}
+			for i, expected := range tt.expected {
+				assert.Equal(t, expected, outs[i].String())
+			}
+			assert.LessOrEqual(t, peak.Load(), int32(tt.workers), "workers should bound concurrent renders")
+		})
+	}
+}
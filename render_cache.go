@@ -0,0 +1,130 @@
+package tempura
+
+import (
+	"context"
+	"sync"
+)
+
+// =================================================================================
+// Per-render memoization scoped to a context
+// =================================================================================
+
+type renderCacheKey struct{}
+
+// WithRenderCache は、探索結果を1回の描画の間だけ保持するキャッシュを ctx に持たせます。
+// 描画ごとにこの ctx で BindContext(ctx) することで、同じ描画の中で繰り返し参照されるキーは一度だけ解決され、異なる描画どうしは互いに影響しません。
+//
+// WithRenderCache returns a ctx carrying a cache that holds lookup results only for a single render.
+// By calling BindContext(ctx) with this ctx for each render, keys referenced repeatedly within the render are resolved once, while different renders remain isolated.
+//
+//	lookup := secrets.BindContext(tempura.WithRenderCache(ctx))
+//	tpl.Funcs(template.FuncMap{"secret": lookup.FuncMapValue}).Execute(w, data)
+func WithRenderCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, renderCacheKey{}, &renderCache{entries: make(map[string]any)})
+}
+
+func renderCacheFrom(ctx context.Context) (Cache, bool) {
+	return renderCacheOf(ctx)
+}
+
+func renderCacheOf(ctx context.Context) (*renderCache, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	c, ok := ctx.Value(renderCacheKey{}).(*renderCache)
+	return c, ok
+}
+
+// renderCache は有効期限を持たない Cache です。描画が終われば ctx ごと破棄されます。
+// RenderAll のように同じ ctx で並行に描画しても同じキーを重ねて解決しないよう、実行中の探索関数の呼び出しを prefix と引数の組ごとにまとめます。
+//
+// renderCache is a Cache without expiry. It is discarded together with ctx when the render finishes.
+// It also merges calls of lookup functions in flight per pair of prefix and arg, so that concurrent renders sharing ctx, such as with RenderAll, never resolve the same key twice.
+type renderCache struct {
+	mu      sync.Mutex
+	entries map[string]any
+	flights map[renderFlightKey]*renderFlight
+}
+
+type renderFlightKey struct {
+	prefix Prefix
+	arg    string
+}
+
+// renderFlight は、実行中または完了した1回の探索関数の呼び出しです。
+//
+// renderFlight is a single call of a lookup function, in flight or completed.
+type renderFlight struct {
+	done chan struct{}
+	res  lookupResult
+}
+
+// flight は key の呼び出しを返します。まだ無い場合は新しく登録し、呼び出し元がそれを実行する leader であることを返します。
+//
+// flight returns the call for key. If there is none yet, it registers a new one and reports that the caller is the leader running it.
+func (c *renderCache) flight(key renderFlightKey) (f *renderFlight, leader bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f, ok := c.flights[key]; ok {
+		return f, false
+	}
+	if c.flights == nil {
+		c.flights = make(map[renderFlightKey]*renderFlight)
+	}
+	f = &renderFlight{done: make(chan struct{})}
+	c.flights[key] = f
+	return f, true
+}
+
+// land は f の結果を res として待っている呼び出し元に知らせます。
+// 見つかった値だけを保持し、見つからなかった場合やエラーは、キャッシュと同じく以降の探索で再び解決します。
+//
+// land delivers res as the result of f to the callers waiting for it.
+// Only found values are retained; misses and errors are resolved again by later lookups, as with the cache.
+func (c *renderCache) land(key renderFlightKey, f *renderFlight, res lookupResult) {
+	f.res = res
+	if !res.ok {
+		c.mu.Lock()
+		if c.flights[key] == f {
+			delete(c.flights, key)
+		}
+		c.mu.Unlock()
+	}
+	close(f.done)
+}
+
+func (c *renderCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	val, ok := c.entries[key]
+	return val, ok
+}
+
+func (c *renderCache) Set(key string, val any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = val
+}
+
+func (c *renderCache) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+	for k := range c.flights {
+		if k.arg == key {
+			delete(c.flights, k)
+		}
+	}
+}
+
+func (c *renderCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]any)
+	c.flights = nil
+}
@@ -0,0 +1,47 @@
+package tempura_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"text/template"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRenderCache(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		calls.Add(1)
+		return "secret-of-" + key, true, nil
+	}
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}
+
+	render := func(ctx context.Context) string {
+		lookup := secrets.BindContext(ctx)
+		tpl := template.Must(template.New("").Funcs(template.FuncMap{
+			"secret": lookup.FuncMapValue,
+		}).Parse(`{{ secret "secret.A" }} {{ secret "secret.A" }} {{ secret "secret.B" }}`))
+
+		buf := &strings.Builder{}
+		require.NoError(t, tpl.Execute(buf, nil))
+		return buf.String()
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, "secret-of-A secret-of-A secret-of-B", render(tempura.WithRenderCache(ctx)))
+	assert.Equal(t, int32(2), calls.Load(), "repeated references should be resolved once within a render")
+
+	render(tempura.WithRenderCache(ctx))
+	assert.Equal(t, int32(4), calls.Load(), "different renders should be isolated")
+
+	render(ctx)
+	assert.Equal(t, int32(7), calls.Load(), "nothing should be cached without WithRenderCache")
+}
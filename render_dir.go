@@ -0,0 +1,196 @@
+package tempura
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// =================================================================================
+// Rendering directory trees of templates
+// =================================================================================
+
+// DefaultRenderDirPattern は、 RenderDir が描画するファイルの名前の既定のパターンです。
+//
+// DefaultRenderDirPattern is the default pattern of the names of files RenderDir renders.
+const DefaultRenderDirPattern = "*.tmpl"
+
+// OverwritePolicy は、 RenderDir の書き込み先にファイルが既にある場合の扱いを表します。
+//
+// OverwritePolicy represents how RenderDir treats files already existing at the destination.
+type OverwritePolicy int
+
+const (
+	// OverwriteAlways は、既にあるファイルを上書きします (既定)。
+	//
+	// OverwriteAlways overwrites existing files (default).
+	OverwriteAlways OverwritePolicy = iota
+
+	// OverwriteSkip は、既にあるファイルをそのまま残し、そのテンプレートを描画しません。
+	//
+	// OverwriteSkip leaves existing files as they are, and does not render the templates for them.
+	OverwriteSkip
+
+	// OverwriteNever は、既にあるファイルを fs.ErrExist を包んだエラーとします。
+	//
+	// OverwriteNever treats existing files as errors wrapping fs.ErrExist.
+	OverwriteNever
+)
+
+// RenderDirOption は RenderDir の振る舞いを変更します。
+//
+// RenderDirOption changes the behavior of RenderDir.
+type RenderDirOption func(*renderDir)
+
+// renderDir は RenderDir の設定です。
+//
+// renderDir is the configuration of RenderDir.
+type renderDir struct {
+	pattern    string
+	overwrite  OverwritePolicy
+	lookupOpts []Option
+}
+
+// WithRenderDirPattern は、 RenderDir が描画するファイルの名前のパターンを pattern に変更します。指定しない場合は DefaultRenderDirPattern です。
+// パターンは filepath.Match の形式でファイルの名前 (ディレクトリを除く) と照合されます。 pattern が * で始まる場合は、それに続く部分 (.tmpl など) を取り除いた名前で書き込まれます。
+//
+// WithRenderDirPattern changes the pattern of the names of files RenderDir renders to pattern. Without it, the pattern is DefaultRenderDirPattern.
+// The pattern is matched against file names (without directories) in the form of filepath.Match. If pattern starts with *, files are written with the part following it (such as .tmpl) removed from their names.
+func WithRenderDirPattern(pattern string) RenderDirOption {
+	return func(r *renderDir) {
+		r.pattern = pattern
+	}
+}
+
+// WithOverwritePolicy は、 RenderDir の書き込み先にファイルが既にある場合の扱いを policy に変更します。
+//
+// WithOverwritePolicy changes how RenderDir treats files already existing at the destination to policy.
+func WithOverwritePolicy(policy OverwritePolicy) RenderDirOption {
+	return func(r *renderDir) {
+		r.overwrite = policy
+	}
+}
+
+// WithLookupOptions は、 RenderDir が描画に使う MultiLookupContext に opts を渡します。
+//
+// WithLookupOptions passes opts to the MultiLookupContext RenderDir renders with.
+func WithLookupOptions(opts ...Option) RenderDirOption {
+	return func(r *renderDir) {
+		r.lookupOpts = append(r.lookupOpts, opts...)
+	}
+}
+
+// RenderDir は、 srcDir 以下のパターンに一致する全てのファイルをテンプレートとして描画し、 dstDir 以下の同じ相対パスに元のファイルと同じパーミッションで書き込みます。
+// 全てのファイルは1つの描画キャッシュ (WithRenderCache) を共有するため、それぞれのキーは一度だけ解決されます。
+// WithLookupOptions で WithDryRun を指定した場合は、描画だけを行いファイルを書き込みません。
+// 1つのファイルの失敗で止まらずに全てのファイルを描画し、失敗した全てのファイルのエラーを errors.Join でまとめて返します。描画に失敗したファイルは書き込まれません。
+//
+// RenderDir renders every file matching the pattern under srcDir as a template, and writes it to the same relative path under dstDir with the permissions of the original.
+// All files share a single render cache (WithRenderCache), so each key is resolved only once.
+// With WithDryRun passed through WithLookupOptions, it only renders and writes no files.
+// It renders all files without stopping at a failure, and returns the errors of all files that failed, joined by errors.Join. Files that failed to render are not written.
+//
+//	err := tempura.RenderDir(ctx, "config", "/etc/app", secrets, tempura.WithOverwritePolicy(tempura.OverwriteNever))
+func RenderDir(ctx context.Context, srcDir, dstDir string, m MultiLookup, opts ...RenderDirOption) error {
+	r := &renderDir{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	mc := m.BindContext(WithRenderCache(ctx), r.lookupOpts...)
+	if err := mc.Validate(); err != nil {
+		return err
+	}
+	pattern := r.pattern
+	if pattern == "" {
+		pattern = DefaultRenderDirPattern
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	var errs []error
+	err := filepath.WalkDir(srcDir, func(src string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if matched, _ := filepath.Match(pattern, d.Name()); !matched {
+			return nil
+		}
+		rel, err := filepath.Rel(srcDir, src)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dstDir, renderedName(rel, pattern))
+		if err := mc.renderFile(src, dst, r.overwrite); err != nil {
+			errs = append(errs, fmt.Errorf("failed to render %s: %w", src, err))
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// renderedName は、 pattern が * で始まる場合に、それに続く部分を name から取り除きます。
+//
+// renderedName removes the part following * from name if pattern starts with *.
+func renderedName(name, pattern string) string {
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok && !strings.ContainsAny(suffix, `*?[\`) {
+		if base := strings.TrimSuffix(filepath.Base(name), suffix); base != "" {
+			return filepath.Join(filepath.Dir(name), base)
+		}
+	}
+	return name
+}
+
+// renderFile は src を描画し、 overwrite に従って dst に書き込みます。
+//
+// renderFile renders src and writes it to dst according to overwrite.
+func (m *MultiLookupContext) renderFile(src, dst string, overwrite OverwritePolicy) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if m.opts.dryRun == nil && overwrite != OverwriteAlways {
+		if _, err := os.Stat(dst); err == nil {
+			if overwrite == OverwriteSkip {
+				return nil
+			}
+			return fmt.Errorf("%s: %w", dst, fs.ErrExist)
+		}
+	}
+
+	text, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if m.opts.dryRun != nil {
+		return m.render(io.Discard, "", "", string(text), nil)
+	}
+	b := &bytes.Buffer{}
+	if err := m.render(b, "", "", string(text), nil); err != nil {
+		return err
+	}
+
+	dirInfo, err := os.Stat(filepath.Dir(src))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), dirInfo.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, b.Bytes(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chmod(dst, info.Mode().Perm()) // en: WriteFile keeps the permissions of existing files and is subject to umask
+}
@@ -0,0 +1,85 @@
+package tempura_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderDir(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.Func(func(key string) (string, bool) {
+			calls++
+			return "secret-of-" + key, key != "MISSING"
+		}),
+	}
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "app.yaml.tmpl"), `pass: {{ lookup "secret.DB_PASS" }}`, 0o600)
+	writeFile(t, filepath.Join(src, "conf.d", "run.sh.tmpl"), `echo {{ lookup "secret.DB_PASS" }}`, 0o755)
+	writeFile(t, filepath.Join(src, "README.md"), `{{ lookup "secret.MISSING" }}`, 0o644)
+
+	dst := t.TempDir()
+	require.NoError(t, tempura.RenderDir(context.Background(), src, dst, secrets))
+	assert.Equal(t, 1, calls, "the cache is shared across files")
+	assertFile(t, filepath.Join(dst, "app.yaml"), "pass: secret-of-DB_PASS", 0o600)
+	assertFile(t, filepath.Join(dst, "conf.d", "run.sh"), "echo secret-of-DB_PASS", 0o755)
+	assert.NoFileExists(t, filepath.Join(dst, "README.md"))
+
+	t.Run("pattern", func(t *testing.T) {
+		dst := t.TempDir()
+		err := tempura.RenderDir(context.Background(), src, dst, secrets, tempura.WithRenderDirPattern("*.md"))
+		assert.ErrorIs(t, err, tempura.ErrNotFound)
+		assert.NoFileExists(t, filepath.Join(dst, "README"))
+	})
+
+	t.Run("overwrite policies", func(t *testing.T) {
+		dst := t.TempDir()
+		writeFile(t, filepath.Join(dst, "app.yaml"), "existing", 0o644)
+
+		require.NoError(t, tempura.RenderDir(context.Background(), src, dst, secrets, tempura.WithOverwritePolicy(tempura.OverwriteSkip)))
+		assertFile(t, filepath.Join(dst, "app.yaml"), "existing", 0o644)
+		assertFile(t, filepath.Join(dst, "conf.d", "run.sh"), "echo secret-of-DB_PASS", 0o755)
+
+		err := tempura.RenderDir(context.Background(), src, dst, secrets, tempura.WithOverwritePolicy(tempura.OverwriteNever))
+		assert.ErrorIs(t, err, fs.ErrExist)
+
+		require.NoError(t, tempura.RenderDir(context.Background(), src, dst, secrets))
+		assertFile(t, filepath.Join(dst, "app.yaml"), "pass: secret-of-DB_PASS", 0o600)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		dst := t.TempDir()
+		report := tempura.NewDryRunReport()
+		require.NoError(t, tempura.RenderDir(context.Background(), src, dst, secrets, tempura.WithLookupOptions(tempura.WithDryRun(report))))
+		assert.Len(t, report.Lookups(), 1)
+		entries, err := os.ReadDir(dst)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func writeFile(t *testing.T, name, content string, mode fs.FileMode) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+	require.NoError(t, os.WriteFile(name, []byte(content), mode))
+	require.NoError(t, os.Chmod(name, mode))
+}
+
+func assertFile(t *testing.T, name, content string, mode fs.FileMode) {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(b))
+	info, err := os.Stat(name)
+	require.NoError(t, err)
+	assert.Equal(t, mode, info.Mode().Perm())
+}
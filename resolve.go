@@ -0,0 +1,87 @@
+package tempura
+
+import (
+	"context"
+)
+
+// Resolve は、1つのキーを ctx で解決します。テンプレートではなく設定の読み込みなどから、単一のキーを最小限のオーバーヘッドで解決したい場合に使います。
+// FuncMapValue とは異なり、可変長引数によるフォールバックを行わず、 goroutine やチャネルも使わずに一致した探索関数を順に呼び出します。
+// キャッシュと WithMaxConcurrency は FuncMapValue と同様に適用されます。
+//
+// Resolve resolves a single key with ctx. Use it for callers such as config loaders, rather than templates, that want single-key resolution with minimal overhead.
+// Unlike FuncMapValue, it has no fallback through variadic args and calls the matching lookup functions in order without goroutines or channels.
+// Caches and WithMaxConcurrency apply as they do for FuncMapValue.
+func (m *MultiLookupContext) Resolve(ctx context.Context, key string) (any, error) {
+	if ctx == nil {
+		return nil, ErrContextUntypedNil
+	}
+	if m.opts.dryRun != nil {
+		return m.dryRun([]string{key})
+	}
+	var end SpanEnd
+	if m.opts.tracer != nil {
+		ctx, end = m.opts.tracer.StartLookup(ctx, []string{m.keyName(key)})
+	}
+	val, err := m.resolve(ctx, key)
+	if err != nil {
+		val, err = m.settleError(ctx, []string{key}, err)
+	}
+	if end != nil {
+		end(outcomeOf(lookupResult{ok: err == nil, err: notFoundAsNil(err)}), notFoundAsNil(err))
+	}
+	return val, err
+}
+
+func (m *MultiLookupContext) resolve(ctx context.Context, key string) (any, error) {
+	if m.opts.strict != nil {
+		return m.lookupStrict(ctx, []string{key})
+	}
+	if val, ok := m.cacheGet(ctx, key); ok {
+		m.cacheHit(ctx, key, outcomeCacheHit)
+		m.recordProvenance(ctx, key, nil, nil, true)
+		return val, nil
+	}
+	if m.cacheMissed(key) {
+		m.cacheHit(ctx, key, outcomeNegativeCacheHit)
+		return nil, ErrNotFound
+	}
+
+	var buf [4]registration
+	matches := m.matchInto(key, buf[:0])
+	if len(matches) == 0 {
+		return nil, ErrMatchFailed
+	}
+	degraded := false
+	for _, reg := range matches {
+		res, err := m.resolveOne(ctx, reg, key)
+		if err != nil {
+			return nil, err
+		}
+		if res.err != nil {
+			return nil, res.err
+		}
+		if res.ok {
+			m.cacheSet(ctx, key, res.val)
+			m.recordProvenance(ctx, key, reg.prefix, reg.fn, false)
+			return res.val, nil
+		}
+		degraded = degraded || res.degraded
+	}
+
+	if !degraded {
+		m.cacheMiss(key)
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MultiLookupContext) resolveOne(ctx context.Context, reg registration, key string) (lookupResult, error) {
+	switch reg.fn.(type) {
+	case LookupAnyWithContext, LookupAnyWithContextError:
+		release, err := m.acquire(ctx, reg.prefix)
+		if err != nil {
+			return lookupResult{}, err
+		}
+		defer release()
+	}
+	return m.runTask(ctx, reg.prefix, reg.fn, key), nil
+}
@@ -0,0 +1,106 @@
+package tempura_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiLookupContext_Resolve(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		if key == "FAIL" {
+			return "", false, errUnavailable
+		}
+		return "secret-of-" + key, key != "MISSING", nil
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+		tempura.DotPrefix("env"):    tempura.Func(func(key string) (string, bool) { return "env-of-" + key, true }),
+	}.BindContext(context.Background())
+
+	tests := []struct {
+		name     string
+		key      string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "context-taking function", key: "secret.DB_PASS", expected: "secret-of-DB_PASS"},
+		{name: "synchronous function", key: "env.HOME", expected: "env-of-HOME"},
+		// ==================== INVALID CASES ====================
+		{
+			name: "not found",
+			key:  "secret.MISSING",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+		{
+			name: "no prefix matched",
+			key:  "vault.DB_PASS",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrMatchFailed)
+			},
+		},
+		{
+			name: "lookup error",
+			key:  "secret.FAIL",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			val, err := lookup.Resolve(context.Background(), tt.key)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestMultiLookupContext_Resolve_Context(t *testing.T) {
+	t.Parallel()
+
+	waitForCancel := func(ctx context.Context, key string) (string, bool, error) {
+		<-ctx.Done()
+		return "", false, ctx.Err()
+	}
+	// en: the bound context is never canceled, so only the context passed to Resolve can stop the lookup
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(waitForCancel),
+	}.BindContext(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := lookup.Resolve(ctx, "secret.DB_PASS")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestMultiLookupContext_Resolve_Allocs(t *testing.T) {
+	var secret any = "XXXXXXXX" // en: boxed in advance so that the lookup function itself does not allocate
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.LookupAnyWithContextError(func(ctx context.Context, key string) (any, bool, error) {
+			return secret, true, nil
+		}),
+	}.BindContext(context.Background())
+	ctx := context.Background()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = lookup.Resolve(ctx, "secret.DB_PASS")
+	})
+	assert.Zero(t, allocs, "Resolve should not allocate even for context-taking functions")
+}
@@ -0,0 +1,99 @@
+package tempura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// readiness は Start の進行状況で、 MultiLookupContext の複製の間で共有されます。
+//
+// readiness is the progress of Start, shared among copies of MultiLookupContext.
+type readiness struct {
+	once  sync.Once
+	ready chan struct{}
+	done  chan struct{}
+	err   error
+}
+
+func newReadiness() *readiness {
+	return &readiness{ready: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Start は、 WithInitializer で登録された初期化処理と keys の先読み (Preload) をバックグラウンドで開始し、すぐに返ります。
+// サーバーは Ready または WaitReady で、設定を解決できるようになるまでトラフィックを待たせることができます。
+// 2回目以降の呼び出しは何もしません。
+//
+// Start kicks off the initializers registered via WithInitializer and preloading of keys (Preload) in the background, and returns immediately.
+// Servers can gate traffic on configuration being resolvable with Ready or WaitReady.
+// Subsequent calls do nothing.
+func (m *MultiLookupContext) Start(ctx context.Context, keys ...string) error {
+	if m.readiness == nil {
+		return fmt.Errorf("consider calling BindContext(ctx): %w", ErrContextUntypedNil)
+	}
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	m.readiness.once.Do(func() {
+		m.track()
+		go func() {
+			defer m.untrack()
+			defer close(m.readiness.done)
+
+			if err := m.start(ctx, keys); err != nil {
+				m.readiness.err = err
+				return
+			}
+			close(m.readiness.ready)
+		}()
+	})
+	return nil
+}
+
+func (m *MultiLookupContext) start(ctx context.Context, keys []string) error {
+	errs := make([]error, len(m.opts.initializers))
+	wg := &sync.WaitGroup{}
+	for index, init := range m.opts.initializers {
+		index, init := index, init
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := init(ctx); err != nil {
+				errs[index] = fmt.Errorf("failed to initialize: %w", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	return m.Preload(ctx, keys...)
+}
+
+// Ready は、 Start が成功したときに閉じられるチャネルを返します。 Start が失敗した場合は閉じられません。
+//
+// Ready returns a channel closed when Start succeeds. It is never closed if Start fails.
+func (m *MultiLookupContext) Ready() <-chan struct{} {
+	if m.readiness == nil {
+		return nil
+	}
+	return m.readiness.ready
+}
+
+// WaitReady は Start が完了するまで待ち、失敗した場合はそのエラーを返します。先に ctx が終了した場合は ctx.Err() を返します。
+//
+// WaitReady waits until Start completes and returns its error if it failed. If ctx is done first, it returns ctx.Err().
+func (m *MultiLookupContext) WaitReady(ctx context.Context) error {
+	if m.readiness == nil {
+		return fmt.Errorf("consider calling BindContext(ctx): %w", ErrContextUntypedNil)
+	}
+	select {
+	case <-m.readiness.done:
+		return m.readiness.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
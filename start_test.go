@@ -0,0 +1,101 @@
+package tempura_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiLookupContext_Start(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		initErr  error
+		keys     []string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{name: "initialized and preloaded", keys: []string{"secret.DB_PASS"}},
+		// ==================== INVALID CASES ====================
+		{
+			name:    "initializer fails",
+			initErr: errUnavailable,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+				assert.ErrorContains(t, err, "failed to initialize")
+			},
+		},
+		{
+			name: "key cannot be resolved",
+			keys: []string{"secret.MISSING"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var initialized atomic.Bool
+			fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+				assert.True(t, initialized.Load(), "initializers should run before preloading")
+				return "secret-of-" + key, key != "MISSING", nil
+			}
+			initClient := func(ctx context.Context) error {
+				time.Sleep(10 * time.Millisecond)
+				initialized.Store(true)
+				return tt.initErr
+			}
+			lookup := tempura.MultiLookup{
+				tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+			}.BindContext(context.Background(), tempura.WithInitializer(initClient))
+
+			require.NoError(t, lookup.Start(context.Background(), tt.keys...))
+			select {
+			case <-lookup.Ready():
+				t.Fatal("should not be ready before initialization completes")
+			default:
+			}
+
+			err := lookup.WaitReady(context.Background())
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				select {
+				case <-lookup.Ready():
+					t.Fatal("should not be ready after failing")
+				default:
+				}
+				return
+			}
+			require.NoError(t, err)
+			<-lookup.Ready()
+		})
+	}
+}
+
+func TestMultiLookupContext_WaitReady_Canceled(t *testing.T) {
+	t.Parallel()
+
+	block := make(chan struct{})
+	defer close(block)
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("env"): tempura.Func(func(key string) (string, bool) { return key, true }),
+	}.BindContext(context.Background(), tempura.WithInitializer(func(ctx context.Context) error {
+		<-block
+		return nil
+	}))
+	require.NoError(t, lookup.Start(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, lookup.WaitReady(ctx), context.DeadlineExceeded)
+}
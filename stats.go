@@ -0,0 +1,129 @@
+package tempura
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// =================================================================================
+// Aggregate statistics
+// =================================================================================
+
+// Stats は、 BindContext から累積された探索の統計です。
+// メトリクスの基盤を用意することなく、アプリケーションの状態を返すエンドポイントに tempura の状態を含めるために使います。
+//
+// Stats is the statistics of lookups accumulated since BindContext.
+// Use it to include the health of tempura in status endpoints of applications without a full metrics stack.
+type Stats struct {
+	// Calls は探索関数の呼び出しの回数で、 Found と Errors はそのうち値を返した回数とエラーを返した回数です。
+	// Calls is the number of calls of lookup functions, and Found and Errors are how many of them returned a value and an error.
+	Calls  uint64 `json:"calls"`
+	Found  uint64 `json:"found"`
+	Errors uint64 `json:"errors"`
+	// CacheHits は、探索関数を呼び出さずにキャッシュから解決された回数です (記録された見つからなかったことを含みます)。
+	// CacheHits is the number of resolutions from caches without calling lookup functions, including recorded misses.
+	CacheHits uint64 `json:"cache_hits"`
+	// AverageLatency は探索関数の呼び出しにかかった時間の平均です。
+	// AverageLatency is the average time taken by calls of lookup functions.
+	AverageLatency time.Duration `json:"average_latency"`
+	Prefixes       []PrefixStats `json:"prefixes"`
+}
+
+// PrefixStats は1つの prefix についての Stats です。
+//
+// PrefixStats is the Stats for a single prefix.
+type PrefixStats struct {
+	Prefix         Prefix        `json:"-"`
+	Name           string        `json:"prefix"`
+	Calls          uint64        `json:"calls"`
+	Found          uint64        `json:"found"`
+	Errors         uint64        `json:"errors"`
+	CacheHits      uint64        `json:"cache_hits"`
+	AverageLatency time.Duration `json:"average_latency"`
+}
+
+// stats は prefix ごとのカウンタで、 MultiLookupContext の複製の間で共有されます。
+// カウンタは BindContext の時点で登録された prefix ごとに作成されるため、探索の間はロックなしで更新できます。
+//
+// stats holds counters per prefix, shared among copies of MultiLookupContext.
+// Counters are created for each prefix registered at BindContext, so they are updated without locks during lookups.
+type stats struct {
+	prefixes map[Prefix]*prefixCounters
+}
+
+type prefixCounters struct {
+	calls, found, errors, cacheHits, nanos atomic.Uint64
+}
+
+func newStats(m MultiLookup) *stats {
+	s := &stats{prefixes: make(map[Prefix]*prefixCounters, len(m))}
+	for prefix := range m {
+		s.prefixes[prefix] = &prefixCounters{}
+	}
+	return s
+}
+
+func (s *stats) record(prefix Prefix, res lookupResult, elapsed time.Duration) {
+	if s == nil {
+		return // en: MultiLookupContext may be generated without BindContext
+	}
+	c, ok := s.prefixes[prefix]
+	if !ok {
+		return
+	}
+	c.calls.Add(1)
+	c.nanos.Add(uint64(elapsed))
+	switch {
+	case res.err != nil:
+		c.errors.Add(1)
+	case res.ok:
+		c.found.Add(1)
+	}
+}
+
+func (s *stats) recordCacheHit(prefix Prefix) {
+	if s == nil || prefix == nil {
+		return
+	}
+	if c, ok := s.prefixes[prefix]; ok {
+		c.cacheHits.Add(1)
+	}
+}
+
+// Stats は、 BindContext から累積された探索の統計を返します。 BindContext で生成した MultiLookupContext の複製 (Preload など) の探索も含みます。
+//
+// Stats returns the statistics of lookups accumulated since BindContext, including lookups via copies of the MultiLookupContext such as Preload.
+func (m *MultiLookupContext) Stats() Stats {
+	var total Stats
+	var totalNanos uint64
+	if m.stats == nil {
+		return total
+	}
+	for prefix, c := range m.stats.prefixes {
+		p := PrefixStats{
+			Prefix:    prefix,
+			Name:      fmt.Sprint(prefix),
+			Calls:     c.calls.Load(),
+			Found:     c.found.Load(),
+			Errors:    c.errors.Load(),
+			CacheHits: c.cacheHits.Load(),
+		}
+		nanos := c.nanos.Load()
+		if p.Calls > 0 {
+			p.AverageLatency = time.Duration(nanos / p.Calls)
+		}
+		total.Calls += p.Calls
+		total.Found += p.Found
+		total.Errors += p.Errors
+		total.CacheHits += p.CacheHits
+		totalNanos += nanos
+		total.Prefixes = append(total.Prefixes, p)
+	}
+	if total.Calls > 0 {
+		total.AverageLatency = time.Duration(totalNanos / total.Calls)
+	}
+	sort.Slice(total.Prefixes, func(i, j int) bool { return total.Prefixes[i].Name < total.Prefixes[j].Name })
+	return total
+}
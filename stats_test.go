@@ -0,0 +1,64 @@
+package tempura_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiLookupContext_Stats(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		switch key {
+		case "FAIL":
+			return "", false, errUnavailable
+		case "MISSING":
+			return "", false, nil
+		}
+		time.Sleep(10 * time.Millisecond)
+		return "secret-of-" + key, true, nil
+	}
+	keyAsValue := func(key string) (string, bool) {
+		return key, true
+	}
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+		tempura.DotPrefix("env"):    tempura.Func(keyAsValue),
+	}.BindContext(context.Background(), tempura.WithCache(tempura.NewTTLCache(time.Minute)))
+
+	for i := 0; i < 2; i++ { // en: the second call hits the cache
+		_, err := lookup.FuncMapValue("secret.DB_PASS")
+		require.NoError(t, err)
+	}
+	_, _ = lookup.FuncMapValue("secret.FAIL")
+	_, _ = lookup.FuncMapValue("secret.MISSING")
+	require.NoError(t, lookup.Preload(context.Background(), "env.HOME"))
+
+	stats := lookup.Stats()
+	assert.Equal(t, uint64(4), stats.Calls)
+	assert.Equal(t, uint64(2), stats.Found)
+	assert.Equal(t, uint64(1), stats.Errors)
+	assert.Equal(t, uint64(1), stats.CacheHits)
+	assert.Positive(t, stats.AverageLatency)
+
+	require.Len(t, stats.Prefixes, 2)
+	env, secret := stats.Prefixes[0], stats.Prefixes[1]
+	assert.Equal(t, tempura.DotPrefix("env"), env.Prefix)
+	assert.Equal(t, []uint64{1, 1, 0, 0}, []uint64{env.Calls, env.Found, env.Errors, env.CacheHits})
+	assert.Equal(t, tempura.DotPrefix("secret"), secret.Prefix)
+	assert.Equal(t, []uint64{3, 1, 1, 1}, []uint64{secret.Calls, secret.Found, secret.Errors, secret.CacheHits})
+	assert.GreaterOrEqual(t, secret.AverageLatency, 10*time.Millisecond/3)
+}
+
+func TestMultiLookupContext_Stats_WithoutBindContext(t *testing.T) {
+	t.Parallel()
+
+	lookup := &tempura.MultiLookupContext{}
+	assert.Equal(t, tempura.Stats{}, lookup.Stats())
+}
@@ -0,0 +1,81 @@
+// Package stdinlookup は、標準入力から JSON または YAML のドキュメントを一度だけ読み込み、そのキーの値を返す探索関数を提供します。
+// tempura を使ったツールをシェルのパイプラインで組み合わせられるようにするためのものです (`fetch-config | mytool render`)。
+//
+// Package stdinlookup provides a lookup function that reads a JSON or YAML document from stdin once and serves keys from it.
+// It lets tempura-based tools be composed in shell pipelines (`fetch-config | mytool render`).
+package stdinlookup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ebi-yade/go-tempura"
+	"gopkg.in/yaml.v3"
+)
+
+// New は、 r から読み込んだドキュメントの値を、prefixを取り除いたキーをドット区切りのパスとして返す探索関数を返します。
+// r は最初の探索時に一度だけ読み込まれます。 r が nil の場合は os.Stdin が使われます。
+// YAML は JSON の上位互換であるため、どちらの形式もそのまま読み込めます。
+//
+// New returns a lookup function that returns values of the document read from r, using the key with the prefix removed as a dot-separated path.
+// r is read only once, on the first lookup. If r is nil, os.Stdin is used.
+// Since YAML is a superset of JSON, both formats are accepted as is.
+//
+// e.g. "db.host", "servers.0.name"
+func New(r io.Reader) tempura.LookupAnyWithError {
+	if r == nil {
+		r = os.Stdin
+	}
+
+	var once sync.Once
+	var doc any
+	var docErr error
+	load := func() {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			docErr = fmt.Errorf("failed to read document: %w", err)
+			return
+		}
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			docErr = fmt.Errorf("failed to parse document: %w", err)
+		}
+	}
+
+	return func(key string) (any, bool, error) {
+		once.Do(load)
+		if docErr != nil {
+			return nil, false, docErr
+		}
+
+		val, ok := walk(doc, strings.Split(key, "."))
+		return val, ok, nil
+	}
+}
+
+func walk(node any, path []string) (any, bool) {
+	for _, name := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			child, ok := n[name]
+			if !ok {
+				return nil, false
+			}
+			node = child
+
+		case []any:
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, false
+			}
+			node = n[i]
+
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
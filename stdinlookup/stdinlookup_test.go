@@ -0,0 +1,83 @@
+package stdinlookup_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/stdinlookup"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	jsonDoc := `{"db": {"host": "db.example.com", "port": 3306}, "servers": [{"name": "web-1"}, {"name": "web-2"}]}`
+	yamlDoc := "db:\n  host: yaml.example.com\nfeature:\n  enabled: true\n"
+
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("json"):    stdinlookup.New(strings.NewReader(jsonDoc)),
+		tempura.DotPrefix("yaml"):    stdinlookup.New(strings.NewReader(yamlDoc)),
+		tempura.DotPrefix("broken"):  stdinlookup.New(strings.NewReader("{not: [valid")),
+		tempura.DotPrefix("default"): tempura.Func(func(val string) (string, bool) { return val, true }),
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "nested json key",
+			args:     []string{"json.db.host"},
+			expected: "db.example.com",
+		},
+		{
+			name:     "json number",
+			args:     []string{"json.db.port"},
+			expected: 3306,
+		},
+		{
+			name:     "array index",
+			args:     []string{"json.servers.1.name"},
+			expected: "web-2",
+		},
+		{
+			name:     "yaml document",
+			args:     []string{"yaml.feature.enabled"},
+			expected: true,
+		},
+		{
+			name:     "yaml string",
+			args:     []string{"yaml.db.host"},
+			expected: "yaml.example.com",
+		},
+		{
+			name:     "missing key falls through to the next arg",
+			args:     []string{"json.servers.2.name", "default.web-0"},
+			expected: "web-0",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "malformed document",
+			args: []string{"broken.key"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "failed to parse document")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
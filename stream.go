@@ -0,0 +1,78 @@
+package tempura
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// =================================================================================
+// Streaming renders with lookups resolved ahead
+// =================================================================================
+
+// RenderStream は、大きなテンプレートのための描画です。 t から name という関数の呼び出しを全て抽出して並行に解決し始め、それと同時に Execute の結果を w に流します。
+// 実行がある呼び出しに達したときは、その値が解決されるまでだけ待つため、描画の結果全体をメモリに保持することなく、逐次の探索の待ち時間も避けられます。
+// 変数などを引数とする、実行するまで分からない呼び出しは、その場で解決されます。先に解決する数は WithMaxConcurrency で制限できます。
+// t は複製してから関数を登録するため、元のテンプレートは変更されません。エラーには AnnotateTemplateError による位置が添えられます。
+//
+// RenderStream is a render for very large templates. It starts resolving every call of the function name in t concurrently, while streaming the output of Execute to w.
+// When execution reaches a call, it waits only until the value is resolved, so it avoids both holding the whole output in memory and the latency of sequential lookups.
+// Calls unknown until execution, such as ones with variables as args, are resolved in place. The number of lookups resolved ahead can be limited with WithMaxConcurrency.
+// It registers the function on a clone of t, so the original is never modified. Errors come with their positions by AnnotateTemplateError.
+//
+//	tpl := template.Must(tempura.ParseFiles("secret", secrets, "bundle.yaml.tmpl"))
+//	err := secrets.BindContext(ctx, tempura.WithMaxConcurrency(16)).RenderStream(ctx, f, tpl, "secret", data)
+func (m *MultiLookupContext) RenderStream(ctx context.Context, w io.Writer, t *template.Template, name string, data any) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	t, err := t.Clone()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	mc := m.withContext(ctx)
+	ahead := make(map[string]*streamResult)
+	wg := &sync.WaitGroup{}
+	defer func() {
+		cancel() // en: stop the lookups no longer needed after a failure
+		wg.Wait()
+	}()
+	for _, args := range lookupCalls(t, name) {
+		args := args
+		res := &streamResult{done: make(chan struct{})}
+		ahead[strings.Join(args, "\x00")] = res
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(res.done)
+			res.val, res.err = mc.FuncMapValue(args...)
+		}()
+	}
+
+	lookup := func(args ...string) (any, error) {
+		if res, ok := ahead[strings.Join(args, "\x00")]; ok {
+			<-res.done
+			return res.val, res.err
+		}
+		return mc.FuncMapValue(args...)
+	}
+	bw := bufio.NewWriter(w)
+	if err := t.Funcs(template.FuncMap{name: lookup}).Execute(bw, data); err != nil {
+		return AnnotateTemplateError(err)
+	}
+	return bw.Flush()
+}
+
+// streamResult は、 RenderStream が先に解決する1つの呼び出しの結果です。 done が閉じられた後に val と err を読めます。
+//
+// streamResult is the result of a call RenderStream resolves ahead. val and err can be read after done is closed.
+type streamResult struct {
+	done chan struct{}
+	val  any
+	err  error
+}
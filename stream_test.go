@@ -0,0 +1,59 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiLookupContext_RenderStream(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return "secret-of-" + key, key != "MISSING", nil
+	}
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}
+	placeholder := template.FuncMap{"secret": func(args ...string) (any, error) { return nil, nil }}
+	tpl := template.Must(template.New("bundle").Funcs(placeholder).Parse(
+		`{{ range .Items }}{{ secret "secret.A" }}{{ end }} {{ secret "secret.B" }} {{ secret "secret.MISSING" "secret.C" }} {{ secret .Key }}`,
+	))
+
+	buf := &bytes.Buffer{}
+	err := secrets.BindContext(context.Background()).RenderStream(context.Background(), buf, tpl, "secret", map[string]any{
+		"Items": []int{1, 2},
+		"Key":   "secret.DYNAMIC",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "secret-of-Asecret-of-A secret-of-B secret-of-C secret-of-DYNAMIC", buf.String())
+	mu.Lock()
+	assert.Greater(t, maxRunning, 1, "literal calls are resolved ahead concurrently")
+	mu.Unlock()
+
+	t.Run("error", func(t *testing.T) {
+		tpl := template.Must(template.New("bundle").Funcs(placeholder).Parse("ok\n{{ secret \"secret.MISSING\" }}"))
+		err := secrets.BindContext(context.Background()).RenderStream(context.Background(), &bytes.Buffer{}, tpl, "secret", nil)
+		assert.ErrorIs(t, err, tempura.ErrNotFound)
+		var te tempura.TemplateError
+		require.ErrorAs(t, err, &te)
+		assert.Equal(t, 2, te.Line)
+	})
+}
@@ -0,0 +1,154 @@
+package tempura
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// =================================================================================
+// Strict detection of ambiguous matches
+// =================================================================================
+
+// ErrAmbiguousMatch は、 WithStrictMatch の下で、1つの引数に一致した複数の探索関数が値を返したことを表します。
+//
+// ErrAmbiguousMatch represents that multiple lookup functions matching a single arg returned values under WithStrictMatch.
+var ErrAmbiguousMatch = fmt.Errorf("ambiguous match: more than one lookup function returned true as the second return value")
+
+// AmbiguousMatchError は、 ErrAmbiguousMatch の詳細として、引数に一致した全ての登録とそれぞれの結果を表します。 errors.Is で ErrAmbiguousMatch に一致します。
+// エラーの文字列に値は含まれません。
+//
+// AmbiguousMatchError represents, as the details of ErrAmbiguousMatch, every registration matching the arg and the result of each. It matches ErrAmbiguousMatch with errors.Is.
+// The error string never contains values.
+type AmbiguousMatchError struct {
+	Arg     string
+	Matches []AmbiguousMatch
+
+	redact *keyRedactor
+}
+
+// AmbiguousMatch は、曖昧な一致の1つの登録による探索の結果です。
+//
+// AmbiguousMatch is the result of a lookup by a single registration of an ambiguous match.
+type AmbiguousMatch struct {
+	Prefix   Prefix
+	Provider string
+	// Outcome は found, not_found, error のいずれかです。
+	// Outcome is one of found, not_found, and error.
+	Outcome string
+	// Value は探索関数が返した値です。 WithStrictMatch で機密として指定された prefix の場合は常に nil です。
+	// Value is the value returned by the lookup function. It is always nil for the prefixes marked sensitive in WithStrictMatch.
+	Value any
+	Err   error
+}
+
+func (e AmbiguousMatchError) Error() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%v: %s matched", ErrAmbiguousMatch, e.redact.key(e.Arg))
+	for i, match := range e.Matches {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(b, " %v (%s, %s)", match.Prefix, match.Provider, match.Outcome)
+	}
+	return b.String()
+}
+
+func (e AmbiguousMatchError) Is(target error) bool {
+	return target == ErrAmbiguousMatch
+}
+
+// WithStrictMatch は、1つの引数に複数の prefix が一致した場合に、一致した全ての探索関数を呼び出し、2つ以上が値を返した場合は AmbiguousMatchError を返します。
+// 登録の順による暗黙の優先順位に頼った設定の衝突を見つけるためのものです。
+// 一致した探索関数は引数ごとに順に呼び出されるため、 FuncMapValue の非同期の探索は行われません。
+// sensitive に含まれる prefix の値は AmbiguousMatchError に残りません。
+//
+// WithStrictMatch calls every lookup function matching an arg if multiple prefixes match it, and returns AmbiguousMatchError if more than one of them returns a value.
+// It is meant to find conflicts of configuration relying on implicit precedence by the order of registration.
+// The matching lookup functions are called in order for each arg, so FuncMapValue performs no asynchronous lookups.
+// Values of the prefixes in sensitive are never kept in AmbiguousMatchError.
+//
+//	lookup := tempura.MultiLookup{...}.BindContext(ctx, tempura.WithStrictMatch(tempura.DotPrefix("secret")))
+func WithStrictMatch(sensitive ...Prefix) Option {
+	s := &strictMatch{sensitive: make(map[Prefix]bool, len(sensitive))}
+	for _, prefix := range sensitive {
+		s.sensitive[prefix] = true
+	}
+	return func(o *options) {
+		o.strict = s
+	}
+}
+
+type strictMatch struct {
+	sensitive map[Prefix]bool
+}
+
+// lookupStrict は、 WithStrictMatch の下で args を順に解決します。
+//
+// lookupStrict resolves args in order under WithStrictMatch.
+func (m *MultiLookupContext) lookupStrict(ctx context.Context, args []string) (any, error) {
+	matched := false
+	for _, arg := range args {
+		if val, ok := m.cacheGet(ctx, arg); ok {
+			m.cacheHit(ctx, arg, outcomeCacheHit)
+			m.recordProvenance(ctx, arg, nil, nil, true)
+			return val, nil
+		}
+		if m.cacheMissed(arg) {
+			m.cacheHit(ctx, arg, outcomeNegativeCacheHit)
+			matched = true
+			continue
+		}
+
+		regs := m.match(arg)
+		matched = matched || len(regs) > 0
+		results := make([]lookupResult, len(regs))
+		found := 0
+		for i, reg := range regs {
+			res, err := m.resolveOne(ctx, reg, arg)
+			if err != nil {
+				res = lookupResult{err: err, matched: true}
+			}
+			results[i] = res
+			if res.ok {
+				found++
+			}
+		}
+		if found > 1 {
+			return nil, m.ambiguousMatch(arg, regs, results)
+		}
+
+		degraded := false
+		for i, res := range results {
+			if res.err != nil {
+				return nil, res.err
+			}
+			if res.ok {
+				m.cacheSet(ctx, arg, res.val)
+				m.recordProvenance(ctx, arg, regs[i].prefix, regs[i].fn, false)
+				return res.val, nil
+			}
+			degraded = degraded || res.degraded
+		}
+		if len(regs) > 0 && !degraded {
+			m.cacheMiss(arg)
+		}
+	}
+
+	if !matched {
+		return nil, ErrMatchFailed
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MultiLookupContext) ambiguousMatch(arg string, regs []registration, results []lookupResult) AmbiguousMatchError {
+	e := AmbiguousMatchError{Arg: arg, Matches: make([]AmbiguousMatch, len(regs)), redact: m.opts.redactor}
+	for i, reg := range regs {
+		match := AmbiguousMatch{Prefix: reg.prefix, Provider: providerType(reg.fn), Outcome: outcomeOf(results[i]), Err: results[i].err}
+		if results[i].ok && !m.opts.strict.sensitive[reg.prefix] {
+			match.Value = results[i].val
+		}
+		e.Matches[i] = match
+	}
+	return e
+}
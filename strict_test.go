@@ -0,0 +1,122 @@
+package tempura_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStrictMatch(t *testing.T) {
+	t.Parallel()
+
+	secrets := map[string]string{"DB_PASS": "p@ssword!"}
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		val, ok := secrets[key]
+		return val, ok, nil
+	}
+	defaults := map[string]string{"db.DB_PASS": "changeme", "db.DB_USER": "root", "DB_USER": "root"}
+	getDefault := func(key string) (string, bool) {
+		val, ok := defaults[key]
+		return val, ok
+	}
+	lookup := func(opts ...tempura.Option) *tempura.MultiLookupContext {
+		return tempura.MultiLookup{
+			tempura.DotPrefix("secret.db"): tempura.FuncWithContextError(fetchSecret),
+			tempura.DotPrefix("secret"):    tempura.Func(getDefault),
+		}.BindContext(context.Background(), opts...)
+	}
+
+	tests := []struct {
+		name     string
+		opts     []tempura.Option
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "a single match",
+			opts:     []tempura.Option{tempura.WithStrictMatch()},
+			args:     []string{"secret.db.MISSING", "secret.DB_USER"},
+			expected: "root",
+		},
+		{
+			name:     "multiple matches but one value",
+			opts:     []tempura.Option{tempura.WithStrictMatch()},
+			args:     []string{"secret.db.DB_USER"},
+			expected: "root",
+		},
+		{
+			name:     "without strict mode, the shorter prefix silently wins",
+			args:     []string{"secret.db.DB_PASS"},
+			expected: "changeme",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "ambiguous",
+			opts: []tempura.Option{tempura.WithStrictMatch()},
+			args: []string{"secret.db.DB_PASS"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrAmbiguousMatch)
+				assert.Equal(t, tempura.ErrAmbiguousMatch.Error()+": secret.db.DB_PASS matched secret (LookupAny, found), secret.db (LookupAnyWithContextError, found)", err.Error())
+				assert.NotContains(t, err.Error(), "p@ssword!")
+
+				var ae tempura.AmbiguousMatchError
+				require.ErrorAs(t, err, &ae)
+				assert.Equal(t, "secret.db.DB_PASS", ae.Arg)
+				require.Len(t, ae.Matches, 2)
+				assert.Equal(t, "changeme", ae.Matches[0].Value)
+				assert.Equal(t, "p@ssword!", ae.Matches[1].Value)
+			},
+		},
+		{
+			name: "values of sensitive prefixes",
+			opts: []tempura.Option{tempura.WithStrictMatch(tempura.DotPrefix("secret.db"))},
+			args: []string{"secret.db.DB_PASS"},
+			checkErr: func(t *testing.T, err error) {
+				var ae tempura.AmbiguousMatchError
+				require.ErrorAs(t, err, &ae)
+				require.Len(t, ae.Matches, 2)
+				assert.Equal(t, "changeme", ae.Matches[0].Value)
+				assert.Equal(t, tempura.DotPrefix("secret.db"), ae.Matches[1].Prefix)
+				assert.Equal(t, "found", ae.Matches[1].Outcome)
+				assert.Nil(t, ae.Matches[1].Value)
+			},
+		},
+		{
+			name: "key names redacted",
+			opts: []tempura.Option{tempura.WithStrictMatch(), tempura.WithKeyRedactor(func(string) string { return "<key>" })},
+			args: []string{"secret.db.DB_PASS"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, ": <key> matched secret (LookupAny, found)")
+				assert.NotContains(t, err.Error(), "DB_PASS")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lookup := lookup(tt.opts...)
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				require.Error(t, err)
+				tt.checkErr(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, val)
+
+			if len(tt.args) == 1 {
+				_, resolveErr := lookup.Resolve(context.Background(), tt.args[0])
+				assert.Equal(t, errors.Is(err, tempura.ErrAmbiguousMatch), errors.Is(resolveErr, tempura.ErrAmbiguousMatch))
+			}
+		})
+	}
+}
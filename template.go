@@ -0,0 +1,264 @@
+package tempura
+
+import (
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// =================================================================================
+// Integration with templates
+// =================================================================================
+
+// FuncMapper は、 FuncMap を提供する MultiLookup と *MultiLookupContext を表します。
+//
+// FuncMapper represents MultiLookup and *MultiLookupContext, which provide FuncMap.
+type FuncMapper interface {
+	FuncMap(name string) template.FuncMap
+}
+
+// FuncMap は、 FuncMapValue を name という関数として登録した template.FuncMap を返します。
+// html/template で使う場合は html/template.FuncMap に変換してください。
+//
+// FuncMap returns a template.FuncMap with FuncMapValue registered as the function name.
+// To use it with html/template, convert it to html/template.FuncMap.
+//
+//	tpl := template.Must(template.New("").Funcs(params.FuncMap("param")).Parse(text))
+func (m MultiLookup) FuncMap(name string) template.FuncMap {
+	return template.FuncMap{name: m.FuncMapValue}
+}
+
+// FuncMap は、 FuncMapValue を name という関数として登録した template.FuncMap を返します。
+// html/template で使う場合は html/template.FuncMap に変換してください。
+//
+// FuncMap returns a template.FuncMap with FuncMapValue registered as the function name.
+// To use it with html/template, convert it to html/template.FuncMap.
+//
+//	tpl := template.Must(template.New("").Funcs(secrets.BindContext(ctx).FuncMap("secret")).Parse(text))
+func (m *MultiLookupContext) FuncMap(name string) template.FuncMap {
+	return template.FuncMap{name: m.FuncMapValue}
+}
+
+// RegisterTo は、 m の FuncMapValue を t に name という関数として登録し、 t を返します。
+// 別の場所で解析されたテンプレートにも1行で組み込めます。ただし、 name を呼び出すテンプレートを解析する前に登録する必要があるため、解析済みのテンプレートでは解析時の仮の関数を置き換えることになります。
+// Render などと異なり、 RegisterTo は Execute が返すエラーに手を加えません。失敗した呼び出しの位置を添えるには、 Execute が返したエラーに AnnotateTemplateError を適用する必要があります。
+//
+// RegisterTo registers FuncMapValue of m to t as the function name, and returns t.
+// It enables one-line integration with templates parsed elsewhere. Note that the function must be registered before parsing templates calling name, so for parsed templates it replaces the placeholder used at parse time.
+// Unlike Render and the like, RegisterTo leaves the errors returned by Execute untouched. To attach the position of the failing call, AnnotateTemplateError must be applied to them.
+//
+//	tpl := tempura.RegisterTo(template.New("config"), "secret", secrets.BindContext(ctx))
+//	template.Must(tpl.Parse(text))
+//	if err := tpl.Execute(w, data); err != nil {
+//		return tempura.AnnotateTemplateError(err)
+//	}
+func RegisterTo(t *template.Template, name string, m FuncMapper) *template.Template {
+	return t.Funcs(m.FuncMap(name))
+}
+
+// RegisterToHTML は、 html/template のための RegisterTo です。 RegisterTo と同じく、エラーには AnnotateTemplateError を適用する必要があります。
+//
+// RegisterToHTML is RegisterTo for html/template. As with RegisterTo, AnnotateTemplateError must be applied to its errors.
+func RegisterToHTML(t *htmltemplate.Template, name string, m FuncMapper) *htmltemplate.Template {
+	return t.Funcs(htmltemplate.FuncMap(m.FuncMap(name)))
+}
+
+// DefaultFuncName は、 Render と RenderString がテンプレートに登録する関数の名前です。
+//
+// DefaultFuncName is the name of the function Render and RenderString register to templates.
+const DefaultFuncName = "lookup"
+
+// Render は、 tmplText を解析し、 ctx と opts で m を結び付けた FuncMapValue を DefaultFuncName という関数として登録して、 data で w に描画します。
+// 失敗した呼び出しのエラーには、 AnnotateTemplateError による位置が添えられます。
+//
+// Render parses tmplText, registers FuncMapValue of m bound with ctx and opts as the function DefaultFuncName, and renders it with data to w.
+// Errors of failing calls come with their positions by AnnotateTemplateError.
+//
+//	err := tempura.Render(ctx, os.Stdout, `db_pass: {{ lookup "secret.DB_PASS" }}`, secrets, nil)
+func Render(ctx context.Context, w io.Writer, tmplText string, m MultiLookup, data any, opts ...Option) error {
+	return RenderDelims(ctx, w, "", "", tmplText, m, data, opts...)
+}
+
+// RenderString は、 Render の結果を文字列として返します。
+//
+// RenderString returns the result of Render as a string.
+//
+//	dsn, err := tempura.RenderString(ctx, `postgres://app:{{ lookup "secret.DB_PASS" }}@db:5432/app`, secrets, nil)
+func RenderString(ctx context.Context, tmplText string, m MultiLookup, data any, opts ...Option) (string, error) {
+	b := &strings.Builder{}
+	if err := Render(ctx, b, tmplText, m, data, opts...); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// RenderDelims は Render と同様ですが、アクションの区切りに left と right を使います。
+// Helm のチャートや GitHub Actions のワークフローのように、内容に {{ }} を含むファイルにエスケープなしで値を埋め込めます。
+// left と right が空文字列の場合は、 template.Template.Delims と同様に既定の {{ と }} を使います。
+//
+// RenderDelims is like Render, but uses left and right as the action delimiters.
+// It embeds values into files whose contents already contain {{ }}, such as Helm charts and GitHub Actions workflows, without escaping.
+// Empty left or right means the default {{ or }}, as with template.Template.Delims.
+//
+//	err := tempura.RenderDelims(ctx, os.Stdout, "[[", "]]", `token: [[ lookup "secret.TOKEN" ]] # ${{ github.sha }}`, secrets, nil)
+func RenderDelims(ctx context.Context, w io.Writer, left, right, tmplText string, m MultiLookup, data any, opts ...Option) error {
+	mc := m.BindContext(ctx, opts...)
+	if err := mc.Validate(); err != nil {
+		return err
+	}
+	return mc.render(w, left, right, tmplText, data)
+}
+
+// render は、 m を DefaultFuncName という関数として登録して tmplText を解析し、 data で w に描画します。
+//
+// render parses tmplText with m registered as the function DefaultFuncName, and renders it with data to w.
+func (m *MultiLookupContext) render(w io.Writer, left, right, tmplText string, data any) error {
+	if m.opts.outputCheck != nil {
+		return m.renderChecked(w, left, right, tmplText, data)
+	}
+	return m.execute(w, left, right, tmplText, data)
+}
+
+// execute は、 WithOutputCheck を除いて render と同様です。
+//
+// execute is the same as render except for WithOutputCheck.
+func (m *MultiLookupContext) execute(w io.Writer, left, right, tmplText string, data any) error {
+	if m.opts.renderPasses > 1 {
+		return m.renderPasses(w, left, right, tmplText, data)
+	}
+	t, err := RegisterTo(template.New("").Delims(left, right), DefaultFuncName, m).Parse(tmplText)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	if err := t.Execute(w, data); err != nil {
+		return AnnotateTemplateError(err)
+	}
+	return nil
+}
+
+// RenderStringDelims は、 RenderDelims の結果を文字列として返します。
+//
+// RenderStringDelims returns the result of RenderDelims as a string.
+func RenderStringDelims(ctx context.Context, left, right, tmplText string, m MultiLookup, data any, opts ...Option) (string, error) {
+	b := &strings.Builder{}
+	if err := RenderDelims(ctx, b, left, right, tmplText, m, data, opts...); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ParseFiles は template.ParseFiles と同様にファイルを解析しますが、解析の前に m の FuncMapValue を funcName という関数として登録します。
+// define ブロックで funcName を使うテンプレートも、解析より前に Funcs を呼び出す順序を気にすることなく解析できます。
+//
+// ParseFiles parses the files as template.ParseFiles does, but registers FuncMapValue of m as the function funcName before parsing.
+// Templates using funcName in define blocks parse correctly without the usual trap of calling Funcs before Parse.
+//
+//	tpl := template.Must(tempura.ParseFiles("secret", secrets.BindContext(ctx), "config.yaml.tmpl", "partials.tmpl"))
+func ParseFiles(funcName string, m FuncMapper, filenames ...string) (*template.Template, error) {
+	if len(filenames) == 0 {
+		return template.ParseFiles() // en: the same error as template.ParseFiles
+	}
+	return RegisterTo(template.New(filepath.Base(filenames[0])), funcName, m).ParseFiles(filenames...)
+}
+
+// ParseFS は template.ParseFS と同様に fsys のファイルを解析しますが、解析の前に m の FuncMapValue を funcName という関数として登録します。
+//
+// ParseFS parses the files in fsys as template.ParseFS does, but registers FuncMapValue of m as the function funcName before parsing.
+//
+//	//go:embed templates
+//	var templates embed.FS
+//	tpl := template.Must(tempura.ParseFS("secret", secrets.BindContext(ctx), templates, "templates/*.tmpl"))
+func ParseFS(funcName string, m FuncMapper, fsys fs.FS, patterns ...string) (*template.Template, error) {
+	name, err := firstMatch(fsys, patterns)
+	if err != nil || name == "" {
+		return template.ParseFS(fsys, patterns...) // en: the same error as template.ParseFS
+	}
+	return RegisterTo(template.New(name), funcName, m).ParseFS(fsys, patterns...)
+}
+
+// ParseHTMLFiles は、 html/template のための ParseFiles です。
+//
+// ParseHTMLFiles is ParseFiles for html/template.
+func ParseHTMLFiles(funcName string, m FuncMapper, filenames ...string) (*htmltemplate.Template, error) {
+	if len(filenames) == 0 {
+		return htmltemplate.ParseFiles()
+	}
+	return RegisterToHTML(htmltemplate.New(filepath.Base(filenames[0])), funcName, m).ParseFiles(filenames...)
+}
+
+// ParseHTMLFS は、 html/template のための ParseFS です。
+//
+// ParseHTMLFS is ParseFS for html/template.
+func ParseHTMLFS(funcName string, m FuncMapper, fsys fs.FS, patterns ...string) (*htmltemplate.Template, error) {
+	name, err := firstMatch(fsys, patterns)
+	if err != nil || name == "" {
+		return htmltemplate.ParseFS(fsys, patterns...)
+	}
+	return RegisterToHTML(htmltemplate.New(name), funcName, m).ParseFS(fsys, patterns...)
+}
+
+// firstMatch は、 template.ParseFS が最初のテンプレートの名前に使うファイルの名前を返します。
+//
+// firstMatch returns the name of the file that template.ParseFS uses as the name of the first template.
+func firstMatch(fsys fs.FS, patterns []string) (string, error) {
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) > 0 {
+			return path.Base(matches[0]), nil
+		}
+	}
+	return "", nil
+}
+
+// FuncConflictError は、 MergeFuncMaps に渡された複数の template.FuncMap が同じ名前の関数を持っていたことを表します。
+//
+// FuncConflictError represents that multiple template.FuncMaps passed to MergeFuncMaps have functions of the same names.
+type FuncConflictError struct {
+	Names []string
+}
+
+func (e FuncConflictError) Error() string {
+	return fmt.Sprintf("conflicting function names: %s", strings.Join(e.Names, ", "))
+}
+
+// MergeFuncMaps は maps を1つの template.FuncMap にまとめます。
+// 後のものが黙って上書きするのではなく、同じ名前の関数が複数の maps にある場合は、その全ての名前を FuncConflictError で返します。
+// tempura の関数を sprig などの他の template.FuncMap と組み合わせるためのものです。
+//
+// MergeFuncMaps merges maps into a single template.FuncMap.
+// Instead of silently overriding with later ones, it returns all the names of functions found in multiple maps as FuncConflictError.
+// It is for combining the functions of tempura with other template.FuncMaps such as sprig.
+//
+//	funcs, err := tempura.MergeFuncMaps(sprig.TxtFuncMap(), secrets.FuncMap("secret"))
+func MergeFuncMaps(maps ...template.FuncMap) (template.FuncMap, error) {
+	merged := make(template.FuncMap)
+	var conflicts []string
+	conflicted := make(map[string]bool)
+	for _, funcs := range maps {
+		for name, fn := range funcs {
+			if _, ok := merged[name]; ok {
+				if !conflicted[name] { // en: report names in three or more maps only once
+					conflicted[name] = true
+					conflicts = append(conflicts, name)
+				}
+				continue
+			}
+			merged[name] = fn
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, FuncConflictError{Names: conflicts}
+	}
+	return merged, nil
+}
@@ -0,0 +1,73 @@
+package tempura
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"text/template"
+)
+
+// =================================================================================
+// Positions of lookups in templates
+// =================================================================================
+
+// TemplateError は、テンプレートの実行中に失敗した関数の呼び出しを、テンプレートの名前とその位置と共に表します。
+// 大きなテンプレートの木の中から、失敗した {{ secret "..." }} の呼び出しを見つけるためのものです。
+//
+// TemplateError represents a call of a function that failed while executing a template, along with the name of the template and its position.
+// It helps users find the failing {{ secret "..." }} call in large template trees.
+type TemplateError struct {
+	// Template は実行していたテンプレートの名前、 File はそれを含むテンプレートを解析したときの名前 (ParseFiles ではファイル名) です。
+	// Template is the name of the template being executed, and File is the name under which the template containing it was parsed, or the file name with ParseFiles.
+	Template string
+	File     string
+	Line     int
+	Column   int
+	// Call は失敗した呼び出しの記述で、長い場合は省略されます。
+	// Call is the text of the failing call, abbreviated if long.
+	Call string
+	Err  error
+}
+
+func (e TemplateError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: template %q at {{ %s }}: %v", e.File, e.Line, e.Column, e.Template, e.Call, e.Err)
+}
+
+func (e TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// execCallError は、 text/template が関数の呼び出しの失敗に付けるメッセージの形式です。
+// 公開されていない形式のため、 TestAnnotateTemplateError_StdlibFormat で実際の出力と一致することを確認しています。
+//
+// execCallError is the format of messages that text/template gives to failures of function calls.
+// Since the format is not part of the API, TestAnnotateTemplateError_StdlibFormat checks that it matches the actual output.
+var execCallError = regexp.MustCompile(`^template: (.*):(\d+):(\d+): executing ".*" at <(.*)>: error calling \S+: `)
+
+// AnnotateTemplateError は、 Execute が返した err がテンプレート (html/template を含む) の関数の呼び出しの失敗であれば、それを TemplateError に変換します。
+// それ以外の場合は err をそのまま返します。 Render 、 RenderString 、 RenderStream 、 RenderAll は全てのエラーにこれを適用しますが、 RegisterTo で登録したテンプレートでは呼び出し元が適用する必要があります。
+//
+// AnnotateTemplateError converts err returned by Execute into a TemplateError if it is a failure of a function call in a template, including html/template.
+// Otherwise, it returns err as is. Render, RenderString, RenderStream and RenderAll apply it to all errors, but for templates registered via RegisterTo, callers must apply it themselves.
+//
+//	if err := tpl.Execute(w, data); err != nil {
+//		return tempura.AnnotateTemplateError(err) // en: config.yaml.tmpl:12:9: template "db" at {{ secret "vault.DB_PASS" }}: ...
+//	}
+func AnnotateTemplateError(err error) error {
+	var ee template.ExecError
+	if !errors.As(err, &ee) {
+		return err
+	}
+	cause := errors.Unwrap(ee.Err)
+	if cause == nil {
+		return err
+	}
+	match := execCallError.FindStringSubmatch(ee.Err.Error())
+	if match == nil {
+		return err
+	}
+	line, _ := strconv.Atoi(match[2])
+	column, _ := strconv.Atoi(match[3])
+	return TemplateError{Template: ee.Name, File: match[1], Line: line, Column: column, Call: match[4], Err: cause}
+}
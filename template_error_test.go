@@ -0,0 +1,127 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"testing"
+	"text/template"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotateTemplateError(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		if key == "FAIL" {
+			return "", false, errUnavailable
+		}
+		return "", false, nil
+	}
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}.BindContext(context.Background())
+	funcs := template.FuncMap{"secret": lookup.FuncMapValue}
+
+	tests := []struct {
+		name     string
+		execute  func() error
+		expected tempura.TemplateError
+		errStr   string
+	}{
+		// ==================== INVALID CASES ====================
+		{
+			name: "text/template",
+			execute: func() error {
+				tpl := template.Must(template.New("config.yaml").Funcs(funcs).Parse("user: root\npass: {{ secret \"secret.FAIL\" }}\n"))
+				return tpl.Execute(io.Discard, nil)
+			},
+			expected: tempura.TemplateError{Template: "config.yaml", File: "config.yaml", Line: 2, Column: 9, Call: `secret "secret.FAIL"`},
+			errStr:   `config.yaml:2:9: template "config.yaml" at {{ secret "secret.FAIL" }}: failed to look up FAIL by secret (LookupAnyWithContextError): ` + errUnavailable.Error(),
+		},
+		{
+			name: "defined template",
+			execute: func() error {
+				tpl := template.Must(template.New("root").Funcs(funcs).Parse("{{ define \"db\" }}\n{{ secret \"secret.MISSING\" }}{{ end }}{{ template \"db\" }}"))
+				return tpl.Execute(io.Discard, nil)
+			},
+			expected: tempura.TemplateError{Template: "db", File: "root", Line: 2, Column: 3, Call: `secret "secret.MISSING"`},
+		},
+		{
+			name: "html/template",
+			execute: func() error {
+				tpl := htmltemplate.Must(htmltemplate.New("page.html").Funcs(htmltemplate.FuncMap(funcs)).Parse(`<p>{{ secret "secret.FAIL" }}</p>`))
+				return tpl.Execute(io.Discard, nil)
+			},
+			expected: tempura.TemplateError{Template: "page.html", File: "page.html", Line: 1, Column: 6, Call: `secret "secret.FAIL"`},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tempura.AnnotateTemplateError(tt.execute())
+			var te tempura.TemplateError
+			require.ErrorAs(t, err, &te)
+			assert.Equal(t, tt.expected.Template, te.Template)
+			assert.Equal(t, tt.expected.File, te.File)
+			assert.Equal(t, tt.expected.Line, te.Line)
+			assert.Equal(t, tt.expected.Column, te.Column)
+			assert.Contains(t, te.Call, tt.expected.Call)
+			if tt.errStr != "" {
+				assert.Equal(t, tt.errStr, err.Error())
+			}
+		})
+	}
+
+	t.Run("other errors as is", func(t *testing.T) {
+		t.Parallel()
+
+		tpl := template.Must(template.New("x").Parse(`{{ template "undefined" }}`))
+		err := tpl.Execute(&bytes.Buffer{}, nil)
+		require.Error(t, err)
+		assert.Equal(t, err, tempura.AnnotateTemplateError(err))
+		assert.Nil(t, tempura.AnnotateTemplateError(nil))
+		assert.False(t, errors.As(tempura.AnnotateTemplateError(errUnavailable), new(tempura.TemplateError)))
+	})
+}
+
+// TestAnnotateTemplateError_StdlibFormat は、 AnnotateTemplateError が依存する text/template のエラーメッセージの形式が変わっていないことを確認します。
+//
+// TestAnnotateTemplateError_StdlibFormat checks that the format of error messages of text/template, which AnnotateTemplateError relies on, has not changed.
+func TestAnnotateTemplateError_StdlibFormat(t *testing.T) {
+	t.Parallel()
+
+	funcs := template.FuncMap{"fail": func(string) (string, error) { return "", errUnavailable }}
+	executes := map[string]func() error{
+		"text/template": func() error {
+			return template.Must(template.New("t").Funcs(funcs).Parse("\n  {{ fail \"x\" }}")).Execute(io.Discard, nil)
+		},
+		"html/template": func() error {
+			return htmltemplate.Must(htmltemplate.New("t").Funcs(htmltemplate.FuncMap(funcs)).Parse("\n  {{ fail \"x\" }}")).Execute(io.Discard, nil)
+		},
+	}
+
+	for name, execute := range executes {
+		name, execute := name, execute
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			raw := execute()
+			require.ErrorIs(t, raw, errUnavailable)
+			var te tempura.TemplateError
+			if !errors.As(tempura.AnnotateTemplateError(raw), &te) {
+				t.Fatalf("the error message format of %s has changed and AnnotateTemplateError no longer recognizes it; update execCallError to match: %q", name, raw.Error())
+			}
+			assert.Equal(t, tempura.TemplateError{Template: "t", File: "t", Line: 2, Column: 5, Call: `fail "x"`, Err: te.Err}, te)
+			assert.ErrorIs(t, te.Err, errUnavailable)
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package tempura_test
+
+import (
+	"bytes"
+	"context"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuncMap(t *testing.T) {
+	t.Parallel()
+
+	getEnv := func(key string) (string, bool) {
+		return "value-of-" + key, key != "MISSING"
+	}
+	params := tempura.MultiLookup{
+		tempura.SlashPrefix("env"):     tempura.Func(getEnv),
+		tempura.SlashPrefix("default"): tempura.Func(func(key string) (string, bool) { return key, true }),
+	}
+
+	tests := []struct {
+		name     string
+		funcs    template.FuncMap
+		html     bool
+		text     string
+		expected string
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "MultiLookup",
+			funcs:    params.FuncMap("param"),
+			text:     `{{ param "env/USER" }}`,
+			expected: "value-of-USER",
+		},
+		{
+			name:     "MultiLookupContext",
+			funcs:    params.BindContext(context.Background()).FuncMap("param"),
+			text:     `{{ param "env/MISSING" "default/root" }}`,
+			expected: "root",
+		},
+		{
+			name:     "html/template",
+			funcs:    params.BindContext(context.Background()).FuncMap("param"),
+			html:     true,
+			text:     `<p>{{ param "default/<b>" }}</p>`,
+			expected: "<p>&lt;b&gt;</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			if tt.html {
+				tpl := htmltemplate.Must(htmltemplate.New("").Funcs(htmltemplate.FuncMap(tt.funcs)).Parse(tt.text))
+				require.NoError(t, tpl.Execute(buf, nil))
+			} else {
+				tpl := template.Must(template.New("").Funcs(tt.funcs).Parse(tt.text))
+				require.NoError(t, tpl.Execute(buf, nil))
+			}
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestRegisterTo(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		return "secret-of-" + key, key != "MISSING", nil
+	}
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}
+	placeholder := template.FuncMap{"secret": func(args ...string) (any, error) { return nil, nil }}
+
+	tests := []struct {
+		name     string
+		render   func(buf *bytes.Buffer) error
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name: "before parsing",
+			render: func(buf *bytes.Buffer) error {
+				tpl := template.Must(tempura.RegisterTo(template.New("config"), "secret", secrets.BindContext(context.Background())).Parse(`pass={{ secret "secret.DB_PASS" }}`))
+				return tpl.Execute(buf, nil)
+			},
+			expected: "pass=secret-of-DB_PASS",
+		},
+		{
+			name: "after parsing elsewhere",
+			render: func(buf *bytes.Buffer) error {
+				tpl := template.Must(template.New("config").Funcs(placeholder).Parse(`pass={{ secret "secret.DB_PASS" }}`))
+				return tempura.RegisterTo(tpl, "secret", secrets.BindContext(context.Background())).Execute(buf, nil)
+			},
+			expected: "pass=secret-of-DB_PASS",
+		},
+		{
+			name: "MultiLookup",
+			render: func(buf *bytes.Buffer) error {
+				params := tempura.MultiLookup{tempura.SlashPrefix("default"): tempura.Func(func(key string) (string, bool) { return key, true })}
+				tpl := template.Must(tempura.RegisterTo(template.New("config"), "param", params).Parse(`user={{ param "default/root" }}`))
+				return tpl.Execute(buf, nil)
+			},
+			expected: "user=root",
+		},
+		{
+			name: "html/template",
+			render: func(buf *bytes.Buffer) error {
+				tpl := htmltemplate.Must(tempura.RegisterToHTML(htmltemplate.New("page"), "secret", secrets.BindContext(context.Background())).Parse(`<p>{{ secret "secret.<b>" }}</p>`))
+				return tpl.Execute(buf, nil)
+			},
+			expected: "<p>secret-of-&lt;b&gt;</p>",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "position of the failing call",
+			render: func(buf *bytes.Buffer) error {
+				tpl := template.Must(tempura.RegisterTo(template.New("config"), "secret", secrets.BindContext(context.Background())).Parse("user: root\npass: {{ secret \"secret.MISSING\" }}"))
+				return tempura.AnnotateTemplateError(tpl.Execute(buf, nil))
+			},
+			expected: "user: root\npass: ",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+				var te tempura.TemplateError
+				require.ErrorAs(t, err, &te)
+				assert.Equal(t, 2, te.Line)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			err := tt.render(buf)
+			if tt.checkErr != nil {
+				require.Error(t, err)
+				tt.checkErr(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestRenderString(t *testing.T) {
+	t.Parallel()
+
+	fetchSecret := func(ctx context.Context, key string) (string, bool, error) {
+		if key == "FAIL" {
+			return "", false, errUnavailable
+		}
+		return "secret-of-" + key, key != "MISSING", nil
+	}
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.FuncWithContextError(fetchSecret),
+	}
+
+	tests := []struct {
+		name     string
+		m        tempura.MultiLookup
+		text     string
+		data     any
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "with data",
+			m:        secrets,
+			text:     `{{ .User }}:{{ lookup "secret.DB_PASS" }}`,
+			data:     map[string]string{"User": "app"},
+			expected: "app:secret-of-DB_PASS",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "parse error",
+			m:    secrets,
+			text: `{{ lookup "secret.DB_PASS" `,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "failed to parse template")
+			},
+		},
+		{
+			name: "lookup error",
+			m:    secrets,
+			text: "user: app\npass: {{ lookup \"secret.FAIL\" }}",
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, errUnavailable)
+				var te tempura.TemplateError
+				require.ErrorAs(t, err, &te)
+				assert.Equal(t, 2, te.Line)
+			},
+		},
+		{
+			name: "invalid function",
+			m:    tempura.MultiLookup{tempura.DotPrefix("secret"): nil},
+			text: `{{ lookup "secret.DB_PASS" }}`,
+			checkErr: func(t *testing.T, err error) {
+				var ife tempura.InvalidFunctionError
+				assert.ErrorAs(t, err, &ife)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := tempura.RenderString(context.Background(), tt.text, tt.m, tt.data)
+			if tt.checkErr != nil {
+				require.Error(t, err)
+				tt.checkErr(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, s)
+
+			buf := &bytes.Buffer{}
+			err = tempura.Render(context.Background(), buf, tt.text, tt.m, tt.data)
+			assert.Equal(t, tt.checkErr == nil, err == nil)
+		})
+	}
+}
+
+func TestRenderStringDelims(t *testing.T) {
+	t.Parallel()
+
+	secrets := tempura.MultiLookup{
+		tempura.DotPrefix("secret"): tempura.Func(func(key string) (string, bool) { return "secret-of-" + key, true }),
+	}
+
+	s, err := tempura.RenderStringDelims(context.Background(), "[[", "]]", `token: [[ lookup "secret.TOKEN" ]] # ${{ github.sha }}`, secrets, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "token: secret-of-TOKEN # ${{ github.sha }}", s)
+
+	s, err = tempura.RenderStringDelims(context.Background(), "", "", `{{ lookup "secret.TOKEN" }}`, secrets, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-of-TOKEN", s)
+
+	buf := &bytes.Buffer{}
+	err = tempura.RenderDelims(context.Background(), buf, "[[", "]]", `[[ lookup "secret.TOKEN" `, secrets, nil)
+	assert.ErrorContains(t, err, "failed to parse template")
+}
+
+func TestParseFiles(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"config.yaml.tmpl": `db: {{ template "db" }}`,
+		"partials.tmpl":    `{{ define "db" }}{{ param "env/DB_USER" }}@{{ param "env/DB_HOST" }}{{ end }}`,
+	}
+	dir := t.TempDir()
+	fsys := fstest.MapFS{}
+	for name, text := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600))
+		fsys["templates/"+name] = &fstest.MapFile{Data: []byte(text)}
+	}
+	params := tempura.MultiLookup{
+		tempura.SlashPrefix("env"): tempura.Func(func(key string) (string, bool) {
+			return map[string]string{"DB_USER": "app", "DB_HOST": "<db>"}[key], true
+		}),
+	}
+
+	type executor interface {
+		Execute(w io.Writer, data any) error
+	}
+	tests := []struct {
+		name     string
+		parse    func() (executor, error)
+		expected string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name: "ParseFiles",
+			parse: func() (executor, error) {
+				return tempura.ParseFiles("param", params, filepath.Join(dir, "config.yaml.tmpl"), filepath.Join(dir, "partials.tmpl"))
+			},
+			expected: "db: app@<db>",
+		},
+		{
+			name: "ParseFS",
+			parse: func() (executor, error) {
+				return tempura.ParseFS("param", params.BindContext(context.Background()), fsys, "templates/config.*", "templates/partials.tmpl")
+			},
+			expected: "db: app@<db>",
+		},
+		{
+			name: "ParseHTMLFiles",
+			parse: func() (executor, error) {
+				return tempura.ParseHTMLFiles("param", params, filepath.Join(dir, "config.yaml.tmpl"), filepath.Join(dir, "partials.tmpl"))
+			},
+			expected: "db: app@&lt;db&gt;",
+		},
+		{
+			name: "ParseHTMLFS",
+			parse: func() (executor, error) {
+				return tempura.ParseHTMLFS("param", params, fsys, "templates/config.*", "templates/partials.tmpl")
+			},
+			expected: "db: app@&lt;db&gt;",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "no files",
+			parse: func() (executor, error) {
+				return tempura.ParseFiles("param", params)
+			},
+			checkErr: func(t *testing.T, err error) {
+				_, expected := template.ParseFiles()
+				assert.EqualError(t, err, expected.Error())
+			},
+		},
+		{
+			name: "no matches",
+			parse: func() (executor, error) {
+				return tempura.ParseFS("param", params, fsys, "missing/*")
+			},
+			checkErr: func(t *testing.T, err error) {
+				_, expected := template.ParseFS(fsys, "missing/*")
+				assert.EqualError(t, err, expected.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tpl, err := tt.parse()
+			if tt.checkErr != nil {
+				require.Error(t, err)
+				tt.checkErr(t, err)
+				return
+			}
+			require.NoError(t, err)
+			buf := &bytes.Buffer{}
+			require.NoError(t, tpl.Execute(buf, nil))
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestMergeFuncMaps(t *testing.T) {
+	t.Parallel()
+
+	params := tempura.MultiLookup{
+		tempura.SlashPrefix("default"): tempura.Func(func(key string) (string, bool) { return key, true }),
+	}
+	sprigLike := template.FuncMap{"upper": func(s string) string { return s }, "default": func(d, v any) any { return v }}
+
+	tests := []struct {
+		name     string
+		maps     []template.FuncMap
+		expected []string
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "no conflicts",
+			maps:     []template.FuncMap{sprigLike, params.FuncMap("param")},
+			expected: []string{"default", "param", "upper"},
+		},
+		{
+			name:     "nothing",
+			expected: []string{},
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "conflicts",
+			maps: []template.FuncMap{sprigLike, params.FuncMap("default"), params.FuncMap("upper")},
+			checkErr: func(t *testing.T, err error) {
+				var fce tempura.FuncConflictError
+				require.ErrorAs(t, err, &fce)
+				assert.Equal(t, []string{"default", "upper"}, fce.Names)
+				assert.EqualError(t, err, "conflicting function names: default, upper")
+			},
+		},
+		{
+			name: "conflicts in three maps",
+			maps: []template.FuncMap{sprigLike, params.FuncMap("default"), params.FuncMap("default")},
+			checkErr: func(t *testing.T, err error) {
+				var fce tempura.FuncConflictError
+				require.ErrorAs(t, err, &fce)
+				assert.Equal(t, []string{"default"}, fce.Names)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			funcs, err := tempura.MergeFuncMaps(tt.maps...)
+			if tt.checkErr != nil {
+				require.Error(t, err)
+				tt.checkErr(t, err)
+				return
+			}
+			require.NoError(t, err)
+			names := make([]string, 0, len(funcs))
+			for name := range funcs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
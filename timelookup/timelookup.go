@@ -0,0 +1,59 @@
+// Package timelookup は、現在時刻を様々な形式で返す探索関数を提供します。
+//
+// Package timelookup provides a lookup function that returns the current time in various formats.
+package timelookup
+
+import (
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+)
+
+// Clock は現在時刻を返す関数です。テストでは固定の時刻を返す関数を注入できます。
+//
+// Clock is a function that returns the current time. Tests can inject one that returns a fixed time.
+type Clock func() time.Time
+
+// New は、prefixを取り除いたキーに応じて clock の時刻を返す探索関数を返します。
+// clock が nil の場合は time.Now が使われます。
+// レイアウトの要素を含まないキー (おそらく書き間違い) は見つからなかったものとして扱われます。
+//
+//   - "now": RFC 3339 形式の文字列 / a string in RFC 3339 format
+//   - "unix", "unixmilli": Unix 時間の int64 / Unix time as int64
+//   - それ以外: time.Format のレイアウトとして解釈 / otherwise: interpreted as a layout of time.Format
+//
+// New returns a lookup function that returns the time of clock depending on the key with the prefix removed.
+// If clock is nil, time.Now is used.
+// A key that contains no layout elements (probably a typo) is treated as not found.
+func New(clock Clock) tempura.LookupAny {
+	if clock == nil {
+		clock = time.Now
+	}
+
+	return func(key string) (any, bool) {
+		now := clock()
+		switch key {
+		case "now":
+			return now.Format(time.RFC3339), true
+		case "unix":
+			return now.Unix(), true
+		case "unixmilli":
+			return now.UnixMilli(), true
+		}
+
+		formatted := now.Format(key)
+		if formatted == key {
+			return nil, false
+		}
+		return formatted, true
+	}
+}
+
+// Fixed は常に t を返す Clock です。
+//
+// Fixed is a Clock that always returns t.
+func Fixed(t time.Time) Clock {
+	return func() time.Time {
+		return t
+	}
+}
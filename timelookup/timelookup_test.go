@@ -0,0 +1,82 @@
+package timelookup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/timelookup"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	jst := time.FixedZone("JST", 9*60*60)
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("time"): timelookup.New(timelookup.Fixed(time.Date(2024, 4, 1, 9, 30, 0, 0, jst))),
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "now",
+			args:     []string{"time.now"},
+			expected: "2024-04-01T09:30:00+09:00",
+		},
+		{
+			name:     "unix",
+			args:     []string{"time.unix"},
+			expected: int64(1711931400),
+		},
+		{
+			name:     "unixmilli",
+			args:     []string{"time.unixmilli"},
+			expected: int64(1711931400000),
+		},
+		{
+			name:     "layout",
+			args:     []string{"time.2006-01-02"},
+			expected: "2024-04-01",
+		},
+		{
+			name:     "layout with time zone",
+			args:     []string{"time.15:04 MST"},
+			expected: "09:30 JST",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "typo without layout elements",
+			args: []string{"time.nwo"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestNew_DefaultClock(t *testing.T) {
+	t.Parallel()
+
+	before := time.Now().Unix()
+	val, ok := timelookup.New(nil)("unix")
+	assert.True(t, ok)
+	assert.GreaterOrEqual(t, val, before)
+}
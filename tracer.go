@@ -0,0 +1,46 @@
+package tempura
+
+import (
+	"context"
+	"errors"
+)
+
+// =================================================================================
+// Tracing of lookups
+// =================================================================================
+
+// Tracer は、探索ごとにスパンを作成します。 OpenTelemetry による実装は oteltempura パッケージにあります。
+// StartLookup は FuncMapValue または Resolve の呼び出しごとに、 StartProvider は探索関数の呼び出しごとに、その子として呼び出されます。
+// 値そのものは Tracer に渡されません。
+//
+// Tracer creates spans for lookups. An implementation with OpenTelemetry is in the oteltempura package.
+// StartLookup is called for each call of FuncMapValue or Resolve, and StartProvider for each call of a lookup function as its child.
+// Values themselves are never passed to Tracer.
+type Tracer interface {
+	StartLookup(ctx context.Context, args []string) (context.Context, SpanEnd)
+	StartProvider(ctx context.Context, prefix Prefix, key string, provider string) (context.Context, SpanEnd)
+}
+
+// SpanEnd はスパンを終了します。 outcome は found, not_found, error のいずれかです。
+//
+// SpanEnd ends a span. outcome is one of found, not_found, and error.
+type SpanEnd func(outcome string, err error)
+
+// WithTracer は、探索ごとに tracer でスパンを作成します。遅い描画が分散トレースの中で分かるようになります。
+//
+// WithTracer creates spans for lookups with tracer, so that slow renders show up in distributed traces.
+func WithTracer(tracer Tracer) Option {
+	return func(o *options) {
+		o.tracer = tracer
+	}
+}
+
+// notFoundAsNil は、見つからなかったことはスパンのエラーとして扱わないために使います。
+//
+// notFoundAsNil is used so that not finding a value is not treated as an error of the span.
+func notFoundAsNil(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
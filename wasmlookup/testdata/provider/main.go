@@ -0,0 +1,41 @@
+// Command provider は wasmlookup のテストで使うプロバイダで、 GOOS=wasip1 GOARCH=wasm でビルドされます。
+//
+// Command provider is a provider used in tests of wasmlookup, built with GOOS=wasip1 GOARCH=wasm.
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/pluginlookup"
+)
+
+func main() {
+	info := pluginlookup.Info{Protocol: pluginlookup.ProtocolVersion, Description: "test provider"}
+	lookup := tempura.FuncWithContextError(func(ctx context.Context, key string) (any, bool, error) {
+		switch key {
+		case "DB_PASS":
+			return "p4ss", true, nil
+		case "PORT":
+			return 5432, true, nil
+		case "GREETING":
+			return os.Getenv("GREETING"), true, nil
+		case "ARG":
+			return os.Args[1], true, nil
+		case "FILE":
+			_, err := os.ReadFile("/etc/passwd")
+			return nil, false, err
+		case "LOOP":
+			for {
+			}
+		case "FAIL":
+			return nil, false, errors.New("permission denied")
+		}
+		return nil, false, nil
+	})
+	if err := pluginlookup.Serve(context.Background(), os.Stdin, os.Stdout, info, lookup); err != nil {
+		os.Exit(1)
+	}
+}
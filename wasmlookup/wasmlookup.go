@@ -0,0 +1,197 @@
+// Package wasmlookup は、 WASI 向けに WebAssembly にコンパイルされたプロバイダを wazero で実行する探索関数を提供します。
+// プロセスを起動することなく、言語に依存しないプロバイダをサンドボックスの中で動かせるため、制限された CI 環境で使えます。
+//
+// Package wasmlookup provides a lookup function that runs providers compiled to WebAssembly for WASI with wazero.
+// Language-agnostic providers run in a sandbox without spawning processes, which suits restricted CI environments.
+//
+// モジュールは pluginlookup と同じプロトコルを標準入出力で話します。探索のたびに新しいインスタンスを作り、1つのリクエストを書き込んだ標準入力を渡します。
+// そのため、 pluginlookup.Serve を使った Go のプラグインは、 GOOS=wasip1 GOARCH=wasm でビルドするだけで使えます。
+// モジュールはファイルシステムやネットワークにアクセスできず、 Config で渡した引数と環境変数だけを受け取ります。
+//
+// Modules speak the same protocol as pluginlookup over stdio. Each lookup creates a new instance and passes stdin holding a single request.
+// Therefore, plugins in Go using pluginlookup.Serve work just by being built with GOOS=wasip1 GOARCH=wasm.
+// Modules cannot access the file system or the network, and receive only the args and the environment variables passed by Config.
+package wasmlookup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/pluginlookup"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const DefaultTimeout = 10 * time.Second
+
+// Config はモジュールの実行条件を定義します。
+//
+// Config defines how the module is run.
+type Config struct {
+	// Name はエラーに表示されるモジュールの名前で、 argv[0] として渡されます。
+	// Name is the name of the module shown in errors, passed as argv[0].
+	Name string
+
+	// Args はモジュールに渡す引数です。
+	// Args is the arguments passed to the module.
+	Args []string
+
+	// Env はモジュールに渡す "KEY=value" 形式の環境変数です。親プロセスの環境変数は引き継ぎません。
+	// Env is the environment variables in the form of "KEY=value" passed to the module. The environment of the parent process is not inherited.
+	Env []string
+
+	// Timeout は1回の実行の制限時間です。0 の場合は DefaultTimeout が使われます。
+	// Timeout limits a single execution. DefaultTimeout is used if it is 0.
+	Timeout time.Duration
+
+	// MemoryLimitPages は、モジュールが使えるメモリの上限を 64KiB のページ数で指定します。0 の場合は wazero の既定値 (4GiB) です。
+	// MemoryLimitPages limits the memory the module can use, in pages of 64KiB. The default of wazero (4GiB) is used if it is 0.
+	MemoryLimitPages uint32
+
+	// Stderr はモジュールの標準エラー出力の書き込み先です。 nil の場合は捨てられます。
+	// Stderr is where the standard error of the module is written. It is discarded if nil.
+	Stderr io.Writer
+}
+
+// Module はコンパイル済みのモジュールです。コンパイルは New で一度だけ行います。
+//
+// Module is a compiled module. Compilation happens only once, in New.
+type Module struct {
+	cfg      Config
+	timeout  time.Duration
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// New は wasm をコンパイルして Module を返します。使い終わったら Close を呼んでください。
+//
+// New compiles wasm and returns a Module. Call Close when done.
+func New(ctx context.Context, wasm []byte, cfg Config) (*Module, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if cfg.Name == "" {
+		cfg.Name = "module"
+	}
+	for _, env := range cfg.Env {
+		if !strings.Contains(env, "=") {
+			return nil, fmt.Errorf("invalid env %q: must be in the form of KEY=value", env)
+		}
+	}
+
+	rc := wazero.NewRuntimeConfig().WithCloseOnContextDone(true) // en: let timeouts interrupt even infinite loops
+	if cfg.MemoryLimitPages > 0 {
+		rc = rc.WithMemoryLimitPages(cfg.MemoryLimitPages)
+	}
+	r := wazero.NewRuntimeWithConfig(ctx, rc)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		r.Close(ctx)
+		return nil, fmt.Errorf("failed to compile %s: %w", cfg.Name, err)
+	}
+	return &Module{cfg: cfg, timeout: timeout, runtime: r, compiled: compiled}, nil
+}
+
+// Lookup は、prefixを取り除いたキーをモジュールに問い合わせる探索関数を返します。
+// 値の数値は json.Number として返されるため、テンプレートでは書かれたとおりに出力されます。
+//
+// Lookup returns a lookup function that asks the module for the key with the prefix removed.
+// Numbers in values are returned as json.Number, so they are output in templates as written.
+func (m *Module) Lookup() tempura.LookupAnyWithContextError {
+	return func(ctx context.Context, key string) (any, bool, error) {
+		b, _ := json.Marshal(pluginlookup.Request{ID: 1, Key: key})
+		_, dec, err := m.run(ctx, append(b, '\n'))
+		if err != nil {
+			return nil, false, err
+		}
+		var res pluginlookup.Response
+		if err := dec.Decode(&res); err != nil {
+			return nil, false, fmt.Errorf("%s: failed to read response: %w: %v", m.cfg.Name, pluginlookup.ErrProtocol, err)
+		}
+		if res.ID != 1 {
+			return nil, false, fmt.Errorf("%s: response to unknown request %d: %w", m.cfg.Name, res.ID, pluginlookup.ErrProtocol)
+		}
+		val, found, err := res.Result()
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", m.cfg.Name, err)
+		}
+		return val, found, nil
+	}
+}
+
+// Info はリクエストを渡さずにモジュールを実行し、ハンドシェイクで返された情報を返します。モジュールが使えるかの確認に使えます。
+//
+// Info runs the module without requests, and returns what it returned in the handshake. It can be used to check whether the module is usable.
+func (m *Module) Info(ctx context.Context) (pluginlookup.Info, error) {
+	info, _, err := m.run(ctx, nil)
+	return info, err
+}
+
+func (m *Module) Close(ctx context.Context) error {
+	return m.runtime.Close(ctx)
+}
+
+// run は、 stdin を標準入力としてモジュールのインスタンスを実行し、ハンドシェイクを検証して、残りの標準出力の Decoder を返します。
+//
+// run runs an instance of the module with stdin as the standard input, verifies the handshake, and returns a Decoder of the rest of the standard output.
+func (m *Module) run(ctx context.Context, stdin []byte) (pluginlookup.Info, *json.Decoder, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	stdout := &bytes.Buffer{}
+	mc := wazero.NewModuleConfig().
+		WithName(""). // en: anonymous, so that instances can run concurrently
+		WithArgs(append([]string{m.cfg.Name}, m.cfg.Args...)...).
+		WithStdin(bytes.NewReader(stdin)).
+		WithStdout(stdout).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader)
+	if m.cfg.Stderr != nil {
+		mc = mc.WithStderr(m.cfg.Stderr)
+	}
+	for _, env := range m.cfg.Env {
+		key, val, _ := strings.Cut(env, "=")
+		mc = mc.WithEnv(key, val)
+	}
+
+	mod, err := m.runtime.InstantiateModule(ctx, m.compiled, mc)
+	if mod != nil {
+		defer mod.Close(ctx)
+	}
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+		err = nil // en: WASI commands exit through proc_exit(0)
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return pluginlookup.Info{}, nil, fmt.Errorf("%s: %w", m.cfg.Name, ctxErr)
+	}
+	if err != nil {
+		return pluginlookup.Info{}, nil, fmt.Errorf("failed to run %s: %w", m.cfg.Name, err)
+	}
+
+	dec := json.NewDecoder(stdout)
+	var info pluginlookup.Info
+	if err := dec.Decode(&info); err != nil {
+		return pluginlookup.Info{}, nil, fmt.Errorf("%s: failed to read handshake: %w: %v", m.cfg.Name, pluginlookup.ErrProtocol, err)
+	}
+	if info.Protocol != pluginlookup.ProtocolVersion {
+		return pluginlookup.Info{}, nil, fmt.Errorf("%s speaks protocol %d, not %d: %w", m.cfg.Name, info.Protocol, pluginlookup.ProtocolVersion, pluginlookup.ErrProtocol)
+	}
+	return info, dec, nil
+}
@@ -0,0 +1,158 @@
+package wasmlookup_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ebi-yade/go-tempura"
+	"github.com/ebi-yade/go-tempura/pluginlookup"
+	"github.com/ebi-yade/go-tempura/wasmlookup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildProvider は testdata/provider を GOOS=wasip1 GOARCH=wasm でビルドします。ビルドはテストの実行ファイルごとに一度だけ行います。
+//
+// buildProvider builds testdata/provider with GOOS=wasip1 GOARCH=wasm. It builds only once per test executable.
+var buildProvider = sync.OnceValues(func() ([]byte, error) {
+	dir, err := os.MkdirTemp("", "wasmlookup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "provider.wasm")
+	cmd := exec.Command("go", "build", "-o", out, "./testdata/provider")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if b, err := cmd.CombinedOutput(); err != nil {
+		return nil, &exec.ExitError{ProcessState: cmd.ProcessState, Stderr: b}
+	}
+	return os.ReadFile(out)
+})
+
+func newModule(t *testing.T, cfg wasmlookup.Config) *wasmlookup.Module {
+	t.Helper()
+
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is required to build the test provider")
+	}
+	wasm, err := buildProvider()
+	require.NoError(t, err)
+	m, err := wasmlookup.New(context.Background(), wasm, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { m.Close(context.Background()) })
+	return m
+}
+
+func TestModule_Lookup(t *testing.T) {
+	t.Parallel()
+
+	m := newModule(t, wasmlookup.Config{
+		Name:    "provider",
+		Args:    []string{"first"},
+		Env:     []string{"GREETING=hello"},
+		Timeout: time.Second,
+	})
+	lookup := tempura.MultiLookup{
+		tempura.DotPrefix("wasm"): m.Lookup(),
+	}.BindContext(context.Background())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+		checkErr func(t *testing.T, err error)
+	}{
+		// ==================== VALID CASES ====================
+		{
+			name:     "string",
+			args:     []string{"wasm.DB_PASS"},
+			expected: "p4ss",
+		},
+		{
+			name:     "number",
+			args:     []string{"wasm.PORT"},
+			expected: json.Number("5432"),
+		},
+		{
+			name:     "environment variables",
+			args:     []string{"wasm.GREETING"},
+			expected: "hello",
+		},
+		{
+			name:     "arguments",
+			args:     []string{"wasm.ARG"},
+			expected: "first",
+		},
+		{
+			name:     "fallback",
+			args:     []string{"wasm.MISSING", "wasm.DB_PASS"},
+			expected: "p4ss",
+		},
+		// ==================== INVALID CASES ====================
+		{
+			name: "not found",
+			args: []string{"wasm.MISSING"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, tempura.ErrNotFound)
+			},
+		},
+		{
+			name: "provider error",
+			args: []string{"wasm.FAIL"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "provider: permission denied")
+			},
+		},
+		{
+			name: "no file system",
+			args: []string{"wasm.FILE"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "/etc/passwd")
+			},
+		},
+		{
+			name: "timeout",
+			args: []string{"wasm.LOOP"},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := lookup.FuncMapValue(tt.args...)
+			if tt.checkErr != nil {
+				tt.checkErr(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestModule_Info(t *testing.T) {
+	t.Parallel()
+
+	info, err := newModule(t, wasmlookup.Config{}).Info(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, pluginlookup.Info{Protocol: pluginlookup.ProtocolVersion, Description: "test provider"}, info)
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := wasmlookup.New(context.Background(), []byte("not wasm"), wasmlookup.Config{Name: "broken"})
+	assert.ErrorContains(t, err, "failed to compile broken")
+}